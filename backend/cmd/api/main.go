@@ -1,123 +1,216 @@
 package main
 
 import (
-	"log"
-	"net/http"
-
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"codefuture-backend/internal/auth"
 	"codefuture-backend/internal/config"
+	"codefuture-backend/internal/email"
 	"codefuture-backend/internal/handlers"
+	"codefuture-backend/internal/logging"
+	"codefuture-backend/internal/metrics"
 	"codefuture-backend/internal/middleware"
+	"codefuture-backend/internal/moderation"
+	"codefuture-backend/internal/router"
+	"codefuture-backend/internal/sandbox"
 	"codefuture-backend/internal/services"
 	"codefuture-backend/internal/store"
 )
 
 func main() {
+	// When started as the code execution sandbox's helper, this runs it and exits
+	sandbox.Init()
+
+	listRoutes := flag.Bool("routes", false, "print the route table and exit")
+	flag.Parse()
+
 	// 1. Load Configuration (Centralized)
 	cfg := config.LoadConfig()
 
+	// Everything logs through slog, tagged per request by RequestLogger;
+	// the standard log package is routed there too
+	logger, err := logging.New(os.Stderr, cfg.LogFormat, cfg.LogLevel)
+	if err != nil {
+		fatal("invalid logging configuration", "err", err)
+	}
+	slog.SetDefault(logger)
+
+	if *listRoutes {
+		// Handlers are only named, never called, so they need no dependencies
+		r := router.New()
+		registerRoutes(r, &handlers.Handler{}, middleware.NewAuthenticator(nil, nil), nil, cfg)
+		r.WriteTable(os.Stdout)
+		return
+	}
+
+	// Half a TLS setup would quietly serve plain HTTP
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		fatal("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+
 	// 2. Initialize Database
-	// We could use cfg.DatabasePath here but NewStore handles it internally for now.
-	// Since NewStore logic was slightly complex with path detection, let's stick to existing
-	// or refactor NewStore to accept path. For this iteration, existing NewStore is fine as
-	// it locates the same file config.go points to.
-	db := store.NewStore()
-	defer db.Close()
-
-	if err := db.Ping(); err != nil {
-		log.Printf("Warning: Database ping failed: %v", err)
+	// DATABASE_URL selects Postgres; when unset NewStore falls back to the local SQLite file.
+	db := store.NewStore(cfg.DatabaseURL)
+
+	// A database that is down at boot is migrated once it answers; /readyz
+	// reports the server unready until then
+	pingErr := db.Ping(context.Background())
+	if pingErr == nil {
+		slog.Info("connected to database", "dialect", db.Dialect())
+		if err := db.Migrate(); err != nil {
+			fatal("failed to migrate database", "err", err)
+		}
+		slog.Info("search backend", "backend", db.SearchBackend())
 	} else {
-		log.Println("Connected to Database")
-		db.InitSchema()
+		slog.Warn("database ping failed; migrating once it is reachable", "err", pingErr)
 	}
 
 	// 3. Initialize AI Service
-	if cfg.GeminiAPIKey == "" {
-		log.Fatal("GEMINI_API_KEY environment variable is required")
-	}
-	aiService, err := services.NewAIService(cfg.GeminiAPIKey)
+	provider, err := services.NewLLMProvider(cfg)
 	if err != nil {
-		log.Fatalf("Failed to initialize AI service: %v", err)
+		fatal("failed to initialize LLM provider", "err", err)
 	}
-	defer aiService.Close()
+	slog.Info("using LLM provider", "provider", provider.Name())
 
-	// 4. Initialize Handlers with dependencies
-	h := handlers.NewHandler(aiService, db, cfg)
+	aiService, err := services.NewAIService(provider)
+	if err != nil {
+		fatal("failed to initialize AI service", "err", err)
+	}
 
-	// 4. Register Routes
-	http.HandleFunc("/api/lesson-plan", middleware.OptionalAuthMiddleware(h.HandleLessonPlan))
-	http.HandleFunc("/api/courses", middleware.AuthMiddleware(h.HandleGetCourses))
-	http.HandleFunc("/api/chat", h.HandleChat)
-	http.HandleFunc("/api/execute", h.HandleExecute)
-	http.HandleFunc("/api/math", h.HandleMath)
-	http.HandleFunc("/api/signup", h.HandleSignup)
-	http.HandleFunc("/api/contact", middleware.OptionalAuthMiddleware(h.HandleContactSubmission))
-	http.HandleFunc("/api/login", h.HandleLogin)
-	http.HandleFunc("/api/auth/social-demo", h.HandleSocialLoginDemo)
-
-	// Real Social Auth
-	http.HandleFunc("/api/auth/google/login", h.HandleGoogleLogin)
-	http.HandleFunc("/api/auth/google/callback", h.HandleGoogleCallback)
-	http.HandleFunc("/api/auth/github/login", h.HandleGitHubLogin)
-	http.HandleFunc("/api/auth/github/callback", h.HandleGitHubCallback)
-
-	// Community
-	http.HandleFunc("/api/community/posts", middleware.OptionalAuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == "POST" {
-			// Enforce Auth for Create Post
-			if r.Context().Value(middleware.UserIDKey) == nil {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
-				return
-			}
-			h.HandleCreatePost(w, r)
-		} else {
-			// Public Read (GET)
-			h.HandleGetPosts(w, r)
+	// Code execution sandbox (learner code runs in isolated child processes)
+	executor := sandbox.NewExecutor(sandbox.Config{
+		WallTimeout:     cfg.SandboxTimeout,
+		CPUSeconds:      cfg.SandboxCPUSeconds,
+		MemoryMB:        cfg.SandboxMemoryMB,
+		MaxProcesses:    cfg.SandboxMaxProcesses,
+		MaxConcurrent:   cfg.SandboxMaxConcurrent,
+		QueueTimeout:    cfg.SandboxQueueTimeout,
+		MaxOutputBytes:  cfg.SandboxMaxOutputKB * 1024,
+		AllowUnisolated: cfg.SandboxAllowUnisolated,
+		ReadOnlyPaths:   cfg.SandboxReadOnlyPaths,
+		GoCacheDir:      cfg.SandboxGoCache,
+	})
+	slog.Info("code execution", "mode", cfg.ExecutionMode)
+
+	// Access tokens are short-lived and tied to a session; refresh tokens renew them
+	tokens := auth.NewTokenManager(cfg.JWTSecret, cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	authn := middleware.NewAuthenticator(tokens, db)
+
+	// New community posts and comments are screened before they go public
+	var screener *moderation.Screener
+	if cfg.ModerationEnabled {
+		var classifier moderation.Classifier
+		if cfg.ModerationLLM {
+			classifier = aiService
 		}
-	}))
-
-	// Roadmap
-	http.HandleFunc("/api/roadmap", middleware.AuthMiddleware(h.HandleGetRoadmap))
-	http.HandleFunc("/api/roadmap/progress", middleware.AuthMiddleware(h.HandleUpdateProgress))
-	// New Custom Roadmap Routes
-	http.HandleFunc("/api/roadmap/generate", middleware.AuthMiddleware(h.HandleGenerateCustomRoadmap))
-	http.HandleFunc("/api/roadmap/view", h.HandleGetRoadmapByID) // Public/Hybrid
+		screener = moderation.NewScreener(moderation.Config{
+			Threshold:         cfg.ModerationThreshold,
+			MaxLinks:          cfg.ModerationMaxLinks,
+			Blocklist:         cfg.ModerationBlocklist,
+			DuplicateWindow:   cfg.ModerationDuplicateWindow,
+			ClassifierTimeout: cfg.ModerationLLMTimeout,
+		}, db, classifier)
+		slog.Info("community moderation enabled", "llm", cfg.ModerationLLM, "threshold", cfg.ModerationThreshold)
+	}
 
-	// 5. Start Server with CORS
-	log.Println("Backend server running on :8081")
+	// Outgoing email is queued in the database and sent in the background
+	var transport email.Transport
+	switch cfg.EmailTransport {
+	case "smtp":
+		transport = &email.SMTPTransport{Host: cfg.SMTPHost, Port: cfg.SMTPPort, Username: cfg.SMTPUser, Password: cfg.SMTPPass}
+	case "file":
+		transport = &email.FileTransport{Dir: cfg.EmailFileDir}
+	case "log":
+		transport = email.LogTransport{}
+	default:
+		fatal("unknown EMAIL_TRANSPORT (want smtp, file or log)", "transport", cfg.EmailTransport)
+	}
+	mailer := email.NewMailer(email.Config{
+		From:         cfg.EmailFrom,
+		MaxAttempts:  cfg.EmailMaxAttempts,
+		BaseBackoff:  cfg.EmailRetryBase,
+		MaxBackoff:   cfg.EmailRetryMax,
+		PollInterval: cfg.EmailPollInterval,
+		BatchSize:    cfg.EmailBatchSize,
+		SendTimeout:  cfg.EmailSendTimeout,
+	}, db, transport)
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	workers.Go(func() { mailer.Run(workerCtx) })
+	if pingErr != nil {
+		workers.Go(func() { migrateWhenReachable(workerCtx, db) })
+	}
+	slog.Info("email transport", "transport", mailer.TransportName())
+	if cfg.MetricsEnabled {
+		metrics.EmailQueue(db.CountOutboxEmails)
+	}
 
-	// Wrap the default mux with CORS middleware
-	handler := corsMiddleware(http.DefaultServeMux)
+	// 4. Initialize Handlers with dependencies
+	h := handlers.NewHandler(aiService, db, executor, tokens, screener, mailer, cfg)
 
-	if err := http.ListenAndServe(":8081", handler); err != nil {
-		log.Fatal(err)
+	// 4. Register Routes
+	r := router.New()
+	registerRoutes(r, h, authn, db, cfg)
+
+	// 5. Start Server with CORS, until SIGTERM or Ctrl-C
+	mux := r.Handler()
+	cors, err := middleware.NewCORS(middleware.CORSConfig{
+		AllowedOrigins:   cfg.CORSAllowedOrigins,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		AllowedHeaders:   cfg.CORSAllowedHeaders,
+		ExposedHeaders: []string{middleware.RequestIDHeader, "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining",
+			"X-AI-Quota-Daily-Limit", "X-AI-Quota-Daily-Remaining", "X-AI-Quota-Monthly-Limit", "X-AI-Quota-Monthly-Remaining"},
+		MaxAge: cfg.CORSMaxAge,
+	}, r)
+	if err != nil {
+		fatal("invalid CORS configuration", "err", err)
+	}
+	corsPolicies(cors)
+	srv := newServer(cfg, middleware.RequestLogger(logger, cfg.AccessLog)(cors.Handler(mux)))
+	err = serve(srv, cfg)
+
+	// 6. Shut down in reverse order: background workers, then the AI client,
+	// then the store everything writes to
+	stopWorkers()
+	workers.Wait()
+	aiService.Close()
+	db.Close()
+	if err != nil {
+		fatal("server failed", "err", err)
 	}
+	slog.Info("shutdown complete")
 }
 
-// corsMiddleware adds CORS headers to all responses
-// corsMiddleware adds CORS headers to all responses
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Allow requests from both Vite dev server and other ports
-		origin := r.Header.Get("Origin")
-
-		// For development, we'll be permissive and reflect the origin if it exists
-		if origin != "" {
-			w.Header().Set("Access-Control-Allow-Origin", origin)
-		} else {
-			// Fallback for tools without Origin header (like curl) - though browsers always send it
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-		}
-
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Title")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
-
-		// Handle preflight requests
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusOK)
+// migrateWhenReachable retries until the database answers and the pending
+// migrations have been applied, or ctx is done.
+func migrateWhenReachable(ctx context.Context, db *store.Store) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
 			return
+		case <-ticker.C:
+		}
+		if err := db.Ping(ctx); err != nil {
+			continue
 		}
+		if err := db.Migrate(); err != nil {
+			slog.Error("failed to migrate database", "err", err)
+			continue
+		}
+		slog.Info("connected to database and migrated", "dialect", db.Dialect(), "search", db.SearchBackend())
+		return
+	}
+}
 
-		next.ServeHTTP(w, r)
-	})
+// fatal logs msg as an error and exits.
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
 }
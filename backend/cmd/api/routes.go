@@ -0,0 +1,170 @@
+package main
+
+import (
+	"net/http"
+
+	"codefuture-backend/internal/config"
+	"codefuture-backend/internal/handlers"
+	"codefuture-backend/internal/middleware"
+	"codefuture-backend/internal/models"
+	"codefuture-backend/internal/router"
+)
+
+// registerRoutes declares the whole API. Groups decide who may call a
+// route: public, optionally signed in, signed in, or holding a permission.
+// `go run ./cmd/api -routes` prints the resulting table.
+func registerRoutes(r *router.Router, h *handlers.Handler, authn *middleware.Authenticator, quotas middleware.QuotaStore, cfg *config.Config) {
+	// Routes that cost LLM calls or sandbox time or send mail are rate
+	// limited per IP and per user, and AI calls count against each user's
+	// daily and monthly quota
+	limiter := func(perIP, perUser config.RateLimit) router.Middleware {
+		if !cfg.RateLimitEnabled {
+			return func(next http.HandlerFunc) http.HandlerFunc { return next }
+		}
+		return middleware.NewRateLimiter(middleware.Rate(perIP), middleware.Rate(perUser), cfg.TrustProxy).Limit
+	}
+	chatLimit := limiter(cfg.RateLimitChatIP, cfg.RateLimitChatUser)
+	aiQuota := middleware.NewAIQuota(quotas, cfg.AIDailyQuota, cfg.AIMonthlyQuota, cfg.AIAllowAnonymous).Limit
+	// Visitors can always write in, limited per IP; the reply the LLM drafts
+	// for signed-in users counts against their quota
+	contactQuota := middleware.NewAIQuota(quotas, cfg.AIDailyQuota, cfg.AIMonthlyQuota, true).Limit
+	// Posts and comments are classified by the LLM when MODERATION_LLM is on
+	community := []router.Middleware{limiter(cfg.RateLimitCommunityIP, cfg.RateLimitCommunityUser)}
+	if cfg.ModerationEnabled && cfg.ModerationLLM {
+		community = append(community, aiQuota)
+	}
+	// Running code and grading a submission share one budget, since both
+	// spend sandbox time
+	sandboxLimit := limiter(cfg.RateLimitExecuteIP, cfg.RateLimitExecuteUser)
+	execute := []router.Middleware{sandboxLimit}
+	if cfg.ExecutionMode == "llm" {
+		execute = append(execute, aiQuota)
+	}
+
+	// Probes and metrics for the platform running the server
+	ops := r.Group("")
+	ops.GET("/healthz", h.HandleHealthz)
+	ops.GET("/readyz", h.HandleReadyz)
+	if cfg.MetricsEnabled {
+		ops.GET("/metrics", h.HandleMetrics)
+	}
+
+	api := r.Group("/api")
+	optional := api.Group("", authn.OptionalAuthMiddleware)
+	authed := api.Group("", authn.AuthMiddleware)
+
+	// Learning
+	optional.POST("/lesson-plan", h.HandleLessonPlan, limiter(cfg.RateLimitLessonIP, cfg.RateLimitLessonUser), aiQuota)
+	optional.POST("/chat", h.HandleChat, chatLimit, aiQuota)
+	optional.POST("/chat/stream", h.HandleChatStream, chatLimit, aiQuota)
+	optional.POST("/execute", h.HandleExecute, execute...)
+	api.POST("/math", h.HandleMath)
+	authed.GET("/courses", h.HandleGetCourses)
+	authed.DELETE("/courses/{id}", h.HandleDeleteCourse)
+	authed.POST("/lessons/{id}/submit", h.HandleSubmitLesson, sandboxLimit)
+	authed.GET("/lessons/{id}/attempts", h.HandleGetLessonAttempts)
+	authed.GET("/me/ai-quota", h.HandleMyAIQuota)
+
+	// Roadmaps
+	authed.GET("/roadmap", h.HandleGetRoadmap)
+	authed.POST("/roadmap/progress", h.HandleUpdateProgress)
+	authed.POST("/roadmap/generate", h.HandleGenerateCustomRoadmap, limiter(cfg.RateLimitRoadmapIP, cfg.RateLimitRoadmapUser), aiQuota)
+	api.GET("/roadmaps/{id}", h.HandleGetRoadmapByID)
+
+	// Contact & support tickets
+	optional.POST("/contact", h.HandleContactSubmission, limiter(cfg.RateLimitContactIP, cfg.RateLimitContactUser), contactQuota)
+	authed.GET("/me/tickets", h.HandleMyTickets)
+	authed.GET("/me/tickets/{id}", h.HandleMyTicket)
+	authed.POST("/me/tickets/{id}/messages", h.HandleMyTicketReply)
+
+	// Accounts
+	api.POST("/signup", h.HandleSignup)
+	api.POST("/login", h.HandleLogin)
+	api.POST("/auth/forgot-password", h.HandleForgotPassword, limiter(cfg.RateLimitAccountIP, config.RateLimit{}))
+	api.POST("/auth/reset-password", h.HandleResetPassword)
+	api.POST("/auth/verify-email", h.HandleVerifyEmail)
+	authed.POST("/auth/resend-verification", h.HandleResendVerification, limiter(cfg.RateLimitAccountIP, config.RateLimit{}))
+
+	// Sessions
+	api.POST("/auth/refresh", h.HandleRefresh)
+	authed.POST("/auth/logout", h.HandleLogout)
+	authed.POST("/auth/logout-all", h.HandleLogoutAll)
+	authed.GET("/auth/sessions", h.HandleListSessions)
+	authed.DELETE("/auth/sessions/{id}", h.HandleRevokeSession)
+
+	// Real Social Auth
+	api.GET("/auth/google/login", h.HandleGoogleLogin)
+	api.GET("/auth/google/callback", h.HandleGoogleCallback)
+	api.GET("/auth/github/login", h.HandleGitHubLogin)
+	api.GET("/auth/github/callback", h.HandleGitHubCallback)
+	api.POST("/auth/exchange", h.HandleOAuthExchange)
+	authed.GET("/auth/identities", h.HandleListIdentities)
+
+	// Community
+	optional.GET("/community/posts", h.HandleGetPosts)
+	authed.POST("/community/posts", h.HandleCreatePost, community...)
+	authed.POST("/community/posts/{id}/reactions/{type}", h.HandleReaction)
+	authed.PUT("/community/posts/{id}/reactions/{type}", h.HandleReaction)
+	authed.DELETE("/community/posts/{id}/reactions/{type}", h.HandleReaction)
+	api.GET("/community/posts/{id}/comments", h.HandleGetComments)
+	authed.POST("/community/posts/{id}/comments", h.HandleCreateComment, community...)
+	authed.PUT("/community/comments/{id}", h.HandleUpdateComment, community...)
+	authed.DELETE("/community/comments/{id}", h.HandleDeleteComment)
+	authed.POST("/community/posts/{id}/report", h.HandleReportPost)
+
+	// Search
+	optional.GET("/search", h.HandleSearch)
+
+	// Moderation (moderators and admins)
+	moderation := api.Group("/moderation", authn.Require(models.PermModerateCommunity))
+	moderation.GET("/queue", h.HandleModerationQueue)
+	moderation.GET("/pending", h.HandlePendingContent)
+	moderation.POST("/posts/{id}", h.HandleModeratePost)
+	moderation.POST("/comments/{id}", h.HandleModerateComment)
+	moderation.POST("/users/{id}/suspend", h.HandleSuspendUser)
+	moderation.DELETE("/users/{id}/suspend", h.HandleLiftSuspension)
+	api.GET("/moderation/audit", h.HandleAuditLog, authn.Require(models.PermViewAuditLog))
+
+	// Admin
+	admin := api.Group("/admin")
+	users := admin.Group("/users", authn.Require(models.PermManageUsers))
+	users.GET("", h.HandleAdminListUsers)
+	users.GET("/{id}", h.HandleAdminGetUser)
+	users.PUT("/{id}/role", h.HandleAdminChangeRole)
+
+	plans := admin.Group("/lesson-plans", authn.Require(models.PermManageLessonPlans))
+	plans.GET("", h.HandleAdminListLessonPlans)
+	plans.GET("/{id}", h.HandleAdminGetLessonPlan)
+	plans.DELETE("/{id}", h.HandleAdminDeleteLessonPlan)
+
+	posts := admin.Group("/posts", authn.Require(models.PermManagePosts))
+	posts.GET("", h.HandleAdminListPosts)
+	posts.GET("/{id}", h.HandleAdminGetPost)
+	posts.POST("/{id}", h.HandleModeratePost)
+
+	contact := admin.Group("", authn.Require(models.PermViewContact))
+	contact.GET("/contact-submissions", h.HandleAdminListContact)
+	contact.GET("/contact-submissions/{id}", h.HandleAdminGetContact)
+	contact.GET("/tickets", h.HandleAdminListContact)
+	contact.GET("/tickets/{id}", h.HandleAdminGetTicket)
+
+	tickets := admin.Group("/tickets/{id}", authn.Require(models.PermManageTickets))
+	tickets.PUT("", h.HandleAdminUpdateTicket)
+	tickets.PUT("/suggested-reply", h.HandleAdminEditSuggestedReply)
+	tickets.POST("/messages", h.HandleAdminTicketMessage)
+	tickets.POST("/send-reply", h.HandleAdminSendReply)
+
+	emails := admin.Group("/emails", authn.Require(models.PermManageEmail))
+	emails.GET("", h.HandleAdminListEmails)
+	emails.POST("/{id}/retry", h.HandleAdminRetryEmail)
+
+	admin.GET("/audit", h.HandleAuditLog, authn.Require(models.PermViewAuditLog))
+}
+
+// corsPolicies narrows CORS for routes that browsers never call from
+// another origin with fetch.
+func corsPolicies(c *middleware.CORS) {
+	// Social sign-in is a full-page redirect through the provider
+	c.Route("/api/auth/google/", middleware.CORSPolicy{Disabled: true})
+	c.Route("/api/auth/github/", middleware.CORSPolicy{Disabled: true})
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"codefuture-backend/internal/config"
+	"codefuture-backend/internal/store"
+)
+
+const usage = `Usage: migrate <command>
+
+Commands:
+  status         list migrations and whether they are applied
+  up             apply all pending migrations
+  down [n]       roll back the last n migrations (default 1)
+  to <version>   migrate up or down to the given version (0 = empty schema)
+  reindex        rebuild the search index from posts, courses and roadmaps
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cfg := config.LoadConfig()
+	db := store.NewStore(cfg.DatabaseURL)
+	defer db.Close()
+
+	if err := db.Ping(context.Background()); err != nil {
+		log.Fatalf("Database ping failed: %v", err)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "status":
+		err = printStatus(db)
+	case "up":
+		err = db.Migrate()
+	case "down":
+		steps := 1
+		if len(os.Args) > 2 {
+			steps, err = strconv.Atoi(os.Args[2])
+			if err != nil || steps < 1 {
+				log.Fatalf("Invalid step count %q", os.Args[2])
+			}
+		}
+		err = db.MigrateDown(steps)
+	case "to":
+		if len(os.Args) < 3 {
+			fmt.Fprint(os.Stderr, usage)
+			os.Exit(2)
+		}
+		version, convErr := strconv.Atoi(os.Args[2])
+		if convErr != nil || version < 0 {
+			log.Fatalf("Invalid version %q", os.Args[2])
+		}
+		err = db.MigrateTo(version)
+	case "reindex":
+		err = db.RebuildSearchIndex()
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+}
+
+func printStatus(db *store.Store) error {
+	status, err := db.MigrationStatus()
+	if err != nil {
+		return err
+	}
+	for _, m := range status {
+		state := "pending"
+		if m.Applied && m.AppliedAt != nil {
+			state = "applied " + m.AppliedAt.Format("2006-01-02 15:04:05")
+		} else if m.Applied {
+			state = "applied"
+		}
+		fmt.Printf("%04d  %-40s %s\n", m.Version, m.Name, state)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+
+	"codefuture-backend/internal/config"
+	"codefuture-backend/internal/models"
+	"codefuture-backend/internal/store"
+)
+
+const usage = `Usage: setrole <email> <learner|moderator|admin>
+
+Changes a user's role. Use it to appoint the first admin, who can then
+moderate the community.
+`
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	email, role := strings.TrimSpace(os.Args[1]), os.Args[2]
+	if !slices.Contains(models.Roles, role) {
+		log.Fatalf("Unknown role %q", role)
+	}
+
+	cfg := config.LoadConfig()
+	db := store.NewStore(cfg.DatabaseURL)
+	defer db.Close()
+
+	if err := db.Migrate(); err != nil {
+		log.Fatalf("Migration failed: %v", err)
+	}
+
+	user, err := db.GetUserByEmail(email)
+	if err != nil {
+		log.Fatalf("Failed to look up user: %v", err)
+	}
+	if user == nil {
+		log.Fatalf("No user with email %s", email)
+	}
+	if _, err := db.SetUserRole(user.ID, role); err != nil {
+		log.Fatalf("Failed to set role: %v", err)
+	}
+	fmt.Printf("%s is now %s (was %s)\n", user.Email, role, user.Role)
+}
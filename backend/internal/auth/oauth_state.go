@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OAuthState is what the login endpoint remembers about an OAuth round trip.
+// It travels in a signed cookie; only Nonce is sent to the provider as the
+// state parameter, and Verifier is the PKCE code verifier.
+type OAuthState struct {
+	Provider  string `json:"p"`
+	Nonce     string `json:"n"`
+	Verifier  string `json:"v"`
+	ExpiresAt int64  `json:"e"`
+}
+
+// NewOAuthState starts an OAuth login for provider.
+func NewOAuthState(provider, verifier string, ttl time.Duration) (*OAuthState, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return &OAuthState{
+		Provider:  provider,
+		Nonce:     base64.RawURLEncoding.EncodeToString(b),
+		Verifier:  verifier,
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}, nil
+}
+
+// SignOAuthState encodes st as payload.signature for the state cookie.
+func (m *TokenManager) SignOAuthState(st *OAuthState) (string, error) {
+	payload, err := json.Marshal(st)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + m.sign(encoded), nil
+}
+
+// VerifyOAuthState checks the cookie's signature and expiry, that it was
+// issued for provider, and that the provider echoed back its nonce.
+func (m *TokenManager) VerifyOAuthState(cookie, provider, state string) (*OAuthState, error) {
+	encoded, sig, ok := strings.Cut(cookie, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(m.sign(encoded))) {
+		return nil, fmt.Errorf("%w: bad state signature", ErrInvalidToken)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	var st OAuthState
+	if err := json.Unmarshal(payload, &st); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if time.Now().Unix() > st.ExpiresAt {
+		return nil, fmt.Errorf("%w: state expired", ErrInvalidToken)
+	}
+	if st.Provider != provider || st.Nonce == "" || subtle.ConstantTimeCompare([]byte(st.Nonce), []byte(state)) != 1 {
+		return nil, fmt.Errorf("%w: state mismatch", ErrInvalidToken)
+	}
+	return &st, nil
+}
+
+func (m *TokenManager) sign(s string) string {
+	mac := hmac.New(sha256.New, m.secret)
+	mac.Write([]byte("oauth-state:" + s))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
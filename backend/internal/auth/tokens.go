@@ -0,0 +1,104 @@
+// Package auth issues and verifies the credentials behind a login session:
+// short-lived HS256 access tokens that name their session, and opaque
+// tokens (refresh tokens, emailed links) that are only ever stored as a
+// SHA-256 hash.
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the access token claims. SessionID ties the token to a row in
+// the sessions table so it can be revoked before it expires.
+type Claims struct {
+	UserID    int `json:"user_id"`
+	SessionID int `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+type TokenManager struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewTokenManager(secret string, accessTTL, refreshTTL time.Duration) *TokenManager {
+	if accessTTL <= 0 {
+		accessTTL = 15 * time.Minute
+	}
+	if refreshTTL <= 0 {
+		refreshTTL = 30 * 24 * time.Hour
+	}
+	return &TokenManager{secret: []byte(secret), accessTTL: accessTTL, refreshTTL: refreshTTL}
+}
+
+func (m *TokenManager) AccessTTL() time.Duration  { return m.accessTTL }
+func (m *TokenManager) RefreshTTL() time.Duration { return m.refreshTTL }
+
+// IssueAccessToken signs a token for userID bound to sessionID.
+func (m *TokenManager) IssueAccessToken(userID, sessionID int) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(m.accessTTL)
+	claims := Claims{
+		UserID:    userID,
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// ParseAccessToken verifies the signature and expiry. Tokens without a
+// session (issued before sessions existed) are rejected.
+func (m *TokenManager) ParseAccessToken(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(t *jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.UserID == 0 || claims.SessionID == 0 {
+		return nil, fmt.Errorf("%w: missing user or session", ErrInvalidToken)
+	}
+	return claims, nil
+}
+
+// NewRefreshToken returns a random refresh token and the hash to store.
+func NewRefreshToken() (token, hash string, err error) {
+	return NewOpaqueToken()
+}
+
+// NewOpaqueToken returns a random URL-safe token and the hash to store, for
+// credentials that are looked up rather than verified (refresh tokens, links
+// mailed to the user).
+func NewOpaqueToken() (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(b)
+	return token, HashToken(token), nil
+}
+
+// HashToken is the stored form of an opaque token.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
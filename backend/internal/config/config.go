@@ -2,6 +2,9 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"golang.org/x/oauth2"
@@ -13,17 +16,119 @@ type Config struct {
 	Port              string
 	GeminiAPIKey      string
 	JWTSecret         string
+	AccessTokenTTL    time.Duration
+	RefreshTokenTTL   time.Duration
 	FrontendURL       string
 	DatabasePath      string
+	DatabaseURL       string // postgres://... selects Postgres; empty or a file path uses SQLite
 	GoogleOAuthConfig *oauth2.Config
 	GitHubOAuthConfig *oauth2.Config
 
+	// Server Config
+	BindAddress       string // interface to listen on; empty means all
+	TLSCertFile       string // serve HTTPS when both TLS files are set
+	TLSKeyFile        string
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration // whole request, body included
+	WriteTimeout      time.Duration // lifted for streamed chat replies
+	IdleTimeout       time.Duration
+	ShutdownTimeout   time.Duration // how long in-flight requests get to finish on SIGTERM
+
+	// Logging Config
+	LogFormat string // "text" or "json"
+	LogLevel  string // debug, info, warn or error
+	AccessLog bool   // log every request with its route, status and latency
+
+	// Health and Metrics Config
+	MetricsEnabled bool // serve Prometheus metrics on /metrics, unauthenticated
+	ReadyzCheckLLM bool // /readyz also checks the LLM provider is reachable
+
+	// CORS Config
+	CORSAllowedOrigins   []string // exact origins or wildcard subdomains like https://*.example.com
+	CORSAllowCredentials bool
+	CORSAllowedHeaders   []string
+	CORSMaxAge           time.Duration
+
+	// LLM Config
+	LLMProvider   string // openrouter (default), openai, ollama or fake
+	LLMAPIKey     string
+	LLMModel      string
+	LLMBaseURL    string
+	LLMRecordPath string // append every LLM exchange to this JSONL file
+	LLMReplayPath string // recordings replayed by the fake provider
+
+	// Code Execution Config
+	ExecutionMode          string // sandbox (default) or llm (simulated, fallback only)
+	SandboxTimeout         time.Duration
+	SandboxCPUSeconds      int
+	SandboxMemoryMB        int
+	SandboxMaxOutputKB     int
+	SandboxAllowUnisolated bool
+	SandboxMaxProcesses    int
+	SandboxMaxConcurrent   int // runs executing at once; the rest queue
+	SandboxQueueTimeout    time.Duration
+	SandboxReadOnlyPaths   []string // toolchains outside /usr, /bin and /lib
+	SandboxGoCache         string   // Go build cache the server builds the standard library into
+
 	// SMTP Config
 	SMTPHost   string
 	SMTPPort   string
 	SMTPUser   string
 	SMTPPass   string
 	AdminEmail string
+
+	// Email Outbox Config
+	EmailTransport    string // "smtp", "file" or "log"
+	EmailFrom         string
+	EmailFileDir      string // maildir for the file transport
+	EmailMaxAttempts  int
+	EmailRetryBase    time.Duration
+	EmailRetryMax     time.Duration
+	EmailPollInterval time.Duration
+	EmailBatchSize    int
+	EmailSendTimeout  time.Duration
+
+	// Account Config
+	PasswordResetTTL     time.Duration
+	EmailVerificationTTL time.Duration
+	AccountEmailCooldown time.Duration // least time between reset or verification emails to one user
+	RequireVerifiedEmail bool          // only verified users may post in the community
+
+	// Moderation Config
+	ModerationEnabled         bool // screen new posts and comments before publishing them
+	ModerationLLM             bool // also ask the LLM provider to classify them
+	ModerationThreshold       float64
+	ModerationMaxLinks        int
+	ModerationBlocklist       []string
+	ModerationDuplicateWindow time.Duration
+	ModerationLLMTimeout      time.Duration
+
+	// Rate Limit and Quota Config
+	RateLimitEnabled       bool
+	TrustProxy             bool // take client IPs from X-Forwarded-For
+	RateLimitChatIP        RateLimit
+	RateLimitChatUser      RateLimit
+	RateLimitExecuteIP     RateLimit
+	RateLimitExecuteUser   RateLimit
+	RateLimitLessonIP      RateLimit
+	RateLimitLessonUser    RateLimit
+	RateLimitRoadmapIP     RateLimit
+	RateLimitRoadmapUser   RateLimit
+	RateLimitContactIP     RateLimit
+	RateLimitContactUser   RateLimit
+	RateLimitAccountIP     RateLimit // forgot password and resend verification
+	RateLimitCommunityIP   RateLimit // new posts and comments, and comment edits
+	RateLimitCommunityUser RateLimit
+	AIDailyQuota           int // AI requests per user per UTC day; 0 means unlimited
+	AIMonthlyQuota         int
+	AIAllowAnonymous       bool // signed-out visitors may use the AI routes, limited per IP only
+}
+
+// RateLimit is a number of requests per period, written like "30/1m". The
+// zero RateLimit is unlimited.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
 }
 
 func LoadConfig() *Config {
@@ -38,7 +143,67 @@ func LoadConfig() *Config {
 		JWTSecret:    getEnv("JWT_SECRET", "default-dev-secret"), // Fallback for dev
 		FrontendURL:  getEnv("FRONTEND_URL", "http://localhost:3000"),
 		DatabasePath: getDBPath(),
+		DatabaseURL:  os.Getenv("DATABASE_URL"),
+	}
+
+	// Server Configuration
+	if cfg.Port == "" {
+		cfg.Port = "8081"
+	}
+	cfg.BindAddress = os.Getenv("BIND_ADDRESS")
+	cfg.TLSCertFile = os.Getenv("TLS_CERT_FILE")
+	cfg.TLSKeyFile = os.Getenv("TLS_KEY_FILE")
+	cfg.ReadHeaderTimeout = getEnvDuration("HTTP_READ_HEADER_TIMEOUT", 10*time.Second)
+	cfg.ReadTimeout = getEnvDuration("HTTP_READ_TIMEOUT", 30*time.Second)
+	cfg.WriteTimeout = getEnvDuration("HTTP_WRITE_TIMEOUT", 3*time.Minute)
+	cfg.IdleTimeout = getEnvDuration("HTTP_IDLE_TIMEOUT", 2*time.Minute)
+	cfg.ShutdownTimeout = getEnvDuration("SHUTDOWN_TIMEOUT", 30*time.Second)
+
+	// Logging Configuration
+	cfg.LogFormat = getEnv("LOG_FORMAT", "text")
+	cfg.LogLevel = getEnv("LOG_LEVEL", "info")
+	cfg.AccessLog = getEnvBool("ACCESS_LOG", true)
+
+	// Health and Metrics Configuration
+	cfg.MetricsEnabled = getEnvBool("METRICS_ENABLED", false)
+	cfg.ReadyzCheckLLM = getEnvBool("READYZ_CHECK_LLM", false)
+
+	// CORS Configuration (browsers may only call the API from these origins)
+	cfg.CORSAllowedOrigins = getEnvList("CORS_ALLOWED_ORIGINS")
+	if len(cfg.CORSAllowedOrigins) == 0 {
+		cfg.CORSAllowedOrigins = []string{cfg.FrontendURL}
+	}
+	cfg.CORSAllowCredentials = getEnvBool("CORS_ALLOW_CREDENTIALS", true)
+	cfg.CORSAllowedHeaders = getEnvList("CORS_ALLOWED_HEADERS")
+	if len(cfg.CORSAllowedHeaders) == 0 {
+		cfg.CORSAllowedHeaders = []string{"Content-Type", "Authorization"}
 	}
+	cfg.CORSMaxAge = getEnvDuration("CORS_MAX_AGE", 10*time.Minute)
+
+	// Session Configuration
+	cfg.AccessTokenTTL = getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute)
+	cfg.RefreshTokenTTL = getEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+
+	// LLM Configuration (GEMINI_API_KEY kept as a fallback for existing .env files)
+	cfg.LLMProvider = getEnv("LLM_PROVIDER", "openrouter")
+	cfg.LLMAPIKey = getEnv("LLM_API_KEY", cfg.GeminiAPIKey)
+	cfg.LLMModel = os.Getenv("LLM_MODEL")
+	cfg.LLMBaseURL = os.Getenv("LLM_BASE_URL")
+	cfg.LLMRecordPath = os.Getenv("LLM_RECORD_PATH")
+	cfg.LLMReplayPath = os.Getenv("LLM_REPLAY_PATH")
+
+	// Code Execution Configuration
+	cfg.ExecutionMode = getEnv("EXECUTION_MODE", "sandbox")
+	cfg.SandboxTimeout = getEnvDuration("SANDBOX_TIMEOUT", 5*time.Second)
+	cfg.SandboxCPUSeconds = getEnvInt("SANDBOX_CPU_SECONDS", 3)
+	cfg.SandboxMemoryMB = getEnvInt("SANDBOX_MEMORY_MB", 256)
+	cfg.SandboxMaxOutputKB = getEnvInt("SANDBOX_MAX_OUTPUT_KB", 64)
+	cfg.SandboxAllowUnisolated = getEnvBool("SANDBOX_ALLOW_UNISOLATED", false)
+	cfg.SandboxMaxProcesses = getEnvInt("SANDBOX_MAX_PROCESSES", 64)
+	cfg.SandboxMaxConcurrent = getEnvInt("SANDBOX_MAX_CONCURRENT", 4)
+	cfg.SandboxQueueTimeout = getEnvDuration("SANDBOX_QUEUE_TIMEOUT", 10*time.Second)
+	cfg.SandboxReadOnlyPaths = getEnvList("SANDBOX_READONLY_PATHS")
+	cfg.SandboxGoCache = getEnv("SANDBOX_GO_CACHE", "")
 
 	// OAuth Configurations
 	callbackBase := getEnv("CALLBACK_URL_BASE", "http://localhost:8081/api/auth")
@@ -50,6 +215,65 @@ func LoadConfig() *Config {
 	cfg.SMTPPass = os.Getenv("SMTP_PASS")
 	cfg.AdminEmail = getEnv("ADMIN_EMAIL", "support@codeanyone.io")
 
+	// Email is queued and sent in the background; without SMTP credentials
+	// it goes to the log, as it always has
+	cfg.EmailTransport = os.Getenv("EMAIL_TRANSPORT")
+	if cfg.EmailTransport == "" {
+		cfg.EmailTransport = "log"
+		if cfg.SMTPUser != "" && cfg.SMTPPass != "" {
+			cfg.EmailTransport = "smtp"
+		}
+	}
+	cfg.EmailFrom = os.Getenv("EMAIL_FROM")
+	if cfg.EmailFrom == "" {
+		cfg.EmailFrom = cfg.SMTPUser
+	}
+	if cfg.EmailFrom == "" {
+		cfg.EmailFrom = cfg.AdminEmail
+	}
+	cfg.EmailFileDir = getEnv("EMAIL_FILE_DIR", "./mail")
+	cfg.EmailMaxAttempts = getEnvInt("EMAIL_MAX_ATTEMPTS", 8)
+	cfg.EmailRetryBase = getEnvDuration("EMAIL_RETRY_BASE", 30*time.Second)
+	cfg.EmailRetryMax = getEnvDuration("EMAIL_RETRY_MAX", time.Hour)
+	cfg.EmailPollInterval = getEnvDuration("EMAIL_POLL_INTERVAL", 10*time.Second)
+	cfg.EmailBatchSize = getEnvInt("EMAIL_BATCH_SIZE", 20)
+	cfg.EmailSendTimeout = getEnvDuration("EMAIL_SEND_TIMEOUT", 30*time.Second)
+
+	// Account Configuration
+	cfg.PasswordResetTTL = getEnvDuration("PASSWORD_RESET_TTL", time.Hour)
+	cfg.EmailVerificationTTL = getEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour)
+	cfg.AccountEmailCooldown = getEnvDuration("ACCOUNT_EMAIL_COOLDOWN", 2*time.Minute)
+	cfg.RequireVerifiedEmail = getEnvBool("REQUIRE_VERIFIED_EMAIL", false)
+
+	// Moderation Configuration
+	cfg.ModerationEnabled = getEnvBool("MODERATION_ENABLED", true)
+	cfg.ModerationLLM = getEnvBool("MODERATION_LLM", false)
+	cfg.ModerationThreshold = getEnvFloat("MODERATION_THRESHOLD", 0.7)
+	cfg.ModerationMaxLinks = getEnvInt("MODERATION_MAX_LINKS", 3)
+	cfg.ModerationBlocklist = getEnvList("MODERATION_BLOCKLIST")
+	cfg.ModerationDuplicateWindow = getEnvDuration("MODERATION_DUPLICATE_WINDOW", 24*time.Hour)
+	cfg.ModerationLLMTimeout = getEnvDuration("MODERATION_LLM_TIMEOUT", 10*time.Second)
+
+	// Rate Limit and Quota Configuration
+	cfg.RateLimitEnabled = getEnvBool("RATE_LIMIT_ENABLED", true)
+	cfg.TrustProxy = getEnvBool("TRUST_PROXY", false)
+	cfg.RateLimitChatIP = getEnvRate("RATE_LIMIT_CHAT_IP", RateLimit{60, time.Minute})
+	cfg.RateLimitChatUser = getEnvRate("RATE_LIMIT_CHAT_USER", RateLimit{20, time.Minute})
+	cfg.RateLimitExecuteIP = getEnvRate("RATE_LIMIT_EXECUTE_IP", RateLimit{60, time.Minute})
+	cfg.RateLimitExecuteUser = getEnvRate("RATE_LIMIT_EXECUTE_USER", RateLimit{30, time.Minute})
+	cfg.RateLimitLessonIP = getEnvRate("RATE_LIMIT_LESSON_PLAN_IP", RateLimit{10, time.Hour})
+	cfg.RateLimitLessonUser = getEnvRate("RATE_LIMIT_LESSON_PLAN_USER", RateLimit{5, time.Hour})
+	cfg.RateLimitRoadmapIP = getEnvRate("RATE_LIMIT_ROADMAP_IP", RateLimit{10, time.Hour})
+	cfg.RateLimitRoadmapUser = getEnvRate("RATE_LIMIT_ROADMAP_USER", RateLimit{5, time.Hour})
+	cfg.RateLimitContactIP = getEnvRate("RATE_LIMIT_CONTACT_IP", RateLimit{5, time.Hour})
+	cfg.RateLimitContactUser = getEnvRate("RATE_LIMIT_CONTACT_USER", RateLimit{5, time.Hour})
+	cfg.RateLimitAccountIP = getEnvRate("RATE_LIMIT_ACCOUNT_EMAIL_IP", RateLimit{10, time.Hour})
+	cfg.RateLimitCommunityIP = getEnvRate("RATE_LIMIT_COMMUNITY_IP", RateLimit{60, time.Hour})
+	cfg.RateLimitCommunityUser = getEnvRate("RATE_LIMIT_COMMUNITY_USER", RateLimit{30, time.Hour})
+	cfg.AIDailyQuota = getEnvInt("AI_DAILY_QUOTA", 100)
+	cfg.AIMonthlyQuota = getEnvInt("AI_MONTHLY_QUOTA", 1500)
+	cfg.AIAllowAnonymous = getEnvBool("AI_ALLOW_ANONYMOUS", true)
+
 	cfg.GoogleOAuthConfig = &oauth2.Config{
 		RedirectURL:  callbackBase + "/google/callback",
 		ClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
@@ -76,6 +300,78 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+func getEnvInt(key string, fallback int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return fallback
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	if value, exists := os.LookupEnv(key); exists {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return fallback
+}
+
+// getEnvList splits a comma separated variable, dropping empty entries.
+func getEnvList(key string) []string {
+	var list []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return fallback
+}
+
+// getEnvRate reads a RateLimit written as requests/period, like "30/1m".
+// "0" turns the limit off.
+func getEnvRate(key string, fallback RateLimit) RateLimit {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	if strings.TrimSpace(value) == "0" {
+		return RateLimit{}
+	}
+	n, per, ok := strings.Cut(value, "/")
+	if !ok {
+		return fallback
+	}
+	requests, err := strconv.Atoi(strings.TrimSpace(n))
+	if err != nil || requests < 0 {
+		return fallback
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(per))
+	if err != nil || d <= 0 {
+		return fallback
+	}
+	return RateLimit{Requests: requests, Per: d}
+}
+
 func getDBPath() string {
 	// Robust DB path checking
 	if _, err := os.Stat("backend/codefuture.db"); err == nil {
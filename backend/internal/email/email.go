@@ -0,0 +1,205 @@
+// Package email sends the platform's outgoing mail. Handlers render a
+// template and queue the result in a persistent outbox, so a slow or broken
+// mail server never holds up a request; a background worker delivers what
+// is due through a Transport, retrying failures with exponential backoff
+// until the email runs out of attempts and is dead-lettered for an admin.
+package email
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"time"
+
+	"codefuture-backend/internal/models"
+)
+
+// Config controls delivery from the outbox.
+type Config struct {
+	From         string        // sender address on every email
+	MaxAttempts  int           // an email is dead after failing this many times
+	BaseBackoff  time.Duration // wait after the first failure; doubles after each one
+	MaxBackoff   time.Duration // longest wait between attempts
+	PollInterval time.Duration // how often the worker looks for due email
+	BatchSize    int           // emails claimed per query
+	SendTimeout  time.Duration // a single delivery attempt is abandoned after this
+}
+
+// Outbox stores queued email. The store implements it.
+type Outbox interface {
+	EnqueueEmail(e *models.OutboxEmail) error
+	// ClaimDueEmails returns up to limit pending emails due at now, counting
+	// an attempt on each and hiding it from other claims until now+lease.
+	ClaimDueEmails(now time.Time, lease time.Duration, limit int) ([]models.OutboxEmail, error)
+	MarkEmailSent(id int, at time.Time) error
+	// MarkEmailFailed records a failed attempt. A nil retryAt dead-letters
+	// the email.
+	MarkEmailFailed(id int, lastError string, retryAt *time.Time, at time.Time) error
+}
+
+// Message is a rendered email ready for a Transport.
+type Message struct {
+	From    string
+	To      string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Transport delivers a message, or reports why it couldn't.
+type Transport interface {
+	Send(ctx context.Context, m *Message) error
+	Name() string
+}
+
+// Mailer queues email and, while Run is going, delivers it.
+type Mailer struct {
+	cfg       Config
+	outbox    Outbox
+	transport Transport
+	wake      chan struct{}
+}
+
+func NewMailer(cfg Config, outbox Outbox, transport Transport) *Mailer {
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.BatchSize < 1 {
+		cfg.BatchSize = 1
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 10 * time.Second
+	}
+	if cfg.SendTimeout <= 0 {
+		cfg.SendTimeout = 30 * time.Second
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = 30 * time.Second
+	}
+	if cfg.MaxBackoff < cfg.BaseBackoff {
+		cfg.MaxBackoff = cfg.BaseBackoff
+	}
+	return &Mailer{cfg: cfg, outbox: outbox, transport: transport, wake: make(chan struct{}, 1)}
+}
+
+// TransportName names where email goes, for the startup log.
+func (m *Mailer) TransportName() string {
+	return m.transport.Name()
+}
+
+// Enqueue renders the named template with data and queues it for to. The
+// worker is nudged so the email usually goes out straight away.
+func (m *Mailer) Enqueue(to, template string, data any) error {
+	addr, err := mail.ParseAddress(to)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", to, err)
+	}
+	msg, err := Render(template, data)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	e := &models.OutboxEmail{
+		To:            addr.Address,
+		Template:      template,
+		Subject:       msg.Subject,
+		HTMLBody:      msg.HTML,
+		TextBody:      msg.Text,
+		Status:        models.EmailStatusPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	if err := m.outbox.EnqueueEmail(e); err != nil {
+		return err
+	}
+	m.Wake()
+	return nil
+}
+
+// Wake asks the worker to look for due email now rather than at its next
+// poll, for instance after an admin retries a dead email.
+func (m *Mailer) Wake() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run delivers due email until ctx is cancelled, then returns once the
+// email it is sending, if any, is done.
+func (m *Mailer) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.PollInterval)
+	defer ticker.Stop()
+	for {
+		m.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-m.wake:
+		}
+	}
+}
+
+// deliverDue sends everything that is due, a batch at a time.
+func (m *Mailer) deliverDue(ctx context.Context) {
+	// An email whose attempt outlives the lease, say because the process
+	// died mid-send, becomes due again.
+	lease := m.cfg.SendTimeout + time.Minute
+	for ctx.Err() == nil {
+		emails, err := m.outbox.ClaimDueEmails(time.Now(), lease, m.cfg.BatchSize)
+		if err != nil {
+			slog.Error("failed to claim due email", "err", err)
+			return
+		}
+		for i := range emails {
+			// Once stopped, the rest of the batch waits out its lease. The
+			// email being sent is finished rather than cut off mid-send.
+			if ctx.Err() != nil {
+				return
+			}
+			m.deliver(context.WithoutCancel(ctx), &emails[i])
+		}
+		if len(emails) < m.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (m *Mailer) deliver(ctx context.Context, e *models.OutboxEmail) {
+	sendCtx, cancel := context.WithTimeout(ctx, m.cfg.SendTimeout)
+	err := m.transport.Send(sendCtx, &Message{From: m.cfg.From, To: e.To, Subject: e.Subject, HTML: e.HTMLBody, Text: e.TextBody})
+	cancel()
+
+	now := time.Now()
+	if err == nil {
+		if err := m.outbox.MarkEmailSent(e.ID, now); err != nil {
+			slog.Error("email sent but not marked", "email_id", e.ID, "err", err)
+		}
+		return
+	}
+
+	var retryAt *time.Time
+	if e.Attempts < m.cfg.MaxAttempts {
+		at := now.Add(m.backoff(e.Attempts))
+		retryAt = &at
+		slog.Warn("email send failed, retrying", "email_id", e.ID, "template", e.Template, "to", e.To,
+			"attempt", e.Attempts, "retry_at", at, "err", err)
+	} else {
+		slog.Error("email send failed, giving up", "email_id", e.ID, "template", e.Template, "to", e.To, "attempts", e.Attempts, "err", err)
+	}
+	if err := m.outbox.MarkEmailFailed(e.ID, err.Error(), retryAt, now); err != nil {
+		slog.Error("failed to record email failure", "email_id", e.ID, "err", err)
+	}
+}
+
+// backoff is the wait after the given number of failed attempts.
+func (m *Mailer) backoff(attempts int) time.Duration {
+	d := m.cfg.BaseBackoff
+	for i := 1; i < attempts && d < m.cfg.MaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, m.cfg.MaxBackoff)
+}
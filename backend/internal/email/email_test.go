@@ -0,0 +1,173 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"codefuture-backend/internal/models"
+)
+
+// memoryOutbox keeps queued email in memory, claiming everything due.
+type memoryOutbox struct {
+	emails []*models.OutboxEmail
+}
+
+func (o *memoryOutbox) EnqueueEmail(e *models.OutboxEmail) error {
+	e.ID = len(o.emails) + 1
+	o.emails = append(o.emails, e)
+	return nil
+}
+
+func (o *memoryOutbox) ClaimDueEmails(now time.Time, lease time.Duration, limit int) ([]models.OutboxEmail, error) {
+	var due []models.OutboxEmail
+	for _, e := range o.emails {
+		if len(due) < limit && e.Status == models.EmailStatusPending && !e.NextAttemptAt.After(now) {
+			e.Attempts++
+			e.NextAttemptAt = now.Add(lease)
+			due = append(due, *e)
+		}
+	}
+	return due, nil
+}
+
+func (o *memoryOutbox) MarkEmailSent(id int, at time.Time) error {
+	o.emails[id-1].Status = models.EmailStatusSent
+	return nil
+}
+
+func (o *memoryOutbox) MarkEmailFailed(id int, lastError string, retryAt *time.Time, at time.Time) error {
+	e := o.emails[id-1]
+	e.LastError = lastError
+	if retryAt == nil {
+		e.Status = models.EmailStatusDead
+		return nil
+	}
+	e.NextAttemptAt = *retryAt
+	return nil
+}
+
+func testConfig() Config {
+	return Config{From: "CodeFuture <noreply@example.com>", MaxAttempts: 3, BaseBackoff: time.Minute, MaxBackoff: time.Hour}
+}
+
+func TestRenderEscapesHTML(t *testing.T) {
+	msg, err := Render(TemplateContactConfirmation, ContactConfirmation{
+		Name:        `<script>alert(1)</script>`,
+		Message:     "line one\n<b>line two</b>",
+		FrontendURL: "https://example.com",
+	})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if strings.Contains(msg.HTML, "<script>") || strings.Contains(msg.HTML, "<b>line two") {
+		t.Errorf("user input not escaped in HTML:\n%s", msg.HTML)
+	}
+	if !strings.Contains(msg.HTML, "&lt;script&gt;") || !strings.Contains(msg.HTML, "line one<br>&lt;b&gt;") {
+		t.Errorf("escaped input or line break missing from HTML:\n%s", msg.HTML)
+	}
+	if strings.Contains(msg.Subject, "\n") || msg.Subject == "" {
+		t.Errorf("Subject = %q, want one non-empty line", msg.Subject)
+	}
+}
+
+func TestRenderPlainText(t *testing.T) {
+	msg, err := Render(TemplatePasswordReset, AccountLink{Name: "Ada <ada>", Link: "https://example.com/reset?token=a&b", ExpiresIn: "1 hour"})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	// The text alternative is not HTML, so nothing in it is escaped
+	for _, want := range []string{"Ada <ada>", "https://example.com/reset?token=a&b", "1 hour"} {
+		if !strings.Contains(msg.Text, want) {
+			t.Errorf("Text lacks %q:\n%s", want, msg.Text)
+		}
+	}
+	if strings.Contains(msg.Text, "<html") || !strings.HasSuffix(msg.Text, "\n") {
+		t.Errorf("Text is not plain text ending in a newline:\n%s", msg.Text)
+	}
+	if _, err := Render("no_such_template", nil); err == nil {
+		t.Error("Render of an unknown template succeeded")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	m := NewMailer(Config{BaseBackoff: time.Minute, MaxBackoff: 5 * time.Minute}, &memoryOutbox{}, &MemoryTransport{})
+	for attempts, want := range map[int]time.Duration{
+		1: time.Minute,
+		2: 2 * time.Minute,
+		3: 4 * time.Minute,
+		4: 5 * time.Minute, // capped
+		9: 5 * time.Minute,
+	} {
+		if got := m.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %s, want %s", attempts, got, want)
+		}
+	}
+}
+
+func TestNewMailerDefaults(t *testing.T) {
+	m := NewMailer(Config{}, &memoryOutbox{}, &MemoryTransport{})
+	if m.cfg.PollInterval <= 0 || m.cfg.SendTimeout <= 0 || m.cfg.BaseBackoff <= 0 || m.cfg.MaxBackoff < m.cfg.BaseBackoff {
+		t.Errorf("zero config not defaulted: %+v", m.cfg)
+	}
+
+	// Run would panic in time.NewTicker with a zero poll interval
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m.Run(ctx)
+}
+
+func TestDeliver(t *testing.T) {
+	outbox := &memoryOutbox{}
+	transport := &MemoryTransport{}
+	m := NewMailer(testConfig(), outbox, transport)
+
+	if err := m.Enqueue("Ada <ada@example.com>", TemplateVerifyEmail, AccountLink{Name: "Ada", Link: "https://example.com/v", ExpiresIn: "2 days"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if err := m.Enqueue("not an address", TemplateVerifyEmail, AccountLink{}); err == nil {
+		t.Error("Enqueue to an invalid address succeeded")
+	}
+	m.deliverDue(context.Background())
+
+	sent := transport.Messages()
+	if len(sent) != 1 || outbox.emails[0].Status != models.EmailStatusSent {
+		t.Fatalf("sent %d messages, status %q; want 1, sent", len(sent), outbox.emails[0].Status)
+	}
+	if sent[0].To != "ada@example.com" || sent[0].From != testConfig().From || sent[0].Text == "" || sent[0].HTML == "" {
+		t.Errorf("sent message = %+v", sent[0])
+	}
+}
+
+func TestDeliverRetriesThenDeadLetters(t *testing.T) {
+	outbox := &memoryOutbox{}
+	transport := &MemoryTransport{Err: errors.New("connection refused")}
+	m := NewMailer(testConfig(), outbox, transport)
+	if err := m.Enqueue("ada@example.com", TemplateVerifyEmail, AccountLink{Name: "Ada"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	e := outbox.emails[0]
+
+	for attempt := 1; attempt < testConfig().MaxAttempts; attempt++ {
+		before := time.Now()
+		m.deliverDue(context.Background())
+		if e.Status != models.EmailStatusPending || e.Attempts != attempt || e.LastError != "connection refused" {
+			t.Fatalf("after attempt %d: %+v", attempt, e)
+		}
+		if wait := e.NextAttemptAt.Sub(before); wait < m.backoff(attempt) {
+			t.Fatalf("attempt %d retries after %s, want at least %s", attempt, wait, m.backoff(attempt))
+		}
+		e.NextAttemptAt = time.Now() // skip the wait
+	}
+
+	m.deliverDue(context.Background())
+	if e.Status != models.EmailStatusDead || e.Attempts != testConfig().MaxAttempts {
+		t.Fatalf("after the last attempt: %+v, want dead", e)
+	}
+	m.deliverDue(context.Background())
+	if e.Attempts != testConfig().MaxAttempts {
+		t.Errorf("dead email was attempted again")
+	}
+}
@@ -0,0 +1,120 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Template names. Each has an HTML body, rendered inside layout.html, and a
+// plain-text alternative that also defines the subject.
+const (
+	TemplateContactAdmin        = "contact_admin"
+	TemplateContactConfirmation = "contact_confirmation"
+	TemplatePasswordReset       = "password_reset"
+	TemplateVerifyEmail         = "verify_email"
+	TemplateTicketReply         = "ticket_reply"
+	TemplateTicketUserReply     = "ticket_user_reply"
+)
+
+// ContactAdmin is the data for TemplateContactAdmin, the notice staff get
+// about a new contact form submission.
+type ContactAdmin struct {
+	TicketID   int
+	FirstName  string
+	LastName   string
+	Email      string
+	UserStatus string // the submitter's user ID, or "Guest"
+	Message    string
+	Draft      string // AI suggested reply, if one was made
+	ReceivedAt string
+}
+
+// ContactConfirmation is the data for TemplateContactConfirmation.
+type ContactConfirmation struct {
+	Name        string
+	Message     string
+	FrontendURL string
+}
+
+// AccountLink is the data for TemplatePasswordReset and TemplateVerifyEmail.
+type AccountLink struct {
+	Name      string
+	Link      string
+	ExpiresIn string
+}
+
+// TicketReply is the data for TemplateTicketReply, a staff reply to the
+// person who opened a ticket.
+type TicketReply struct {
+	TicketID int
+	Name     string
+	Reply    string
+	Original string
+}
+
+// TicketUserReply is the data for TemplateTicketUserReply, the notice staff
+// get when a submitter replies on their ticket.
+type TicketUserReply struct {
+	TicketID  int
+	FirstName string
+	LastName  string
+	Body      string
+}
+
+//go:embed templates
+var templateFS embed.FS
+
+type emailTemplate struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+var templates = map[string]*emailTemplate{}
+
+func init() {
+	funcs := htmltemplate.FuncMap{"lines": lines}
+	for _, name := range []string{
+		TemplateContactAdmin, TemplateContactConfirmation, TemplatePasswordReset,
+		TemplateVerifyEmail, TemplateTicketReply, TemplateTicketUserReply,
+	} {
+		templates[name] = &emailTemplate{
+			html: htmltemplate.Must(htmltemplate.New(name).Funcs(funcs).
+				ParseFS(templateFS, "templates/layout.html", "templates/"+name+".html")),
+			text: texttemplate.Must(texttemplate.New(name).ParseFS(templateFS, "templates/"+name+".txt")),
+		}
+	}
+}
+
+// Render fills in the named template. User input in data is escaped in the
+// HTML body; the subject is folded onto one line.
+func Render(name string, data any) (*Message, error) {
+	t, ok := templates[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := t.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("render %s subject: %w", name, err)
+	}
+	if err := t.text.ExecuteTemplate(&text, name+".txt", data); err != nil {
+		return nil, fmt.Errorf("render %s text: %w", name, err)
+	}
+	if err := t.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return nil, fmt.Errorf("render %s html: %w", name, err)
+	}
+	return &Message{
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		HTML:    html.String(),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+	}, nil
+}
+
+// lines escapes plain text for an HTML body, keeping its line breaks.
+func lines(s string) htmltemplate.HTML {
+	return htmltemplate.HTML(strings.ReplaceAll(htmltemplate.HTMLEscapeString(s), "\n", "<br>"))
+}
@@ -0,0 +1,233 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SMTPTransport delivers through an SMTP server, upgrading to TLS when the
+// server offers STARTTLS.
+type SMTPTransport struct {
+	Host     string
+	Port     string
+	Username string // no authentication when empty
+	Password string
+}
+
+func (t *SMTPTransport) Name() string {
+	return "smtp " + net.JoinHostPort(t.Host, t.Port)
+}
+
+func (t *SMTPTransport) Send(ctx context.Context, m *Message) error {
+	from, to, err := addresses(m)
+	if err != nil {
+		return err
+	}
+	raw, err := buildMIME(m, from, to, time.Now())
+	if err != nil {
+		return err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(t.Host, t.Port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, t.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: t.Host}); err != nil {
+			return err
+		}
+	}
+	if t.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", t.Username, t.Password, t.Host)); err != nil {
+			return err
+		}
+	}
+	// The envelope takes bare addresses; display names belong in the headers
+	if err := c.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(raw); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// FileTransport writes each message into a maildir, for development: point
+// a mail client at Dir or just read the files.
+type FileTransport struct {
+	Dir string
+	seq atomic.Int64
+}
+
+func (t *FileTransport) Name() string {
+	return "maildir " + t.Dir
+}
+
+func (t *FileTransport) Send(ctx context.Context, m *Message) error {
+	from, to, err := addresses(m)
+	if err != nil {
+		return err
+	}
+	raw, err := buildMIME(m, from, to, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(t.Dir, sub), 0o755); err != nil {
+			return err
+		}
+	}
+
+	host, _ := os.Hostname()
+	name := fmt.Sprintf("%d.M%dP%dQ%d.%s", time.Now().Unix(), time.Now().Nanosecond()/1000, os.Getpid(), t.seq.Add(1),
+		strings.NewReplacer("/", "_", ":", "_").Replace(host))
+	// Maildir readers only look in new/, so the rename makes the message
+	// appear whole.
+	tmp := filepath.Join(t.Dir, "tmp", name)
+	if err := os.WriteFile(tmp, raw, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(t.Dir, "new", name))
+}
+
+// MemoryTransport keeps messages in memory, for tests. Setting Err makes
+// every send fail with it.
+type MemoryTransport struct {
+	mu       sync.Mutex
+	messages []Message
+	Err      error
+}
+
+func (t *MemoryTransport) Name() string {
+	return "memory"
+}
+
+func (t *MemoryTransport) Send(ctx context.Context, m *Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.Err != nil {
+		return t.Err
+	}
+	t.messages = append(t.messages, *m)
+	return nil
+}
+
+// Messages returns what has been sent so far.
+func (t *MemoryTransport) Messages() []Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Message(nil), t.messages...)
+}
+
+// LogTransport writes the plain-text version of each message to the log,
+// which is where email went before SMTP was configured.
+type LogTransport struct{}
+
+func (LogTransport) Name() string {
+	return "log"
+}
+
+func (LogTransport) Send(ctx context.Context, m *Message) error {
+	slog.Info("mock email", "to", m.To, "subject", m.Subject, "text", m.Text)
+	return nil
+}
+
+// addresses parses m's sender and recipient, either of which may carry a
+// display name like "CodeFuture <noreply@example.com>".
+func addresses(m *Message) (from, to *mail.Address, err error) {
+	from, err = mail.ParseAddress(m.From)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid sender %q: %w", m.From, err)
+	}
+	to, err = mail.ParseAddress(m.To)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid recipient %q: %w", m.To, err)
+	}
+	return from, to, nil
+}
+
+// buildMIME formats m, sent from and to the parsed addresses, as a
+// multipart/alternative message with the plain text first, so clients that
+// can show HTML prefer it.
+func buildMIME(m *Message, from, to *mail.Address, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	domain := "localhost"
+	if at := strings.LastIndex(from.Address, "@"); at >= 0 {
+		domain = from.Address[at+1:]
+	}
+	id := make([]byte, 12)
+	rand.Read(id)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "Message-ID: <%s@%s>\r\n", hex.EncodeToString(id), domain)
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", mw.Boundary())
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", m.Text},
+		{"text/html; charset=UTF-8", m.HTML},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
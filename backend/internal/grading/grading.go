@@ -0,0 +1,85 @@
+// Package grading runs a learner's submission against an exercise's test
+// cases in the sandbox and compares the program output.
+package grading
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"codefuture-backend/internal/models"
+	"codefuture-backend/internal/sandbox"
+)
+
+// Report is the outcome of grading one submission.
+type Report struct {
+	Passed      bool
+	PassedCount int
+	TotalCount  int
+	Results     []models.TestResult
+}
+
+// Grade runs code once per test case, feeding the case's stdin and comparing
+// stdout with the expected output. An exercise without test cases passes
+// when the program runs to completion without error. Every test runs;
+// curricula with more than models.MaxExerciseTests per exercise fail
+// validation before they are stored, and Grade refuses any that slip past
+// rather than pass a submission on some of its tests.
+func Grade(ctx context.Context, executor *sandbox.Executor, language, code string, exercise *models.Exercise) (*Report, error) {
+	tests := exercise.Tests
+	if len(tests) == 0 {
+		tests = []models.TestCase{{Name: "Runs without errors"}}
+	}
+	if len(tests) > models.MaxExerciseTests {
+		return nil, fmt.Errorf("exercise has %d tests, more than the %d allowed", len(tests), models.MaxExerciseTests)
+	}
+
+	report := &Report{TotalCount: len(tests)}
+	for _, tc := range tests {
+		res, err := executor.Run(ctx, sandbox.Request{Language: language, Code: code, Stdin: tc.Stdin})
+		if err != nil {
+			return nil, err
+		}
+
+		passed := res.ExitCode == 0 && !res.TimedOut
+		if len(exercise.Tests) > 0 {
+			passed = passed && OutputMatches(res.Stdout, tc.ExpectedOutput)
+		}
+
+		result := models.TestResult{
+			Name:     tc.Name,
+			Passed:   passed,
+			Hidden:   tc.Hidden,
+			TimedOut: res.TimedOut,
+		}
+		if !tc.Hidden {
+			result.Stdin = tc.Stdin
+			result.Expected = tc.ExpectedOutput
+			result.Actual = res.Stdout
+			result.Stderr = res.Stderr
+		}
+
+		report.Results = append(report.Results, result)
+		if passed {
+			report.PassedCount++
+		}
+	}
+
+	report.Passed = report.PassedCount == report.TotalCount
+	return report, nil
+}
+
+// OutputMatches compares program output with the expected output, ignoring
+// trailing whitespace on each line and trailing blank lines.
+func OutputMatches(actual, expected string) bool {
+	return normalize(actual) == normalize(expected)
+}
+
+func normalize(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimRight(strings.Join(lines, "\n"), "\n")
+}
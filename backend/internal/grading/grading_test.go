@@ -0,0 +1,58 @@
+package grading
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"codefuture-backend/internal/models"
+)
+
+func TestOutputMatches(t *testing.T) {
+	for _, tc := range []struct {
+		name             string
+		actual, expected string
+		want             bool
+	}{
+		{"exact", "hello\n", "hello\n", true},
+		{"missing final newline", "hello", "hello\n", true},
+		{"CRLF", "a\r\nb\r\n", "a\nb\n", true},
+		{"trailing spaces and tabs", "a  \nb\t\n", "a\nb", true},
+		{"trailing blank lines", "a\n\n\n", "a", true},
+		{"leading spaces matter", "  a", "a", false},
+		{"inner blank line matters", "a\n\nb", "a\nb", false},
+		{"different output", "hello", "world", false},
+		{"empty against output", "", "a", false},
+		{"both empty", "\n", "", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := OutputMatches(tc.actual, tc.expected); got != tc.want {
+				t.Errorf("OutputMatches(%q, %q) = %v, want %v", tc.actual, tc.expected, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for in, want := range map[string]string{
+		"a\r\nb \r\n\r\n": "a\nb",
+		"x\t \ny  ":       "x\ny",
+		"\n\n":            "",
+		"a\n \nb":         "a\n\nb",
+	} {
+		if got := normalize(in); got != want {
+			t.Errorf("normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestGradeRefusesTooManyTests(t *testing.T) {
+	exercise := &models.Exercise{Prompt: "Echo"}
+	for i := range models.MaxExerciseTests + 1 {
+		exercise.Tests = append(exercise.Tests, models.TestCase{Name: fmt.Sprint(i), ExpectedOutput: "x"})
+	}
+	// Refused before anything runs, so no executor is needed
+	if report, err := Grade(context.Background(), nil, "python", "print('x')", exercise); err == nil {
+		t.Fatalf("Grade = %+v, want an error", report)
+	}
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"codefuture-backend/internal/auth"
+	"codefuture-backend/internal/email"
+	"codefuture-backend/internal/logging"
+	"codefuture-backend/internal/middleware"
+	"codefuture-backend/internal/models"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// HandleForgotPassword emails a password reset link. It answers the same way
+// whether or not the email is registered so it can't be used to probe accounts.
+func (h *Handler) HandleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		sendJSONError(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.dataStore.GetUserByEmail(req.Email)
+	if err != nil {
+		sendJSONError(w, "Failed to process request", http.StatusInternalServerError)
+		return
+	}
+	wait, err := h.accountMailWait(user, models.TokenPurposePasswordReset)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("forgot password failed", "err", err)
+		sendJSONError(w, "Failed to process request", http.StatusInternalServerError)
+		return
+	}
+	// Within the cooldown nothing is sent, but the answer is the same so it
+	// doesn't reveal whether the account exists
+	if user != nil && wait == 0 {
+		link, err := h.issueAccountLink(user, models.TokenPurposePasswordReset, h.config.PasswordResetTTL, "/reset-password")
+		if err != nil {
+			logging.FromContext(r.Context()).Error("forgot password failed", "err", err)
+			sendJSONError(w, "Failed to process request", http.StatusInternalServerError)
+			return
+		}
+		h.queueEmail(r.Context(), user.Email, email.TemplatePasswordReset,
+			email.AccountLink{Name: user.Name, Link: link, ExpiresIn: formatTTL(h.config.PasswordResetTTL)})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": "If an account exists for that email, a reset link has been sent.",
+	})
+}
+
+// HandleResetPassword sets a new password using a reset token and signs the
+// user out of every device.
+func (h *Handler) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Token == "" || req.Password == "" {
+		sendJSONError(w, "Token and password are required", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	userID, err := h.dataStore.ConsumeAccountToken(models.TokenPurposePasswordReset, auth.HashToken(req.Token), now)
+	if err != nil {
+		sendJSONError(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+	if userID == 0 {
+		sendJSONError(w, "Invalid or expired reset link", http.StatusBadRequest)
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		sendJSONError(w, "Error hashing password", http.StatusInternalServerError)
+		return
+	}
+	if err := h.dataStore.UpdateUserPassword(userID, string(hashedPassword)); err != nil {
+		sendJSONError(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	// Any other reset links are now stale, and whoever knew the old password
+	// should lose access. Receiving the link also proves the email is theirs.
+	if err := h.dataStore.InvalidateAccountTokens(userID, models.TokenPurposePasswordReset, now); err != nil {
+		logging.FromContext(r.Context()).Error("failed to invalidate reset tokens", "err", err)
+	}
+	if err := h.dataStore.RevokeAllSessions(userID, now); err != nil {
+		logging.FromContext(r.Context()).Error("failed to revoke sessions after password reset", "err", err)
+	}
+	if err := h.dataStore.MarkEmailVerified(userID, now); err != nil {
+		logging.FromContext(r.Context()).Error("failed to mark email verified", "err", err)
+	}
+
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// HandleVerifyEmail confirms the user's email address with a verification token.
+func (h *Handler) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req models.VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		sendJSONError(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	userID, err := h.dataStore.ConsumeAccountToken(models.TokenPurposeEmailVerification, auth.HashToken(req.Token), now)
+	if err != nil {
+		sendJSONError(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+	if userID == 0 {
+		sendJSONError(w, "Invalid or expired verification link", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.dataStore.MarkEmailVerified(userID, now); err != nil {
+		sendJSONError(w, "Failed to verify email", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// HandleResendVerification mails the signed-in user a fresh verification link.
+func (h *Handler) HandleResendVerification(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.dataStore.GetUserByID(userID)
+	if err != nil || user == nil {
+		sendJSONError(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.EmailVerified {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "message": "Email already verified"})
+		return
+	}
+
+	wait, err := h.accountMailWait(user, models.TokenPurposeEmailVerification)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to resend verification email", "err", err)
+		sendJSONError(w, "Failed to send verification email", http.StatusInternalServerError)
+		return
+	}
+	if wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		sendJSONError(w, "A verification email was sent recently; please wait before asking again", http.StatusTooManyRequests)
+		return
+	}
+
+	if err := h.sendVerificationEmail(user); err != nil {
+		logging.FromContext(r.Context()).Error("failed to resend verification email", "err", err)
+		sendJSONError(w, "Failed to send verification email", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+func (h *Handler) sendVerificationEmail(user *models.User) error {
+	link, err := h.issueAccountLink(user, models.TokenPurposeEmailVerification, h.config.EmailVerificationTTL, "/verify-email")
+	if err != nil {
+		return err
+	}
+	return h.mailer.Enqueue(user.Email, email.TemplateVerifyEmail,
+		email.AccountLink{Name: user.Name, Link: link, ExpiresIn: formatTTL(h.config.EmailVerificationTTL)})
+}
+
+// accountMailWait is how long until the user may be mailed another token for
+// purpose, so the account routes can't be used to flood an inbox. It is 0
+// when user is nil.
+func (h *Handler) accountMailWait(user *models.User, purpose string) (time.Duration, error) {
+	if user == nil || h.config.AccountEmailCooldown <= 0 {
+		return 0, nil
+	}
+	last, err := h.dataStore.LatestAccountTokenAt(user.ID, purpose)
+	if err != nil || last.IsZero() {
+		return 0, err
+	}
+	return max(0, h.config.AccountEmailCooldown-time.Since(last)), nil
+}
+
+// issueAccountLink replaces the user's outstanding tokens for purpose with a
+// new one and returns the frontend link that redeems it.
+func (h *Handler) issueAccountLink(user *models.User, purpose string, ttl time.Duration, path string) (string, error) {
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	if err := h.dataStore.InvalidateAccountTokens(user.ID, purpose, now); err != nil {
+		return "", err
+	}
+	if err := h.dataStore.CreateAccountToken(user.ID, purpose, hash, now, now.Add(ttl)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s%s?token=%s", h.config.FrontendURL, path, url.QueryEscape(token)), nil
+}
+
+func formatTTL(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		if d == time.Hour {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", d/time.Hour)
+	}
+	return d.String()
+}
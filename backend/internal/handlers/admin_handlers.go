@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"codefuture-backend/internal/logging"
+	"codefuture-backend/internal/models"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxAdminSearchLength = 200
+
+// The admin API. Every route is wrapped with RequirePermission, and every
+// request that reaches a record is written to the audit log, reads included:
+// a listing or lookup that can't be audited fails instead.
+
+// HandleAdminListUsers lists users, oldest first. Query parameters: q
+// (matches name or email), role, limit and offset.
+func (h *Handler) HandleAdminListUsers(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	limit, offset, ok := parseAdminPage(w, r)
+	if !ok {
+		return
+	}
+	text, ok := parseAdminSearch(w, r)
+	if !ok {
+		return
+	}
+	role := params.Get("role")
+	if role != "" && !slices.Contains(models.Roles, role) {
+		sendJSONError(w, "role must be one of "+strings.Join(models.Roles, ", "), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.dataStore.ListUsers(models.UserQuery{Text: text, Role: role, Limit: limit, Offset: offset})
+	if err != nil {
+		sendJSONError(w, "Failed to fetch users", http.StatusInternalServerError)
+		return
+	}
+	if !h.audit(w, r, models.AuditActionList, models.AuditTargetUser, 0) {
+		return
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+// HandleAdminGetUser returns one user.
+func (h *Handler) HandleAdminGetUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.dataStore.GetUserByID(userID)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch user", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		sendJSONError(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if !h.audit(w, r, models.AuditActionView, models.AuditTargetUser, userID) {
+		return
+	}
+	json.NewEncoder(w).Encode(user)
+}
+
+// HandleAdminChangeRole gives a user a new role. Admins can't change their
+// own, so there is always someone left who can undo a mistake.
+func (h *Handler) HandleAdminChangeRole(w http.ResponseWriter, r *http.Request) {
+	adminID, _ := actor(r)
+	userID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid user id", http.StatusBadRequest)
+		return
+	}
+	if userID == adminID {
+		sendJSONError(w, "You can't change your own role", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ChangeRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(models.Roles, req.Role) {
+		sendJSONError(w, "role must be one of "+strings.Join(models.Roles, ", "), http.StatusBadRequest)
+		return
+	}
+	reason, msg := validateModerationReason(req.Reason)
+	if msg != "" {
+		sendJSONError(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	found, err := h.dataStore.ChangeUserRole(userID, adminID, req.Role, reason, time.Now())
+	if err != nil {
+		sendJSONError(w, "Failed to change role", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		sendJSONError(w, "User not found", http.StatusNotFound)
+		return
+	}
+	h.writeModeratedUser(w, userID)
+}
+
+// HandleAdminListLessonPlans lists lesson plans without their content,
+// newest first. Query parameters: user_id, kind, limit and offset.
+func (h *Handler) HandleAdminListLessonPlans(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := parseAdminPage(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := parseAdminID(w, r, "user_id")
+	if !ok {
+		return
+	}
+	kind := r.URL.Query().Get("kind")
+	if kind != "" && kind != models.PlanKindCurriculum && kind != models.PlanKindRoadmap {
+		sendJSONError(w, "kind must be "+models.PlanKindCurriculum+" or "+models.PlanKindRoadmap, http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.dataStore.ListLessonPlans(models.LessonPlanQuery{UserID: userID, Kind: kind, Limit: limit, Offset: offset})
+	if err != nil {
+		sendJSONError(w, "Failed to fetch lesson plans", http.StatusInternalServerError)
+		return
+	}
+	if !h.audit(w, r, models.AuditActionList, models.AuditTargetLessonPlan, 0) {
+		return
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+// HandleAdminGetLessonPlan returns any user's lesson plan with its content.
+func (h *Handler) HandleAdminGetLessonPlan(w http.ResponseWriter, r *http.Request) {
+	planID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid lesson plan id", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.dataStore.GetLessonPlanByID(planID)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch lesson plan", http.StatusInternalServerError)
+		return
+	}
+	if plan == nil {
+		sendJSONError(w, "Lesson plan not found", http.StatusNotFound)
+		return
+	}
+	if !h.audit(w, r, models.AuditActionView, models.AuditTargetLessonPlan, planID) {
+		return
+	}
+	json.NewEncoder(w).Encode(plan)
+}
+
+// HandleAdminDeleteLessonPlan deletes any user's lesson plan. An optional
+// reason query parameter goes into the audit log.
+func (h *Handler) HandleAdminDeleteLessonPlan(w http.ResponseWriter, r *http.Request) {
+	adminID, _ := actor(r)
+	planID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid lesson plan id", http.StatusBadRequest)
+		return
+	}
+	reason, msg := validateModerationReason(r.URL.Query().Get("reason"))
+	if msg != "" {
+		sendJSONError(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	found, err := h.dataStore.DeleteLessonPlan(planID, adminID, reason, time.Now())
+	if err != nil {
+		sendJSONError(w, "Failed to delete lesson plan", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		sendJSONError(w, "Lesson plan not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleAdminListPosts lists posts in every status, newest first. Query
+// parameters: status, author (user id), limit and offset. Posts are changed
+// through the moderation actions on POST /api/admin/posts/{id}.
+func (h *Handler) HandleAdminListPosts(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := parseAdminPage(w, r)
+	if !ok {
+		return
+	}
+	authorID, ok := parseAdminID(w, r, "author")
+	if !ok {
+		return
+	}
+	statuses := []string{models.PostStatusVisible, models.PostStatusPending, models.PostStatusHidden, models.PostStatusRemoved}
+	status := r.URL.Query().Get("status")
+	if status != "" && !slices.Contains(statuses, status) {
+		sendJSONError(w, "status must be one of "+strings.Join(statuses, ", "), http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.dataStore.ListAllPosts(models.AdminPostQuery{Status: status, AuthorID: authorID, Limit: limit, Offset: offset})
+	if err != nil {
+		sendJSONError(w, "Failed to fetch posts", http.StatusInternalServerError)
+		return
+	}
+	if !h.audit(w, r, models.AuditActionList, models.AuditTargetPost, 0) {
+		return
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+// HandleAdminGetPost returns a post whatever its status.
+func (h *Handler) HandleAdminGetPost(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid post id", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.dataStore.GetPostByID(postID)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch post", http.StatusInternalServerError)
+		return
+	}
+	if post == nil {
+		sendJSONError(w, "Post not found", http.StatusNotFound)
+		return
+	}
+	if !h.audit(w, r, models.AuditActionView, models.AuditTargetPost, postID) {
+		return
+	}
+	json.NewEncoder(w).Encode(post)
+}
+
+// HandleAdminListContact lists contact form submissions, which are also
+// support tickets, newest first. Query parameters: q (matches name, email or
+// message), status, assignee (a user id, or "none"), limit and offset.
+func (h *Handler) HandleAdminListContact(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := parseAdminPage(w, r)
+	if !ok {
+		return
+	}
+	text, ok := parseAdminSearch(w, r)
+	if !ok {
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status != "" && !slices.Contains(models.TicketStatuses, status) {
+		sendJSONError(w, "status must be one of "+strings.Join(models.TicketStatuses, ", "), http.StatusBadRequest)
+		return
+	}
+	assigneeID := -1
+	if r.URL.Query().Get("assignee") != "none" {
+		if assigneeID, ok = parseAdminID(w, r, "assignee"); !ok {
+			return
+		}
+	}
+
+	q := models.ContactQuery{Text: text, Status: status, AssigneeID: assigneeID, Limit: limit, Offset: offset}
+	page, err := h.dataStore.ListContactSubmissions(q)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch contact submissions", http.StatusInternalServerError)
+		return
+	}
+	if !h.audit(w, r, models.AuditActionList, models.AuditTargetContact, 0) {
+		return
+	}
+	json.NewEncoder(w).Encode(page)
+}
+
+// HandleAdminGetContact returns one contact form submission.
+func (h *Handler) HandleAdminGetContact(w http.ResponseWriter, r *http.Request) {
+	subID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid submission id", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.dataStore.GetContactSubmission(subID)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch contact submission", http.StatusInternalServerError)
+		return
+	}
+	if sub == nil {
+		sendJSONError(w, "Contact submission not found", http.StatusNotFound)
+		return
+	}
+	if !h.audit(w, r, models.AuditActionView, models.AuditTargetContact, subID) {
+		return
+	}
+	json.NewEncoder(w).Encode(sub)
+}
+
+// audit records an admin read, with the query string as the reason so the
+// log shows what was searched for. targetID is 0 for listings. It writes the
+// error response and returns false if the entry can't be stored.
+func (h *Handler) audit(w http.ResponseWriter, r *http.Request, action, targetType string, targetID int) bool {
+	adminID, _ := actor(r)
+	reason := r.URL.Query().Encode()
+	if len(reason) > maxModerationReasonLength {
+		reason = reason[:maxModerationReasonLength]
+	}
+
+	entry := &models.AuditEntry{ActorID: adminID, Action: action, TargetType: targetType, TargetID: targetID,
+		Reason: reason, CreatedAt: time.Now()}
+	if err := h.dataStore.RecordAudit(entry); err != nil {
+		logging.FromContext(r.Context()).Error("failed to write audit entry", "action", action, "target_type", targetType, "target_id", targetID, "err", err)
+		sendJSONError(w, "Failed to record audit entry", http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+func parseAdminPage(w http.ResponseWriter, r *http.Request) (limit, offset int, ok bool) {
+	limit, ok = parseModerationLimit(w, r)
+	if !ok {
+		return 0, 0, false
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			sendJSONError(w, "Invalid offset", http.StatusBadRequest)
+			return 0, 0, false
+		}
+		offset = n
+	}
+	return limit, offset, true
+}
+
+func parseAdminSearch(w http.ResponseWriter, r *http.Request) (string, bool) {
+	text := strings.TrimSpace(r.URL.Query().Get("q"))
+	if len(text) > maxAdminSearchLength {
+		sendJSONError(w, "Search is too long", http.StatusBadRequest)
+		return "", false
+	}
+	return text, true
+}
+
+// parseAdminID reads an optional id filter; 0 means it wasn't given.
+func parseAdminID(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		sendJSONError(w, "Invalid "+name, http.StatusBadRequest)
+		return 0, false
+	}
+	return n, true
+}
@@ -1,25 +1,16 @@
 package handlers
 
 import (
+	"codefuture-backend/internal/logging"
 	"codefuture-backend/internal/models"
+	"codefuture-backend/internal/store"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
-	"os"
-	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func getJWTSecret() []byte {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return []byte("dev_secret_key_change_in_prod")
-	}
-	return []byte(secret)
-}
-
 func (h *Handler) HandleSignup(w http.ResponseWriter, r *http.Request) {
 
 	var req models.SignupRequest
@@ -51,21 +42,27 @@ func (h *Handler) HandleSignup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.dataStore.CreateUser(user); err != nil {
-		// Differentiate error types ideally (duplicate email)
-		sendJSONError(w, "Error creating user (email may be taken)", http.StatusConflict)
+		if errors.Is(err, store.ErrEmailExists) {
+			sendJSONError(w, "Email already registered", http.StatusConflict)
+			return
+		}
+		logging.FromContext(r.Context()).Error("signup failed", "err", err)
+		sendJSONError(w, "Error creating user", http.StatusInternalServerError)
 		return
 	}
 
-	token, err := generateToken(user.ID)
+	if err := h.sendVerificationEmail(user); err != nil {
+		logging.FromContext(r.Context()).Error("failed to send verification email", "err", err)
+	}
+
+	resp, err := h.startSession(r, user)
 	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to start session", "err", err)
 		sendJSONError(w, "Error generating token", http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(models.AuthResponse{
-		Token: token,
-		User:  *user,
-	})
+	json.NewEncoder(w).Encode(resp)
 }
 
 func (h *Handler) HandleLogin(w http.ResponseWriter, r *http.Request) {
@@ -90,67 +87,16 @@ func (h *Handler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		sendJSONError(w, "Invalid credentials", http.StatusUnauthorized)
 		return
 	}
-
-	token, err := generateToken(user.ID)
-	if err != nil {
-		sendJSONError(w, "Error generating token", http.StatusInternalServerError)
+	if refuseSuspended(w, user) {
 		return
 	}
 
-	json.NewEncoder(w).Encode(models.AuthResponse{
-		Token: token,
-		User:  *user,
-	})
-}
-
-func generateToken(userID int) (string, error) {
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 72).Unix(), // 3 days
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(getJWTSecret())
-}
-
-// HandleSocialLoginDemo simulates a social login for development/demo purposes
-func (h *Handler) HandleSocialLoginDemo(w http.ResponseWriter, r *http.Request) {
-
-	var req struct {
-		Provider string `json:"provider"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		sendJSONError(w, "Invalid request", http.StatusBadRequest)
-		return
-	}
-
-	// Create or get a demo user based on provider
-	email := fmt.Sprintf("demo.%s@example.com", req.Provider)
-	name := fmt.Sprintf("%s User (Demo)", req.Provider)
-
-	// Check if user exists
-	user, _ := h.dataStore.GetUserByEmail(email)
-
-	if user == nil {
-		// Create new demo user
-		user = &models.User{
-			Name:     name,
-			Email:    email,
-			Password: "social_login_dummy_password", // In real world, social auth uses different flow
-		}
-		if err := h.dataStore.CreateUser(user); err != nil {
-			sendJSONError(w, "Error creating demo user", http.StatusInternalServerError)
-			return
-		}
-	}
-
-	token, err := generateToken(user.ID)
+	resp, err := h.startSession(r, user)
 	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to start session", "err", err)
 		sendJSONError(w, "Error generating token", http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(models.AuthResponse{
-		Token: token,
-		User:  *user,
-	})
+	json.NewEncoder(w).Encode(resp)
 }
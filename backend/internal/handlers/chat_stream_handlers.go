@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"codefuture-backend/internal/logging"
+	"codefuture-backend/internal/models"
+)
+
+// wantsEventStream reports whether the client asked for Server-Sent Events.
+func wantsEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// sseWriter writes Server-Sent Events and flushes after each one so
+// deltas reach the browser immediately.
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func newSSEWriter(w http.ResponseWriter) (*sseWriter, bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, false
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+	// A reply streams for as long as the provider takes, so the server's
+	// write timeout doesn't apply
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &sseWriter{w: w, flusher: flusher}, true
+}
+
+func (s *sseWriter) send(event string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// HandleChatStream streams the tutor reply as Server-Sent Events:
+//
+//	event: delta  data: {"text": "..."}          (repeated)
+//	event: done   data: {"text": "...", "usage": {...}}
+//	event: error  data: {"error": "..."}
+//
+// Closing the connection cancels the request context, which aborts the
+// upstream provider call.
+func (h *Handler) HandleChatStream(w http.ResponseWriter, r *http.Request) {
+
+	var req models.ChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	stream, ok := newSSEWriter(w)
+	if !ok {
+		sendJSONError(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := h.aiStore.ChatStream(r.Context(), req.Persona, req.CurrentCode, req.Message, req.History, func(delta string) error {
+		return stream.send("delta", models.ChatStreamDelta{Text: delta})
+	})
+	if err != nil {
+		if r.Context().Err() != nil {
+			// Client went away; nobody is left to read an error event.
+			logging.FromContext(r.Context()).Info("chat stream cancelled by client", "err", r.Context().Err())
+			return
+		}
+		stream.send("error", models.ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	stream.send("done", models.ChatStreamDone{
+		Text:  resp.Content,
+		Model: resp.Model,
+		Usage: models.TokenUsage{
+			PromptTokens:     resp.Usage.PromptTokens,
+			CompletionTokens: resp.Usage.CompletionTokens,
+			TotalTokens:      resp.Usage.TotalTokens,
+		},
+	})
+}
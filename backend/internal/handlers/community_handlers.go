@@ -1,61 +1,497 @@
 package handlers
 
 import (
+	"codefuture-backend/internal/logging"
 	"codefuture-backend/internal/middleware"
 	"codefuture-backend/internal/models"
+	"codefuture-backend/internal/store"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 )
 
+const (
+	maxCommentLength = 5000
+
+	defaultPostPageSize = 20
+	maxPostPageSize     = 50
+)
+
+// HandleGetPosts lists community posts a page at a time. Query parameters:
+// sort (newest, top, trending, discussed), topic, author (user id), from and
+// to (RFC 3339 or YYYY-MM-DD; a bare "to" date includes that day), limit and
+// cursor (next_cursor of the previous page).
 func (h *Handler) HandleGetPosts(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
 
-	topic := r.URL.Query().Get("topic")
-	posts, err := h.dataStore.GetPosts(topic)
+	// Signed-in viewers also learn which reactions they left
+	viewerID, _ := r.Context().Value(middleware.UserIDKey).(int)
+	q := models.PostQuery{
+		Topic:    params.Get("topic"),
+		Sort:     params.Get("sort"),
+		Cursor:   params.Get("cursor"),
+		Limit:    defaultPostPageSize,
+		ViewerID: viewerID,
+	}
+
+	switch q.Sort {
+	case "":
+		q.Sort = models.PostSortNewest
+	case models.PostSortNewest, models.PostSortTop, models.PostSortTrending, models.PostSortDiscussed:
+	default:
+		sendJSONError(w, "sort must be one of newest, top, trending, discussed", http.StatusBadRequest)
+		return
+	}
+
+	if v := params.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxPostPageSize {
+			sendJSONError(w, fmt.Sprintf("limit must be between 1 and %d", maxPostPageSize), http.StatusBadRequest)
+			return
+		}
+		q.Limit = n
+	}
+	if v := params.Get("author"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			sendJSONError(w, "Invalid author id", http.StatusBadRequest)
+			return
+		}
+		q.AuthorID = n
+	}
+
+	var err error
+	if q.From, err = parseDateParam(params.Get("from"), false); err != nil {
+		sendJSONError(w, "Invalid from date", http.StatusBadRequest)
+		return
+	}
+	if q.To, err = parseDateParam(params.Get("to"), true); err != nil {
+		sendJSONError(w, "Invalid to date", http.StatusBadRequest)
+		return
+	}
+
+	page, err := h.dataStore.GetPosts(q)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		sendJSONError(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		sendJSONError(w, "Failed to fetch posts", http.StatusInternalServerError)
 		return
 	}
 
-	if posts == nil {
-		posts = []models.Post{}
-	}
+	json.NewEncoder(w).Encode(page)
+}
 
-	json.NewEncoder(w).Encode(posts)
+// parseDateParam accepts an RFC 3339 timestamp or a YYYY-MM-DD date. A date
+// given as an upper bound stands for the end of that day. Empty means no bound.
+func parseDateParam(v string, upper bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, v)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if upper {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
 }
 
 func (h *Handler) HandleCreatePost(w http.ResponseWriter, r *http.Request) {
 
+	user, ok := h.communityAuthor(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreatePostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	post := &models.Post{
+		UserID:     user.ID,
+		AuthorName: user.Name,
+		Title:      req.Title,
+		Content:    req.Content,
+		Topic:      req.Topic,
+	}
+
+	decision, err := h.screen(r, user, models.ContentTypePost, req.Title, req.Content)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("post screening failed", "err", err)
+		sendJSONError(w, "Failed to create post", http.StatusInternalServerError)
+		return
+	}
+	if decision != nil && decision.Verdict == models.VerdictPending {
+		post.Status = models.PostStatusPending
+	}
+
+	if err := h.dataStore.CreatePost(post); err != nil {
+		sendJSONError(w, "Failed to create post", http.StatusInternalServerError)
+		return
+	}
+	h.recordDecision(r.Context(), decision, post.ID)
+
+	post.Reactions = map[string]int{}
+	post.ViewerReacted = []string{}
+	if post.Status == models.PostStatusPending {
+		// Held for review: only the author learns it exists
+		w.WriteHeader(http.StatusAccepted)
+	}
+	json.NewEncoder(w).Encode(post)
+}
+
+// HandleReaction changes the user's reaction of one type on a post: POST
+// toggles it, PUT adds it and DELETE removes it. Repeating a PUT or DELETE is
+// harmless. It answers with the post's updated reaction summary.
+func (h *Handler) HandleReaction(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
 	if !ok {
 		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
+	postID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid post id", http.StatusBadRequest)
+		return
+	}
+	reaction := r.PathValue("type")
+	if !models.ValidReaction(reaction) {
+		sendJSONError(w, "Unknown reaction type", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.dataStore.GetPostByID(postID)
+	if err != nil {
+		sendJSONError(w, "Failed to update reaction", http.StatusInternalServerError)
+		return
+	}
+	if post == nil || post.Status != models.PostStatusVisible {
+		sendJSONError(w, "Post not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		_, err = h.dataStore.AddReaction(postID, userID, reaction, time.Now())
+	case http.MethodDelete:
+		_, err = h.dataStore.RemoveReaction(postID, userID, reaction)
+	default:
+		var added bool
+		added, err = h.dataStore.AddReaction(postID, userID, reaction, time.Now())
+		if err == nil && !added {
+			_, err = h.dataStore.RemoveReaction(postID, userID, reaction)
+		}
+	}
+	if err != nil {
+		sendJSONError(w, "Failed to update reaction", http.StatusInternalServerError)
+		return
+	}
+
+	summary, err := h.dataStore.GetReactionSummary(postID, userID)
+	if err != nil {
+		sendJSONError(w, "Failed to update reaction", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(summary)
+}
+
+// communityAuthor loads the signed-in user who is about to write to the
+// community, writing the error response if they may not.
+func (h *Handler) communityAuthor(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+
 	// Fetch user name for author_name (could also be done via join)
 	user, err := h.dataStore.GetUserByID(userID)
 	if err != nil || user == nil {
 		sendJSONError(w, "User not found", http.StatusUnauthorized)
+		return nil, false
+	}
+	if h.config.RequireVerifiedEmail && !user.EmailVerified {
+		sendJSONError(w, "Please verify your email address before posting", http.StatusForbidden)
+		return nil, false
+	}
+	return user, true
+}
+
+// HandleGetComments returns a post's comments as a tree of replies.
+func (h *Handler) HandleGetComments(w http.ResponseWriter, r *http.Request) {
+	postID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid post id", http.StatusBadRequest)
 		return
 	}
 
-	var req models.CreatePostRequest
+	post, err := h.dataStore.GetPostByID(postID)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch comments", http.StatusInternalServerError)
+		return
+	}
+	if post == nil || post.Status != models.PostStatusVisible {
+		sendJSONError(w, "Post not found", http.StatusNotFound)
+		return
+	}
+
+	comments, err := h.dataStore.GetCommentsByPostID(postID)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch comments", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(buildCommentTree(comments))
+}
+
+func (h *Handler) HandleCreateComment(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.communityAuthor(w, r)
+	if !ok {
+		return
+	}
+
+	postID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid post id", http.StatusBadRequest)
+		return
+	}
+
+	var req models.CreateCommentRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		sendJSONError(w, "Invalid input", http.StatusBadRequest)
 		return
 	}
+	content, msg := validateComment(req.Content)
+	if msg != "" {
+		sendJSONError(w, msg, http.StatusBadRequest)
+		return
+	}
 
-	post := &models.Post{
-		UserID:     userID,
+	post, err := h.dataStore.GetPostByID(postID)
+	if err != nil {
+		sendJSONError(w, "Failed to create comment", http.StatusInternalServerError)
+		return
+	}
+	if post == nil || post.Status != models.PostStatusVisible {
+		sendJSONError(w, "Post not found", http.StatusNotFound)
+		return
+	}
+	if post.Locked {
+		sendJSONError(w, "This post is locked", http.StatusForbidden)
+		return
+	}
+	if req.ParentID != nil {
+		parent, err := h.dataStore.GetCommentByID(*req.ParentID)
+		if err != nil {
+			sendJSONError(w, "Failed to create comment", http.StatusInternalServerError)
+			return
+		}
+		if parent == nil || parent.PostID != postID || parent.Deleted || parent.Status != models.CommentStatusVisible {
+			sendJSONError(w, "Parent comment not found", http.StatusBadRequest)
+			return
+		}
+	}
+
+	comment := &models.Comment{
+		PostID:     postID,
+		ParentID:   req.ParentID,
+		UserID:     user.ID,
 		AuthorName: user.Name,
-		Title:      req.Title,
-		Content:    req.Content,
-		Topic:      req.Topic,
+		Content:    content,
+		Replies:    []*models.Comment{},
 	}
 
-	if err := h.dataStore.CreatePost(post); err != nil {
-		sendJSONError(w, "Failed to create post", http.StatusInternalServerError)
+	decision, err := h.screen(r, user, models.ContentTypeComment, "", content)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("comment screening failed", "err", err)
+		sendJSONError(w, "Failed to create comment", http.StatusInternalServerError)
 		return
 	}
+	if decision != nil && decision.Verdict == models.VerdictPending {
+		comment.Status = models.CommentStatusPending
+	}
 
-	json.NewEncoder(w).Encode(post)
+	if err := h.dataStore.CreateComment(comment); err != nil {
+		sendJSONError(w, "Failed to create comment", http.StatusInternalServerError)
+		return
+	}
+	h.recordDecision(r.Context(), decision, comment.ID)
+
+	if comment.Status == models.CommentStatusPending {
+		w.WriteHeader(http.StatusAccepted)
+	} else {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(comment)
+}
+
+// HandleUpdateComment lets the author edit their comment. Edited text is
+// screened like a new comment, and a held edit hides the comment until a
+// moderator approves it.
+func (h *Handler) HandleUpdateComment(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.communityAuthor(w, r)
+	if !ok {
+		return
+	}
+
+	commentID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid comment id", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	content, msg := validateComment(req.Content)
+	if msg != "" {
+		sendJSONError(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	existing, err := h.dataStore.GetCommentByID(commentID)
+	if err != nil {
+		sendJSONError(w, "Failed to update comment", http.StatusInternalServerError)
+		return
+	}
+	if existing == nil || existing.Deleted {
+		sendJSONError(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+	if existing.UserID != user.ID {
+		sendJSONError(w, "You can only change your own comments", http.StatusForbidden)
+		return
+	}
+
+	// Unchanged text was screened when it was posted
+	var decision *models.ModerationDecision
+	if content != existing.Content {
+		decision, err = h.screen(r, user, models.ContentTypeComment, "", content)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("comment screening failed", "comment_id", commentID, "err", err)
+			sendJSONError(w, "Failed to update comment", http.StatusInternalServerError)
+			return
+		}
+	}
+	status := models.CommentStatusVisible
+	if decision != nil && decision.Verdict == models.VerdictPending {
+		status = models.CommentStatusPending
+	}
+
+	updated, err := h.dataStore.UpdateComment(commentID, user.ID, content, status, time.Now())
+	if err != nil {
+		sendJSONError(w, "Failed to update comment", http.StatusInternalServerError)
+		return
+	}
+	if !updated {
+		h.commentNotWritable(w, commentID)
+		return
+	}
+	h.recordDecision(r.Context(), decision, commentID)
+
+	comment, err := h.dataStore.GetCommentByID(commentID)
+	if err != nil || comment == nil {
+		sendJSONError(w, "Failed to update comment", http.StatusInternalServerError)
+		return
+	}
+	comment.Replies = []*models.Comment{}
+	if comment.Status == models.CommentStatusPending {
+		w.WriteHeader(http.StatusAccepted)
+	}
+	json.NewEncoder(w).Encode(comment)
+}
+
+// HandleDeleteComment lets the author delete their comment. Replies to it stay.
+func (h *Handler) HandleDeleteComment(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	commentID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid comment id", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := h.dataStore.DeleteComment(commentID, userID, time.Now())
+	if err != nil {
+		sendJSONError(w, "Failed to delete comment", http.StatusInternalServerError)
+		return
+	}
+	if !deleted {
+		h.commentNotWritable(w, commentID)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// commentNotWritable explains why an edit or delete matched no comment.
+func (h *Handler) commentNotWritable(w http.ResponseWriter, commentID int) {
+	comment, err := h.dataStore.GetCommentByID(commentID)
+	switch {
+	case err != nil:
+		sendJSONError(w, "Failed to fetch comment", http.StatusInternalServerError)
+	case comment == nil || comment.Deleted:
+		sendJSONError(w, "Comment not found", http.StatusNotFound)
+	default:
+		sendJSONError(w, "You can only change your own comments", http.StatusForbidden)
+	}
+}
+
+func validateComment(content string) (string, string) {
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return "", "Comment cannot be empty"
+	}
+	if len(content) > maxCommentLength {
+		return "", "Comment is too long"
+	}
+	return content, ""
+}
+
+// buildCommentTree nests a post's comments (oldest first) under their
+// parents. Deleted comments stay in place, without author or content, so
+// their replies keep their context.
+func buildCommentTree(comments []models.Comment) []*models.Comment {
+	byID := make(map[int]*models.Comment, len(comments))
+	for i := range comments {
+		c := &comments[i]
+		c.Replies = []*models.Comment{}
+		if c.Deleted {
+			c.UserID = 0
+			c.AuthorName = ""
+			c.Content = ""
+		}
+		byID[c.ID] = c
+	}
+
+	roots := []*models.Comment{}
+	for i := range comments {
+		c := &comments[i]
+		if c.ParentID != nil {
+			if parent, ok := byID[*c.ParentID]; ok {
+				parent.Replies = append(parent.Replies, c)
+				continue
+			}
+		}
+		roots = append(roots, c)
+	}
+	return roots
 }
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"codefuture-backend/internal/logging"
+	"codefuture-backend/internal/models"
+)
+
+// queueEmail renders template for to and puts it in the outbox. Email is
+// never worth failing a request over once it got this far, so problems are
+// only logged.
+func (h *Handler) queueEmail(ctx context.Context, to, template string, data any) bool {
+	if err := h.mailer.Enqueue(to, template, data); err != nil {
+		logging.FromContext(ctx).Error("failed to queue email", "template", template, "to", to, "err", err)
+		return false
+	}
+	return true
+}
+
+// HandleAdminListEmails lists outbox emails, newest first. Query
+// parameters: status (pending, sent or dead) and limit.
+func (h *Handler) HandleAdminListEmails(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseModerationLimit(w, r)
+	if !ok {
+		return
+	}
+	status := r.URL.Query().Get("status")
+	if status != "" && !slices.Contains(models.EmailStatuses, status) {
+		sendJSONError(w, "status must be one of "+strings.Join(models.EmailStatuses, ", "), http.StatusBadRequest)
+		return
+	}
+
+	emails, err := h.dataStore.ListOutboxEmails(models.OutboxQuery{Status: status, Limit: limit})
+	if err != nil {
+		sendJSONError(w, "Failed to fetch emails", http.StatusInternalServerError)
+		return
+	}
+	if !h.audit(w, r, models.AuditActionList, models.AuditTargetEmail, 0) {
+		return
+	}
+	json.NewEncoder(w).Encode(emails)
+}
+
+// HandleAdminRetryEmail gives a dead or still pending email a fresh set of
+// attempts, starting now.
+func (h *Handler) HandleAdminRetryEmail(w http.ResponseWriter, r *http.Request) {
+	adminID, _ := actor(r)
+	emailID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid email id", http.StatusBadRequest)
+		return
+	}
+
+	ok, err := h.dataStore.RetryEmail(emailID, adminID, time.Now())
+	if err != nil {
+		sendJSONError(w, "Failed to retry email", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		sendJSONError(w, "Email not found or already sent", http.StatusNotFound)
+		return
+	}
+	h.mailer.Wake()
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
@@ -2,26 +2,40 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 
+	"codefuture-backend/internal/auth"
 	"codefuture-backend/internal/config"
+	"codefuture-backend/internal/email"
+	"codefuture-backend/internal/logging"
 	"codefuture-backend/internal/middleware"
 	"codefuture-backend/internal/models"
+	"codefuture-backend/internal/moderation"
+	"codefuture-backend/internal/sandbox"
 	"codefuture-backend/internal/services"
 	"codefuture-backend/internal/store"
 )
 
 type Handler struct {
 	aiStore   *services.AIService
-	dataStore *store.Store
+	dataStore store.Repository
+	executor  *sandbox.Executor
+	tokens    *auth.TokenManager
+	screener  *moderation.Screener // nil when screening is disabled
+	mailer    *email.Mailer
 	config    *config.Config
 }
 
-func NewHandler(ai *services.AIService, db *store.Store, cfg *config.Config) *Handler {
+func NewHandler(ai *services.AIService, db store.Repository, executor *sandbox.Executor, tokens *auth.TokenManager, screener *moderation.Screener, mailer *email.Mailer, cfg *config.Config) *Handler {
 	return &Handler{
 		aiStore:   ai,
 		dataStore: db,
+		executor:  executor,
+		tokens:    tokens,
+		screener:  screener,
+		mailer:    mailer,
 		config:    cfg,
 	}
 }
@@ -34,9 +48,9 @@ func (h *Handler) HandleLessonPlan(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	content, err := h.aiStore.GenerateLessonPlan(req.Persona, req.Goals)
+	curriculum, err := h.aiStore.GenerateLessonPlan(r.Context(), req.Persona, req.Goals)
 	if err != nil {
-		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		sendJSONError(w, err.Error(), aiErrorStatus(err))
 		return
 	}
 
@@ -47,23 +61,37 @@ func (h *Handler) HandleLessonPlan(w http.ResponseWriter, r *http.Request) {
 		userID = &val
 	}
 
-	id, err := h.dataStore.SaveLessonPlan(userID, req.Persona, req.Goals, content)
+	// The client redirects to /course/:id, so without a saved plan there is
+	// nothing to return. Lessons and exercises are stored separately so hidden
+	// tests never reach the client.
+	id, text, err := h.saveCurriculum(userID, req.Persona, req.Goals, curriculum)
 	if err != nil {
-		// If save fails, we should probably still return content but maybe warn?
-		// For now, let's treat DB error as non-fatal for generation but fatal for "saving" feature.
-		// Actually, if we want to redirect to /course/:id, we NEED the ID.
-		// So we must return error.
-		sendJSONError(w, "Failed to save lesson plan: "+err.Error(), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error("failed to save lesson plan", "err", err)
+		sendJSONError(w, "Failed to save lesson plan", http.StatusInternalServerError)
 		return
 	}
 
 	json.NewEncoder(w).Encode(models.LessonPlanResponse{
-		ID:   id,
-		Text: content,
+		ID:         id,
+		Text:       text,
+		Curriculum: curriculum,
 	})
 }
 
+// aiErrorStatus maps generation failures to a status: a model that keeps
+// returning unusable content is an upstream problem, not ours.
+func aiErrorStatus(err error) int {
+	if errors.Is(err, services.ErrInvalidAIOutput) {
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
 func (h *Handler) HandleChat(w http.ResponseWriter, r *http.Request) {
+	if wantsEventStream(r) {
+		h.HandleChatStream(w, r)
+		return
+	}
 
 	var req models.ChatRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -80,21 +108,76 @@ func (h *Handler) HandleChat(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(models.Response{Text: resp})
 }
 
+// maxCodeBodyBytes bounds requests carrying code to run.
+const maxCodeBodyBytes = 256 << 10
+
 func (h *Handler) HandleExecute(w http.ResponseWriter, r *http.Request) {
 
 	var req models.ExecuteRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxCodeBodyBytes)
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			sendJSONError(w, "Code is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
 		sendJSONError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	resp, err := h.aiStore.ExecuteCode(r.Context(), req.Code, req.Language)
+	// The LLM "interpreter" invents output; it is only used when explicitly configured.
+	if h.config.ExecutionMode == "llm" {
+		resp, err := h.aiStore.ExecuteCode(r.Context(), req.Code, req.Language)
+		if err != nil {
+			sendJSONError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		json.NewEncoder(w).Encode(models.ExecuteResponse{Text: resp, Stdout: resp, Mode: "llm"})
+		return
+	}
+
+	result, err := h.executor.Run(r.Context(), sandbox.Request{
+		Language: req.Language,
+		Code:     req.Code,
+		Stdin:    req.Stdin,
+	})
+	if errors.Is(err, sandbox.ErrUnsupportedLanguage) {
+		sendJSONError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, sandbox.ErrBusy) {
+		sendSandboxBusy(w)
+		return
+	}
 	if err != nil {
-		sendJSONError(w, err.Error(), http.StatusInternalServerError)
+		logging.FromContext(r.Context()).Error("sandbox failed", "language", req.Language, "err", err)
+		sendJSONError(w, "Code execution is unavailable", http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(models.Response{Text: resp})
+	json.NewEncoder(w).Encode(newExecuteResponse(result))
+}
+
+// sendSandboxBusy tells the client every sandbox slot stayed taken and to
+// retry shortly.
+func sendSandboxBusy(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "5")
+	sendJSONError(w, sandbox.ErrBusy.Error(), http.StatusServiceUnavailable)
+}
+
+func newExecuteResponse(result *sandbox.Result) models.ExecuteResponse {
+	return models.ExecuteResponse{
+		Text:       result.Stdout + result.Stderr,
+		Stdout:     result.Stdout,
+		Stderr:     result.Stderr,
+		ExitCode:   result.ExitCode,
+		TimedOut:   result.TimedOut,
+		Truncated:  result.Truncated,
+		Phase:      result.Phase,
+		DurationMs: result.Duration.Milliseconds(),
+		Mode:       "sandbox",
+	}
 }
 
 // HandleMath performs a simple arithmetic operation (addition) on two numbers.
@@ -118,41 +201,40 @@ func (h *Handler) HandleGetCourses(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Refactoring HandleGetCourses to switch on method
-	if r.Method == "DELETE" {
-		idStr := r.URL.Query().Get("id")
-		if idStr == "" {
-			sendJSONError(w, "Missing id parameter", http.StatusBadRequest)
-			return
-		}
+	courses, err := h.dataStore.GetCoursesByUserID(userID)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch courses", http.StatusInternalServerError)
+		return
+	}
 
-		courseID, err := strconv.Atoi(idStr)
-		if err != nil {
-			sendJSONError(w, "Invalid id parameter", http.StatusBadRequest)
-			return
-		}
+	if courses == nil {
+		courses = []map[string]interface{}{}
+	}
 
-		if err := h.dataStore.DeleteCourse(userID, courseID); err != nil {
-			sendJSONError(w, "Failed to delete course", http.StatusInternalServerError)
-			return
-		}
+	json.NewEncoder(w).Encode(courses)
+}
 
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+// HandleDeleteCourse deletes one of the signed-in user's courses.
+func (h *Handler) HandleDeleteCourse(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	courses, err := h.dataStore.GetCoursesByUserID(userID)
+	courseID, err := strconv.Atoi(r.PathValue("id"))
 	if err != nil {
-		sendJSONError(w, "Failed to fetch courses", http.StatusInternalServerError)
+		sendJSONError(w, "Invalid course id", http.StatusBadRequest)
 		return
 	}
 
-	if courses == nil {
-		courses = []map[string]interface{}{}
+	if err := h.dataStore.DeleteCourse(userID, courseID); err != nil {
+		sendJSONError(w, "Failed to delete course", http.StatusInternalServerError)
+		return
 	}
 
-	json.NewEncoder(w).Encode(courses)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
 }
 
 func sendJSONError(w http.ResponseWriter, message string, status int) {
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"codefuture-backend/internal/logging"
+	"codefuture-backend/internal/metrics"
+	"codefuture-backend/internal/models"
+)
+
+// readyTimeout bounds each readiness check.
+const readyTimeout = 3 * time.Second
+
+var metricsHandler = metrics.Handler()
+
+// HandleHealthz reports that the process is up and serving. It checks
+// nothing else, so a slow database never gets the process restarted.
+func (h *Handler) HandleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// HandleReadyz reports whether the server can take traffic: the database
+// answers a ping and has been migrated and, with READYZ_CHECK_LLM, the LLM
+// provider is reachable.
+// Anyone can call it, so failures are only named; the details are logged.
+func (h *Handler) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	ready := models.Readiness{Status: "ready", Checks: map[string]string{}}
+	check := func(name string, err error) {
+		if err != nil {
+			logging.FromContext(r.Context()).Warn("readiness check failed", "check", name, "err", err)
+			ready.Status = "unavailable"
+			ready.Checks[name] = "failed"
+			return
+		}
+		ready.Checks[name] = "ok"
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	check("database", h.dataStore.Ping(ctx))
+	cancel()
+	var schema error
+	if !h.dataStore.Migrated() {
+		schema = errors.New("migrations have not run")
+	}
+	check("schema", schema)
+	if h.config.ReadyzCheckLLM {
+		ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+		check("llm", h.aiStore.Ping(ctx))
+		cancel()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if ready.Status != "ready" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(ready)
+}
+
+// HandleMetrics serves the Prometheus metrics.
+func (h *Handler) HandleMetrics(w http.ResponseWriter, r *http.Request) {
+	metricsHandler.ServeHTTP(w, r)
+}
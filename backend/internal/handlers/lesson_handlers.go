@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"codefuture-backend/internal/grading"
+	"codefuture-backend/internal/logging"
+	"codefuture-backend/internal/middleware"
+	"codefuture-backend/internal/models"
+	"codefuture-backend/internal/sandbox"
+)
+
+// saveCurriculum stores a freshly generated curriculum as a plan with its
+// lessons (full exercises, hidden tests included) in one go, then rewrites c
+// as the client should see it: every lesson tagged with its LessonID and
+// hidden tests removed. The public JSON is saved as the plan content and
+// returned with the plan's ID.
+func (h *Handler) saveCurriculum(userID *int, persona, goals string, c *models.Curriculum) (int, string, error) {
+	records := make([]*models.LessonRecord, len(c.Lessons))
+	for i, l := range c.Lessons {
+		records[i] = &models.LessonRecord{
+			LessonIndex: i,
+			Title:       l.Title,
+			Language:    c.Language,
+			Exercise:    l.Exercise,
+		}
+	}
+
+	var public string
+	id, err := h.dataStore.SaveCurriculum(userID, persona, goals, records, func() (string, error) {
+		for i, record := range records {
+			c.Lessons[i].LessonID = record.ID
+		}
+		encoded, err := json.Marshal(publicCurriculum(c))
+		public = string(encoded)
+		return public, err
+	})
+	if err != nil {
+		return 0, "", err
+	}
+	*c = *publicCurriculum(c)
+	return id, public, nil
+}
+
+// publicCurriculum returns a copy of c with hidden test cases stripped.
+func publicCurriculum(c *models.Curriculum) *models.Curriculum {
+	public := *c
+	public.Lessons = make([]models.Lesson, len(c.Lessons))
+	for i, l := range c.Lessons {
+		if l.Exercise != nil {
+			l.Exercise = publicExercise(l.Exercise)
+		}
+		public.Lessons[i] = l
+	}
+	return &public
+}
+
+// publicExercise strips hidden test cases before an exercise leaves the server.
+func publicExercise(e *models.Exercise) *models.Exercise {
+	visible := &models.Exercise{Prompt: e.Prompt, Tests: []models.TestCase{}}
+	for _, tc := range e.Tests {
+		if !tc.Hidden {
+			visible.Tests = append(visible.Tests, tc)
+		}
+	}
+	return visible
+}
+
+// HandleSubmitLesson grades the learner's code against the lesson's exercise,
+// records the attempt and advances the plan when every test passes.
+func (h *Handler) HandleSubmitLesson(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	lessonID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid lesson id", http.StatusBadRequest)
+		return
+	}
+
+	var req models.SubmitLessonRequest
+	r.Body = http.MaxBytesReader(w, r.Body, maxCodeBodyBytes)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			sendJSONError(w, "Code is too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		sendJSONError(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	lesson, err := h.dataStore.GetLessonByID(lessonID)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch lesson", http.StatusInternalServerError)
+		return
+	}
+	if lesson == nil {
+		sendJSONError(w, "Lesson not found", http.StatusNotFound)
+		return
+	}
+	if lesson.Exercise == nil {
+		sendJSONError(w, "This lesson has no exercise", http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.dataStore.GetLessonPlanByID(lesson.PlanID)
+	if err != nil || plan == nil {
+		sendJSONError(w, "Failed to fetch lesson plan", http.StatusInternalServerError)
+		return
+	}
+	if plan.UserID != nil && *plan.UserID != userID {
+		sendJSONError(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	report, err := grading.Grade(r.Context(), h.executor, lesson.Language, req.Code, lesson.Exercise)
+	if errors.Is(err, sandbox.ErrBusy) {
+		sendSandboxBusy(w)
+		return
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("lesson grading failed", "err", err)
+		sendJSONError(w, "Failed to run tests", http.StatusInternalServerError)
+		return
+	}
+
+	attempt := &models.LessonAttempt{
+		UserID:      userID,
+		LessonID:    lesson.ID,
+		Code:        req.Code,
+		Passed:      report.Passed,
+		PassedCount: report.PassedCount,
+		TotalCount:  report.TotalCount,
+	}
+	if err := h.dataStore.CreateLessonAttempt(attempt); err != nil {
+		sendJSONError(w, "Failed to save attempt", http.StatusInternalServerError)
+		return
+	}
+
+	resp := models.SubmitLessonResponse{
+		AttemptID:    attempt.ID,
+		Passed:       report.Passed,
+		PassedCount:  report.PassedCount,
+		TotalCount:   report.TotalCount,
+		Results:      report.Results,
+		CurrentIndex: plan.CurrentLessonIndex,
+	}
+
+	// Only the owner's own plan advances, and only from the lesson they are on
+	if report.Passed && plan.UserID != nil && plan.CurrentLessonIndex == lesson.LessonIndex {
+		if err := h.dataStore.UpdateLessonProgress(plan.ID, lesson.LessonIndex+1); err != nil {
+			sendJSONError(w, "Failed to update progress", http.StatusInternalServerError)
+			return
+		}
+		resp.Advanced = true
+		resp.CurrentIndex = lesson.LessonIndex + 1
+	}
+
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandleGetLessonAttempts lists the caller's attempts for a lesson, newest first.
+func (h *Handler) HandleGetLessonAttempts(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	lessonID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid lesson id", http.StatusBadRequest)
+		return
+	}
+
+	attempts, err := h.dataStore.GetLessonAttempts(userID, lessonID)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch attempts", http.StatusInternalServerError)
+		return
+	}
+	if attempts == nil {
+		attempts = []models.LessonAttempt{}
+	}
+
+	json.NewEncoder(w).Encode(attempts)
+}
+
+// lockedLesson returns the first lesson between from (inclusive) and to
+// (exclusive) whose exercise the user has not passed yet, or nil.
+func (h *Handler) lockedLesson(userID, planID, from, to int) (*models.LessonRecord, error) {
+	lessons, err := h.dataStore.GetLessonsByPlanID(planID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range lessons {
+		l := &lessons[i]
+		if l.LessonIndex < from || l.LessonIndex >= to || l.Exercise == nil {
+			continue
+		}
+		passed, err := h.dataStore.HasPassedLesson(userID, l.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !passed {
+			return l, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,396 @@
+package handlers
+
+import (
+	"codefuture-backend/internal/logging"
+	"codefuture-backend/internal/middleware"
+	"codefuture-backend/internal/models"
+	"codefuture-backend/internal/moderation"
+	"codefuture-backend/internal/store"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxReportDetailsLength    = 1000
+	maxModerationReasonLength = 500
+	maxSuspensionHours        = 24 * 365
+
+	defaultModerationPageSize = 50
+	maxModerationPageSize     = 100
+)
+
+// permanentSuspension is the suspended_until stored for a suspension with no end.
+var permanentSuspension = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// HandleReportPost lets a user flag someone else's post for the moderators.
+// Each user can report a post once.
+func (h *Handler) HandleReportPost(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	postID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid post id", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ReportPostRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(models.ReportReasons, req.Reason) {
+		sendJSONError(w, "reason must be one of "+strings.Join(models.ReportReasons, ", "), http.StatusBadRequest)
+		return
+	}
+	details := strings.TrimSpace(req.Details)
+	if len(details) > maxReportDetailsLength {
+		sendJSONError(w, "Details are too long", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.dataStore.GetPostByID(postID)
+	if err != nil {
+		sendJSONError(w, "Failed to report post", http.StatusInternalServerError)
+		return
+	}
+	if post == nil || post.Status != models.PostStatusVisible {
+		sendJSONError(w, "Post not found", http.StatusNotFound)
+		return
+	}
+	if post.UserID == userID {
+		sendJSONError(w, "You can't report your own post", http.StatusBadRequest)
+		return
+	}
+
+	report := &models.Report{PostID: postID, ReporterID: userID, Reason: req.Reason, Details: details}
+	err = h.dataStore.CreateReport(report)
+	if errors.Is(err, store.ErrAlreadyReported) {
+		sendJSONError(w, "You have already reported this post", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		sendJSONError(w, "Failed to report post", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
+}
+
+// actor returns the signed-in user's ID and role. Only routes wrapped with
+// RequirePermission have the role in their context.
+func actor(r *http.Request) (int, string) {
+	userID, _ := r.Context().Value(middleware.UserIDKey).(int)
+	role, _ := r.Context().Value(middleware.UserRoleKey).(string)
+	return userID, role
+}
+
+// HandleModerationQueue lists posts with open reports, most reported first.
+func (h *Handler) HandleModerationQueue(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseModerationLimit(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := h.dataStore.GetModerationQueue(limit)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch moderation queue", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(items)
+}
+
+// HandleModeratePost applies a moderator action (hide, unhide, lock,
+// unlock, remove, dismiss, or approve for a post held by screening) to a
+// post and answers with the post.
+func (h *Handler) HandleModeratePost(w http.ResponseWriter, r *http.Request) {
+	moderatorID, _ := actor(r)
+
+	postID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid post id", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ModerateContentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(models.PostActions, req.Action) {
+		sendJSONError(w, "action must be one of "+strings.Join(models.PostActions, ", "), http.StatusBadRequest)
+		return
+	}
+	reason, msg := validateModerationReason(req.Reason)
+	if msg != "" {
+		sendJSONError(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.dataStore.ApplyPostAction(postID, moderatorID, req.Action, reason, time.Now())
+	if errors.Is(err, store.ErrPostRemoved) {
+		sendJSONError(w, "Post has been removed", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		sendJSONError(w, "Failed to moderate post", http.StatusInternalServerError)
+		return
+	}
+	if post == nil {
+		sendJSONError(w, "Post not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(post)
+}
+
+// HandleModerateComment approves a comment held by screening or removes a
+// comment, and answers with the comment.
+func (h *Handler) HandleModerateComment(w http.ResponseWriter, r *http.Request) {
+	moderatorID, _ := actor(r)
+
+	commentID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid comment id", http.StatusBadRequest)
+		return
+	}
+
+	var req models.ModerateContentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(models.CommentActions, req.Action) {
+		sendJSONError(w, "action must be one of "+strings.Join(models.CommentActions, ", "), http.StatusBadRequest)
+		return
+	}
+	reason, msg := validateModerationReason(req.Reason)
+	if msg != "" {
+		sendJSONError(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	comment, err := h.dataStore.ApplyCommentAction(commentID, moderatorID, req.Action, reason, time.Now())
+	if err != nil {
+		sendJSONError(w, "Failed to moderate comment", http.StatusInternalServerError)
+		return
+	}
+	if comment == nil {
+		sendJSONError(w, "Comment not found", http.StatusNotFound)
+		return
+	}
+	comment.Replies = []*models.Comment{}
+	json.NewEncoder(w).Encode(comment)
+}
+
+// HandlePendingContent lists posts and comments held by screening, oldest
+// first, each with the labels and reasons that held it.
+func (h *Handler) HandlePendingContent(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseModerationLimit(w, r)
+	if !ok {
+		return
+	}
+
+	items, err := h.dataStore.GetPendingContent(limit)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch pending content", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(items)
+}
+
+// screen runs automated moderation on content the author is about to
+// publish. It returns nil when screening is off; moderators' own content
+// isn't screened.
+func (h *Handler) screen(r *http.Request, author *models.User, contentType, title, body string) (*models.ModerationDecision, error) {
+	if h.screener == nil || author.IsModerator() {
+		return nil, nil
+	}
+	return h.screener.Screen(r.Context(), moderation.Content{Type: contentType, AuthorID: author.ID, Title: title, Body: body})
+}
+
+// recordDecision stores the screening decision for content that has just
+// been saved. The content is already published or held by then, so a failure
+// here is only logged.
+func (h *Handler) recordDecision(ctx context.Context, d *models.ModerationDecision, contentID int) {
+	if d == nil {
+		return
+	}
+	d.ContentID = contentID
+	if err := h.dataStore.SaveModerationDecision(d); err != nil {
+		logging.FromContext(ctx).Error("failed to record moderation decision", "content_type", d.ContentType, "content_id", contentID, "err", err)
+	}
+}
+
+// HandleSuspendUser suspends a user for a number of hours, until a given
+// time, or permanently. Their sessions end at once.
+func (h *Handler) HandleSuspendUser(w http.ResponseWriter, r *http.Request) {
+	moderatorID, target, ok := h.moderationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.SuspendUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	reason, msg := validateModerationReason(req.Reason)
+	if msg == "" && reason == "" {
+		msg = "A reason is required"
+	}
+	if msg != "" {
+		sendJSONError(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	var until time.Time
+	switch {
+	case req.Permanent && req.Until == nil && req.Hours == 0:
+		until = permanentSuspension
+	case req.Until != nil && !req.Permanent && req.Hours == 0:
+		if !req.Until.After(now) {
+			sendJSONError(w, "until must be in the future", http.StatusBadRequest)
+			return
+		}
+		until = *req.Until
+	case req.Hours != 0 && !req.Permanent && req.Until == nil:
+		if req.Hours < 1 || req.Hours > maxSuspensionHours {
+			sendJSONError(w, fmt.Sprintf("hours must be between 1 and %d", maxSuspensionHours), http.StatusBadRequest)
+			return
+		}
+		until = now.Add(time.Duration(req.Hours) * time.Hour)
+	default:
+		sendJSONError(w, "Give exactly one of hours, until or permanent", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.dataStore.SuspendUser(target.ID, moderatorID, until, reason, now); err != nil {
+		sendJSONError(w, "Failed to suspend user", http.StatusInternalServerError)
+		return
+	}
+	h.writeModeratedUser(w, target.ID)
+}
+
+// HandleLiftSuspension ends a user's suspension early.
+func (h *Handler) HandleLiftSuspension(w http.ResponseWriter, r *http.Request) {
+	moderatorID, target, ok := h.moderationTarget(w, r)
+	if !ok {
+		return
+	}
+
+	lifted, err := h.dataStore.LiftSuspension(target.ID, moderatorID, "", time.Now())
+	if err != nil {
+		sendJSONError(w, "Failed to lift suspension", http.StatusInternalServerError)
+		return
+	}
+	if !lifted {
+		sendJSONError(w, "User is not suspended", http.StatusConflict)
+		return
+	}
+	h.writeModeratedUser(w, target.ID)
+}
+
+// moderationTarget returns the moderator's ID and loads the user named in the
+// path, writing the error response unless the moderator may act on that user:
+// moderators only on learners, admins on anyone but other admins, and
+// nobody on themselves.
+func (h *Handler) moderationTarget(w http.ResponseWriter, r *http.Request) (int, *models.User, bool) {
+	moderatorID, role := actor(r)
+
+	targetID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid user id", http.StatusBadRequest)
+		return 0, nil, false
+	}
+	if targetID == moderatorID {
+		sendJSONError(w, "You can't moderate your own account", http.StatusBadRequest)
+		return 0, nil, false
+	}
+
+	target, err := h.dataStore.GetUserByID(targetID)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch user", http.StatusInternalServerError)
+		return 0, nil, false
+	}
+	if target == nil {
+		sendJSONError(w, "User not found", http.StatusNotFound)
+		return 0, nil, false
+	}
+	if target.Role == models.RoleAdmin || (target.IsModerator() && role != models.RoleAdmin) {
+		sendJSONError(w, "You can't moderate this user", http.StatusForbidden)
+		return 0, nil, false
+	}
+	return moderatorID, target, true
+}
+
+func (h *Handler) writeModeratedUser(w http.ResponseWriter, userID int) {
+	user, err := h.dataStore.GetUserByID(userID)
+	if err != nil || user == nil {
+		sendJSONError(w, "Failed to fetch user", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(user)
+}
+
+// HandleAuditLog lists moderation actions, newest first. Query parameters:
+// actor (user id), target_type, target_id, before (an entry id, to page back)
+// and limit.
+func (h *Handler) HandleAuditLog(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	limit, ok := parseModerationLimit(w, r)
+	if !ok {
+		return
+	}
+	q := models.AuditQuery{TargetType: params.Get("target_type"), Limit: limit}
+	for name, dst := range map[string]*int{"actor": &q.ActorID, "target_id": &q.TargetID, "before": &q.BeforeID} {
+		if v := params.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 {
+				sendJSONError(w, "Invalid "+name, http.StatusBadRequest)
+				return
+			}
+			*dst = n
+		}
+	}
+
+	entries, err := h.dataStore.ListAuditLog(q)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch audit log", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(entries)
+}
+
+func parseModerationLimit(w http.ResponseWriter, r *http.Request) (int, bool) {
+	v := r.URL.Query().Get("limit")
+	if v == "" {
+		return defaultModerationPageSize, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > maxModerationPageSize {
+		sendJSONError(w, fmt.Sprintf("limit must be between 1 and %d", maxModerationPageSize), http.StatusBadRequest)
+		return 0, false
+	}
+	return n, true
+}
+
+func validateModerationReason(reason string) (string, string) {
+	reason = strings.TrimSpace(reason)
+	if len(reason) > maxModerationReasonLength {
+		return "", "Reason is too long"
+	}
+	return reason, ""
+}
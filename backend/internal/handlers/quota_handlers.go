@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"codefuture-backend/internal/middleware"
+)
+
+// HandleMyAIQuota reports the signed-in user's AI usage for today and this
+// month against their quota.
+func (h *Handler) HandleMyAIQuota(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	quota, err := h.dataStore.GetAIQuota(userID, time.Now(), h.config.AIDailyQuota, h.config.AIMonthlyQuota)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch AI quota", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(quota)
+}
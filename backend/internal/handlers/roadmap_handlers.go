@@ -1,29 +1,32 @@
 package handlers
 
 import (
+	"codefuture-backend/internal/logging"
 	"codefuture-backend/internal/middleware"
+	"codefuture-backend/internal/models"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 )
 
 type RoadmapResponse struct {
-	PlanID       int         `json:"plan_id"`
-	Content      interface{} `json:"content"` // Parsed JSON content
-	CurrentIndex int         `json:"current_index"`
+	PlanID       int                `json:"plan_id"`
+	Kind         string             `json:"kind"`
+	Content      models.PlanContent `json:"content"` // *models.Curriculum or *models.Roadmap, per Kind
+	CurrentIndex int                `json:"current_index"`
 }
 
 func (h *Handler) HandleGetRoadmap(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
 	if !ok {
-		fmt.Println("[Error] HandleGetRoadmap: Unauthorized")
 		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	plan, err := h.dataStore.GetLatestLessonPlan(userID)
 	if err != nil {
-		fmt.Printf("[Error] HandleGetRoadmap: DB Fetch failed: %v\n", err)
+		logging.FromContext(r.Context()).Error("failed to load roadmap", "err", err)
 		sendJSONError(w, "Failed to fetch roadmap", http.StatusInternalServerError)
 		return
 	}
@@ -34,17 +37,17 @@ func (h *Handler) HandleGetRoadmap(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Parse the content string (which is JSON) back to object
-	var contentObj interface{}
-	if err := json.Unmarshal([]byte(plan.Content), &contentObj); err != nil {
-		fmt.Printf("[Warning] HandleGetRoadmap: JSON Parse failed for plan content: %v (Content len: %d)\n", err, len(plan.Content))
-		// If fails (maybe plain text? shouldn't happen with new AI), return raw
-		contentObj = plan.Content
+	content, err := plan.DecodeContent()
+	if err != nil {
+		logging.FromContext(r.Context()).Error("saved plan has invalid content", "plan_id", plan.ID, "kind", plan.Kind, "content_len", len(plan.Content), "err", err)
+		sendJSONError(w, "Stored roadmap is corrupted", http.StatusInternalServerError)
+		return
 	}
 
 	resp := RoadmapResponse{
 		PlanID:       plan.ID,
-		Content:      contentObj,
+		Kind:         plan.Kind,
+		Content:      content,
 		CurrentIndex: plan.CurrentLessonIndex,
 	}
 
@@ -57,6 +60,12 @@ func (h *Handler) HandleGetRoadmap(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) HandleUpdateProgress(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
 	var req struct {
 		PlanID int `json:"plan_id"`
 		Index  int `json:"index"`
@@ -67,6 +76,40 @@ func (h *Handler) HandleUpdateProgress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	plan, err := h.dataStore.GetLessonPlanByID(req.PlanID)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch plan", http.StatusInternalServerError)
+		return
+	}
+	if plan == nil || plan.UserID == nil || *plan.UserID != userID {
+		sendJSONError(w, "Plan not found", http.StatusNotFound)
+		return
+	}
+
+	// Index is the next step to take; one past the last means all done
+	steps, err := progressSteps(plan)
+	if err != nil {
+		sendJSONError(w, "Failed to read plan", http.StatusInternalServerError)
+		return
+	}
+	if req.Index < 0 || req.Index > steps {
+		sendJSONError(w, fmt.Sprintf("Index must be between 0 and %d", steps), http.StatusBadRequest)
+		return
+	}
+
+	// Moving forward past a lesson with an exercise requires a passing submission
+	if req.Index > plan.CurrentLessonIndex {
+		locked, err := h.lockedLesson(userID, plan.ID, plan.CurrentLessonIndex, req.Index)
+		if err != nil {
+			sendJSONError(w, "Failed to check progress", http.StatusInternalServerError)
+			return
+		}
+		if locked != nil {
+			sendJSONError(w, fmt.Sprintf("Complete the exercise in %q to continue", locked.Title), http.StatusConflict)
+			return
+		}
+	}
+
 	if err := h.dataStore.UpdateLessonProgress(req.PlanID, req.Index); err != nil {
 		sendJSONError(w, "Failed to update progress", http.StatusInternalServerError)
 		return
@@ -75,10 +118,29 @@ func (h *Handler) HandleUpdateProgress(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]bool{"success": true})
 }
 
+// progressSteps is how many steps a plan's progress moves through: the
+// lessons of a curriculum or the topics of a roadmap.
+func progressSteps(plan *models.LessonPlan) (int, error) {
+	content, err := plan.DecodeContent()
+	if err != nil {
+		return 0, err
+	}
+	switch c := content.(type) {
+	case *models.Curriculum:
+		return len(c.Lessons), nil
+	case *models.Roadmap:
+		steps := 0
+		for _, s := range c.Sections {
+			steps += len(s.Topics)
+		}
+		return steps, nil
+	}
+	return 0, nil
+}
+
 func (h *Handler) HandleGenerateCustomRoadmap(w http.ResponseWriter, r *http.Request) {
 	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
 	if !ok {
-		fmt.Println("[Error] HandleGenerateCustomRoadmap: Unauthorized")
 		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
@@ -91,32 +153,36 @@ func (h *Handler) HandleGenerateCustomRoadmap(w http.ResponseWriter, r *http.Req
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		fmt.Printf("[Error] HandleGenerateCustomRoadmap: Invalid Body: %v\n", err)
 		sendJSONError(w, "Invalid input", http.StatusBadRequest)
 		return
 	}
 
-	fmt.Printf("[Info] Generating Roadmap for User %d: Role=%s, Exp=%s\n", userID, req.Role, req.Experience)
+	logger := logging.FromContext(r.Context())
+	logger.Info("generating roadmap", "role", req.Role, "experience", req.Experience)
 
 	// 1. Generate via AI
-	jsonContent, err := h.aiStore.GenerateFullRoadmap(req.Role, req.Experience, req.Goal, req.Other)
+	roadmap, err := h.aiStore.GenerateFullRoadmap(r.Context(), req.Role, req.Experience, req.Goal, req.Other)
 	if err != nil {
-		fmt.Printf("[Error] HandleGenerateCustomRoadmap: AI Generation Failed: %v\n", err)
-		sendJSONError(w, "Failed to generate roadmap: "+err.Error(), http.StatusInternalServerError)
+		logger.Error("roadmap generation failed", "provider", h.aiStore.ProviderName(), "err", err)
+		sendJSONError(w, "Failed to generate roadmap: "+err.Error(), aiErrorStatus(err))
+		return
+	}
+	jsonContent, err := json.Marshal(roadmap)
+	if err != nil {
+		sendJSONError(w, "Failed to encode roadmap", http.StatusInternalServerError)
 		return
 	}
-
-	fmt.Println("[Info] AI Generation Success. Saving to DB...")
 
 	// 2. Save to DB
 	// We reuse 'persona' for Role/Experience and 'goals' for Goal
 	personaStr := req.Role + " (" + req.Experience + ")"
-	planID, err := h.dataStore.SaveLessonPlan(&userID, personaStr, req.Goal, jsonContent)
+	planID, err := h.dataStore.SaveLessonPlan(&userID, models.PlanKindRoadmap, personaStr, req.Goal, string(jsonContent))
 	if err != nil {
-		fmt.Printf("[Error] HandleGenerateCustomRoadmap: DB Save Failed: %v\n", err)
+		logger.Error("failed to save generated roadmap", "err", err)
 		sendJSONError(w, "Failed to save roadmap", http.StatusInternalServerError)
 		return
 	}
+	logger.Info("saved generated roadmap", "plan_id", planID)
 
 	// 3. Return ID
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -125,26 +191,15 @@ func (h *Handler) HandleGenerateCustomRoadmap(w http.ResponseWriter, r *http.Req
 	})
 }
 
+// HandleGetRoadmapByID returns any saved plan by ID. It is public so
+// roadmaps can be shared by link.
 func (h *Handler) HandleGetRoadmapByID(w http.ResponseWriter, r *http.Request) {
-	// Public access allowed for viewing ID (for simplicity, or check auth)
-	// path is /api/roadmap/{id} usually, but using query param for speed
-	// actually standard is mux var. Let's assume query?id=X for now to avoid mux change complexity if standard http
-	// Checking how routes are defined in main...
-	// Ah, I don't see routing code here. I'll assume standard net/http with query params easiest.
-
-	idStr := r.URL.Query().Get("id")
-	if idStr == "" {
-		// Try path value if using Chi/Mux (dummy check, likely not needed if using standard mux without vars)
-		sendJSONError(w, "Missing id", http.StatusBadRequest)
+	planID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid roadmap id", http.StatusBadRequest)
 		return
 	}
 
-	// Convert ID
-	var planID int
-	// ... minimal implementation assuming basic stdlib ...
-	// Actually better to just parse it
-	fmt.Sscanf(idStr, "%d", &planID)
-
 	plan, err := h.dataStore.GetLessonPlanByID(planID)
 	if err != nil {
 		sendJSONError(w, "Failed to fetch roadmap", http.StatusInternalServerError)
@@ -155,16 +210,19 @@ func (h *Handler) HandleGetRoadmapByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var contentObj interface{}
-	if err := json.Unmarshal([]byte(plan.Content), &contentObj); err != nil {
-		contentObj = plan.Content
+	content, err := plan.DecodeContent()
+	if err != nil {
+		logging.FromContext(r.Context()).Error("saved plan has invalid content", "plan_id", plan.ID, "kind", plan.Kind, "err", err)
+		sendJSONError(w, "Stored roadmap is corrupted", http.StatusInternalServerError)
+		return
 	}
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
 		"data": RoadmapResponse{
 			PlanID:       plan.ID,
-			Content:      contentObj,
+			Kind:         plan.Kind,
+			Content:      content,
 			CurrentIndex: plan.CurrentLessonIndex,
 		},
 	})
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"codefuture-backend/internal/middleware"
+	"codefuture-backend/internal/models"
+)
+
+const (
+	maxSearchQueryLength = 200
+
+	defaultSearchLimit = 20
+	maxSearchLimit     = 50
+)
+
+// HandleSearch runs a full-text search. Query parameters: q, type (a comma
+// separated list of post, course, lesson, roadmap), limit and offset.
+// Anonymous users only see posts; signed-in users also find their own
+// courses, lessons and roadmaps.
+func (h *Handler) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	text := strings.TrimSpace(params.Get("q"))
+	if text == "" {
+		sendJSONError(w, "Search query is required", http.StatusBadRequest)
+		return
+	}
+	if len(text) > maxSearchQueryLength {
+		sendJSONError(w, fmt.Sprintf("Search query must be at most %d characters", maxSearchQueryLength), http.StatusBadRequest)
+		return
+	}
+
+	viewerID, _ := r.Context().Value(middleware.UserIDKey).(int)
+	q := models.SearchQuery{Text: text, ViewerID: viewerID, Limit: defaultSearchLimit}
+
+	if v := params.Get("type"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			t = strings.TrimSpace(t)
+			if !slices.Contains(models.SearchTypes, t) {
+				sendJSONError(w, "type must be a comma separated list of post, course, lesson, roadmap", http.StatusBadRequest)
+				return
+			}
+			q.Types = append(q.Types, t)
+		}
+	}
+	if v := params.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSearchLimit {
+			sendJSONError(w, fmt.Sprintf("limit must be between 1 and %d", maxSearchLimit), http.StatusBadRequest)
+			return
+		}
+		q.Limit = n
+	}
+	if v := params.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			sendJSONError(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+		q.Offset = n
+	}
+
+	results, err := h.dataStore.Search(q)
+	if err != nil {
+		sendJSONError(w, "Search failed", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(models.SearchResponse{Query: text, Results: results})
+}
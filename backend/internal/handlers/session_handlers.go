@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"codefuture-backend/internal/auth"
+	"codefuture-backend/internal/logging"
+	"codefuture-backend/internal/middleware"
+	"codefuture-backend/internal/models"
+)
+
+// refreshReuseGrace is how long after a rotation the previous refresh token is
+// answered with a plain 401 instead of being treated as stolen. Two tabs
+// refreshing at the same moment would otherwise sign each other out.
+const refreshReuseGrace = 30 * time.Second
+
+const maxUserAgentLength = 255
+
+// refuseSuspended answers 403 and reports true if the user is suspended.
+func refuseSuspended(w http.ResponseWriter, user *models.User) bool {
+	if !user.Suspended(time.Now()) {
+		return false
+	}
+	sendJSONError(w, "Account suspended", http.StatusForbidden)
+	return true
+}
+
+// startSession records a new session for user on this device and returns a
+// fresh access/refresh token pair.
+func (h *Handler) startSession(r *http.Request, user *models.User) (*models.AuthResponse, error) {
+	refreshToken, refreshHash, err := auth.NewRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sess := &models.Session{
+		UserID:     user.ID,
+		UserAgent:  truncate(r.UserAgent(), maxUserAgentLength),
+		IP:         middleware.ClientIP(r, h.config.TrustProxy),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(h.tokens.RefreshTTL()),
+	}
+	if err := h.dataStore.CreateSession(sess, refreshHash); err != nil {
+		return nil, err
+	}
+
+	accessToken, expiresAt, err := h.tokens.IssueAccessToken(user.ID, sess.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AuthResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresAt:    expiresAt,
+		User:         *user,
+	}, nil
+}
+
+// HandleRefresh exchanges a refresh token for a new access token and a new
+// refresh token. The old refresh token stops working immediately.
+func (h *Handler) HandleRefresh(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		sendJSONError(w, "Refresh token required", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	hash := auth.HashToken(req.RefreshToken)
+
+	sess, err := h.dataStore.GetSessionByRefreshHash(hash)
+	if err != nil {
+		sendJSONError(w, "Failed to refresh session", http.StatusInternalServerError)
+		return
+	}
+	if sess == nil {
+		h.handleRefreshReuse(r.Context(), hash, now)
+		sendJSONError(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+	if !sess.Active(now) {
+		sendJSONError(w, "Session expired", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.dataStore.GetUserByID(sess.UserID)
+	if err != nil || user == nil {
+		sendJSONError(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+	if refuseSuspended(w, user) {
+		return
+	}
+
+	newToken, newHash, err := auth.NewRefreshToken()
+	if err != nil {
+		sendJSONError(w, "Failed to refresh session", http.StatusInternalServerError)
+		return
+	}
+	rotated, err := h.dataStore.RotateSession(sess.ID, hash, newHash, now.Add(h.tokens.RefreshTTL()), now)
+	if err != nil {
+		sendJSONError(w, "Failed to refresh session", http.StatusInternalServerError)
+		return
+	}
+	if !rotated {
+		// Another request rotated this token first
+		sendJSONError(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	accessToken, expiresAt, err := h.tokens.IssueAccessToken(user.ID, sess.ID)
+	if err != nil {
+		sendJSONError(w, "Error generating token", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(models.AuthResponse{
+		Token:        accessToken,
+		RefreshToken: newToken,
+		ExpiresAt:    expiresAt,
+		User:         *user,
+	})
+}
+
+// handleRefreshReuse revokes the session a replayed, already-rotated refresh
+// token belongs to: either the legitimate client or an attacker holds a copy.
+func (h *Handler) handleRefreshReuse(ctx context.Context, hash string, now time.Time) {
+	sess, err := h.dataStore.GetSessionByPreviousHash(hash)
+	if err != nil || sess == nil || sess.RevokedAt != nil {
+		return
+	}
+	if sess.RotatedAt != nil && now.Sub(*sess.RotatedAt) < refreshReuseGrace {
+		return
+	}
+	logging.FromContext(ctx).Warn("rotated refresh token reused, revoking session", "session_id", sess.ID, "user_id", sess.UserID)
+	if _, err := h.dataStore.RevokeSession(sess.UserID, sess.ID, now); err != nil {
+		logging.FromContext(ctx).Error("failed to revoke session", "session_id", sess.ID, "err", err)
+	}
+}
+
+// HandleLogout revokes the session the request's access token belongs to.
+func (h *Handler) HandleLogout(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	sessionID, hasSession := r.Context().Value(middleware.SessionIDKey).(int)
+	if !ok || !hasSession {
+		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if _, err := h.dataStore.RevokeSession(userID, sessionID, time.Now()); err != nil {
+		sendJSONError(w, "Failed to log out", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// HandleLogoutAll revokes every session of the user, including this one.
+func (h *Handler) HandleLogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.dataStore.RevokeAllSessions(userID, time.Now()); err != nil {
+		sendJSONError(w, "Failed to log out", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+// HandleListSessions lists the user's signed-in devices.
+func (h *Handler) HandleListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	currentID, _ := r.Context().Value(middleware.SessionIDKey).(int)
+
+	sessions, err := h.dataStore.ListSessions(userID)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch sessions", http.StatusInternalServerError)
+		return
+	}
+	if sessions == nil {
+		sessions = []models.Session{}
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	json.NewEncoder(w).Encode(sessions)
+}
+
+// HandleRevokeSession signs out one of the user's devices.
+func (h *Handler) HandleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid session id", http.StatusBadRequest)
+		return
+	}
+
+	revoked, err := h.dataStore.RevokeSession(userID, sessionID, time.Now())
+	if err != nil {
+		sendJSONError(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+	if !revoked {
+		sendJSONError(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]bool{"success": true})
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
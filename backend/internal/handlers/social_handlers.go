@@ -3,108 +3,351 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
+	"codefuture-backend/internal/auth"
+	"codefuture-backend/internal/logging"
+	"codefuture-backend/internal/middleware"
 	"codefuture-backend/internal/models"
+	"codefuture-backend/internal/store"
 
 	"golang.org/x/oauth2"
 )
 
-// No globals or init functions needed anymore!
+const (
+	// oauthStateTTL bounds how long the user may spend on the provider's consent screen.
+	oauthStateTTL = 10 * time.Minute
+	// oauthCodeTTL is how long the frontend has to exchange the one-time login code.
+	oauthCodeTTL = 2 * time.Minute
+)
+
+var errNoVerifiedEmail = errors.New("no verified email address")
+
+// socialProfile is what a provider tells us about the signed-in account.
+// Email is only set when the provider has verified it.
+type socialProfile struct {
+	Provider       string
+	ProviderUserID string
+	Email          string
+	Name           string
+}
 
 func (h *Handler) HandleGoogleLogin(w http.ResponseWriter, r *http.Request) {
-	url := h.config.GoogleOAuthConfig.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+	h.startOAuth(w, r, "google", h.config.GoogleOAuthConfig)
 }
 
 func (h *Handler) HandleGitHubLogin(w http.ResponseWriter, r *http.Request) {
-	url := h.config.GitHubOAuthConfig.AuthCodeURL("state-token", oauth2.AccessTypeOffline)
-	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
+	h.startOAuth(w, r, "github", h.config.GitHubOAuthConfig)
 }
 
-func (h *Handler) HandleGoogleCallback(w http.ResponseWriter, r *http.Request) {
-	code := r.URL.Query().Get("code")
-	token, err := h.config.GoogleOAuthConfig.Exchange(context.Background(), code)
+// startOAuth sends the browser to the provider with a fresh state nonce and
+// PKCE challenge. Both are remembered in a signed, HttpOnly cookie that the
+// callback checks, so a callback can't be forged cross-site or replayed.
+func (h *Handler) startOAuth(w http.ResponseWriter, r *http.Request, provider string, cfg *oauth2.Config) {
+	verifier := oauth2.GenerateVerifier()
+	st, err := auth.NewOAuthState(provider, verifier, oauthStateTTL)
 	if err != nil {
-		http.Error(w, "Failed to exchange token", http.StatusInternalServerError)
+		http.Error(w, "Failed to start login", http.StatusInternalServerError)
 		return
 	}
-
-	client := h.config.GoogleOAuthConfig.Client(context.Background(), token)
-	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
+	cookie, err := h.tokens.SignOAuthState(st)
 	if err != nil {
-		http.Error(w, "Failed to get user info", http.StatusInternalServerError)
+		http.Error(w, "Failed to start login", http.StatusInternalServerError)
 		return
 	}
-	defer resp.Body.Close()
 
-	var googleUser struct {
-		Email string `json:"email"`
-		Name  string `json:"name"`
-		ID    string `json:"id"`
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthCookieName(provider),
+		Value:    cookie,
+		Path:     "/api/auth/" + provider,
+		MaxAge:   int(oauthStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   secureCallback(cfg),
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	authURL := cfg.AuthCodeURL(st.Nonce, oauth2.S256ChallengeOption(verifier))
+	http.Redirect(w, r, authURL, http.StatusTemporaryRedirect)
+}
+
+// secureCallback reports whether the provider sends the browser back over
+// HTTPS, so the state cookie can be Secure. The public callback URL says so
+// even behind a proxy that terminates TLS, where r.TLS is nil.
+func secureCallback(cfg *oauth2.Config) bool {
+	return strings.HasPrefix(cfg.RedirectURL, "https://")
+}
+
+// finishOAuth checks the callback against the state cookie and exchanges the
+// authorization code using the PKCE verifier.
+func (h *Handler) finishOAuth(w http.ResponseWriter, r *http.Request, provider string, cfg *oauth2.Config) (*oauth2.Token, error) {
+	cookieName := oauthCookieName(provider)
+	c, err := r.Cookie(cookieName)
+	// The state cookie is single use
+	http.SetCookie(w, &http.Cookie{Name: cookieName, Path: "/api/auth/" + provider, MaxAge: -1, HttpOnly: true, Secure: secureCallback(cfg)})
+	if err != nil {
+		return nil, fmt.Errorf("missing state cookie")
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&googleUser); err != nil {
-		http.Error(w, "Failed to decode user info", http.StatusInternalServerError)
-		return
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		return nil, fmt.Errorf("provider returned %s", e)
+	}
+	st, err := h.tokens.VerifyOAuthState(c.Value, provider, q.Get("state"))
+	if err != nil {
+		return nil, err
 	}
 
-	// Process User (Login/Signup)
-	h.processSocialLogin(w, r, googleUser.Name, googleUser.Email, "google")
+	return cfg.Exchange(r.Context(), q.Get("code"), oauth2.VerifierOption(st.Verifier))
 }
 
-func (h *Handler) HandleGitHubCallback(w http.ResponseWriter, r *http.Request) {
-	code := r.URL.Query().Get("code")
-	token, err := h.config.GitHubOAuthConfig.Exchange(context.Background(), code)
+func (h *Handler) HandleGoogleCallback(w http.ResponseWriter, r *http.Request) {
+	token, err := h.finishOAuth(w, r, "google", h.config.GoogleOAuthConfig)
 	if err != nil {
-		http.Error(w, "Failed to exchange token", http.StatusInternalServerError)
+		h.oauthFailed(w, r, "google", err)
+		return
+	}
+
+	client := h.config.GoogleOAuthConfig.Client(r.Context(), token)
+	var googleUser struct {
+		ID            string `json:"id"`
+		Email         string `json:"email"`
+		VerifiedEmail bool   `json:"verified_email"`
+		Name          string `json:"name"`
+	}
+	if err := getJSON(r.Context(), client, "https://www.googleapis.com/oauth2/v2/userinfo", &googleUser); err != nil {
+		h.oauthFailed(w, r, "google", err)
+		return
+	}
+	if googleUser.ID == "" {
+		h.oauthFailed(w, r, "google", fmt.Errorf("userinfo has no id"))
 		return
 	}
 
-	client := h.config.GitHubOAuthConfig.Client(context.Background(), token)
-	resp, err := client.Get("https://api.github.com/user")
+	profile := socialProfile{Provider: "google", ProviderUserID: googleUser.ID, Name: googleUser.Name}
+	if googleUser.VerifiedEmail {
+		profile.Email = googleUser.Email
+	}
+	h.processSocialLogin(w, r, profile)
+}
+
+func (h *Handler) HandleGitHubCallback(w http.ResponseWriter, r *http.Request) {
+	token, err := h.finishOAuth(w, r, "github", h.config.GitHubOAuthConfig)
 	if err != nil {
-		http.Error(w, "Failed to get user info", http.StatusInternalServerError)
+		h.oauthFailed(w, r, "github", err)
 		return
 	}
-	defer resp.Body.Close()
 
+	client := h.config.GitHubOAuthConfig.Client(r.Context(), token)
 	var githubUser struct {
+		ID    int64  `json:"id"`
 		Login string `json:"login"`
 		Name  string `json:"name"`
-		Email string `json:"email"`
 	}
-	// Note: GitHub email might be private, need extra call properly, but keeping simple
-	if err := json.NewDecoder(resp.Body).Decode(&githubUser); err != nil {
-		http.Error(w, "Failed to decode user info", http.StatusInternalServerError)
+	if err := getJSON(r.Context(), client, "https://api.github.com/user", &githubUser); err != nil {
+		h.oauthFailed(w, r, "github", err)
+		return
+	}
+	if githubUser.ID == 0 {
+		h.oauthFailed(w, r, "github", fmt.Errorf("user has no id"))
+		return
+	}
+
+	// The profile email may be private or unverified; /user/emails has the truth
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(r.Context(), client, "https://api.github.com/user/emails", &emails); err != nil {
+		h.oauthFailed(w, r, "github", err)
 		return
 	}
 
-	email := githubUser.Email
-	if email == "" {
-		email = fmt.Sprintf("%s@github.com", githubUser.Login) // Fallback
+	profile := socialProfile{Provider: "github", ProviderUserID: strconv.FormatInt(githubUser.ID, 10), Name: githubUser.Name}
+	if profile.Name == "" {
+		profile.Name = githubUser.Login
 	}
-	name := githubUser.Name
-	if name == "" {
-		name = githubUser.Login
+	for _, e := range emails {
+		if e.Primary && e.Verified {
+			profile.Email = e.Email
+			break
+		}
 	}
+	h.processSocialLogin(w, r, profile)
+}
 
-	h.processSocialLogin(w, r, name, email, "github")
+// processSocialLogin resolves the provider account to a user and hands the
+// frontend a short-lived one-time code for a session, so no token ever
+// appears in a URL.
+func (h *Handler) processSocialLogin(w http.ResponseWriter, r *http.Request, p socialProfile) {
+	user, err := h.resolveSocialUser(p)
+	if err != nil {
+		h.oauthFailed(w, r, p.Provider, err)
+		return
+	}
+
+	code, hash, err := auth.NewOpaqueToken()
+	if err == nil {
+		now := time.Now()
+		err = h.dataStore.CreateAccountToken(user.ID, models.TokenPurposeOAuthLogin, hash, now, now.Add(oauthCodeTTL))
+	}
+	if err != nil {
+		h.oauthFailed(w, r, p.Provider, err)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("%s/login?code=%s", h.config.FrontendURL, url.QueryEscape(code)), http.StatusTemporaryRedirect)
 }
 
-func (h *Handler) processSocialLogin(w http.ResponseWriter, r *http.Request, name, email, provider string) {
-	user, _ := h.dataStore.GetUserByEmail(email)
+// resolveSocialUser finds the user for a provider account, linking it to an
+// existing account with the same verified email or creating a new one.
+func (h *Handler) resolveSocialUser(p socialProfile) (*models.User, error) {
+	user, err := h.dataStore.GetUserByIdentity(p.Provider, p.ProviderUserID)
+	if err != nil || user != nil {
+		return user, err
+	}
+
+	// Without a verified email we can't tell which account this is
+	if p.Email == "" {
+		return nil, errNoVerifiedEmail
+	}
+
+	now := time.Now()
+	user, err = h.dataStore.GetUserByEmail(p.Email)
+	if err != nil {
+		return nil, err
+	}
+	if user != nil && !user.EmailVerified {
+		// Whoever registered this address never proved they own it and the
+		// provider just did, so the existing password and sessions go.
+		if err := h.dataStore.UpdateUserPassword(user.ID, ""); err != nil {
+			return nil, err
+		}
+		if err := h.dataStore.RevokeAllSessions(user.ID, now); err != nil {
+			return nil, err
+		}
+	}
 	if user == nil {
-		user = &models.User{
-			Name:     name,
-			Email:    email,
-			Password: fmt.Sprintf("social_%s_%s", provider, "secure"), // Dummy
+		// OAuth-only accounts have no usable password until one is set
+		// through the reset flow.
+		name := p.Name
+		if name == "" {
+			name = p.Email
 		}
-		h.dataStore.CreateUser(user)
+		user = &models.User{Name: name, Email: p.Email}
+		if err := h.dataStore.CreateUser(user); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := h.dataStore.MarkEmailVerified(user.ID, now); err != nil {
+		return nil, err
+	}
+	user.EmailVerified = true
+
+	err = h.dataStore.LinkIdentity(&models.UserIdentity{
+		UserID:         user.ID,
+		Provider:       p.Provider,
+		ProviderUserID: p.ProviderUserID,
+		Email:          p.Email,
+		CreatedAt:      now,
+	})
+	if errors.Is(err, store.ErrIdentityExists) {
+		// Linked by a concurrent callback
+		return h.dataStore.GetUserByIdentity(p.Provider, p.ProviderUserID)
+	}
+	return user, err
+}
+
+// HandleOAuthExchange trades the one-time code from the OAuth redirect for a session.
+func (h *Handler) HandleOAuthExchange(w http.ResponseWriter, r *http.Request) {
+	var req models.OAuthExchangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		sendJSONError(w, "Code is required", http.StatusBadRequest)
+		return
 	}
 
-	token, _ := generateToken(user.ID)
+	userID, err := h.dataStore.ConsumeAccountToken(models.TokenPurposeOAuthLogin, auth.HashToken(req.Code), time.Now())
+	if err != nil {
+		sendJSONError(w, "Failed to complete login", http.StatusInternalServerError)
+		return
+	}
+	if userID == 0 {
+		sendJSONError(w, "Invalid or expired login code", http.StatusUnauthorized)
+		return
+	}
+
+	user, err := h.dataStore.GetUserByID(userID)
+	if err != nil || user == nil {
+		sendJSONError(w, "Failed to complete login", http.StatusInternalServerError)
+		return
+	}
+	if refuseSuspended(w, user) {
+		return
+	}
 
-	// Redirect to frontend with token
-	http.Redirect(w, r, fmt.Sprintf("%s/login?token=%s&user_id=%d&name=%s&email=%s", h.config.FrontendURL, token, user.ID, user.Name, user.Email), http.StatusTemporaryRedirect)
+	resp, err := h.startSession(r, user)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("failed to start session", "err", err)
+		sendJSONError(w, "Error generating token", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HandleListIdentities lists the login providers linked to the user.
+func (h *Handler) HandleListIdentities(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	identities, err := h.dataStore.ListIdentities(userID)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch linked accounts", http.StatusInternalServerError)
+		return
+	}
+	if identities == nil {
+		identities = []models.UserIdentity{}
+	}
+	json.NewEncoder(w).Encode(identities)
+}
+
+// oauthFailed logs why a social login failed and sends the user back to the
+// login page with a message that is safe to show.
+func (h *Handler) oauthFailed(w http.ResponseWriter, r *http.Request, provider string, err error) {
+	logging.FromContext(r.Context()).Error("social login failed", "provider", provider, "err", err)
+	msg := "Social login failed. Please try again."
+	if errors.Is(err, errNoVerifiedEmail) {
+		msg = fmt.Sprintf("Your %s account has no verified email address.", provider)
+	}
+	http.Redirect(w, r, fmt.Sprintf("%s/login?error=%s", h.config.FrontendURL, url.QueryEscape(msg)), http.StatusTemporaryRedirect)
+}
+
+func oauthCookieName(provider string) string {
+	return "oauth_state_" + provider
+}
+
+func getJSON(ctx context.Context, client *http.Client, endpoint string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", endpoint, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
 }
@@ -0,0 +1,342 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"codefuture-backend/internal/email"
+	"codefuture-backend/internal/logging"
+	"codefuture-backend/internal/middleware"
+	"codefuture-backend/internal/models"
+)
+
+const maxTicketMessageLength = 5000
+
+// HandleMyTickets lists the tickets the signed-in user opened through the
+// contact form, newest first.
+func (h *Handler) HandleMyTickets(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	tickets, err := h.dataStore.ListTicketsByUser(userID)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch tickets", http.StatusInternalServerError)
+		return
+	}
+	for i := range tickets {
+		forSubmitter(&tickets[i])
+	}
+	json.NewEncoder(w).Encode(tickets)
+}
+
+// HandleMyTicket returns one of the signed-in user's tickets with its replies.
+func (h *Handler) HandleMyTicket(w http.ResponseWriter, r *http.Request) {
+	ticket, ok := h.ownTicket(w, r)
+	if !ok {
+		return
+	}
+	forSubmitter(&ticket.ContactSubmission)
+	json.NewEncoder(w).Encode(ticket)
+}
+
+// HandleMyTicketReply adds the submitter's reply to their ticket. It reopens
+// the ticket, pending or resolved, for staff.
+func (h *Handler) HandleMyTicketReply(w http.ResponseWriter, r *http.Request) {
+	ticket, ok := h.ownTicket(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.TicketMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	body, msg := validateTicketMessage(req.Body)
+	if msg != "" {
+		sendJSONError(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	m := &models.TicketMessage{TicketID: ticket.ID, AuthorID: *ticket.UserID, Body: body, CreatedAt: time.Now()}
+	if _, err := h.dataStore.AddTicketMessage(m, models.TicketStatusOpen); err != nil {
+		sendJSONError(w, "Failed to send reply", http.StatusInternalServerError)
+		return
+	}
+
+	h.queueEmail(r.Context(), h.config.AdminEmail, email.TemplateTicketUserReply, email.TicketUserReply{
+		TicketID: ticket.ID, FirstName: ticket.FirstName, LastName: ticket.LastName, Body: body})
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(m)
+}
+
+// ownTicket loads the ticket named in the path, writing the error response
+// unless the signed-in user opened it. Other people's tickets are reported
+// as not found.
+func (h *Handler) ownTicket(w http.ResponseWriter, r *http.Request) (*models.Ticket, bool) {
+	userID, ok := r.Context().Value(middleware.UserIDKey).(int)
+	if !ok {
+		sendJSONError(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	ticketID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid ticket id", http.StatusBadRequest)
+		return nil, false
+	}
+
+	ticket, err := h.dataStore.GetTicket(ticketID)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch ticket", http.StatusInternalServerError)
+		return nil, false
+	}
+	if ticket == nil || ticket.UserID == nil || *ticket.UserID != userID {
+		sendJSONError(w, "Ticket not found", http.StatusNotFound)
+		return nil, false
+	}
+	return ticket, true
+}
+
+// forSubmitter hides what only staff should see.
+func forSubmitter(sub *models.ContactSubmission) {
+	sub.SuggestedReply = ""
+	sub.AssigneeID = nil
+}
+
+// HandleAdminGetTicket returns a ticket with its replies and suggested reply.
+func (h *Handler) HandleAdminGetTicket(w http.ResponseWriter, r *http.Request) {
+	ticketID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid ticket id", http.StatusBadRequest)
+		return
+	}
+
+	ticket, err := h.dataStore.GetTicket(ticketID)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch ticket", http.StatusInternalServerError)
+		return
+	}
+	if ticket == nil {
+		sendJSONError(w, "Ticket not found", http.StatusNotFound)
+		return
+	}
+	if !h.audit(w, r, models.AuditActionView, models.AuditTargetContact, ticketID) {
+		return
+	}
+	json.NewEncoder(w).Encode(ticket)
+}
+
+// HandleAdminUpdateTicket changes a ticket's status or assignee. Tickets can
+// only be assigned to someone who may manage them.
+func (h *Handler) HandleAdminUpdateTicket(w http.ResponseWriter, r *http.Request) {
+	staffID, _ := actor(r)
+	ticketID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid ticket id", http.StatusBadRequest)
+		return
+	}
+
+	var req models.UpdateTicketRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if req.Status != nil && !slices.Contains(models.TicketStatuses, *req.Status) {
+		sendJSONError(w, "status must be one of "+strings.Join(models.TicketStatuses, ", "), http.StatusBadRequest)
+		return
+	}
+	if req.AssigneeID != nil && *req.AssigneeID != 0 {
+		assignee, err := h.dataStore.GetUserByID(*req.AssigneeID)
+		if err != nil {
+			sendJSONError(w, "Failed to fetch assignee", http.StatusInternalServerError)
+			return
+		}
+		if assignee == nil || !assignee.Can(models.PermManageTickets) {
+			sendJSONError(w, "Tickets can only be assigned to staff", http.StatusBadRequest)
+			return
+		}
+	}
+
+	found, err := h.dataStore.UpdateTicket(ticketID, staffID, req, time.Now())
+	if err != nil {
+		sendJSONError(w, "Failed to update ticket", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		sendJSONError(w, "Ticket not found", http.StatusNotFound)
+		return
+	}
+	h.writeTicket(w, ticketID)
+}
+
+// HandleAdminEditSuggestedReply replaces the reply drafted for a ticket, so
+// staff can polish it before sending.
+func (h *Handler) HandleAdminEditSuggestedReply(w http.ResponseWriter, r *http.Request) {
+	staffID, _ := actor(r)
+	ticketID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid ticket id", http.StatusBadRequest)
+		return
+	}
+
+	var req models.SuggestedReplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	body := strings.TrimSpace(req.Body)
+	if len(body) > maxTicketMessageLength {
+		sendJSONError(w, "Reply is too long", http.StatusBadRequest)
+		return
+	}
+
+	found, err := h.dataStore.SetSuggestedReply(ticketID, staffID, body, time.Now())
+	if err != nil {
+		sendJSONError(w, "Failed to save suggested reply", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		sendJSONError(w, "Ticket not found", http.StatusNotFound)
+		return
+	}
+	h.writeTicket(w, ticketID)
+}
+
+// HandleAdminTicketMessage adds a staff reply that the submitter sees when
+// they sign in. The ticket moves to pending unless another status is given.
+func (h *Handler) HandleAdminTicketMessage(w http.ResponseWriter, r *http.Request) {
+	staffID, _ := actor(r)
+	ticketID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid ticket id", http.StatusBadRequest)
+		return
+	}
+
+	var req models.TicketMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	body, msg := validateTicketMessage(req.Body)
+	if msg != "" {
+		sendJSONError(w, msg, http.StatusBadRequest)
+		return
+	}
+	status, ok := staffReplyStatus(w, req.Status)
+	if !ok {
+		return
+	}
+
+	m := &models.TicketMessage{TicketID: ticketID, AuthorID: staffID, FromStaff: true, Body: body, CreatedAt: time.Now()}
+	found, err := h.dataStore.AddTicketMessage(m, status)
+	if err != nil {
+		sendJSONError(w, "Failed to add reply", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		sendJSONError(w, "Ticket not found", http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+	h.writeTicket(w, ticketID)
+}
+
+// HandleAdminSendReply queues a staff reply to the person who opened the
+// ticket and adds it to the thread. Without a body it sends the suggested
+// reply. The ticket moves to pending unless another status is given.
+func (h *Handler) HandleAdminSendReply(w http.ResponseWriter, r *http.Request) {
+	staffID, _ := actor(r)
+	ticketID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		sendJSONError(w, "Invalid ticket id", http.StatusBadRequest)
+		return
+	}
+
+	var req models.SendReplyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		sendJSONError(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	status, ok := staffReplyStatus(w, req.Status)
+	if !ok {
+		return
+	}
+
+	ticket, err := h.dataStore.GetTicket(ticketID)
+	if err != nil {
+		sendJSONError(w, "Failed to fetch ticket", http.StatusInternalServerError)
+		return
+	}
+	if ticket == nil {
+		sendJSONError(w, "Ticket not found", http.StatusNotFound)
+		return
+	}
+	to := strings.TrimSpace(ticket.Email)
+	if to == "" {
+		sendJSONError(w, "Ticket has no email address to reply to", http.StatusConflict)
+		return
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		req.Body = ticket.SuggestedReply
+	}
+	body, msg := validateTicketMessage(req.Body)
+	if msg != "" {
+		sendJSONError(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	if !h.queueEmail(r.Context(), to, email.TemplateTicketReply,
+		email.TicketReply{TicketID: ticket.ID, Name: ticket.FirstName, Reply: body, Original: ticket.Message}) {
+		sendJSONError(w, "Failed to queue email", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	m := &models.TicketMessage{TicketID: ticket.ID, AuthorID: staffID, FromStaff: true, Body: body, EmailedAt: &now, CreatedAt: now}
+	if _, err := h.dataStore.AddTicketMessage(m, status); err != nil {
+		// The email is already queued, so say so rather than invite a resend
+		logging.FromContext(r.Context()).Error("ticket reply queued but not recorded", "ticket_id", ticket.ID, "err", err)
+		sendJSONError(w, "Reply was emailed but could not be saved", http.StatusInternalServerError)
+		return
+	}
+	h.writeTicket(w, ticket.ID)
+}
+
+func (h *Handler) writeTicket(w http.ResponseWriter, ticketID int) {
+	ticket, err := h.dataStore.GetTicket(ticketID)
+	if err != nil || ticket == nil {
+		sendJSONError(w, "Failed to fetch ticket", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(ticket)
+}
+
+func staffReplyStatus(w http.ResponseWriter, status string) (string, bool) {
+	if status == "" {
+		return models.TicketStatusPending, true
+	}
+	if !slices.Contains(models.TicketStatuses, status) {
+		sendJSONError(w, "status must be one of "+strings.Join(models.TicketStatuses, ", "), http.StatusBadRequest)
+		return "", false
+	}
+	return status, true
+}
+
+func validateTicketMessage(body string) (string, string) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return "", "Message is required"
+	}
+	if len(body) > maxTicketMessageLength {
+		return "", "Message is too long"
+	}
+	return body, ""
+}
@@ -0,0 +1,159 @@
+// Package metrics holds the Prometheus collectors the backend exports on
+// /metrics: HTTP requests by route, LLM calls by AIService method, database
+// query timings and the email outbox.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "codefuture"
+
+// Registry holds every collector below plus the Go runtime and process
+// collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route pattern and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time to serve HTTP requests by method and route pattern.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"method", "route"})
+
+	llmCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_calls_total",
+		Help:      "LLM provider calls by AIService method, provider and outcome (ok, error or canceled).",
+	}, []string{"method", "provider", "outcome"})
+
+	llmDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "llm_call_duration_seconds",
+		Help:      "LLM provider call latency by AIService method and provider.",
+		Buckets:   []float64{.25, .5, 1, 2, 4, 8, 15, 30, 60, 120},
+	}, []string{"method", "provider"})
+
+	llmTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_tokens_total",
+		Help:      "Tokens reported by the LLM provider by AIService method, provider and kind (prompt or completion).",
+	}, []string{"method", "provider", "kind"})
+
+	llmRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "llm_rejected_replies_total",
+		Help:      "LLM replies rejected as malformed JSON or failing validation, by AIService method.",
+	}, []string{"method"})
+
+	dbDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Database query time by operation (exec or query).",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"op"})
+
+	dbErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "Failed database queries by operation.",
+	}, []string{"op"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration,
+		llmCalls, llmDuration, llmTokens, llmRejected,
+		dbDuration, dbErrors,
+	)
+}
+
+// Handler serves the registry in the Prometheus text format. A collector
+// that fails, say the outbox count while the database is down, is left
+// out rather than failing the whole scrape.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+}
+
+// ObserveHTTP records a served request. route is the pattern it matched,
+// never the raw path, so the number of series stays bounded.
+func ObserveHTTP(method, route string, status int, d time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(d.Seconds())
+}
+
+// ObserveLLM records one provider call made for an AIService method.
+func ObserveLLM(method, provider string, d time.Duration, promptTokens, completionTokens int, err error) {
+	outcome := "ok"
+	switch {
+	case errors.Is(err, context.Canceled):
+		outcome = "canceled"
+	case err != nil:
+		outcome = "error"
+	}
+	llmCalls.WithLabelValues(method, provider, outcome).Inc()
+	llmDuration.WithLabelValues(method, provider).Observe(d.Seconds())
+	if promptTokens > 0 {
+		llmTokens.WithLabelValues(method, provider, "prompt").Add(float64(promptTokens))
+	}
+	if completionTokens > 0 {
+		llmTokens.WithLabelValues(method, provider, "completion").Add(float64(completionTokens))
+	}
+}
+
+// LLMReplyRejected counts a reply an AIService method had to send back.
+func LLMReplyRejected(method string) {
+	llmRejected.WithLabelValues(method).Inc()
+}
+
+// ObserveQuery records a database query.
+func ObserveQuery(op string, d time.Duration, err error) {
+	dbDuration.WithLabelValues(op).Observe(d.Seconds())
+	if err != nil {
+		dbErrors.WithLabelValues(op).Inc()
+	}
+}
+
+// EmailQueue reports the outbox by status when /metrics is scraped.
+// count is called on every scrape.
+func EmailQueue(count func() (map[string]int, error)) {
+	Registry.MustRegister(&emailQueue{count: count})
+}
+
+var emailQueueDesc = prometheus.NewDesc(namespace+"_email_outbox",
+	"Emails in the outbox by status; pending is the queue still to send.", []string{"status"}, nil)
+
+type emailQueue struct {
+	count func() (map[string]int, error)
+}
+
+func (q *emailQueue) Describe(ch chan<- *prometheus.Desc) {
+	ch <- emailQueueDesc
+}
+
+func (q *emailQueue) Collect(ch chan<- prometheus.Metric) {
+	counts, err := q.count()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(emailQueueDesc, err)
+		return
+	}
+	for status, n := range counts {
+		ch <- prometheus.MustNewConstMetric(emailQueueDesc, prometheus.GaugeValue, float64(n), status)
+	}
+}
@@ -2,27 +2,106 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
-	"os"
 	"strings"
+	"time"
 
-	"github.com/golang-jwt/jwt/v5"
+	"codefuture-backend/internal/auth"
+	"codefuture-backend/internal/logging"
+	"codefuture-backend/internal/models"
 )
 
 type contextKey string
 
-const UserIDKey contextKey = "userID"
+const (
+	UserIDKey    contextKey = "userID"
+	SessionIDKey contextKey = "sessionID"
+	UserRoleKey  contextKey = "userRole" // set by RequirePermission only
+)
+
+// touchInterval limits how often a session's last-seen time is written.
+const touchInterval = time.Minute
+
+// SessionValidator looks up the session an access token belongs to, and the
+// user behind it when a route needs their role.
+type SessionValidator interface {
+	GetSessionByID(id int) (*models.Session, error)
+	TouchSession(id int, at time.Time) error
+	GetUserByID(id int) (*models.User, error)
+}
+
+// Authenticator verifies access tokens and checks that their session is
+// still active, so logging out or revoking a device takes effect immediately.
+type Authenticator struct {
+	tokens   *auth.TokenManager
+	sessions SessionValidator
+}
 
-func getJWTSecret() []byte {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return []byte("dev_secret_key_change_in_prod")
+func NewAuthenticator(tokens *auth.TokenManager, sessions SessionValidator) *Authenticator {
+	return &Authenticator{tokens: tokens, sessions: sessions}
+}
+
+// Reasons authenticate refuses a request.
+var (
+	errInvalidToken = errors.New("invalid token")
+	errSuspended    = errors.New("account suspended")
+)
+
+// authenticate returns the user and session IDs for a valid bearer token
+// whose user is not suspended.
+func (a *Authenticator) authenticate(r *http.Request) (userID, sessionID int, err error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return 0, 0, errInvalidToken
 	}
-	return []byte(secret)
+
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	claims, err := a.tokens.ParseAccessToken(tokenString)
+	if err != nil {
+		return 0, 0, errInvalidToken
+	}
+
+	sess, err := a.sessions.GetSessionByID(claims.SessionID)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("session lookup failed", "session_id", claims.SessionID, "err", err)
+		return 0, 0, errInvalidToken
+	}
+	now := time.Now()
+	if sess == nil || sess.UserID != claims.UserID {
+		return 0, 0, errInvalidToken
+	}
+	// Suspension revokes the user's sessions too; say why their tokens
+	// stopped working
+	if sess.UserSuspended(now) {
+		return 0, 0, errSuspended
+	}
+	if !sess.Active(now) {
+		return 0, 0, errInvalidToken
+	}
+
+	if now.Sub(sess.LastSeenAt) > touchInterval {
+		if err := a.sessions.TouchSession(sess.ID, now); err != nil {
+			logging.FromContext(r.Context()).Warn("failed to update session last seen", "session_id", sess.ID, "err", err)
+		}
+	}
+	return claims.UserID, claims.SessionID, nil
 }
 
-// AuthMiddleware validates the JWT token and adds user_id to context
-func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+// withIdentity adds the user to the context, its logger and the access log.
+func withIdentity(r *http.Request, userID, sessionID int) *http.Request {
+	ctx := context.WithValue(r.Context(), UserIDKey, userID)
+	ctx = context.WithValue(ctx, SessionIDKey, sessionID)
+	ctx = logging.With(ctx, "user_id", userID)
+	if info, ok := ctx.Value(requestInfoKey).(*requestInfo); ok {
+		info.userID = userID
+	}
+	return r.WithContext(ctx)
+}
+
+// AuthMiddleware validates the access token and its session and adds
+// user_id and session_id to the context.
+func (a *Authenticator) AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// 1. Handle Preflight Options
 		if r.Method == "OPTIONS" {
@@ -30,62 +109,64 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			return
 		}
 
-		// 2. Helper to set CORS locally in case we return error before handler
-		setCORS := func() {
-			w.Header().Set("Access-Control-Allow-Origin", "*")
-			w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
-		}
-
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			setCORS()
+		// 2. Require a bearer token
+		if r.Header.Get("Authorization") == "" {
 			http.Error(w, "Authorization header required", http.StatusUnauthorized)
 			return
 		}
 
-		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return getJWTSecret(), nil
-		})
-
-		if err != nil || !token.Valid {
-			setCORS()
+		userID, sessionID, err := a.authenticate(r)
+		if errors.Is(err, errSuspended) {
+			http.Error(w, "Account suspended", http.StatusForbidden)
+			return
+		}
+		if err != nil {
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
+		next(w, withIdentity(r, userID, sessionID))
+	}
+}
+
+// RequirePermission is AuthMiddleware for routes that also need perm. The
+// user's role is looked up on every request, so a role change takes effect
+// at once, and added to the context as user_role.
+func (a *Authenticator) RequirePermission(perm string, next http.HandlerFunc) http.HandlerFunc {
+	return a.AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			next(w, r)
+			return
+		}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			userID := int(claims["user_id"].(float64))
-			ctx := context.WithValue(r.Context(), UserIDKey, userID)
-			next(w, r.WithContext(ctx))
-		} else {
-			setCORS()
-			http.Error(w, "Invalid token claims", http.StatusUnauthorized)
+		user, err := a.sessions.GetUserByID(r.Context().Value(UserIDKey).(int))
+		if err != nil {
+			logging.FromContext(r.Context()).Error("user lookup for permission check failed", "permission", perm, "err", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
 		}
+		if user == nil || !user.Can(perm) {
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), UserRoleKey, user.Role)))
+	})
+}
+
+// Require is RequirePermission as a middleware, for route groups.
+func (a *Authenticator) Require(perm string) func(http.HandlerFunc) http.HandlerFunc {
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return a.RequirePermission(perm, next)
 	}
 }
 
 // OptionalAuthMiddleware adds user_id to context if token is present, but doesn't block if missing
-func OptionalAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+func (a *Authenticator) OptionalAuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader != "" {
-			tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
-			token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-				return getJWTSecret(), nil
-			})
-
-			if err == nil && token.Valid {
-				if claims, ok := token.Claims.(jwt.MapClaims); ok {
-					userID := int(claims["user_id"].(float64))
-					ctx := context.WithValue(r.Context(), UserIDKey, userID)
-					next(w, r.WithContext(ctx))
-					return
-				}
-			}
+		if userID, sessionID, err := a.authenticate(r); err == nil {
+			next(w, withIdentity(r, userID, sessionID))
+			return
 		}
-		// Continue without user_id if check fails or no token
+		// Continue without user_id if check fails, no token or the user is suspended
 		next(w, r)
 	}
 }
@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig says which origins may call the API from a browser.
+type CORSConfig struct {
+	// AllowedOrigins are exact origins like https://app.example.com,
+	// wildcard subdomains like https://*.example.com, or "*" for any origin
+	// (which rules out credentials).
+	AllowedOrigins   []string
+	AllowCredentials bool
+	AllowedHeaders   []string      // request headers a cross-origin request may send
+	ExposedHeaders   []string      // response headers scripts may read
+	MaxAge           time.Duration // how long browsers may cache a preflight
+}
+
+// CORSPolicy narrows what cross-origin requests the routes under a path
+// accept. The zero CORSPolicy allows the defaults.
+type CORSPolicy struct {
+	Methods  []string // empty allows every method a route is registered for
+	Headers  []string // empty allows CORSConfig.AllowedHeaders
+	Disabled bool     // refuse cross-origin requests outright
+}
+
+// RouteMatcher finds the route path a request is for and the methods
+// registered for it. The router implements it.
+type RouteMatcher interface {
+	Match(r *http.Request) (path string, methods []string, ok bool)
+}
+
+// CORS answers preflight requests and adds CORS headers to responses for
+// allowed origins. Requests from other origins get no CORS headers, so
+// browsers refuse to hand their responses to the calling page.
+type CORS struct {
+	cfg       CORSConfig
+	anyOrigin bool
+	exact     map[string]bool
+	wildcards []originWildcard
+	routes    RouteMatcher
+	policies  map[string]CORSPolicy
+}
+
+// originWildcard matches any subdomain: https://*.example.com is
+// prefix https:// and suffix .example.com.
+type originWildcard struct {
+	prefix, suffix string
+}
+
+// NewCORS checks cfg's origins and returns CORS for the routes found by
+// routes.
+func NewCORS(cfg CORSConfig, routes RouteMatcher) (*CORS, error) {
+	c := &CORS{cfg: cfg, exact: map[string]bool{}, routes: routes, policies: map[string]CORSPolicy{}}
+	for _, o := range cfg.AllowedOrigins {
+		o = strings.ToLower(strings.TrimSuffix(o, "/"))
+		scheme, host, ok := strings.Cut(o, "://")
+		switch {
+		case o == "*":
+			if cfg.AllowCredentials {
+				return nil, fmt.Errorf("cors: origin * cannot be combined with credentials")
+			}
+			c.anyOrigin = true
+		case !ok || scheme == "" || host == "" || strings.Contains(host, "/"):
+			return nil, fmt.Errorf("cors: origin %q is not scheme://host[:port]", o)
+		case strings.HasPrefix(host, "*."):
+			if strings.Contains(host[2:], "*") {
+				return nil, fmt.Errorf("cors: origin %q may only have a leading * label", o)
+			}
+			c.wildcards = append(c.wildcards, originWildcard{prefix: scheme + "://", suffix: host[1:]})
+		case strings.Contains(host, "*"):
+			return nil, fmt.Errorf("cors: origin %q may only have a leading * label", o)
+		default:
+			c.exact[o] = true
+		}
+	}
+	return c, nil
+}
+
+// Route applies p to the routes whose path starts with prefix. When several
+// prefixes match, the longest wins.
+func (c *CORS) Route(prefix string, p CORSPolicy) {
+	c.policies[prefix] = p
+}
+
+func (c *CORS) policy(path string) CORSPolicy {
+	best, found := "", false
+	for prefix := range c.policies {
+		if strings.HasPrefix(path, prefix) && (!found || len(prefix) > len(best)) {
+			best, found = prefix, true
+		}
+	}
+	return c.policies[best]
+}
+
+func (c *CORS) allowOrigin(origin string) bool {
+	if c.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if c.exact[origin] {
+		return true
+	}
+	for _, w := range c.wildcards {
+		if len(origin) > len(w.prefix)+len(w.suffix) && strings.HasPrefix(origin, w.prefix) && strings.HasSuffix(origin, w.suffix) &&
+			isHostLabels(origin[len(w.prefix):len(origin)-len(w.suffix)]) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHostLabels reports whether s is one or more DNS labels, so a wildcard
+// can't match something like https://evil.com?.example.com.
+func isHostLabels(s string) bool {
+	for _, label := range strings.Split(s, ".") {
+		if label == "" {
+			return false
+		}
+		for _, ch := range label {
+			if !(ch >= 'a' && ch <= 'z' || ch >= '0' && ch <= '9' || ch == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Handler wraps the whole API, ahead of routing.
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !c.anyOrigin {
+			// The response depends on the origin, so caches must not share it
+			w.Header().Add("Vary", "Origin")
+		}
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		path, methods, found := c.routes.Match(r)
+		policy := c.policy(path)
+		if found && r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			c.preflight(w, r, origin, policy, methods)
+			return
+		}
+
+		if !policy.Disabled && c.allowOrigin(origin) {
+			c.setOrigin(w, origin)
+			if len(c.cfg.ExposedHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(c.cfg.ExposedHeaders, ", "))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// preflight answers the OPTIONS request a browser sends before a
+// cross-origin request that isn't a simple GET or form POST.
+func (c *CORS) preflight(w http.ResponseWriter, r *http.Request, origin string, policy CORSPolicy, methods []string) {
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+	if policy.Disabled || !c.allowOrigin(origin) {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	if len(policy.Methods) > 0 {
+		methods = slices.DeleteFunc(slices.Clone(methods), func(m string) bool {
+			return !slices.Contains(policy.Methods, m)
+		})
+	}
+	if !slices.Contains(methods, r.Header.Get("Access-Control-Request-Method")) {
+		http.Error(w, "Method not allowed for cross-origin requests", http.StatusForbidden)
+		return
+	}
+
+	headers := policy.Headers
+	if len(headers) == 0 {
+		headers = c.cfg.AllowedHeaders
+	}
+	for _, h := range strings.Split(r.Header.Get("Access-Control-Request-Headers"), ",") {
+		h = strings.TrimSpace(h)
+		if h != "" && !slices.ContainsFunc(headers, func(allowed string) bool { return strings.EqualFold(allowed, h) }) {
+			http.Error(w, "Header "+h+" not allowed for cross-origin requests", http.StatusForbidden)
+			return
+		}
+	}
+
+	c.setOrigin(w, origin)
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+	if len(headers) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if c.cfg.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(c.cfg.MaxAge.Seconds())))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *CORS) setOrigin(w http.ResponseWriter, origin string) {
+	if c.anyOrigin {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	if c.cfg.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
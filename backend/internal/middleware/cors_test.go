@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+// routeTable matches every path, with the methods registered for it.
+type routeTable map[string][]string
+
+func (t routeTable) Match(r *http.Request) (string, []string, bool) {
+	methods, ok := t[r.URL.Path]
+	return r.URL.Path, methods, ok
+}
+
+var testRoutes = routeTable{"/api/posts": {http.MethodGet, http.MethodPost}}
+
+func newTestCORS(t *testing.T, cfg CORSConfig) http.Handler {
+	t.Helper()
+	c, err := NewCORS(cfg, testRoutes)
+	if err != nil {
+		t.Fatalf("NewCORS: %v", err)
+	}
+	return c.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+}
+
+func serveCORS(h http.Handler, method, origin string, header map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, "/api/posts", nil)
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestCORSExactOriginWithCredentials(t *testing.T) {
+	h := newTestCORS(t, CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true})
+
+	w := serveCORS(h, http.MethodGet, "https://app.example.com", nil)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want the origin echoed", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want true", got)
+	}
+
+	w = serveCORS(h, http.MethodGet, "https://other.example.com", nil)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("other origin got Access-Control-Allow-Origin %q, want none", got)
+	}
+}
+
+func TestCORSWildcardOrigin(t *testing.T) {
+	h := newTestCORS(t, CORSConfig{AllowedOrigins: []string{"https://*.example.com"}})
+
+	tests := []struct {
+		origin string
+		allow  bool
+	}{
+		{"https://app.example.com", true},
+		{"https://a.b.example.com", true},
+		{"https://example.com", false},
+		{"http://app.example.com", false},
+		{"https://evil.com?.example.com", false},
+		{"https://evil.com/.example.com", false},
+		{"https://app.example.com.evil.com", false},
+	}
+	for _, tt := range tests {
+		w := serveCORS(h, http.MethodGet, tt.origin, nil)
+		got := w.Header().Get("Access-Control-Allow-Origin")
+		if tt.allow && got != tt.origin {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q, want the origin echoed", tt.origin, got)
+		}
+		if !tt.allow && got != "" {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q, want none", tt.origin, got)
+		}
+	}
+}
+
+func TestNewCORSRejectsAnyOriginWithCredentials(t *testing.T) {
+	if _, err := NewCORS(CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}, testRoutes); err == nil {
+		t.Error("NewCORS accepted origin * with credentials")
+	}
+	if _, err := NewCORS(CORSConfig{AllowedOrigins: []string{"*"}}, testRoutes); err != nil {
+		t.Errorf("NewCORS with origin * and no credentials: %v", err)
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	h := newTestCORS(t, CORSConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedHeaders: []string{"Authorization", "Content-Type"},
+	})
+
+	tests := []struct {
+		name   string
+		origin string
+		method string
+		header string
+		status int
+	}{
+		{"allowed", "https://app.example.com", http.MethodPost, "content-type, authorization", http.StatusNoContent},
+		{"disallowed method", "https://app.example.com", http.MethodDelete, "", http.StatusForbidden},
+		{"disallowed header", "https://app.example.com", http.MethodPost, "X-Admin", http.StatusForbidden},
+		{"disallowed origin", "https://evil.com", http.MethodPost, "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		w := serveCORS(h, http.MethodOptions, tt.origin, map[string]string{
+			"Access-Control-Request-Method":  tt.method,
+			"Access-Control-Request-Headers": tt.header,
+		})
+		if w.Code != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, w.Code, tt.status)
+		}
+		if allowed := w.Header().Get("Access-Control-Allow-Origin") != ""; allowed != (tt.status == http.StatusNoContent) {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q", tt.name, w.Header().Get("Access-Control-Allow-Origin"))
+		}
+	}
+}
+
+func TestCORSVaryOrigin(t *testing.T) {
+	h := newTestCORS(t, CORSConfig{AllowedOrigins: []string{"https://app.example.com"}})
+
+	// Caches must key on Origin whether or not the request had one
+	for _, origin := range []string{"https://app.example.com", "https://evil.com", ""} {
+		w := serveCORS(h, http.MethodGet, origin, nil)
+		if !slices.Contains(w.Header().Values("Vary"), "Origin") {
+			t.Errorf("origin %q: Vary = %q, want Origin", origin, w.Header().Values("Vary"))
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"codefuture-backend/internal/logging"
+	"codefuture-backend/internal/models"
+)
+
+// QuotaStore counts AI requests per user. The store implements it.
+type QuotaStore interface {
+	UseAIQuota(userID int, at time.Time, dailyLimit, monthlyLimit int) (*models.AIQuota, bool, error)
+}
+
+// AIQuota holds signed-in users to a daily and monthly number of AI
+// requests. A limit of 0 means unlimited.
+type AIQuota struct {
+	store          QuotaStore
+	daily          int
+	monthly        int
+	allowAnonymous bool
+}
+
+// NewAIQuota returns quotas backed by store. Anonymous requests carry no
+// quota, so unless allowAnonymous they are turned away.
+func NewAIQuota(store QuotaStore, daily, monthly int, allowAnonymous bool) *AIQuota {
+	return &AIQuota{store: store, daily: daily, monthly: monthly, allowAnonymous: allowAnonymous}
+}
+
+// Limit counts the request against the user's quota, rejecting it with 429
+// and Retry-After once the quota is used up. The remaining quota is reported
+// in X-AI-Quota-Daily-Remaining and X-AI-Quota-Monthly-Remaining. Wrap it in
+// an auth middleware so the user is known.
+func (q *AIQuota) Limit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			next(w, r)
+			return
+		}
+
+		userID, ok := r.Context().Value(UserIDKey).(int)
+		if !ok {
+			if !q.allowAnonymous {
+				http.Error(w, "Sign in to use AI features", http.StatusUnauthorized)
+				return
+			}
+			next(w, r)
+			return
+		}
+		if q.daily <= 0 && q.monthly <= 0 {
+			next(w, r)
+			return
+		}
+
+		now := time.Now()
+		usage, allowed, err := q.store.UseAIQuota(userID, now, q.daily, q.monthly)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("failed to count AI quota usage", "err", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		setQuotaHeaders(w, usage)
+		if !allowed {
+			resetsAt, _ := usage.Exhausted()
+			tooManyRequests(w, resetsAt.Sub(now), "AI quota exceeded")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// setQuotaHeaders reports the limits that apply and what is left of them.
+func setQuotaHeaders(w http.ResponseWriter, usage *models.AIQuota) {
+	if usage.DailyLimit > 0 {
+		w.Header().Set("X-AI-Quota-Daily-Limit", strconv.Itoa(usage.DailyLimit))
+		w.Header().Set("X-AI-Quota-Daily-Remaining", strconv.Itoa(usage.DailyRemaining()))
+	}
+	if usage.MonthlyLimit > 0 {
+		w.Header().Set("X-AI-Quota-Monthly-Limit", strconv.Itoa(usage.MonthlyLimit))
+		w.Header().Set("X-AI-Quota-Monthly-Remaining", strconv.Itoa(usage.MonthlyRemaining()))
+	}
+}
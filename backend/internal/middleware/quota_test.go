@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"codefuture-backend/internal/models"
+)
+
+// fakeQuotaStore counts usage in memory; quotas reset an hour after at.
+type fakeQuotaStore struct {
+	used  map[int]int
+	calls int
+	err   error
+}
+
+func (s *fakeQuotaStore) UseAIQuota(userID int, at time.Time, daily, monthly int) (*models.AIQuota, bool, error) {
+	s.calls++
+	if s.err != nil {
+		return nil, false, s.err
+	}
+	q := &models.AIQuota{
+		DailyLimit: daily, MonthlyLimit: monthly,
+		DailyUsed: s.used[userID], MonthlyUsed: s.used[userID],
+		DayResetsAt: at.Add(time.Hour), MonthResetsAt: at.Add(24 * time.Hour),
+	}
+	if _, exhausted := q.Exhausted(); exhausted {
+		return q, false, nil
+	}
+	s.used[userID]++
+	q.DailyUsed++
+	q.MonthlyUsed++
+	return q, true, nil
+}
+
+func TestAIQuotaLimit(t *testing.T) {
+	call := func(q *AIQuota, userID int) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/api/chat", nil)
+		if userID != 0 {
+			r = r.WithContext(context.WithValue(r.Context(), UserIDKey, userID))
+		}
+		w := httptest.NewRecorder()
+		q.Limit(func(w http.ResponseWriter, r *http.Request) {})(w, r)
+		return w
+	}
+
+	t.Run("anonymous refused", func(t *testing.T) {
+		store := &fakeQuotaStore{used: map[int]int{}}
+		if w := call(NewAIQuota(store, 2, 10, false), 0); w.Code != http.StatusUnauthorized {
+			t.Errorf("status = %d, want 401", w.Code)
+		}
+	})
+
+	t.Run("anonymous allowed", func(t *testing.T) {
+		store := &fakeQuotaStore{used: map[int]int{}}
+		if w := call(NewAIQuota(store, 2, 10, true), 0); w.Code != http.StatusOK || store.calls != 0 {
+			t.Errorf("status = %d, store calls = %d; want 200 without counting", w.Code, store.calls)
+		}
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		store := &fakeQuotaStore{used: map[int]int{}}
+		w := call(NewAIQuota(store, 0, 0, false), 1)
+		if w.Code != http.StatusOK || store.calls != 0 || w.Header().Get("X-AI-Quota-Daily-Limit") != "" {
+			t.Errorf("status = %d, store calls = %d; want 200 without counting", w.Code, store.calls)
+		}
+	})
+
+	t.Run("daily limit", func(t *testing.T) {
+		q := NewAIQuota(&fakeQuotaStore{used: map[int]int{}}, 2, 10, false)
+		w := call(q, 1)
+		if w.Code != http.StatusOK || w.Header().Get("X-AI-Quota-Daily-Remaining") != "1" || w.Header().Get("X-AI-Quota-Monthly-Remaining") != "9" {
+			t.Fatalf("first: %d, daily %q, monthly %q", w.Code, w.Header().Get("X-AI-Quota-Daily-Remaining"), w.Header().Get("X-AI-Quota-Monthly-Remaining"))
+		}
+		call(q, 1)
+		w = call(q, 1)
+		if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "3600" || w.Header().Get("X-AI-Quota-Daily-Remaining") != "0" {
+			t.Fatalf("over quota: %d, Retry-After %q, daily %q", w.Code, w.Header().Get("Retry-After"), w.Header().Get("X-AI-Quota-Daily-Remaining"))
+		}
+		if w := call(q, 2); w.Code != http.StatusOK {
+			t.Errorf("other user: %d, want 200", w.Code)
+		}
+	})
+
+	t.Run("store error", func(t *testing.T) {
+		store := &fakeQuotaStore{err: errors.New("db down")}
+		if w := call(NewAIQuota(store, 2, 10, false), 1); w.Code != http.StatusInternalServerError {
+			t.Errorf("status = %d, want 500", w.Code)
+		}
+	})
+}
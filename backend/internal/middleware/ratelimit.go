@@ -0,0 +1,168 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rate allows Requests per Per, refilled continuously, with bursts of up to
+// Requests. The zero Rate is unlimited.
+type Rate struct {
+	Requests int
+	Per      time.Duration
+}
+
+func (r Rate) unlimited() bool {
+	return r.Requests <= 0 || r.Per <= 0
+}
+
+// RateLimiter limits a route with a token bucket per client IP and another
+// per signed-in user. Give each route its own so they don't share buckets.
+type RateLimiter struct {
+	perIP      *buckets
+	perUser    *buckets
+	trustProxy bool
+}
+
+// NewRateLimiter limits each IP to perIP and each user to perUser. With
+// trustProxy the client IP is taken from X-Forwarded-For, as ClientIP
+// describes.
+func NewRateLimiter(perIP, perUser Rate, trustProxy bool) *RateLimiter {
+	return &RateLimiter{perIP: newBuckets(perIP), perUser: newBuckets(perUser), trustProxy: trustProxy}
+}
+
+// Limit rejects requests over either limit with 429 and Retry-After, and
+// reports the tighter limit in X-RateLimit-Limit and X-RateLimit-Remaining.
+// Wrap it in an auth middleware for the per-user limit to apply.
+func (l *RateLimiter) Limit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "OPTIONS" {
+			next(w, r)
+			return
+		}
+
+		now := time.Now()
+		limit, remaining := -1, -1
+		for _, check := range []struct {
+			b   *buckets
+			key string
+		}{
+			{l.perIP, ClientIP(r, l.trustProxy)},
+			{l.perUser, userKey(r)},
+		} {
+			if check.b == nil || check.key == "" {
+				continue
+			}
+			left, wait, ok := check.b.take(check.key, now)
+			if !ok {
+				w.Header().Set("X-RateLimit-Limit", strconv.Itoa(check.b.rate.Requests))
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				tooManyRequests(w, wait, "Too many requests")
+				return
+			}
+			if remaining < 0 || left < remaining {
+				limit, remaining = check.b.rate.Requests, left
+			}
+		}
+		if remaining >= 0 {
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		}
+		next(w, r)
+	}
+}
+
+func userKey(r *http.Request) string {
+	if userID, ok := r.Context().Value(UserIDKey).(int); ok {
+		return strconv.Itoa(userID)
+	}
+	return ""
+}
+
+// ClientIP is the address the request came from. With trustProxy it is the
+// last address in X-Forwarded-For: the one the proxy in front of the server
+// appended. Earlier entries come from the client and can be anything.
+func ClientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		fwd := r.Header.Values("X-Forwarded-For")
+		if len(fwd) > 0 {
+			last := fwd[len(fwd)-1]
+			if i := strings.LastIndex(last, ","); i >= 0 {
+				last = last[i+1:]
+			}
+			if ip := strings.TrimSpace(last); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// tooManyRequests writes a 429 telling the client when to try again, in
+// whole seconds rounded up.
+func tooManyRequests(w http.ResponseWriter, wait time.Duration, msg string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	http.Error(w, msg, http.StatusTooManyRequests)
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// buckets holds one token bucket per key. A bucket left alone for a whole
+// period is full again, so it is dropped rather than kept around.
+type buckets struct {
+	rate      Rate
+	mu        sync.Mutex
+	m         map[string]*bucket
+	lastSweep time.Time
+}
+
+func newBuckets(rate Rate) *buckets {
+	if rate.unlimited() {
+		return nil
+	}
+	return &buckets{rate: rate, m: map[string]*bucket{}, lastSweep: time.Now()}
+}
+
+// take spends a token from key's bucket. It returns the tokens left, or how
+// long until one is available when the bucket is empty.
+func (b *buckets) take(key string, now time.Time) (int, time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.Sub(b.lastSweep) > b.rate.Per {
+		for k, bk := range b.m {
+			if now.Sub(bk.last) >= b.rate.Per {
+				delete(b.m, k)
+			}
+		}
+		b.lastSweep = now
+	}
+
+	capacity := float64(b.rate.Requests)
+	perSecond := capacity / b.rate.Per.Seconds()
+	bk, ok := b.m[key]
+	if !ok {
+		bk = &bucket{tokens: capacity, last: now}
+		b.m[key] = bk
+	}
+	bk.tokens = min(capacity, bk.tokens+now.Sub(bk.last).Seconds()*perSecond)
+	bk.last = now
+
+	if bk.tokens < 1 {
+		return 0, time.Duration((1 - bk.tokens) / perSecond * float64(time.Second)), false
+	}
+	bk.tokens--
+	return int(bk.tokens), 0, true
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientIP(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		forwarded  []string
+		trustProxy bool
+		want       string
+	}{
+		{"peer address", nil, false, "10.0.0.1"},
+		{"forwarding ignored untrusted", []string{"203.0.113.7"}, false, "10.0.0.1"},
+		{"no header behind proxy", nil, true, "10.0.0.1"},
+		{"single entry", []string{"203.0.113.7"}, true, "203.0.113.7"},
+		{"client-supplied entries skipped", []string{"1.2.3.4, 203.0.113.7"}, true, "203.0.113.7"},
+		{"repeated headers", []string{"1.2.3.4", "5.6.7.8,203.0.113.7"}, true, "203.0.113.7"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = "10.0.0.1:5000"
+			for _, v := range tc.forwarded {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if got := ClientIP(r, tc.trustProxy); got != tc.want {
+				t.Errorf("ClientIP = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBucketsTake(t *testing.T) {
+	b := newBuckets(Rate{Requests: 2, Per: time.Second})
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, step := range []struct {
+		at      time.Duration
+		key     string
+		left    int
+		wait    time.Duration
+		allowed bool
+	}{
+		{0, "a", 1, 0, true},
+		{0, "a", 0, 0, true},
+		{0, "a", 0, 500 * time.Millisecond, false},
+		{0, "b", 1, 0, true}, // keys don't share a bucket
+		{250 * time.Millisecond, "a", 0, 250 * time.Millisecond, false},
+		{500 * time.Millisecond, "a", 0, 0, true}, // one token refilled
+		{10 * time.Second, "a", 1, 0, true},       // refill stops at capacity
+	} {
+		left, wait, ok := b.take(step.key, t0.Add(step.at))
+		if left != step.left || wait != step.wait || ok != step.allowed {
+			t.Errorf("take(%s) at +%s = %d, %s, %v; want %d, %s, %v",
+				step.key, step.at, left, wait, ok, step.left, step.wait, step.allowed)
+		}
+	}
+}
+
+func TestBucketsSweep(t *testing.T) {
+	b := newBuckets(Rate{Requests: 1, Per: time.Minute})
+	t0 := b.lastSweep
+	b.take("idle", t0)
+	b.take("busy", t0.Add(50*time.Second))
+	b.take("busy", t0.Add(2*time.Minute))
+
+	if _, ok := b.m["idle"]; ok {
+		t.Error("bucket idle for a whole period was not swept")
+	}
+	if _, ok := b.m["busy"]; !ok {
+		t.Error("bucket in use was swept")
+	}
+}
+
+func TestUnlimitedRate(t *testing.T) {
+	for _, r := range []Rate{{}, {Requests: 5}, {Per: time.Minute}} {
+		if b := newBuckets(r); b != nil {
+			t.Errorf("newBuckets(%+v) = %v, want nil", r, b)
+		}
+	}
+}
+
+func TestRateLimiterLimit(t *testing.T) {
+	l := NewRateLimiter(Rate{Requests: 5, Per: time.Hour}, Rate{Requests: 2, Per: time.Hour}, false)
+	h := l.Limit(func(w http.ResponseWriter, r *http.Request) {})
+	call := func(method, addr string, userID int) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/api/chat", nil)
+		r.RemoteAddr = addr
+		if userID != 0 {
+			r = r.WithContext(context.WithValue(r.Context(), UserIDKey, userID))
+		}
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w
+	}
+
+	// The per-user limit is the tighter one, so it is reported
+	w := call("POST", "10.0.0.1:1", 7)
+	if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "2" || w.Header().Get("X-RateLimit-Remaining") != "1" {
+		t.Fatalf("first request: %d, limit %q, remaining %q", w.Code, w.Header().Get("X-RateLimit-Limit"), w.Header().Get("X-RateLimit-Remaining"))
+	}
+	call("POST", "10.0.0.1:1", 7)
+	w = call("POST", "10.0.0.1:1", 7)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Fatalf("over the user limit: %d, Retry-After %q, remaining %q", w.Code, w.Header().Get("Retry-After"), w.Header().Get("X-RateLimit-Remaining"))
+	}
+
+	// The same user from another address is still limited
+	if w := call("POST", "10.0.0.2:1", 7); w.Code != http.StatusTooManyRequests {
+		t.Errorf("same user, new IP: %d, want 429", w.Code)
+	}
+	// Signed-out clients only have the per-IP limit
+	w = call("POST", "10.0.0.3:1", 0)
+	if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "5" || w.Header().Get("X-RateLimit-Remaining") != "4" {
+		t.Errorf("anonymous: %d, limit %q, remaining %q", w.Code, w.Header().Get("X-RateLimit-Limit"), w.Header().Get("X-RateLimit-Remaining"))
+	}
+	// Preflights are never limited
+	if w := call("OPTIONS", "10.0.0.1:1", 7); w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "" {
+		t.Errorf("preflight: %d, limit %q", w.Code, w.Header().Get("X-RateLimit-Limit"))
+	}
+}
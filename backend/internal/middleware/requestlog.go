@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"codefuture-backend/internal/logging"
+	"codefuture-backend/internal/metrics"
+)
+
+// RequestIDHeader carries the request ID in from a proxy and back out to
+// the client.
+const RequestIDHeader = "X-Request-ID"
+
+const RequestIDKey contextKey = "requestID"
+
+// requestInfo is filled in as the request passes through the middleware
+// stack, for the access log written once it is done.
+type requestInfo struct {
+	userID int
+}
+
+const requestInfoKey contextKey = "requestInfo"
+
+// RequestLogger gives every request an ID, taken from X-Request-ID when a
+// proxy sent a sensible one, and returns it in the same header. The ID and
+// a logger tagged with it go in the context, and once the request is done
+// it is counted in the HTTP metrics and logged with its route, status,
+// size, latency and user. Wrap it around everything else.
+func RequestLogger(logger *slog.Logger, accessLog bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			id := r.Header.Get(RequestIDHeader)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			info := &requestInfo{}
+			ctx := logging.NewContext(r.Context(), logger.With("request_id", id))
+			ctx = context.WithValue(ctx, RequestIDKey, id)
+			ctx = context.WithValue(ctx, requestInfoKey, info)
+			r = r.WithContext(ctx)
+
+			rec := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+
+			elapsed := time.Since(start)
+			route := r.Pattern // set by the router's ServeMux
+			if route == "" {
+				route = "unmatched"
+			}
+			status := rec.status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			_, path, found := strings.Cut(route, " ")
+			if !found {
+				path = route
+			}
+			metrics.ObserveHTTP(r.Method, path, status, elapsed)
+			if !accessLog {
+				return
+			}
+
+			attrs := []any{
+				"method", r.Method,
+				"route", route,
+				"path", r.URL.Path,
+				"status", status,
+				"bytes", rec.bytes,
+				"duration_ms", elapsed.Milliseconds(),
+				"remote", r.RemoteAddr,
+			}
+			if info.userID != 0 {
+				attrs = append(attrs, "user_id", info.userID)
+			}
+			level := slog.LevelInfo
+			if status >= 500 {
+				level = slog.LevelError
+			}
+			logging.FromContext(ctx).Log(ctx, level, "request", attrs...)
+		})
+	}
+}
+
+// validRequestID accepts IDs a proxy might use, like UUIDs, but nothing
+// long or odd enough to mess up a log line.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, ch := range id {
+		if !(ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9' || ch == '-' || ch == '_' || ch == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder remembers the status and size of a response. It keeps
+// http.Flusher and Unwrap working so streamed replies still stream.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += int64(n)
+	return n, err
+}
+
+func (rec *statusRecorder) Flush() {
+	http.NewResponseController(rec.ResponseWriter).Flush()
+}
+
+func (rec *statusRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
@@ -0,0 +1,98 @@
+package models
+
+import "time"
+
+// Audit log actions and target types for the admin API. Reads of personal
+// data are logged as well as changes.
+const (
+	AuditActionList       = "list"
+	AuditActionView       = "view"
+	AuditActionDelete     = "delete"
+	AuditActionChangeRole = "change_role"
+	AuditActionReply      = "reply"       // a staff message on a ticket
+	AuditActionEmailReply = "email_reply" // a staff reply emailed to the submitter
+	AuditActionUpdate     = "update"
+	AuditActionEditDraft  = "edit_draft" // the suggested reply on a ticket
+	AuditActionRetry      = "retry"      // a dead email sent back to the outbox
+
+	AuditTargetLessonPlan = "lesson_plan"
+	AuditTargetContact    = "contact_submission"
+	AuditTargetEmail      = "email"
+)
+
+// UserQuery selects users for the admin API, oldest first. Text matches
+// anywhere in the name or email, ignoring case.
+type UserQuery struct {
+	Text   string
+	Role   string
+	Limit  int
+	Offset int
+}
+
+// UserPage is one page of users and how many match in total.
+type UserPage struct {
+	Users []User `json:"users"`
+	Total int    `json:"total"`
+}
+
+// ChangeRoleRequest is an admin's request to change a user's role.
+type ChangeRoleRequest struct {
+	Role   string `json:"role"`
+	Reason string `json:"reason"`
+}
+
+// LessonPlanQuery selects lesson plans for the admin API, newest first.
+type LessonPlanQuery struct {
+	UserID int
+	Kind   string
+	Limit  int
+	Offset int
+}
+
+// LessonPlanSummary is a lesson plan without its content.
+type LessonPlanSummary struct {
+	ID         int       `json:"id"`
+	UserID     *int      `json:"user_id,omitempty"`
+	OwnerEmail string    `json:"owner_email,omitempty"`
+	Kind       string    `json:"kind"`
+	Persona    string    `json:"persona"`
+	Goals      string    `json:"goals"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// LessonPlanPage is one page of lesson plans and how many match in total.
+type LessonPlanPage struct {
+	Plans []LessonPlanSummary `json:"plans"`
+	Total int                 `json:"total"`
+}
+
+// AdminPostQuery selects posts in any status, newest first.
+type AdminPostQuery struct {
+	Status   string
+	AuthorID int
+	Limit    int
+	Offset   int
+}
+
+// AdminPostPage is one page of posts and how many match in total.
+type AdminPostPage struct {
+	Posts []Post `json:"posts"`
+	Total int    `json:"total"`
+}
+
+// ContactQuery selects contact submissions, newest first. Text matches
+// anywhere in the name, email or message, ignoring case. An AssigneeID of -1
+// selects unassigned tickets.
+type ContactQuery struct {
+	Text       string
+	Status     string
+	AssigneeID int
+	Limit      int
+	Offset     int
+}
+
+// ContactPage is one page of contact submissions and how many match in total.
+type ContactPage struct {
+	Submissions []ContactSubmission `json:"submissions"`
+	Total       int                 `json:"total"`
+}
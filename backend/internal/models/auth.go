@@ -1,14 +1,69 @@
 package models
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 // User represents a registered user
 type User struct {
-	ID        int       `json:"id"`
-	Email     string    `json:"email"`
-	Name      string    `json:"name"`
-	Password  string    `json:"-"` // Don't expose password in JSON
-	CreatedAt time.Time `json:"created_at"`
+	ID               int        `json:"id"`
+	Email            string     `json:"email"`
+	Name             string     `json:"name"`
+	Password         string     `json:"-"` // Don't expose password in JSON
+	EmailVerified    bool       `json:"email_verified"`
+	Role             string     `json:"role"`
+	SuspendedUntil   *time.Time `json:"suspended_until,omitempty"`
+	SuspensionReason string     `json:"suspension_reason,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// User roles, from least to most privileged.
+const (
+	RoleLearner   = "learner"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+var Roles = []string{RoleLearner, RoleModerator, RoleAdmin}
+
+// Permissions a role can grant. Routes that need one are wrapped with
+// middleware.Authenticator.RequirePermission.
+const (
+	PermModerateCommunity = "community.moderate"  // reports, held content, suspensions
+	PermViewAuditLog      = "audit.view"          // read the audit log
+	PermManageUsers       = "users.manage"        // list and search users, change roles
+	PermManageLessonPlans = "lesson_plans.manage" // view and delete anyone's plans
+	PermManagePosts       = "posts.manage"        // see and act on posts in any status
+	PermViewContact       = "contact.view"        // read contact form submissions
+	PermManageTickets     = "tickets.manage"      // reply to, assign and resolve support tickets
+	PermManageEmail       = "email.manage"        // inspect the outbox and retry dead emails
+)
+
+var rolePermissions = map[string][]string{
+	RoleModerator: {PermModerateCommunity, PermViewAuditLog},
+	RoleAdmin: {PermModerateCommunity, PermViewAuditLog, PermManageUsers, PermManageLessonPlans,
+		PermManagePosts, PermViewContact, PermManageTickets, PermManageEmail},
+}
+
+// RoleCan reports whether role grants perm. Learners have no permissions.
+func RoleCan(role, perm string) bool {
+	return slices.Contains(rolePermissions[role], perm)
+}
+
+// Can reports whether the user's role grants perm.
+func (u *User) Can(perm string) bool {
+	return RoleCan(u.Role, perm)
+}
+
+// IsModerator reports whether the user may moderate the community. Admins can.
+func (u *User) IsModerator() bool {
+	return u.Can(PermModerateCommunity)
+}
+
+// Suspended reports whether the user is barred from signing in at now.
+func (u *User) Suspended(now time.Time) bool {
+	return u.SuspendedUntil != nil && now.Before(*u.SuspendedUntil)
 }
 
 // SignupRequest payload
@@ -24,8 +79,81 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
-// AuthResponse payload
+// AuthResponse payload. Token is the short-lived access token; RefreshToken
+// is exchanged at /api/auth/refresh for a new pair.
 type AuthResponse struct {
+	Token        string    `json:"token"`
+	RefreshToken string    `json:"refresh_token"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	User         User      `json:"user"`
+}
+
+// Session is one signed-in device. The refresh token itself is never stored,
+// only its hash.
+type Session struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RotatedAt  *time.Time `json:"-"` // when the refresh token last changed
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	Current    bool       `json:"current"`
+
+	UserSuspendedUntil *time.Time `json:"-"` // filled in by GetSessionByID only
+}
+
+// UserSuspended reports whether the session's user is suspended at now.
+func (s *Session) UserSuspended(now time.Time) bool {
+	return s.UserSuspendedUntil != nil && now.Before(*s.UserSuspendedUntil)
+}
+
+// Active reports whether the session can still be used at now.
+func (s *Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// RefreshRequest payload
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Purposes of the single-use account tokens
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposeOAuthLogin        = "oauth_login" // one-time code handed to the frontend after OAuth
+)
+
+// UserIdentity links an external login provider account to a user.
+type UserIdentity struct {
+	ID             int       `json:"id"`
+	UserID         int       `json:"-"`
+	Provider       string    `json:"provider"`
+	ProviderUserID string    `json:"-"`
+	Email          string    `json:"email"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// OAuthExchangeRequest payload
+type OAuthExchangeRequest struct {
+	Code string `json:"code"`
+}
+
+// ForgotPasswordRequest payload
+type ForgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+// ResetPasswordRequest payload
+type ResetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// VerifyEmailRequest payload
+type VerifyEmailRequest struct {
 	Token string `json:"token"`
-	User  User   `json:"user"`
 }
@@ -3,14 +3,88 @@ package models
 import "time"
 
 type Post struct {
-	ID         int       `json:"id"`
-	UserID     int       `json:"user_id"`
-	AuthorName string    `json:"author_name"`
-	Title      string    `json:"title"`
-	Content    string    `json:"content"`
-	Topic      string    `json:"topic"`
-	Likes      int       `json:"likes"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID           int            `json:"id"`
+	UserID       int            `json:"user_id"`
+	AuthorName   string         `json:"author_name"`
+	Title        string         `json:"title"`
+	Content      string         `json:"content"`
+	Topic        string         `json:"topic"`
+	Likes        int            `json:"likes"` // total reactions of every type
+	Reactions    map[string]int `json:"reactions"`
+	CommentCount int            `json:"comment_count"`
+	Status       string         `json:"status"`
+	Locked       bool           `json:"locked"`
+	CreatedAt    time.Time      `json:"created_at"`
+
+	// TrendingScore is the post's rank in the trending sort.
+	TrendingScore float64 `json:"-"`
+
+	// ViewerReacted lists the signed-in viewer's reactions; nil for anonymous requests.
+	ViewerReacted []string `json:"viewer_reacted"`
+}
+
+// Post statuses. Only visible posts are listed and searchable; hidden ones
+// can be restored by a moderator and removed ones can't. Pending posts were
+// held by automated screening until a moderator approves them.
+const (
+	PostStatusVisible = "visible"
+	PostStatusPending = "pending"
+	PostStatusHidden  = "hidden"
+	PostStatusRemoved = "removed"
+)
+
+// Sort orders for community post listings.
+const (
+	PostSortNewest    = "newest"
+	PostSortTop       = "top"       // most reactions
+	PostSortTrending  = "trending"  // reactions, decayed by age
+	PostSortDiscussed = "discussed" // most comments
+)
+
+// PostQuery selects a page of community posts. Zero fields don't filter;
+// ViewerID, when set, fills in Post.ViewerReacted.
+type PostQuery struct {
+	Topic    string
+	AuthorID int
+	From     time.Time // created at or after
+	To       time.Time // created before
+	Sort     string    // defaults to PostSortNewest
+	Cursor   string    // NextCursor of the previous page
+	Limit    int       // 0 means no limit
+	ViewerID int
+}
+
+// PostPage is one page of a post listing. NextCursor is empty on the last page.
+type PostPage struct {
+	Posts      []Post `json:"posts"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// Reaction types a user can leave on a post, once each.
+const (
+	ReactionLike       = "like"
+	ReactionLove       = "love"
+	ReactionCelebrate  = "celebrate"
+	ReactionInsightful = "insightful"
+)
+
+var ReactionTypes = []string{ReactionLike, ReactionLove, ReactionCelebrate, ReactionInsightful}
+
+func ValidReaction(reaction string) bool {
+	for _, r := range ReactionTypes {
+		if r == reaction {
+			return true
+		}
+	}
+	return false
+}
+
+// ReactionSummary is a post's reaction counts after a change.
+type ReactionSummary struct {
+	PostID        int            `json:"post_id"`
+	Likes         int            `json:"likes"`
+	Reactions     map[string]int `json:"reactions"`
+	ViewerReacted []string       `json:"viewer_reacted"`
 }
 
 type CreatePostRequest struct {
@@ -18,3 +92,36 @@ type CreatePostRequest struct {
 	Content string `json:"content"`
 	Topic   string `json:"topic"`
 }
+
+// Comment is a reply to a post, or to another comment when ParentID is set.
+// A deleted comment keeps its place in the thread but loses its content.
+type Comment struct {
+	ID         int        `json:"id"`
+	PostID     int        `json:"post_id"`
+	ParentID   *int       `json:"parent_id"`
+	UserID     int        `json:"user_id"`
+	AuthorName string     `json:"author_name"`
+	Content    string     `json:"content"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+	Deleted    bool       `json:"deleted"`
+	Status     string     `json:"status"`
+	Replies    []*Comment `json:"replies"`
+}
+
+// Comment statuses. Pending comments are left out of threads until a
+// moderator approves them; removed ones show like deleted comments.
+const (
+	CommentStatusVisible = "visible"
+	CommentStatusPending = "pending"
+	CommentStatusRemoved = "removed"
+)
+
+type CreateCommentRequest struct {
+	Content  string `json:"content"`
+	ParentID *int   `json:"parent_id"`
+}
+
+type UpdateCommentRequest struct {
+	Content string `json:"content"`
+}
@@ -2,12 +2,82 @@ package models
 
 import "time"
 
+// ContactSubmission is a message from the contact form. Each one is a
+// support ticket; Message opens the thread and replies follow as
+// TicketMessages.
 type ContactSubmission struct {
-	ID        int       `json:"id"`
-	UserID    *int      `json:"user_id,omitempty"`
-	FirstName string    `json:"first_name"`
-	LastName  string    `json:"last_name"`
-	Email     string    `json:"email"`
-	Message   string    `json:"message"`
-	CreatedAt time.Time `json:"created_at"`
+	ID             int       `json:"id"`
+	UserID         *int      `json:"user_id,omitempty"`
+	FirstName      string    `json:"first_name"`
+	LastName       string    `json:"last_name"`
+	Email          string    `json:"email"`
+	Message        string    `json:"message"`
+	Status         string    `json:"status"`
+	AssigneeID     *int      `json:"assignee_id,omitempty"`
+	SuggestedReply string    `json:"suggested_reply,omitempty"` // staff only
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Ticket statuses. A ticket is open while staff owe a reply and pending
+// while it waits on the submitter; a reply from the submitter reopens it.
+const (
+	TicketStatusOpen     = "open"
+	TicketStatusPending  = "pending"
+	TicketStatusResolved = "resolved"
+)
+
+var TicketStatuses = []string{TicketStatusOpen, TicketStatusPending, TicketStatusResolved}
+
+// TicketMessage is a reply on a ticket, from staff or the submitter.
+type TicketMessage struct {
+	ID         int        `json:"id"`
+	TicketID   int        `json:"ticket_id"`
+	AuthorID   int        `json:"author_id"`
+	AuthorName string     `json:"author_name"`
+	FromStaff  bool       `json:"from_staff"`
+	Body       string     `json:"body"`
+	EmailedAt  *time.Time `json:"emailed_at,omitempty"` // when a staff reply was queued to be emailed to the submitter
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// Ticket is a contact submission with its replies, oldest first.
+type Ticket struct {
+	ContactSubmission
+	Messages []TicketMessage `json:"messages"`
+}
+
+// ContactRequest is a contact form submission. The ticket is tied to the
+// signed-in user, if any, never to one named in the request.
+type ContactRequest struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+	Message   string `json:"message"`
+}
+
+// TicketMessageRequest is a reply on a ticket. Staff may set Status to
+// something other than the default the reply implies.
+type TicketMessageRequest struct {
+	Body   string `json:"body"`
+	Status string `json:"status"`
+}
+
+// UpdateTicketRequest changes a ticket's status or assignee; nil fields are
+// left alone and an AssigneeID of 0 unassigns it.
+type UpdateTicketRequest struct {
+	Status     *string `json:"status"`
+	AssigneeID *int    `json:"assignee_id"`
+}
+
+// SuggestedReplyRequest replaces a ticket's suggested reply.
+type SuggestedReplyRequest struct {
+	Body string `json:"body"`
+}
+
+// SendReplyRequest emails a staff reply to the submitter. An empty Body
+// sends the ticket's suggested reply.
+type SendReplyRequest struct {
+	Body   string `json:"body"`
+	Status string `json:"status"`
 }
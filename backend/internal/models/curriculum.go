@@ -0,0 +1,195 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Plan kinds stored in lesson_plans.kind.
+const (
+	PlanKindCurriculum = "curriculum"
+	PlanKindRoadmap    = "roadmap"
+)
+
+// PlanContent is the typed body of a lesson plan: a *Curriculum or a *Roadmap.
+type PlanContent interface {
+	Validate() error
+}
+
+// DecodeContent parses the stored JSON content into its typed form.
+func (lp *LessonPlan) DecodeContent() (PlanContent, error) {
+	var content PlanContent = &Curriculum{}
+	if lp.Kind == PlanKindRoadmap {
+		content = &Roadmap{}
+	}
+	if err := json.Unmarshal([]byte(lp.Content), content); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// Curriculum is a generated course: an ordered list of lessons in one language.
+type Curriculum struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Language    string   `json:"language"`
+	Lessons     []Lesson `json:"lessons"`
+}
+
+// Lesson is one step of a Curriculum. LessonID is assigned once the lesson is
+// stored and is what the client submits exercise solutions against.
+type Lesson struct {
+	ID          string    `json:"id"`
+	LessonID    int       `json:"lessonId,omitempty"`
+	Title       string    `json:"title"`
+	Content     string    `json:"content"`
+	InitialCode string    `json:"initialCode"`
+	Exercise    *Exercise `json:"exercise,omitempty"`
+}
+
+// Roadmap is a generated career path split into sections of topics.
+type Roadmap struct {
+	ID          string    `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Sections    []Section `json:"sections"`
+}
+
+type Section struct {
+	Title  string  `json:"title"`
+	Topics []Topic `json:"topics"`
+}
+
+type Topic struct {
+	Title        string   `json:"title"`
+	Description  string   `json:"description"`
+	Priority     Priority `json:"priority"`
+	Technologies []string `json:"technologies"`
+}
+
+type Priority string
+
+const (
+	PriorityHigh   Priority = "high"
+	PriorityMedium Priority = "medium"
+	PriorityLow    Priority = "low"
+)
+
+func (p Priority) Valid() bool {
+	switch p {
+	case PriorityHigh, PriorityMedium, PriorityLow:
+		return true
+	}
+	return false
+}
+
+// Limits enforced on generated content.
+const (
+	MaxCurriculumLessons = 20
+	MaxExerciseTests     = 10
+	MaxRoadmapSections   = 12
+	MaxSectionTopics     = 20
+)
+
+// CurriculumLanguages are the languages a curriculum may be written in; they
+// match the runtimes the sandbox can grade.
+var CurriculumLanguages = []string{"python", "javascript", "go"}
+
+// Validate reports every problem with c, one per line, so the list can be
+// handed back to the model verbatim when asking it to repair its output.
+func (c *Curriculum) Validate() error {
+	var errs []error
+	if strings.TrimSpace(c.Title) == "" {
+		errs = append(errs, errors.New("title is required"))
+	}
+	if !validLanguage(c.Language) {
+		errs = append(errs, fmt.Errorf("language must be one of %s, got %q", strings.Join(CurriculumLanguages, ", "), c.Language))
+	}
+	switch {
+	case len(c.Lessons) == 0:
+		errs = append(errs, errors.New("lessons must contain at least one lesson"))
+	case len(c.Lessons) > MaxCurriculumLessons:
+		errs = append(errs, fmt.Errorf("lessons must contain at most %d lessons, got %d", MaxCurriculumLessons, len(c.Lessons)))
+	}
+	for i, l := range c.Lessons {
+		at := fmt.Sprintf("lessons[%d]", i)
+		if strings.TrimSpace(l.Title) == "" {
+			errs = append(errs, fmt.Errorf("%s.title is required", at))
+		}
+		if strings.TrimSpace(l.Content) == "" {
+			errs = append(errs, fmt.Errorf("%s.content is required", at))
+		}
+		if l.Exercise != nil {
+			errs = append(errs, l.Exercise.validate(at+".exercise")...)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (e *Exercise) validate(at string) []error {
+	var errs []error
+	if strings.TrimSpace(e.Prompt) == "" {
+		errs = append(errs, fmt.Errorf("%s.prompt is required", at))
+	}
+	switch {
+	case len(e.Tests) == 0:
+		errs = append(errs, fmt.Errorf("%s.tests must contain at least one test", at))
+	case len(e.Tests) > MaxExerciseTests:
+		errs = append(errs, fmt.Errorf("%s.tests must contain at most %d tests, got %d", at, MaxExerciseTests, len(e.Tests)))
+	}
+	for i, tc := range e.Tests {
+		if strings.TrimSpace(tc.Name) == "" {
+			errs = append(errs, fmt.Errorf("%s.tests[%d].name is required", at, i))
+		}
+		if strings.TrimSpace(tc.ExpectedOutput) == "" {
+			errs = append(errs, fmt.Errorf("%s.tests[%d].expectedOutput is required", at, i))
+		}
+	}
+	return errs
+}
+
+// Validate reports every problem with r, one per line.
+func (r *Roadmap) Validate() error {
+	var errs []error
+	if strings.TrimSpace(r.Title) == "" {
+		errs = append(errs, errors.New("title is required"))
+	}
+	switch {
+	case len(r.Sections) == 0:
+		errs = append(errs, errors.New("sections must contain at least one section"))
+	case len(r.Sections) > MaxRoadmapSections:
+		errs = append(errs, fmt.Errorf("sections must contain at most %d sections, got %d", MaxRoadmapSections, len(r.Sections)))
+	}
+	for i, s := range r.Sections {
+		at := fmt.Sprintf("sections[%d]", i)
+		if strings.TrimSpace(s.Title) == "" {
+			errs = append(errs, fmt.Errorf("%s.title is required", at))
+		}
+		switch {
+		case len(s.Topics) == 0:
+			errs = append(errs, fmt.Errorf("%s.topics must contain at least one topic", at))
+		case len(s.Topics) > MaxSectionTopics:
+			errs = append(errs, fmt.Errorf("%s.topics must contain at most %d topics, got %d", at, MaxSectionTopics, len(s.Topics)))
+		}
+		for j, t := range s.Topics {
+			if strings.TrimSpace(t.Title) == "" {
+				errs = append(errs, fmt.Errorf("%s.topics[%d].title is required", at, j))
+			}
+			if !t.Priority.Valid() {
+				errs = append(errs, fmt.Errorf("%s.topics[%d].priority must be \"high\", \"medium\" or \"low\", got %q", at, j, t.Priority))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func validLanguage(language string) bool {
+	for _, l := range CurriculumLanguages {
+		if language == l {
+			return true
+		}
+	}
+	return false
+}
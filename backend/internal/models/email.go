@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Outbox email statuses. A pending email is retried until it is sent or
+// runs out of attempts and becomes dead.
+const (
+	EmailStatusPending = "pending"
+	EmailStatusSent    = "sent"
+	EmailStatusDead    = "dead"
+)
+
+var EmailStatuses = []string{EmailStatusPending, EmailStatusSent, EmailStatusDead}
+
+// OutboxEmail is a rendered email waiting in, or delivered from, the outbox.
+type OutboxEmail struct {
+	ID            int        `json:"id"`
+	To            string     `json:"to"`
+	Template      string     `json:"template"`
+	Subject       string     `json:"subject"`
+	HTMLBody      string     `json:"-"`
+	TextBody      string     `json:"-"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+}
+
+// OutboxQuery selects outbox emails, newest first.
+type OutboxQuery struct {
+	Status string
+	Limit  int
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// Exercise is the graded task attached to a lesson. Tests marked Hidden are
+// stored server-side only and never sent to the browser.
+type Exercise struct {
+	Prompt string     `json:"prompt"`
+	Tests  []TestCase `json:"tests"`
+}
+
+type TestCase struct {
+	Name           string `json:"name"`
+	Stdin          string `json:"stdin,omitempty"`
+	ExpectedOutput string `json:"expectedOutput"`
+	Hidden         bool   `json:"hidden,omitempty"`
+}
+
+// LessonRecord is a stored lesson of a saved curriculum, addressable by ID.
+// It keeps the full exercise, hidden tests included.
+type LessonRecord struct {
+	ID          int       `json:"id"`
+	PlanID      int       `json:"plan_id"`
+	LessonIndex int       `json:"lesson_index"`
+	Title       string    `json:"title"`
+	Language    string    `json:"language"`
+	Exercise    *Exercise `json:"exercise,omitempty"`
+}
+
+type LessonAttempt struct {
+	ID          int       `json:"id"`
+	UserID      int       `json:"user_id"`
+	LessonID    int       `json:"lesson_id"`
+	Code        string    `json:"code"`
+	Passed      bool      `json:"passed"`
+	PassedCount int       `json:"passed_count"`
+	TotalCount  int       `json:"total_count"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type SubmitLessonRequest struct {
+	Code string `json:"code"`
+}
+
+// TestResult reports one test case. For hidden tests only the verdict is
+// returned so the expected output cannot be scraped.
+type TestResult struct {
+	Name     string `json:"name"`
+	Passed   bool   `json:"passed"`
+	Hidden   bool   `json:"hidden"`
+	Stdin    string `json:"stdin,omitempty"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Stderr   string `json:"stderr,omitempty"`
+	TimedOut bool   `json:"timed_out,omitempty"`
+}
+
+type SubmitLessonResponse struct {
+	AttemptID    int          `json:"attempt_id"`
+	Passed       bool         `json:"passed"`
+	PassedCount  int          `json:"passed_count"`
+	TotalCount   int          `json:"total_count"`
+	Results      []TestResult `json:"results"`
+	Advanced     bool         `json:"advanced"`
+	CurrentIndex int          `json:"current_index"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// Readiness is the /readyz report: "ready" or "unavailable", with the
+// result of each check, "ok" or "failed".
+type Readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
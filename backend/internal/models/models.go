@@ -19,18 +19,50 @@ type ChatHistory struct {
 	Text string `json:"text"`
 }
 
+type ChatStreamDelta struct {
+	Text string `json:"text"`
+}
+
+type ChatStreamDone struct {
+	Text  string     `json:"text"`
+	Model string     `json:"model,omitempty"`
+	Usage TokenUsage `json:"usage"`
+}
+
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
 type ExecuteRequest struct {
 	Code     string `json:"code"`
 	Language string `json:"language"`
+	Stdin    string `json:"stdin,omitempty"`
+}
+
+// ExecuteResponse keeps Text (stdout followed by stderr) for older clients.
+type ExecuteResponse struct {
+	Text       string `json:"text"`
+	Stdout     string `json:"stdout"`
+	Stderr     string `json:"stderr"`
+	ExitCode   int    `json:"exit_code"`
+	TimedOut   bool   `json:"timed_out"`
+	Truncated  bool   `json:"truncated"`
+	Phase      string `json:"phase,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Mode       string `json:"mode"` // "sandbox" or "llm"
 }
 
 type Response struct {
 	Text string `json:"text"`
 }
 
+// LessonPlanResponse keeps Text (the curriculum as a JSON string) for older clients.
 type LessonPlanResponse struct {
-	ID   int    `json:"id"`
-	Text string `json:"text"`
+	ID         int         `json:"id"`
+	Text       string      `json:"text"`
+	Curriculum *Curriculum `json:"curriculum"`
 }
 
 type ErrorResponse struct {
@@ -39,6 +71,8 @@ type ErrorResponse struct {
 
 type LessonPlan struct {
 	ID                 int       `json:"id"`
+	UserID             *int      `json:"user_id,omitempty"`
+	Kind               string    `json:"kind"` // PlanKindCurriculum or PlanKindRoadmap
 	Persona            string    `json:"persona"`
 	Goals              string    `json:"goals"`
 	Content            string    `json:"content"`
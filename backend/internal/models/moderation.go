@@ -0,0 +1,188 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Reasons a user can give when reporting a post.
+const (
+	ReportReasonSpam          = "spam"
+	ReportReasonHarassment    = "harassment"
+	ReportReasonInappropriate = "inappropriate"
+	ReportReasonOffTopic      = "off_topic"
+	ReportReasonOther         = "other"
+)
+
+var ReportReasons = []string{ReportReasonSpam, ReportReasonHarassment, ReportReasonInappropriate, ReportReasonOffTopic, ReportReasonOther}
+
+// Report statuses. A report is resolved when a moderator acted on the post
+// and dismissed when they decided no action was needed.
+const (
+	ReportStatusOpen      = "open"
+	ReportStatusResolved  = "resolved"
+	ReportStatusDismissed = "dismissed"
+)
+
+type Report struct {
+	ID         int        `json:"id"`
+	PostID     int        `json:"post_id"`
+	ReporterID int        `json:"reporter_id"`
+	Reason     string     `json:"reason"`
+	Details    string     `json:"details"`
+	Status     string     `json:"status"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy *int       `json:"resolved_by,omitempty"`
+}
+
+type ReportPostRequest struct {
+	Reason  string `json:"reason"`
+	Details string `json:"details"`
+}
+
+// ModerationQueueItem is a post with the reports still waiting on it.
+type ModerationQueueItem struct {
+	Post    Post     `json:"post"`
+	Reports []Report `json:"reports"`
+}
+
+// Moderator actions on a post. Dismiss closes its reports without changing it.
+const (
+	PostActionHide    = "hide"
+	PostActionUnhide  = "unhide"
+	PostActionLock    = "lock"
+	PostActionUnlock  = "unlock"
+	PostActionRemove  = "remove"
+	PostActionDismiss = "dismiss"
+)
+
+var PostActions = []string{PostActionHide, PostActionUnhide, PostActionLock, PostActionUnlock, PostActionRemove, PostActionDismiss, ContentActionApprove}
+
+// ModerateContentRequest is a moderator's action on a post or comment.
+type ModerateContentRequest struct {
+	Action string `json:"action"`
+	Reason string `json:"reason"`
+}
+
+// SuspendUserRequest suspends for Hours, until Until, or for good when
+// Permanent is set.
+type SuspendUserRequest struct {
+	Hours     int        `json:"hours"`
+	Until     *time.Time `json:"until"`
+	Permanent bool       `json:"permanent"`
+	Reason    string     `json:"reason"`
+}
+
+// Audit log actions and target types outside the post actions above.
+const (
+	AuditActionSuspend   = "suspend"
+	AuditActionUnsuspend = "unsuspend"
+
+	AuditTargetPost    = "post"
+	AuditTargetComment = "comment"
+	AuditTargetUser    = "user"
+)
+
+// AuditEntry records one moderation or administrative action.
+type AuditEntry struct {
+	ID         int       `json:"id"`
+	ActorID    int       `json:"actor_id"`
+	ActorName  string    `json:"actor_name"`
+	Action     string    `json:"action"`
+	TargetType string    `json:"target_type"`
+	TargetID   int       `json:"target_id"`
+	Reason     string    `json:"reason"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AuditQuery selects audit entries, newest first. Zero fields don't filter;
+// BeforeID pages back from the oldest entry of the previous page.
+type AuditQuery struct {
+	ActorID    int
+	TargetType string
+	TargetID   int
+	BeforeID   int
+	Limit      int
+}
+
+// Labels assigned by automated screening, each scored from 0 to 1.
+const (
+	LabelToxicity   = "toxicity"
+	LabelSpam       = "spam"
+	LabelOffTopic   = "off_topic"
+	LabelAnswerLeak = "answer_leak" // gives away the solution to a course exercise
+)
+
+var ModerationLabels = []string{LabelToxicity, LabelSpam, LabelOffTopic, LabelAnswerLeak}
+
+// Screening verdicts. Allowed content is published at once; pending content
+// waits for a moderator.
+const (
+	VerdictAllow   = "allow"
+	VerdictPending = "pending"
+)
+
+// Kinds of content that are screened.
+const (
+	ContentTypePost    = "post"
+	ContentTypeComment = "comment"
+)
+
+// ContentClassification is what the LLM says about a piece of content.
+type ContentClassification struct {
+	Labels  map[string]float64 `json:"labels"`
+	Reasons []string           `json:"reasons"`
+}
+
+// Validate reports every problem with c, one per line.
+func (c *ContentClassification) Validate() error {
+	var errs []error
+	for _, label := range ModerationLabels {
+		score, ok := c.Labels[label]
+		if !ok {
+			errs = append(errs, fmt.Errorf("labels.%s is required", label))
+		} else if score < 0 || score > 1 {
+			errs = append(errs, fmt.Errorf("labels.%s must be between 0 and 1, got %v", label, score))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ModerationDecision is the outcome of screening one post or comment. Score
+// is its highest label score; Classifier names the LLM provider consulted,
+// if any.
+type ModerationDecision struct {
+	ID           int                `json:"id"`
+	ContentType  string             `json:"content_type"`
+	ContentID    int                `json:"content_id"`
+	AuthorID     int                `json:"author_id"`
+	Verdict      string             `json:"verdict"`
+	Score        float64            `json:"score"`
+	Labels       map[string]float64 `json:"labels"`
+	Reasons      []string           `json:"reasons"`
+	Classifier   string             `json:"classifier,omitempty"`
+	ContentHash  string             `json:"-"`
+	CreatedAt    time.Time          `json:"created_at"`
+	ReviewedAt   *time.Time         `json:"reviewed_at,omitempty"`
+	ReviewedBy   *int               `json:"reviewed_by,omitempty"`
+	ReviewAction string             `json:"review_action,omitempty"`
+}
+
+// PendingItem is a post or comment held by screening, with the decision
+// that held it.
+type PendingItem struct {
+	Post     *Post              `json:"post,omitempty"`
+	Comment  *Comment           `json:"comment,omitempty"`
+	Decision ModerationDecision `json:"decision"`
+}
+
+// Moderator actions on a comment, and the approve action that publishes a
+// pending post.
+const (
+	ContentActionApprove = "approve"
+	ContentActionRemove  = "remove"
+)
+
+var CommentActions = []string{ContentActionApprove, ContentActionRemove}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// AIQuota is a user's AI usage in the current UTC day and month against
+// their limits. A limit of 0 means unlimited.
+type AIQuota struct {
+	DailyLimit    int       `json:"daily_limit"`
+	DailyUsed     int       `json:"daily_used"`
+	MonthlyLimit  int       `json:"monthly_limit"`
+	MonthlyUsed   int       `json:"monthly_used"`
+	DayResetsAt   time.Time `json:"day_resets_at"`
+	MonthResetsAt time.Time `json:"month_resets_at"`
+}
+
+// DailyRemaining is how many requests are left today, or -1 if unlimited.
+func (q *AIQuota) DailyRemaining() int {
+	return remaining(q.DailyLimit, q.DailyUsed)
+}
+
+// MonthlyRemaining is how many requests are left this month, or -1 if
+// unlimited.
+func (q *AIQuota) MonthlyRemaining() int {
+	return remaining(q.MonthlyLimit, q.MonthlyUsed)
+}
+
+// Exhausted reports whether either limit has been reached, and if so when
+// the earliest one resets.
+func (q *AIQuota) Exhausted() (time.Time, bool) {
+	if q.MonthlyLimit > 0 && q.MonthlyUsed >= q.MonthlyLimit {
+		return q.MonthResetsAt, true
+	}
+	if q.DailyLimit > 0 && q.DailyUsed >= q.DailyLimit {
+		return q.DayResetsAt, true
+	}
+	return time.Time{}, false
+}
+
+func remaining(limit, used int) int {
+	if limit <= 0 {
+		return -1
+	}
+	return max(limit-used, 0)
+}
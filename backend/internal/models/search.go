@@ -0,0 +1,37 @@
+package models
+
+// Kinds of document returned by search.
+const (
+	SearchTypePost    = "post"
+	SearchTypeCourse  = "course"
+	SearchTypeLesson  = "lesson"
+	SearchTypeRoadmap = "roadmap"
+)
+
+var SearchTypes = []string{SearchTypePost, SearchTypeCourse, SearchTypeLesson, SearchTypeRoadmap}
+
+// SearchQuery is a full-text search. Posts are visible to everyone; courses,
+// lessons and roadmaps only to their owner, ViewerID.
+type SearchQuery struct {
+	Text     string
+	Types    []string // empty means every type
+	ViewerID int
+	Limit    int
+	Offset   int
+}
+
+// SearchResult is one match. Title and Snippet are HTML-escaped with the
+// matched terms wrapped in <mark>.
+type SearchResult struct {
+	Type     string  `json:"type"`
+	ID       int     `json:"id"`
+	ParentID *int    `json:"parent_id,omitempty"` // the course a lesson belongs to
+	Title    string  `json:"title"`
+	Snippet  string  `json:"snippet"`
+	Rank     float64 `json:"rank"` // higher is better; only comparable within one response
+}
+
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+}
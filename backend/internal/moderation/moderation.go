@@ -0,0 +1,241 @@
+// Package moderation screens new community posts and comments before they
+// are published. A rule layer catches blocklisted terms, link spam, spam
+// phrases, shouting and reposts; an optional LLM classifier adds toxicity,
+// spam, off-topic and answer-leak scores. Content whose highest score reaches
+// the threshold is held for a moderator instead of going public.
+package moderation
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"codefuture-backend/internal/logging"
+	"codefuture-backend/internal/models"
+)
+
+// Config controls how strict screening is.
+type Config struct {
+	Threshold         float64       // hold content whose highest label score reaches this
+	MaxLinks          int           // links allowed in one post or comment; 0 means no limit
+	Blocklist         []string      // terms that always hold content, matched as whole words
+	DuplicateWindow   time.Duration // how far back to look for the same text by the same author
+	ClassifierTimeout time.Duration // the LLM call is skipped once this passes
+}
+
+// Classifier labels content with an LLM. services.AIService implements it.
+type Classifier interface {
+	ClassifyContent(ctx context.Context, contentType, title, body string) (*models.ContentClassification, error)
+	ProviderName() string
+}
+
+// History looks up what an author posted before.
+type History interface {
+	CountDuplicateContent(authorID int, contentHash string, since time.Time) (int, error)
+}
+
+// Content is a post or comment about to be published.
+type Content struct {
+	Type     string // models.ContentTypePost or models.ContentTypeComment
+	AuthorID int
+	Title    string // posts only
+	Body     string
+}
+
+type Screener struct {
+	cfg        Config
+	blocklist  []string // cfg.Blocklist split into words the way text is
+	history    History
+	classifier Classifier
+}
+
+// NewScreener returns a screener that consults classifier, when it isn't
+// nil, after the rules.
+func NewScreener(cfg Config, history History, classifier Classifier) *Screener {
+	s := &Screener{cfg: cfg, history: history, classifier: classifier}
+	for _, term := range cfg.Blocklist {
+		if words := words(strings.ToLower(term)); words != "" {
+			s.blocklist = append(s.blocklist, words)
+		}
+	}
+	return s
+}
+
+// Rule scores. A single strong signal holds content at the default
+// threshold of 0.7; weak ones only do so together with the classifier.
+const (
+	blocklistScore   = 1.0
+	linkLimitScore   = 0.9
+	duplicateScore   = 0.8
+	spamPhraseScore  = 0.35 // per phrase
+	shoutingScore    = 0.5
+	repeatedRunScore = 0.4
+)
+
+const (
+	// Short texts like "thanks!" are repeated innocently all the time
+	minDuplicateLength = 20
+	minShoutingLetters = 20
+	shoutingRatio      = 0.7
+	maxRepeatedRun     = 10
+)
+
+var spamPhrases = []string{
+	"buy now", "click here", "free money", "limited time offer", "work from home",
+	"casino", "crypto giveaway", "100% free", "make money fast", "dm me for",
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\bhttps?://|\bwww\.`)
+
+// Screen scores c and decides whether it can be published. The decision's
+// ContentID is left for the caller to fill in once the content is stored.
+func (s *Screener) Screen(ctx context.Context, c Content) (*models.ModerationDecision, error) {
+	d := &models.ModerationDecision{
+		ContentType: c.Type,
+		AuthorID:    c.AuthorID,
+		Verdict:     models.VerdictAllow,
+		Labels:      map[string]float64{},
+		Reasons:     []string{},
+		CreatedAt:   time.Now().UTC(),
+	}
+	for _, label := range models.ModerationLabels {
+		d.Labels[label] = 0
+	}
+
+	normalized := normalize(c.Title + "\n" + c.Body)
+	d.ContentHash = contentHash(normalized)
+
+	s.applyRules(d, c.Title+"\n"+c.Body, normalized)
+
+	if len(normalized) >= minDuplicateLength && s.history != nil {
+		n, err := s.history.CountDuplicateContent(c.AuthorID, d.ContentHash, d.CreatedAt.Add(-s.cfg.DuplicateWindow))
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			raise(d, models.LabelSpam, duplicateScore, "Repeats text the author posted recently")
+		}
+	}
+
+	if s.classifier != nil {
+		s.classify(ctx, d, c)
+	}
+
+	for _, score := range d.Labels {
+		d.Score = max(d.Score, score)
+	}
+	if d.Score >= s.cfg.Threshold {
+		d.Verdict = models.VerdictPending
+	}
+	return d, nil
+}
+
+func (s *Screener) applyRules(d *models.ModerationDecision, text, normalized string) {
+	// Pad with spaces so terms only match whole words
+	padded := " " + words(normalized) + " "
+	for _, term := range s.blocklist {
+		if strings.Contains(padded, " "+term+" ") {
+			raise(d, models.LabelToxicity, blocklistScore, fmt.Sprintf("Contains blocked term %q", term))
+		}
+	}
+
+	if links := len(linkPattern.FindAllStringIndex(text, -1)); s.cfg.MaxLinks > 0 && links > s.cfg.MaxLinks {
+		raise(d, models.LabelSpam, linkLimitScore, fmt.Sprintf("Contains %d links (limit %d)", links, s.cfg.MaxLinks))
+	}
+
+	var phrases []string
+	for _, p := range spamPhrases {
+		if strings.Contains(normalized, p) {
+			phrases = append(phrases, p)
+		}
+	}
+	if len(phrases) > 0 {
+		score := min(spamPhraseScore*float64(len(phrases)), 1)
+		raise(d, models.LabelSpam, score, "Uses spam phrases: "+strings.Join(phrases, ", "))
+	}
+
+	var letters, upper int
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters >= minShoutingLetters && float64(upper) > shoutingRatio*float64(letters) {
+		raise(d, models.LabelSpam, shoutingScore, "Written mostly in capital letters")
+	}
+
+	if longestRun(text) >= maxRepeatedRun {
+		raise(d, models.LabelSpam, repeatedRunScore, "Repeats the same character many times")
+	}
+}
+
+// classify adds the LLM's scores. When the call fails the rules alone decide,
+// so an unavailable provider never blocks posting.
+func (s *Screener) classify(ctx context.Context, d *models.ModerationDecision, c Content) {
+	if s.cfg.ClassifierTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.ClassifierTimeout)
+		defer cancel()
+	}
+
+	result, err := s.classifier.ClassifyContent(ctx, c.Type, c.Title, c.Body)
+	if err != nil {
+		logging.FromContext(ctx).Warn("moderation classifier failed, using rules only", "err", err)
+		return
+	}
+	d.Classifier = s.classifier.ProviderName()
+	for _, label := range models.ModerationLabels {
+		d.Labels[label] = max(d.Labels[label], result.Labels[label])
+	}
+	d.Reasons = append(d.Reasons, result.Reasons...)
+}
+
+func raise(d *models.ModerationDecision, label string, score float64, reason string) {
+	d.Labels[label] = max(d.Labels[label], score)
+	d.Reasons = append(d.Reasons, reason)
+}
+
+// normalize lowercases text and collapses whitespace, so reposts that only
+// differ in case or spacing hash the same.
+func normalize(text string) string {
+	return strings.Join(strings.Fields(strings.ToLower(text)), " ")
+}
+
+// words splits text on anything but letters and digits and rejoins the
+// words with single spaces. Blocklist terms and the text they are looked for
+// in both go through it, so "buy-now" matches "Buy now!" and "buy.now".
+func words(text string) string {
+	return strings.Join(strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+func contentHash(normalized string) string {
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// longestRun is the longest run of one repeated letter or ! or ?. Markdown
+// rules like "-----" and code don't count.
+func longestRun(text string) int {
+	longest, run := 0, 0
+	var prev rune
+	for _, r := range text {
+		if r == prev && (unicode.IsLetter(r) || r == '!' || r == '?') {
+			run++
+		} else {
+			run = 1
+		}
+		prev = r
+		longest = max(longest, run)
+	}
+	return longest
+}
@@ -0,0 +1,116 @@
+package moderation
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"codefuture-backend/internal/models"
+)
+
+type fakeHistory struct{ hashes map[string]bool }
+
+func (h fakeHistory) CountDuplicateContent(authorID int, contentHash string, since time.Time) (int, error) {
+	if h.hashes[contentHash] {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+func TestScreenRules(t *testing.T) {
+	cfg := Config{
+		Threshold: 0.7,
+		MaxLinks:  2,
+		Blocklist: []string{"Badword", "spam-bot", "f.u", "  two   words "},
+	}
+	for _, tc := range []struct {
+		name    string
+		title   string
+		body    string
+		label   string // raised label, empty for none
+		score   float64
+		verdict string
+	}{
+		{"clean", "Loops in Go", "How do I break out of a nested loop?", "", 0, models.VerdictAllow},
+		{"blocked term", "", "this is a BADWORD here", models.LabelToxicity, blocklistScore, models.VerdictPending},
+		{"blocked term whole words only", "", "badwords are fine", "", 0, models.VerdictAllow},
+		{"hyphenated term", "", "Hired a Spam bot!", models.LabelToxicity, blocklistScore, models.VerdictPending},
+		{"hyphenated term as written", "", "see spam-bot.example", models.LabelToxicity, blocklistScore, models.VerdictPending},
+		{"dotted term", "", "well f.u too", models.LabelToxicity, blocklistScore, models.VerdictPending},
+		{"dotted term spaced", "", "well F U too", models.LabelToxicity, blocklistScore, models.VerdictPending},
+		{"multi-word term", "", "two words, spaced oddly", models.LabelToxicity, blocklistScore, models.VerdictPending},
+		{"links within limit", "", "see https://a.example and www.b.example", "", 0, models.VerdictAllow},
+		{"too many links", "", "https://a.example http://b.example www.c.example", models.LabelSpam, linkLimitScore, models.VerdictPending},
+		{"one spam phrase", "", "click here for the docs", models.LabelSpam, spamPhraseScore, models.VerdictAllow},
+		{"spam phrases add up", "", "click here for free money", models.LabelSpam, 2 * spamPhraseScore, models.VerdictPending},
+		{"shouting", "", "WHY DOES MY PROGRAM NOT COMPILE AT ALL", models.LabelSpam, shoutingScore, models.VerdictAllow},
+		{"short caps are fine", "", "OK THANKS", "", 0, models.VerdictAllow},
+		{"repeated run", "", "helloooooooooooo", models.LabelSpam, repeatedRunScore, models.VerdictAllow},
+		{"markdown rule is fine", "", "above\n----------------\nbelow", "", 0, models.VerdictAllow},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := NewScreener(cfg, nil, nil).Screen(context.Background(), Content{Type: models.ContentTypePost, Title: tc.title, Body: tc.body})
+			if err != nil {
+				t.Fatalf("Screen: %v", err)
+			}
+			for _, label := range models.ModerationLabels {
+				want := 0.0
+				if label == tc.label {
+					want = tc.score
+				}
+				if d.Labels[label] != want {
+					t.Errorf("Labels[%s] = %v, want %v (reasons %q)", label, d.Labels[label], want, d.Reasons)
+				}
+			}
+			if d.Verdict != tc.verdict {
+				t.Errorf("Verdict = %s, want %s (score %v)", d.Verdict, tc.verdict, d.Score)
+			}
+			if (tc.label == "") != (len(d.Reasons) == 0) {
+				t.Errorf("Reasons = %q", d.Reasons)
+			}
+		})
+	}
+}
+
+func TestScreenThreshold(t *testing.T) {
+	body := "click here for free money" // two spam phrases, 0.7
+	for _, tc := range []struct {
+		threshold float64
+		verdict   string
+	}{
+		{0.7, models.VerdictPending},
+		{0.71, models.VerdictAllow},
+		{0.3, models.VerdictPending},
+	} {
+		d, err := NewScreener(Config{Threshold: tc.threshold}, nil, nil).Screen(context.Background(), Content{Body: body})
+		if err != nil {
+			t.Fatalf("Screen: %v", err)
+		}
+		if d.Verdict != tc.verdict {
+			t.Errorf("threshold %v: Verdict = %s (score %v), want %s", tc.threshold, d.Verdict, d.Score, tc.verdict)
+		}
+	}
+}
+
+func TestScreenDuplicate(t *testing.T) {
+	body := "Here is my   solution to the loops exercise"
+	seen := fakeHistory{hashes: map[string]bool{contentHash(normalize("\n" + strings.ToUpper(body))): true}}
+	s := NewScreener(Config{Threshold: 0.7, DuplicateWindow: time.Hour}, seen, nil)
+
+	d, err := s.Screen(context.Background(), Content{Body: body})
+	if err != nil {
+		t.Fatalf("Screen: %v", err)
+	}
+	if d.Labels[models.LabelSpam] != duplicateScore || d.Verdict != models.VerdictPending {
+		t.Errorf("repost: spam %v, verdict %s; want %v, pending", d.Labels[models.LabelSpam], d.Verdict, duplicateScore)
+	}
+
+	d, err = s.Screen(context.Background(), Content{Body: "thanks!"})
+	if err != nil {
+		t.Fatalf("Screen: %v", err)
+	}
+	if d.Verdict != models.VerdictAllow {
+		t.Errorf("short text counted as a repost")
+	}
+}
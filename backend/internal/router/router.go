@@ -0,0 +1,186 @@
+// Package router registers the API on a net/http ServeMux using Go 1.22
+// method and path patterns ("GET /api/roadmaps/{id}"). Routes are declared
+// in groups that share a path prefix and a middleware stack. A path that
+// exists but not for the request's method gets a 405 with an Allow header,
+// and every route is kept in a table that can be listed for docs.
+package router
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"codefuture-backend/internal/models"
+)
+
+// Middleware wraps a handler, like the Authenticator's AuthMiddleware.
+type Middleware func(http.HandlerFunc) http.HandlerFunc
+
+// Route is one entry in the route table.
+type Route struct {
+	Method  string
+	Path    string
+	Handler string // the handler's function name
+}
+
+// Router collects routes and builds the ServeMux that serves them.
+type Router struct {
+	root    Group
+	routes  []Route
+	mux     *http.ServeMux
+	built   bool
+	allowed map[string][]string // methods by path, set by Handler
+}
+
+// Group registers routes under a prefix, wrapped in its middleware: the
+// group's own first, outermost, then any added per route.
+type Group struct {
+	router     *Router
+	prefix     string
+	middleware []Middleware
+}
+
+func New() *Router {
+	r := &Router{mux: http.NewServeMux()}
+	r.root = Group{router: r}
+	return r
+}
+
+// Group returns a group of routes under prefix that run mw.
+func (r *Router) Group(prefix string, mw ...Middleware) *Group {
+	return r.root.Group(prefix, mw...)
+}
+
+// Group returns a subgroup whose routes are under g's prefix plus prefix
+// and run g's middleware, then mw.
+func (g *Group) Group(prefix string, mw ...Middleware) *Group {
+	return &Group{
+		router:     g.router,
+		prefix:     g.prefix + prefix,
+		middleware: append(slices.Clip(g.middleware), mw...),
+	}
+}
+
+func (g *Group) GET(path string, h http.HandlerFunc, mw ...Middleware) {
+	g.Handle(http.MethodGet, path, h, mw...)
+}
+
+func (g *Group) POST(path string, h http.HandlerFunc, mw ...Middleware) {
+	g.Handle(http.MethodPost, path, h, mw...)
+}
+
+func (g *Group) PUT(path string, h http.HandlerFunc, mw ...Middleware) {
+	g.Handle(http.MethodPut, path, h, mw...)
+}
+
+func (g *Group) DELETE(path string, h http.HandlerFunc, mw ...Middleware) {
+	g.Handle(http.MethodDelete, path, h, mw...)
+}
+
+// Handle registers h for method and path, a ServeMux pattern path that may
+// hold {name} wildcards for r.PathValue.
+func (g *Group) Handle(method, path string, h http.HandlerFunc, mw ...Middleware) {
+	r := g.router
+	if r.built {
+		panic("router: route " + method + " " + path + " added after Handler")
+	}
+	full := g.prefix + path
+	r.routes = append(r.routes, Route{Method: method, Path: full, Handler: funcName(h)})
+
+	wrapped := h
+	stack := append(slices.Clip(g.middleware), mw...)
+	for i := len(stack) - 1; i >= 0; i-- {
+		wrapped = stack[i](wrapped)
+	}
+	r.mux.HandleFunc(method+" "+full, wrapped)
+}
+
+// Routes returns the route table in the order routes were added.
+func (r *Router) Routes() []Route {
+	return slices.Clone(r.routes)
+}
+
+// WriteTable lists the routes as aligned METHOD PATH HANDLER columns.
+func (r *Router) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tPATH\tHANDLER")
+	for _, rt := range r.routes {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", rt.Method, rt.Path, rt.Handler)
+	}
+	return tw.Flush()
+}
+
+// Handler returns the mux serving every route added so far. Other methods
+// on a known path get a 405 listing the allowed ones, and unknown paths a
+// 404, both as JSON errors like the handlers write.
+func (r *Router) Handler() http.Handler {
+	if r.built {
+		return r.mux
+	}
+	r.built = true
+
+	allowed := map[string][]string{}
+	r.allowed = allowed
+	var paths []string
+	for _, rt := range r.routes {
+		if _, ok := allowed[rt.Path]; !ok {
+			paths = append(paths, rt.Path)
+		}
+		allowed[rt.Path] = append(allowed[rt.Path], rt.Method)
+		if rt.Method == http.MethodGet {
+			allowed[rt.Path] = append(allowed[rt.Path], http.MethodHead)
+		}
+	}
+	// A pattern without a method is less specific than the same path with
+	// one, so these only see requests no registered method matched
+	for _, path := range paths {
+		allow := strings.Join(allowed[path], ", ")
+		r.mux.HandleFunc(path, func(w http.ResponseWriter, req *http.Request) {
+			w.Header().Set("Allow", allow)
+			writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		})
+	}
+	if _, ok := allowed["/"]; !ok {
+		r.mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+			writeError(w, "Not found", http.StatusNotFound)
+		})
+	}
+	return r.mux
+}
+
+// Match returns the path of the route req is for, whatever its method, and
+// the methods registered for that path. It is only valid after Handler.
+func (r *Router) Match(req *http.Request) (path string, methods []string, ok bool) {
+	_, pattern := r.mux.Handler(req)
+	if _, p, found := strings.Cut(pattern, " "); found {
+		pattern = p
+	}
+	methods, ok = r.allowed[pattern]
+	return pattern, methods, ok
+}
+
+func writeError(w http.ResponseWriter, message string, status int) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(models.ErrorResponse{Error: message})
+}
+
+// funcName turns a method value like handlers.(*Handler).HandleLogin-fm
+// into HandleLogin.
+func funcName(h http.HandlerFunc) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(h).Pointer())
+	if fn == nil {
+		return "?"
+	}
+	name := strings.TrimSuffix(fn.Name(), "-fm")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
@@ -0,0 +1,173 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func ok(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(r.Method + " " + r.PathValue("id")))
+}
+
+func newTestRouter() *Router {
+	r := New()
+	api := r.Group("/api")
+	api.GET("/items", ok)
+	api.POST("/items", ok)
+	api.GET("/items/{id}", ok)
+	api.DELETE("/items/{id}", ok)
+	return r
+}
+
+func serve(h http.Handler, method, target string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+	return rec
+}
+
+func TestHandlerRoutesByMethod(t *testing.T) {
+	h := newTestRouter().Handler()
+
+	tests := []struct {
+		method, target string
+		status         int
+		body           string
+		allow          string
+	}{
+		{"GET", "/api/items", 200, "GET ", ""},
+		{"POST", "/api/items", 200, "POST ", ""},
+		{"HEAD", "/api/items", 200, "", ""},
+		{"GET", "/api/items/7", 200, "GET 7", ""},
+		{"DELETE", "/api/items/7", 200, "DELETE 7", ""},
+		{"PUT", "/api/items", 405, `"Method not allowed"`, "GET, HEAD, POST"},
+		{"POST", "/api/items/7", 405, `"Method not allowed"`, "GET, HEAD, DELETE"},
+		{"GET", "/api/missing", 404, `"Not found"`, ""},
+		{"GET", "/", 404, `"Not found"`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			rec := serve(h, tt.method, tt.target)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if !strings.Contains(rec.Body.String(), tt.body) {
+				t.Errorf("body = %q, want it to contain %q", rec.Body.String(), tt.body)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.allow {
+				t.Errorf("Allow = %q, want %q", got, tt.allow)
+			}
+			if rec.Code >= 400 && rec.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %q, want JSON errors", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestRootRouteIsNotReplaced(t *testing.T) {
+	r := New()
+	r.Group("").GET("/", ok)
+	h := r.Handler()
+
+	if rec := serve(h, "GET", "/"); rec.Code != 200 {
+		t.Errorf("GET / status = %d, want the registered route", rec.Code)
+	}
+	if rec := serve(h, "POST", "/"); rec.Code != 405 {
+		t.Errorf("POST / status = %d, want 405", rec.Code)
+	}
+}
+
+func TestGroupPrefixAndMiddlewareOrder(t *testing.T) {
+	var order []string
+	mark := func(name string) Middleware {
+		return func(next http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next(w, r)
+			}
+		}
+	}
+
+	r := New()
+	api := r.Group("/api", mark("api"))
+	admin := api.Group("/admin", mark("admin"))
+	admin.GET("/users", func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	}, mark("route"))
+	// A sibling group must not pick up admin's middleware
+	api.Group("/public").GET("/ping", func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "handler")
+	})
+	h := r.Handler()
+
+	serve(h, "GET", "/api/admin/users")
+	if want := []string{"api", "admin", "route", "handler"}; !slices.Equal(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+
+	order = nil
+	serve(h, "GET", "/api/public/ping")
+	if want := []string{"api", "handler"}; !slices.Equal(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+
+	want := []Route{
+		{Method: "GET", Path: "/api/admin/users"},
+		{Method: "GET", Path: "/api/public/ping"},
+	}
+	got := r.Routes()
+	if len(got) != len(want) {
+		t.Fatalf("Routes() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i].Method != want[i].Method || got[i].Path != want[i].Path {
+			t.Errorf("Routes()[%d] = %v, want %s %s", i, got[i], want[i].Method, want[i].Path)
+		}
+	}
+}
+
+func TestHandlerName(t *testing.T) {
+	r := New()
+	r.Group("/api").GET("/items", ok)
+	if got := r.Routes()[0].Handler; got != "ok" {
+		t.Errorf("Handler = %q, want %q", got, "ok")
+	}
+}
+
+func TestAddAfterHandlerPanics(t *testing.T) {
+	r := newTestRouter()
+	r.Handler()
+	defer func() {
+		if recover() == nil {
+			t.Error("adding a route after Handler did not panic")
+		}
+	}()
+	r.Group("/api").GET("/late", ok)
+}
+
+func TestMatch(t *testing.T) {
+	r := newTestRouter()
+	r.Handler()
+
+	tests := []struct {
+		method, target string
+		path           string
+		methods        []string
+		ok             bool
+	}{
+		{"GET", "/api/items", "/api/items", []string{"GET", "HEAD", "POST"}, true},
+		// A CORS preflight matches the route whatever method it asks about
+		{"OPTIONS", "/api/items/3", "/api/items/{id}", []string{"GET", "HEAD", "DELETE"}, true},
+		{"OPTIONS", "/api/missing", "/", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			path, methods, ok := r.Match(httptest.NewRequest(tt.method, tt.target, nil))
+			if path != tt.path || ok != tt.ok || !slices.Equal(methods, tt.methods) {
+				t.Errorf("Match = %q, %v, %v; want %q, %v, %v", path, methods, ok, tt.path, tt.methods, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+)
+
+// helperName is argv[0] of the sandbox helper, this binary re-executed by
+// runIsolated inside the new namespaces.
+const helperName = "codefuture-sandbox"
+
+// statusFD is where the helper reports how the program ended.
+const statusFD = 3
+
+// helperSpec tells the helper what to mount and run.
+type helperSpec struct {
+	Root   string   // empty host dir to build the root filesystem on
+	Mounts []mount  // in order
+	Argv   []string // run in /work
+	Env    []string
+}
+
+// mount puts the host file or dir Source at Target in the sandbox, or a
+// symlink when Link is set.
+type mount struct {
+	Source   string `json:",omitempty"`
+	Target   string
+	Link     string `json:",omitempty"`
+	Dir      bool   `json:",omitempty"`
+	Device   bool   `json:",omitempty"`
+	Writable bool   `json:",omitempty"`
+}
+
+// Init runs the sandbox helper when this process is one, and otherwise
+// returns at once. Call it first thing in main of any program that runs an
+// Executor.
+func Init() {
+	if len(os.Args) != 2 || os.Args[0] != helperName {
+		return
+	}
+	syscall.CloseOnExec(statusFD)
+	status := os.NewFile(statusFD, "status")
+
+	var spec helperSpec
+	report := ""
+	err := json.Unmarshal([]byte(os.Args[1]), &spec)
+	if err == nil {
+		err = buildRoot(spec)
+	}
+	if err == nil {
+		report, err = runProgram(spec)
+	}
+	if err != nil {
+		report = "setup " + err.Error()
+	}
+	fmt.Fprintln(status, report)
+	os.Exit(0)
+}
+
+// buildRoot replaces the root filesystem with a tmpfs holding only spec's
+// mounts and a /proc for the new PID namespace.
+func buildRoot(spec helperSpec) error {
+	// Keep the program from ptracing the helper, which still holds
+	// capabilities over the mounts
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, syscall.PR_SET_DUMPABLE, 0, 0); errno != 0 {
+		return fmt.Errorf("prctl: %v", errno)
+	}
+	if err := syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %v", err)
+	}
+	if err := syscall.Mount("tmpfs", spec.Root, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV, "size=1m,mode=0755"); err != nil {
+		return fmt.Errorf("mount root: %v", err)
+	}
+	if err := os.Chdir(spec.Root); err != nil {
+		return err
+	}
+
+	for _, m := range spec.Mounts {
+		if err := bind(m); err != nil {
+			return fmt.Errorf("mount %s: %v", m.Target, err)
+		}
+	}
+	if err := os.Mkdir("proc", 0o555); err != nil {
+		return err
+	}
+	// Best effort: some container runtimes refuse new proc mounts
+	syscall.Mount("proc", "proc", "proc", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, "")
+
+	if err := syscall.PivotRoot(".", "."); err != nil {
+		return fmt.Errorf("pivot_root: %v", err)
+	}
+	if err := syscall.Unmount(".", syscall.MNT_DETACH); err != nil {
+		return fmt.Errorf("unmount host root: %v", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return err
+	}
+	return syscall.Mount("", "/", "", syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_RDONLY|syscall.MS_NOSUID|syscall.MS_NODEV, "")
+}
+
+// Flags from statfs that a bind mount in a user namespace must keep.
+const (
+	stRdonly = 0x1
+	stNodev  = 0x4
+	stNoexec = 0x8
+)
+
+// bind mounts m relative to the new root, the current directory.
+func bind(m mount) error {
+	target := "." + m.Target
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	if m.Link != "" {
+		return os.Symlink(m.Link, target)
+	}
+	if m.Dir {
+		if err := os.Mkdir(target, 0o755); err != nil {
+			return err
+		}
+	} else if err := os.WriteFile(target, nil, 0o644); err != nil {
+		return err
+	}
+
+	if err := syscall.Mount(m.Source, target, "", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+		return err
+	}
+
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(target, &st); err != nil {
+		return err
+	}
+	flags := uintptr(syscall.MS_BIND | syscall.MS_REMOUNT | syscall.MS_NOSUID)
+	if !m.Writable || st.Flags&stRdonly != 0 {
+		flags |= syscall.MS_RDONLY
+	}
+	if !m.Device || st.Flags&stNodev != 0 {
+		flags |= syscall.MS_NODEV
+	}
+	if m.Device || st.Flags&stNoexec != 0 {
+		flags |= syscall.MS_NOEXEC
+	}
+	return syscall.Mount("", target, "", flags, "")
+}
+
+// runProgram runs the program in a user namespace of its own with no uid
+// mapped, so it runs as nobody with no capabilities over the mounts, and
+// reports how it ended.
+func runProgram(spec helperSpec) (string, error) {
+	if len(spec.Argv) == 0 {
+		return "", errors.New("no program")
+	}
+	cmd := exec.Command(spec.Argv[0], spec.Argv[1:]...)
+	cmd.Dir = "/work"
+	cmd.Env = spec.Env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER,
+		Pdeathsig:  syscall.SIGKILL,
+	}
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return "", err
+	}
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return fmt.Sprintf("signal %d", status.Signal()), nil
+	}
+	return fmt.Sprintf("exit %d", cmd.ProcessState.ExitCode()), nil
+}
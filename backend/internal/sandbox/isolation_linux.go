@@ -0,0 +1,181 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// sandboxUID is the host uid the sandbox runs as when the server itself runs
+// as root. Unprivileged servers can only map their own uid; the sandbox's
+// private root filesystem keeps the server's files out of reach either way.
+// Everything the sandbox mounts must be reachable by this uid.
+const sandboxUID = 65534 // nobody
+
+func hostID() int {
+	if os.Geteuid() == 0 {
+		return sandboxUID
+	}
+	return os.Geteuid()
+}
+
+// run runs c in fresh user, mount, PID, network, IPC and UTS namespaces. The
+// helper (this binary re-executed, see Init) is PID 1 there: it builds the
+// root filesystem and runs c as an unmapped user. Killing the helper on
+// timeout kills everything c started, however it detached itself.
+func run(ctx context.Context, c *command, allowUnisolated bool) (exitStatus, error) {
+	status, err := runIsolated(ctx, c)
+	if err == nil || !allowUnisolated || !errors.Is(err, ErrIsolationUnavailable) {
+		return status, err
+	}
+
+	slog.Warn("sandbox namespaces unavailable; running without isolation", "err", err)
+	cmd := c.hostCmd(ctx)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Pdeathsig: syscall.SIGKILL}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	if err := cmd.Start(); err != nil {
+		return exitStatus{}, err
+	}
+	return exitStatusOf(ctx, cmd.Wait())
+}
+
+func runIsolated(ctx context.Context, c *command) (exitStatus, error) {
+	spec := helperSpec{Root: c.root, Argv: c.argv}
+	for _, p := range c.readOnly {
+		info, err := os.Lstat(p)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			continue
+		case err != nil:
+			return exitStatus{}, err
+		case info.Mode()&os.ModeSymlink != 0:
+			// Merged /usr: /bin -> usr/bin is recreated as a link
+			link, err := os.Readlink(p)
+			if err != nil {
+				return exitStatus{}, err
+			}
+			spec.Mounts = append(spec.Mounts, mount{Target: p, Link: link})
+		default:
+			spec.Mounts = append(spec.Mounts, mount{Source: p, Target: p, Dir: info.IsDir()})
+		}
+	}
+	for _, dev := range []string{"/dev/null", "/dev/zero", "/dev/random", "/dev/urandom"} {
+		spec.Mounts = append(spec.Mounts, mount{Source: dev, Target: dev, Device: true})
+	}
+	spec.Mounts = append(spec.Mounts, mount{Source: c.work, Target: "/work", Dir: true, Writable: true})
+	goCache := ""
+	if c.goCache != "" {
+		goCache = "/gocache"
+		spec.Mounts = append(spec.Mounts, mount{Source: c.goCache, Target: goCache, Dir: true})
+	}
+	spec.Env = c.env("/work", goCache)
+
+	encoded, err := json.Marshal(spec)
+	if err != nil {
+		return exitStatus{}, err
+	}
+	statusR, statusW, err := os.Pipe()
+	if err != nil {
+		return exitStatus{}, err
+	}
+	defer statusR.Close()
+
+	cmd := exec.CommandContext(ctx, "/proc/self/exe", string(encoded))
+	cmd.Args[0] = helperName
+	cmd.Env = []string{}
+	cmd.Stdin = strings.NewReader(c.stdin)
+	cmd.Stdout = c.stdout
+	cmd.Stderr = c.stderr
+	cmd.ExtraFiles = []*os.File{statusW}
+	cmd.WaitDelay = time.Second
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid:   true,
+		Pdeathsig: syscall.SIGKILL,
+		Cloneflags: syscall.CLONE_NEWUSER | syscall.CLONE_NEWNS | syscall.CLONE_NEWPID |
+			syscall.CLONE_NEWNET | syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS,
+		UidMappings: []syscall.SysProcIDMap{
+			{ContainerID: 0, HostID: hostID(), Size: 1},
+		},
+		GidMappings: []syscall.SysProcIDMap{
+			{ContainerID: 0, HostID: hostID(), Size: 1},
+		},
+		GidMappingsEnableSetgroups: false,
+		// Become root of the new user namespace, which the helper needs to
+		// mount; without this it keeps the server's own credentials.
+		Credential: &syscall.Credential{Uid: 0, Gid: 0, NoSetGroups: true},
+	}
+	cmd.Cancel = func() error {
+		return cmd.Process.Kill()
+	}
+
+	err = cmd.Start()
+	statusW.Close()
+	if err != nil {
+		if namespaceUnavailable(err) {
+			return exitStatus{}, fmt.Errorf("%w: %v", ErrIsolationUnavailable, err)
+		}
+		return exitStatus{}, err
+	}
+	waitErr := cmd.Wait()
+	report, _ := io.ReadAll(statusR)
+	return parseReport(ctx, strings.TrimSpace(string(report)), waitErr)
+}
+
+// parseReport reads the line the helper reports the program's end with.
+func parseReport(ctx context.Context, report string, waitErr error) (exitStatus, error) {
+	kind, value, _ := strings.Cut(report, " ")
+	switch kind {
+	case "exit":
+		code, err := strconv.Atoi(value)
+		return exitStatus{code: code}, err
+	case "signal":
+		sig, err := strconv.Atoi(value)
+		return exitStatus{code: -1, signal: syscall.Signal(sig).String()}, err
+	case "setup":
+		return exitStatus{}, fmt.Errorf("%w: %s", ErrIsolationUnavailable, value)
+	}
+	if ctx.Err() != nil {
+		// Killed on timeout before the program ended
+		return exitStatus{code: -1}, nil
+	}
+	return exitStatus{}, fmt.Errorf("sandbox helper exited without a report: %v", waitErr)
+}
+
+func namespaceUnavailable(err error) bool {
+	return errors.Is(err, syscall.EPERM) || errors.Is(err, syscall.EINVAL) ||
+		errors.Is(err, syscall.ENOSPC) || errors.Is(err, syscall.EACCES)
+}
+
+// prepareScratchDir makes the scratch dir usable by the mapped sandbox uid.
+func prepareScratchDir(dir string) error {
+	if os.Geteuid() != 0 {
+		return nil
+	}
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, sandboxUID, sandboxUID)
+	})
+}
+
+func signalName(state *os.ProcessState) string {
+	if status, ok := state.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return status.Signal().String()
+	}
+	return ""
+}
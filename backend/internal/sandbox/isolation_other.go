@@ -0,0 +1,33 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"context"
+	"os"
+)
+
+// Init does nothing outside Linux, where there is no sandbox helper.
+func Init() {}
+
+// run has no namespace support outside Linux. Running learner code there
+// requires SANDBOX_ALLOW_UNISOLATED=true and is meant for local development
+// only: resource limits still apply, filesystem and network isolation do not.
+func run(ctx context.Context, c *command, allowUnisolated bool) (exitStatus, error) {
+	if !allowUnisolated {
+		return exitStatus{}, ErrIsolationUnavailable
+	}
+	cmd := c.hostCmd(ctx)
+	if err := cmd.Start(); err != nil {
+		return exitStatus{}, err
+	}
+	return exitStatusOf(ctx, cmd.Wait())
+}
+
+func prepareScratchDir(dir string) error {
+	return nil
+}
+
+func signalName(state *os.ProcessState) string {
+	return ""
+}
@@ -0,0 +1,527 @@
+// Package sandbox runs learner code in short-lived, resource-limited child
+// processes. Each run gets its own scratch directory, a minimal environment,
+// CPU/memory/file-size/process limits applied with ulimit, a wall-clock
+// deadline and, on Linux, fresh user, mount, PID and network namespaces: the
+// code sees a root filesystem holding only read-only toolchains and its
+// scratch directory, runs as an unmapped nobody user and has no network.
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrUnsupportedLanguage  = errors.New("unsupported language")
+	ErrIsolationUnavailable = errors.New("sandbox isolation is not available on this platform")
+	ErrBusy                 = errors.New("too many programs are running; try again shortly")
+)
+
+// Config controls the limits applied to every run.
+type Config struct {
+	WallTimeout     time.Duration // hard deadline for the whole run
+	CPUSeconds      int           // RLIMIT_CPU
+	MemoryMB        int           // RLIMIT_DATA (heap + anonymous mappings)
+	MaxFileSizeMB   int           // RLIMIT_FSIZE for files written to the scratch dir
+	MaxProcesses    int           // RLIMIT_NPROC: processes and threads per run
+	MaxOutputBytes  int           // per stream; the rest is discarded and flagged
+	CompileTimeout  time.Duration // Go only
+	AllowUnisolated bool          // run without namespaces where they are unavailable
+
+	// MaxConcurrent is how many runs may execute at once. Every run uses the
+	// same host uid, so RLIMIT_NPROC is shared between them: MaxConcurrent
+	// times MaxProcesses has to stay below what that uid may have. Runs past
+	// the limit wait up to QueueTimeout for a slot, then fail with ErrBusy.
+	MaxConcurrent int
+	QueueTimeout  time.Duration
+
+	// ReadOnlyPaths are host paths mounted read-only into the sandbox besides
+	// /usr, /bin and /lib, e.g. a toolchain installed under /opt.
+	ReadOnlyPaths []string
+	// GoCacheDir holds the Go build cache with the standard library built by
+	// the server; empty uses the user cache dir, or /var/cache as root.
+	GoCacheDir string
+
+	PythonBin string
+	NodeBin   string
+	GoBin     string
+}
+
+// Request is a single piece of code to run.
+type Request struct {
+	Language string
+	Code     string
+	Stdin    string
+}
+
+// Result is what the learner's program did.
+type Result struct {
+	Language  string        `json:"language"`
+	Stdout    string        `json:"stdout"`
+	Stderr    string        `json:"stderr"`
+	ExitCode  int           `json:"exit_code"`
+	Signal    string        `json:"signal,omitempty"`
+	TimedOut  bool          `json:"timed_out"`
+	Truncated bool          `json:"truncated"`
+	Phase     string        `json:"phase"` // "compile" or "run"
+	Duration  time.Duration `json:"-"`
+}
+
+type runtimeSpec struct {
+	fileName string
+	// compile, when set, turns fileName into ./prog before running.
+	compile func(cfg Config) []string
+	run     func(cfg Config) []string
+}
+
+var runtimes = map[string]runtimeSpec{
+	"python": {
+		fileName: "main.py",
+		// -I: isolated mode (ignore env vars and user site-packages), -B: no .pyc files,
+		// -u: unbuffered, so output before a timeout is kept (-I ignores PYTHONUNBUFFERED)
+		run: func(cfg Config) []string { return []string{cfg.PythonBin, "-I", "-B", "-u", "main.py"} },
+	},
+	"javascript": {
+		fileName: "main.js",
+		run: func(cfg Config) []string {
+			return []string{cfg.NodeBin, fmt.Sprintf("--max-old-space-size=%d", cfg.MemoryMB/2), "main.js"}
+		},
+	},
+	"go": {
+		fileName: "main.go",
+		compile:  func(cfg Config) []string { return []string{cfg.GoBin, "build", "-o", "prog", "main.go"} },
+		run:      func(cfg Config) []string { return []string{"./prog"} },
+	},
+}
+
+var languageAliases = map[string]string{
+	"py":      "python",
+	"python3": "python",
+	"js":      "javascript",
+	"node":    "javascript",
+	"golang":  "go",
+}
+
+// NormalizeLanguage maps user-facing names ("Python", "js") to a runtime key.
+func NormalizeLanguage(language string) string {
+	l := strings.ToLower(strings.TrimSpace(language))
+	if alias, ok := languageAliases[l]; ok {
+		return alias
+	}
+	return l
+}
+
+// Supported reports whether the executor can run the given language.
+func Supported(language string) bool {
+	_, ok := runtimes[NormalizeLanguage(language)]
+	return ok
+}
+
+// sandboxPath is PATH inside the sandbox; toolchains are looked up there.
+const sandboxPath = "/usr/local/bin:/usr/bin:/bin:/usr/local/go/bin"
+
+// hostReadOnly are the host paths every sandbox sees, read-only: the
+// toolchains and the libraries they load. Missing ones are skipped.
+var hostReadOnly = []string{"/usr", "/bin", "/lib", "/lib32", "/lib64", "/libx32", "/etc/alternatives", "/etc/ld.so.cache"}
+
+type Executor struct {
+	cfg      Config
+	slots    chan struct{} // one per run executing
+	readOnly []string      // host paths mounted read-only into the sandbox
+	goRoot   string
+
+	// Go builds read a cache holding the standard library, built once by
+	// the server. Sandboxed compiles get it read-only and sandboxed programs
+	// never see it, so learner code cannot poison it for others.
+	goCacheOnce sync.Once
+	goCacheDir  string
+}
+
+func NewExecutor(cfg Config) *Executor {
+	if cfg.WallTimeout <= 0 {
+		cfg.WallTimeout = 5 * time.Second
+	}
+	if cfg.CPUSeconds <= 0 {
+		cfg.CPUSeconds = 5
+	}
+	if cfg.MemoryMB <= 0 {
+		cfg.MemoryMB = 256
+	}
+	if cfg.MaxFileSizeMB <= 0 {
+		cfg.MaxFileSizeMB = 10
+	}
+	if cfg.MaxProcesses <= 0 {
+		cfg.MaxProcesses = 64
+	}
+	if cfg.MaxOutputBytes <= 0 {
+		cfg.MaxOutputBytes = 64 * 1024
+	}
+	if cfg.CompileTimeout <= 0 {
+		cfg.CompileTimeout = 30 * time.Second
+	}
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = 4
+	}
+	if cfg.QueueTimeout <= 0 {
+		cfg.QueueTimeout = 10 * time.Second
+	}
+	if cfg.PythonBin == "" {
+		cfg.PythonBin = "python3"
+	}
+	if cfg.NodeBin == "" {
+		cfg.NodeBin = "node"
+	}
+	if cfg.GoBin == "" {
+		cfg.GoBin = "go"
+	}
+
+	e := &Executor{cfg: cfg, slots: make(chan struct{}, cfg.MaxConcurrent), readOnly: append(slices.Clone(hostReadOnly), cfg.ReadOnlyPaths...)}
+	for _, bin := range []string{cfg.PythonBin, cfg.NodeBin, cfg.GoBin} {
+		path, ok := lookPath(bin)
+		if !ok {
+			continue
+		}
+		// A toolchain outside the default paths is mounted as a whole:
+		// /opt/node/bin/node brings in /opt/node
+		root := filepath.Dir(filepath.Dir(path))
+		if !slices.ContainsFunc(e.readOnly, func(p string) bool { return path == p || strings.HasPrefix(path, p+"/") }) {
+			e.readOnly = append(e.readOnly, root)
+		}
+		if bin == cfg.GoBin {
+			e.goRoot = root
+			go e.goCache()
+		}
+	}
+	return e
+}
+
+// lookPath finds bin the way the sandbox's shell will, and resolves
+// symlinks so the real install is what gets mounted.
+func lookPath(bin string) (string, bool) {
+	candidates := []string{bin}
+	if !filepath.IsAbs(bin) {
+		candidates = nil
+		for _, dir := range filepath.SplitList(sandboxPath) {
+			candidates = append(candidates, filepath.Join(dir, bin))
+		}
+	}
+	for _, c := range candidates {
+		if info, err := os.Stat(c); err == nil && !info.IsDir() && info.Mode()&0o111 != 0 {
+			if real, err := filepath.EvalSymlinks(c); err == nil {
+				return real, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Run executes req in a fresh scratch directory and returns its captured
+// output. Errors are reserved for sandbox failures; a learner program that
+// crashes or times out still yields a Result. When MaxConcurrent runs are
+// already executing it waits for one to finish, and returns ErrBusy if none
+// does within QueueTimeout or ctx is done first.
+func (e *Executor) Run(ctx context.Context, req Request) (*Result, error) {
+	language := NormalizeLanguage(req.Language)
+	spec, ok := runtimes[language]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedLanguage, req.Language)
+	}
+
+	if err := e.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer func() { <-e.slots }()
+
+	// base holds the scratch dir the code works in and an empty dir its
+	// root filesystem is built on
+	base, err := os.MkdirTemp("", "codefuture-run-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scratch dir: %v", err)
+	}
+	defer os.RemoveAll(base)
+
+	dir := filepath.Join(base, "work")
+	for _, d := range []string{dir, filepath.Join(base, "root")} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create scratch dir: %v", err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, spec.fileName), []byte(req.Code), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write source: %v", err)
+	}
+	if err := prepareScratchDir(base); err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+
+	if spec.compile != nil {
+		// Waits for the standard library build the first time, outside the
+		// compile's own deadline
+		goCache := e.goCache()
+		res, err := e.exec(ctx, language, dir, spec.compile(e.cfg), "", goCache, e.cfg.CompileTimeout, true)
+		if err != nil {
+			return nil, err
+		}
+		if res.ExitCode != 0 || res.TimedOut {
+			res.Phase = "compile"
+			res.Duration = time.Since(start)
+			return res, nil
+		}
+	}
+
+	res, err := e.exec(ctx, language, dir, spec.run(e.cfg), req.Stdin, "", e.cfg.WallTimeout, false)
+	if err != nil {
+		return nil, err
+	}
+	res.Phase = "run"
+	res.Duration = time.Since(start)
+	return res, nil
+}
+
+// acquire takes a run slot, waiting at most QueueTimeout for one.
+func (e *Executor) acquire(ctx context.Context) error {
+	select {
+	case e.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	wait := time.NewTimer(e.cfg.QueueTimeout)
+	defer wait.Stop()
+	select {
+	case e.slots <- struct{}{}:
+		return nil
+	case <-wait.C:
+		return ErrBusy
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %v", ErrBusy, ctx.Err())
+	}
+}
+
+func (e *Executor) exec(ctx context.Context, language, dir string, argv []string, stdin, goCache string, timeout time.Duration, compiling bool) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Limits are applied by the shell right before exec'ing the real program,
+	// so they are inherited by anything it spawns. Compiling gets the same
+	// CPU, memory and process limits as running.
+	fileSizeKB := e.cfg.MaxFileSizeMB * 1024
+	if compiling {
+		// The Go toolchain writes bigger files than learner programs do.
+		fileSizeKB = 512 * 1024
+	}
+	// The program only starts once every limit is in place; dash calls the
+	// process limit -p, other shells -u.
+	script := fmt.Sprintf(`ulimit -t %d && ulimit -d %d && ulimit -f %d && { ulimit -u %[4]d 2>/dev/null || ulimit -p %[4]d; } && exec "$@"; exit 126`,
+		e.cfg.CPUSeconds, e.cfg.MemoryMB*1024, fileSizeKB, e.cfg.MaxProcesses)
+
+	stdout := &limitedBuffer{limit: e.cfg.MaxOutputBytes}
+	stderr := &limitedBuffer{limit: e.cfg.MaxOutputBytes}
+
+	c := &command{
+		argv:     append([]string{"/bin/sh", "-c", script, "sandbox"}, argv...),
+		work:     dir,
+		root:     filepath.Join(filepath.Dir(dir), "root"),
+		readOnly: e.readOnly,
+		env: func(work, goCache string) []string {
+			return e.environment(work, goCache, compiling)
+		},
+		goCache: goCache,
+		stdin:   stdin,
+		stdout:  stdout,
+		stderr:  stderr,
+	}
+
+	status, err := run(ctx, c, e.cfg.AllowUnisolated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %s: %v", language, err)
+	}
+
+	res := &Result{
+		Language:  language,
+		Stdout:    stdout.String(),
+		Stderr:    stderr.String(),
+		ExitCode:  status.code,
+		Signal:    status.signal,
+		Truncated: stdout.truncated || stderr.truncated,
+		TimedOut:  errors.Is(ctx.Err(), context.DeadlineExceeded),
+	}
+	if res.TimedOut {
+		res.Stderr += fmt.Sprintf("\nExecution timed out after %s", timeout)
+	} else if res.Signal != "" {
+		res.Stderr += fmt.Sprintf("\nProcess terminated (%s): CPU, memory or process limit exceeded", res.Signal)
+	}
+	return res, nil
+}
+
+// command is one process to run in the sandbox.
+type command struct {
+	argv     []string
+	work     string   // host scratch dir, the working directory
+	root     string   // empty host dir the sandbox's root filesystem is built on
+	readOnly []string // host paths mounted read-only
+	goCache  string   // host Go build cache, mounted read-only; empty if none
+	// env builds the environment for the paths the process sees its
+	// scratch dir and Go cache at.
+	env            func(work, goCache string) []string
+	stdin          string
+	stdout, stderr io.Writer
+}
+
+// hostCmd runs c directly on the host, for when there are no namespaces.
+func (c *command) hostCmd(ctx context.Context) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, c.argv[0], c.argv[1:]...)
+	cmd.Dir = c.work
+	cmd.Env = c.env(c.work, c.goCache)
+	cmd.Stdin = strings.NewReader(c.stdin)
+	cmd.Stdout = c.stdout
+	cmd.Stderr = c.stderr
+	cmd.WaitDelay = time.Second
+	return cmd
+}
+
+// exitStatus is how a sandboxed program ended.
+type exitStatus struct {
+	code   int
+	signal string // set when it was killed by a signal
+}
+
+// exitStatusOf turns cmd.Wait's error into an exitStatus.
+func exitStatusOf(ctx context.Context, waitErr error) (exitStatus, error) {
+	var exitErr *exec.ExitError
+	switch {
+	case waitErr == nil:
+		return exitStatus{}, nil
+	case errors.As(waitErr, &exitErr):
+		return exitStatus{code: exitErr.ExitCode(), signal: signalName(exitErr.ProcessState)}, nil
+	case ctx.Err() != nil:
+		return exitStatus{code: -1}, nil
+	default:
+		return exitStatus{}, waitErr
+	}
+}
+
+// environment is deliberately minimal: nothing from the server's own
+// environment (API keys, DB paths) leaks into learner code. GOMAXPROCS keeps
+// Go's thread count, which counts against MaxProcesses, small on big hosts.
+func (e *Executor) environment(dir, goCache string, compiling bool) []string {
+	env := []string{
+		"PATH=" + sandboxPath,
+		"HOME=" + dir,
+		"TMPDIR=" + dir,
+		"LANG=C.UTF-8",
+		"PYTHONUNBUFFERED=1",
+		"PYTHONDONTWRITEBYTECODE=1",
+		"GOMAXPROCS=2",
+	}
+	if compiling {
+		if goCache == "" {
+			// No shared cache: build everything in a cache private to the run
+			goCache = filepath.Join(dir, ".gocache")
+		}
+		env = append(env,
+			"GOCACHE="+goCache,
+			"GOPATH="+filepath.Join(dir, ".gopath"),
+			"GOTOOLCHAIN=local",
+			"GOFLAGS=-p=2",
+			"CGO_ENABLED=0",
+		)
+		if e.goRoot != "" {
+			// The go command would otherwise look for it through /proc
+			env = append(env, "GOROOT="+e.goRoot)
+		}
+	}
+	return env
+}
+
+// goCache returns the host dir of the shared Go build cache, building the
+// standard library into it the first time, or "" if it is unavailable. Only
+// the server user can write it.
+func (e *Executor) goCache() string {
+	e.goCacheOnce.Do(func() {
+		base := e.cfg.GoCacheDir
+		if base == "" {
+			base = defaultGoCacheDir()
+		}
+		dir := filepath.Join(base, "go-build")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			slog.Warn("sandbox Go build cache unavailable", "err", err)
+			return
+		}
+		// The sandbox may pass through to the cache, nobody may list or
+		// change it
+		if err := os.Chmod(base, 0o711); err != nil {
+			slog.Warn("sandbox Go build cache unavailable", "err", err)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		start := time.Now()
+		cmd := exec.CommandContext(ctx, e.cfg.GoBin, "build", "std")
+		cmd.Env = []string{
+			"PATH=" + sandboxPath,
+			"HOME=" + base,
+			"GOCACHE=" + dir,
+			"GOPATH=" + filepath.Join(base, "gopath"),
+			"GOROOT=" + e.goRoot, // as sandboxed compiles set it, so cache entries match
+			"GOTOOLCHAIN=local",
+			"CGO_ENABLED=0",
+		}
+		if out, err := cmd.CombinedOutput(); err != nil {
+			slog.Warn("failed to build the Go standard library for the sandbox", "err", err, "output", string(out))
+			return
+		}
+		slog.Info("sandbox Go build cache ready", "dir", dir, "duration", time.Since(start).String())
+		e.goCacheDir = dir
+	})
+	return e.goCacheDir
+}
+
+func defaultGoCacheDir() string {
+	// A root server's sandbox runs as nobody, which cannot reach /root
+	if userCache, err := os.UserCacheDir(); err == nil && os.Geteuid() != 0 {
+		return filepath.Join(userCache, "codefuture-sandbox")
+	}
+	if os.Geteuid() == 0 {
+		return "/var/cache/codefuture-sandbox"
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("codefuture-sandbox-%d", os.Geteuid()))
+}
+
+// limitedBuffer keeps the first limit bytes and silently drops the rest,
+// so a runaway print loop cannot exhaust server memory.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	remaining := b.limit - b.buf.Len()
+	if remaining <= 0 {
+		b.truncated = len(p) > 0 || b.truncated
+		return len(p), nil
+	}
+	if len(p) > remaining {
+		b.buf.Write(p[:remaining])
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
@@ -0,0 +1,157 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestExecutor returns an executor for Python runs, skipping the test
+// where Python or the namespaces the sandbox needs are unavailable.
+func newTestExecutor(t *testing.T, cfg Config) *Executor {
+	t.Helper()
+	if _, ok := lookPath("python3"); !ok {
+		t.Skip("python3 is not installed in the sandbox's PATH")
+	}
+	cfg.GoBin = "/nonexistent/go" // skip building the Go cache
+	e := NewExecutor(cfg)
+	_, err := e.Run(context.Background(), Request{Language: "python", Code: "pass"})
+	if errors.Is(err, ErrIsolationUnavailable) {
+		t.Skipf("sandbox namespaces unavailable: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return e
+}
+
+func runPython(t *testing.T, e *Executor, code string) *Result {
+	t.Helper()
+	res, err := e.Run(context.Background(), Request{Language: "python", Code: code})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	return res
+}
+
+func TestWallTimeout(t *testing.T) {
+	e := newTestExecutor(t, Config{WallTimeout: time.Second, CPUSeconds: 30})
+	res := runPython(t, e, "import time\ntime.sleep(30)")
+	if !res.TimedOut {
+		t.Fatalf("TimedOut = false, want true (result %+v)", res)
+	}
+	if res.Duration > 10*time.Second {
+		t.Errorf("Duration = %s, want about the 1s timeout", res.Duration)
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	e := newTestExecutor(t, Config{MemoryMB: 64})
+	res := runPython(t, e, "b = bytearray(512 * 1024 * 1024)\nprint('allocated')")
+	if res.ExitCode == 0 || strings.Contains(res.Stdout, "allocated") {
+		t.Fatalf("512MB allocation under a 64MB limit succeeded: %+v", res)
+	}
+}
+
+func TestOutputLimit(t *testing.T) {
+	e := newTestExecutor(t, Config{MaxOutputBytes: 1024})
+	res := runPython(t, e, "print('x' * 100000)")
+	if !res.Truncated || len(res.Stdout) != 1024 {
+		t.Fatalf("Truncated = %v, len(Stdout) = %d; want true, 1024", res.Truncated, len(res.Stdout))
+	}
+}
+
+func TestNoNetwork(t *testing.T) {
+	e := newTestExecutor(t, Config{})
+	res := runPython(t, e, `
+import socket
+for addr in [("1.1.1.1", 53), ("127.0.0.1", 80)]:
+    try:
+        socket.create_connection(addr, timeout=2)
+        print("connected", addr)
+    except OSError as err:
+        print("failed", err)
+`)
+	if strings.Contains(res.Stdout, "connected") || strings.Count(res.Stdout, "failed") != 2 {
+		t.Fatalf("network reachable from the sandbox: %q %q", res.Stdout, res.Stderr)
+	}
+}
+
+func TestHostFilesystemHidden(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(secret, []byte("JWT_SECRET=hunter2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	e := newTestExecutor(t, Config{})
+	paths := []string{wd, filepath.Join(wd, "sandbox.go"), secret, filepath.Dir(secret), "/root", "/home", "/etc/passwd", "/tmp"}
+	code := "import os\n"
+	for _, p := range paths {
+		code += fmt.Sprintf("print(%q, os.path.exists(%[1]q))\n", p)
+	}
+	res := runPython(t, e, code)
+	if res.ExitCode != 0 {
+		t.Fatalf("exit %d: %s", res.ExitCode, res.Stderr)
+	}
+	for _, p := range paths {
+		if !strings.Contains(res.Stdout, p+" False") {
+			t.Errorf("%s is visible in the sandbox", p)
+		}
+	}
+}
+
+func TestScratchDirWritable(t *testing.T) {
+	e := newTestExecutor(t, Config{})
+	res := runPython(t, e, `
+with open("out.txt", "w") as f:
+    f.write("written")
+print(open("out.txt").read())
+`)
+	if res.ExitCode != 0 || strings.TrimSpace(res.Stdout) != "written" {
+		t.Fatalf("writing the scratch dir failed: %q %q", res.Stdout, res.Stderr)
+	}
+}
+
+func TestEnvironmentNotInherited(t *testing.T) {
+	t.Setenv("CODEFUTURE_TEST_SECRET", "hunter2")
+	e := newTestExecutor(t, Config{})
+	res := runPython(t, e, "import os\nfor k, v in os.environ.items():\n    print(k + '=' + v)")
+	if res.ExitCode != 0 {
+		t.Fatalf("exit %d: %s", res.ExitCode, res.Stderr)
+	}
+
+	child := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(res.Stdout), "\n") {
+		child[line] = true
+	}
+	if child["CODEFUTURE_TEST_SECRET=hunter2"] {
+		t.Error("CODEFUTURE_TEST_SECRET leaked into the sandbox")
+	}
+	// The shell sets PWD itself, to the scratch dir, and the sandbox sets
+	// its own PATH, HOME and so on; anything else equal to a server variable
+	// was inherited
+	own := map[string]bool{}
+	for _, kv := range e.environment("/work", "", false) {
+		own[kv] = true
+	}
+	for _, kv := range os.Environ() {
+		if child[kv] && !own[kv] {
+			name, _, _ := strings.Cut(kv, "=")
+			t.Errorf("server env var %s leaked into the sandbox", name)
+		}
+	}
+	if !child["PATH="+sandboxPath] {
+		t.Errorf("sandbox env lacks its PATH: %q", res.Stdout)
+	}
+}
@@ -0,0 +1,31 @@
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestMain lets the test binary act as the sandbox helper it re-executes.
+func TestMain(m *testing.M) {
+	Init()
+	os.Exit(m.Run())
+}
+
+func TestRunBusy(t *testing.T) {
+	e := NewExecutor(Config{MaxConcurrent: 1, QueueTimeout: 50 * time.Millisecond, GoBin: "/nonexistent/go"})
+	e.slots <- struct{}{} // the only slot is taken
+
+	if _, err := e.Run(context.Background(), Request{Language: "python", Code: "print(1)"}); !errors.Is(err, ErrBusy) {
+		t.Fatalf("Run with every slot taken = %v, want ErrBusy", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	e.cfg.QueueTimeout = time.Hour
+	if _, err := e.Run(ctx, Request{Language: "python", Code: "print(1)"}); !errors.Is(err, ErrBusy) {
+		t.Fatalf("Run with a cancelled context = %v, want ErrBusy", err)
+	}
+}
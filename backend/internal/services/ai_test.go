@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// scripted returns a fake provider that answers with replies in order,
+// repeating the last one once they run out.
+func scripted(replies ...string) *FakeProvider {
+	p := NewFakeProvider()
+	p.Respond = func(req CompletionRequest) (string, error) {
+		n := len(p.Calls()) - 1
+		if n >= len(replies) {
+			n = len(replies) - 1
+		}
+		return replies[n], nil
+	}
+	return p
+}
+
+func newTestService(t *testing.T, p LLMProvider) *AIService {
+	t.Helper()
+	s, err := NewAIService(p)
+	if err != nil {
+		t.Fatalf("NewAIService: %v", err)
+	}
+	return s
+}
+
+func TestCannedResponsesPassValidation(t *testing.T) {
+	s := newTestService(t, NewFakeProvider())
+	ctx := context.Background()
+
+	c, err := s.GenerateLessonPlan(ctx, "beginner", "learn python")
+	if err != nil {
+		t.Fatalf("GenerateLessonPlan: %v", err)
+	}
+	if c.Language != "python" || len(c.Lessons) != 3 {
+		t.Errorf("curriculum = %q with %d lessons, want python with 3", c.Language, len(c.Lessons))
+	}
+
+	r, err := s.GenerateFullRoadmap(ctx, "Backend Developer", "junior", "get hired", "")
+	if err != nil {
+		t.Fatalf("GenerateFullRoadmap: %v", err)
+	}
+	if len(r.Sections) != 1 {
+		t.Errorf("roadmap has %d sections, want 1", len(r.Sections))
+	}
+
+	cl, err := s.ClassifyContent(ctx, "post", "Hello", "How do loops work?")
+	if err != nil {
+		t.Fatalf("ClassifyContent: %v", err)
+	}
+	if cl.Reasons == nil {
+		t.Error("ClassifyContent left Reasons nil")
+	}
+}
+
+func TestGenerateJSONRepairsReply(t *testing.T) {
+	p := scripted(
+		"Sure! Here is the classification.",
+		`{"labels": {"toxicity": 2, "spam": 0, "off_topic": 0, "answer_leak": 0}}`,
+		"```json\n"+`{"labels": {"toxicity": 0.1, "spam": 0, "off_topic": 0, "answer_leak": 0}}`+"\n```",
+	)
+	s := newTestService(t, p)
+
+	cl, err := s.ClassifyContent(context.Background(), "comment", "", "Nice post")
+	if err != nil {
+		t.Fatalf("ClassifyContent: %v", err)
+	}
+	if cl.Labels["toxicity"] != 0.1 {
+		t.Errorf("toxicity = %v, want 0.1 from the repaired reply", cl.Labels["toxicity"])
+	}
+
+	calls := p.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("provider called %d times, want 3", len(calls))
+	}
+	// Each retry sends the rejected reply back with what was wrong with it
+	last := calls[2].Messages
+	if len(last) != 5 {
+		t.Fatalf("third request has %d messages, want 5", len(last))
+	}
+	if last[3].Role != "assistant" || !strings.Contains(last[3].Content, `"toxicity": 2`) {
+		t.Errorf("message 3 = %+v, want the rejected reply", last[3])
+	}
+	if !strings.Contains(last[4].Content, "labels.toxicity must be between 0 and 1") {
+		t.Errorf("message 4 = %q, want the validation error", last[4].Content)
+	}
+}
+
+func TestGenerateJSONGivesUp(t *testing.T) {
+	p := scripted(`{"title": "No lessons", "language": "python", "lessons": []}`)
+	s := newTestService(t, p)
+
+	_, err := s.GenerateLessonPlan(context.Background(), "beginner", "anything")
+	if !errors.Is(err, ErrInvalidAIOutput) {
+		t.Fatalf("err = %v, want ErrInvalidAIOutput", err)
+	}
+	if n := len(p.Calls()); n != maxRepairAttempts+1 {
+		t.Errorf("provider called %d times, want %d", n, maxRepairAttempts+1)
+	}
+}
+
+func TestGenerateJSONProviderError(t *testing.T) {
+	p := NewFakeProvider()
+	boom := errors.New("provider down")
+	p.Respond = func(CompletionRequest) (string, error) { return "", boom }
+	s := newTestService(t, p)
+
+	if _, err := s.GenerateFullRoadmap(context.Background(), "role", "exp", "goal", ""); !errors.Is(err, boom) {
+		t.Errorf("err = %v, want the provider error", err)
+	}
+	if n := len(p.Calls()); n != 1 {
+		t.Errorf("provider called %d times, want 1: provider errors are not retried", n)
+	}
+}
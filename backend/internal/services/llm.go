@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"codefuture-backend/internal/config"
+)
+
+// Message is a single chat turn sent to an LLM provider.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// CompletionRequest is the provider-agnostic input for a chat completion.
+// An empty Model means "use the provider's configured default".
+type CompletionRequest struct {
+	Model    string
+	Messages []Message
+}
+
+// Usage reports token accounting for a completion, when the provider returns it.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// CompletionResponse is the provider-agnostic result of a chat completion.
+type CompletionResponse struct {
+	Content string
+	Model   string
+	Usage   Usage
+}
+
+// LLMProvider is implemented by every backend AIService can talk to.
+type LLMProvider interface {
+	// Name identifies the provider in logs (e.g. "openrouter", "ollama").
+	Name() string
+	Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error)
+}
+
+// StreamingProvider is implemented by providers that can emit a completion
+// incrementally. onDelta is called for every text fragment in order; returning
+// an error from it aborts the stream. The returned response carries the full
+// text and, when the provider reports it, token usage.
+type StreamingProvider interface {
+	LLMProvider
+	Stream(ctx context.Context, req CompletionRequest, onDelta func(delta string) error) (*CompletionResponse, error)
+}
+
+// closer is implemented by providers holding resources, such as pooled
+// HTTP connections, to release when the service shuts down.
+type closer interface {
+	Close()
+}
+
+// pinger is implemented by providers that can check they are reachable
+// without paying for a completion.
+type pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// ping sends req, a cheap GET such as a model listing, and fails on an
+// error status.
+func ping(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%s returned %s", req.URL.Path, resp.Status)
+	}
+	return nil
+}
+
+// NewLLMProvider builds the provider selected by cfg.LLMProvider.
+// When cfg.LLMRecordPath is set the provider is wrapped so every exchange is
+// appended to that file, which the fake provider can later replay offline.
+func NewLLMProvider(cfg *config.Config) (LLMProvider, error) {
+	var provider LLMProvider
+
+	switch strings.ToLower(cfg.LLMProvider) {
+	case "", "openrouter":
+		if cfg.LLMAPIKey == "" {
+			return nil, fmt.Errorf("LLM_API_KEY (or GEMINI_API_KEY) is required for the openrouter provider")
+		}
+		provider = NewOpenRouterProvider(cfg.LLMAPIKey, cfg.LLMModel, cfg.LLMBaseURL, cfg.FrontendURL)
+	case "openai":
+		if cfg.LLMAPIKey == "" {
+			return nil, fmt.Errorf("LLM_API_KEY is required for the openai provider")
+		}
+		provider = NewOpenAIProvider(cfg.LLMAPIKey, cfg.LLMModel, cfg.LLMBaseURL)
+	case "ollama":
+		provider = NewOllamaProvider(cfg.LLMModel, cfg.LLMBaseURL)
+	case "fake":
+		if cfg.LLMReplayPath != "" {
+			fake, err := NewFakeProviderFromFile(cfg.LLMReplayPath)
+			if err != nil {
+				return nil, err
+			}
+			provider = fake
+		} else {
+			provider = NewFakeProvider()
+		}
+	default:
+		return nil, fmt.Errorf("unknown LLM provider %q", cfg.LLMProvider)
+	}
+
+	if cfg.LLMRecordPath != "" {
+		return NewRecordingProvider(provider, cfg.LLMRecordPath), nil
+	}
+	return provider, nil
+}
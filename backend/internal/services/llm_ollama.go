@@ -0,0 +1,188 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultOllamaBaseURL = "http://localhost:11434"
+	defaultOllamaModel   = "llama3.1"
+)
+
+// OllamaProvider talks to a local Ollama-style server via /api/chat.
+// No API key is needed, which makes it handy for fully offline development.
+type OllamaProvider struct {
+	model   string
+	baseURL string
+	client  *http.Client
+}
+
+func NewOllamaProvider(model, baseURL string) *OllamaProvider {
+	if model == "" {
+		model = defaultOllamaModel
+	}
+	if baseURL == "" {
+		baseURL = defaultOllamaBaseURL
+	}
+	return &OllamaProvider{
+		model:   model,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  &http.Client{},
+	}
+}
+
+func (p *OllamaProvider) Name() string {
+	return "ollama"
+}
+
+// Close drops the client's idle keep-alive connections.
+func (p *OllamaProvider) Close() {
+	p.client.CloseIdleConnections()
+}
+
+// Ping lists the locally installed models.
+func (p *OllamaProvider) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/api/tags", nil)
+	if err != nil {
+		return err
+	}
+	return ping(p.client, req)
+}
+
+// Ollama wire format
+type ollamaRequest struct {
+	Model    string    `json:"model"`
+	Messages []Message `json:"messages"`
+	Stream   bool      `json:"stream"`
+}
+
+type ollamaResponse struct {
+	Model           string  `json:"model"`
+	Message         Message `json:"message"`
+	Done            bool    `json:"done"`
+	PromptEvalCount int     `json:"prompt_eval_count"`
+	EvalCount       int     `json:"eval_count"`
+	Error           string  `json:"error,omitempty"`
+}
+
+func (p *OllamaProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = p.model
+	}
+
+	jsonData, err := json.Marshal(ollamaRequest{Model: model, Messages: req.Messages, Stream: false})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/api/chat", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	var apiResp ollamaResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response (status %d): %v", resp.StatusCode, err)
+	}
+	if apiResp.Error != "" {
+		return nil, fmt.Errorf("ollama API error: %s", apiResp.Error)
+	}
+
+	return &CompletionResponse{
+		Content: apiResp.Message.Content,
+		Model:   apiResp.Model,
+		Usage: Usage{
+			PromptTokens:     apiResp.PromptEvalCount,
+			CompletionTokens: apiResp.EvalCount,
+			TotalTokens:      apiResp.PromptEvalCount + apiResp.EvalCount,
+		},
+	}, nil
+}
+
+// Stream uses Ollama's newline-delimited JSON streaming; the final object
+// (done=true) carries the token counts.
+func (p *OllamaProvider) Stream(ctx context.Context, req CompletionRequest, onDelta func(delta string) error) (*CompletionResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = p.model
+	}
+
+	jsonData, err := json.Marshal(ollamaRequest{Model: model, Messages: req.Messages, Stream: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/api/chat", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	out := &CompletionResponse{Model: model}
+	var content strings.Builder
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var chunk ollamaResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return nil, fmt.Errorf("failed to parse stream chunk: %v", err)
+		}
+		if chunk.Error != "" {
+			return nil, fmt.Errorf("ollama API error: %s", chunk.Error)
+		}
+
+		if chunk.Message.Content != "" {
+			content.WriteString(chunk.Message.Content)
+			if err := onDelta(chunk.Message.Content); err != nil {
+				return nil, err
+			}
+		}
+
+		if chunk.Done {
+			out.Usage = Usage{
+				PromptTokens:     chunk.PromptEvalCount,
+				CompletionTokens: chunk.EvalCount,
+				TotalTokens:      chunk.PromptEvalCount + chunk.EvalCount,
+			}
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %v", err)
+	}
+
+	out.Content = content.String()
+	return out, nil
+}
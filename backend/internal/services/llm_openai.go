@@ -0,0 +1,256 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+	defaultOpenAIModel   = "gpt-4o-mini"
+)
+
+// OpenAIProvider talks to any server implementing the OpenAI
+// /chat/completions API (OpenAI itself, OpenRouter, vLLM, LM Studio, ...).
+type OpenAIProvider struct {
+	name    string
+	apiKey  string
+	model   string
+	baseURL string
+	headers map[string]string
+	client  *http.Client
+}
+
+func NewOpenAIProvider(apiKey, model, baseURL string) *OpenAIProvider {
+	if model == "" {
+		model = defaultOpenAIModel
+	}
+	if baseURL == "" {
+		baseURL = defaultOpenAIBaseURL
+	}
+	return &OpenAIProvider{
+		name:    "openai",
+		apiKey:  apiKey,
+		model:   model,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		headers: map[string]string{},
+		client:  &http.Client{},
+	}
+}
+
+func (p *OpenAIProvider) Name() string {
+	return p.name
+}
+
+// Close drops the client's idle keep-alive connections.
+func (p *OpenAIProvider) Close() {
+	p.client.CloseIdleConnections()
+}
+
+// Ping lists the available models.
+func (p *OpenAIProvider) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+	return ping(p.client, req)
+}
+
+// OpenAI wire format
+type openAIRequest struct {
+	Model         string               `json:"model"`
+	Messages      []Message            `json:"messages"`
+	Stream        bool                 `json:"stream,omitempty"`
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+}
+
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openAIStreamChunk is one "data:" event of a streamed completion.
+type openAIStreamChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage,omitempty"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+type openAIChoice struct {
+	Message Message `json:"message"`
+}
+
+type openAIResponse struct {
+	Model   string         `json:"model"`
+	Choices []openAIChoice `json:"choices"`
+	Usage   *Usage         `json:"usage,omitempty"`
+	Error   *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+func (p *OpenAIProvider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = p.model
+	}
+
+	jsonData, err := json.Marshal(openAIRequest{Model: model, Messages: req.Messages})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	httpReq, err := p.newRequest(ctx, jsonData)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	var apiResp openAIResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("failed to parse response (status %d): %v", resp.StatusCode, err)
+	}
+
+	if apiResp.Error != nil {
+		return nil, fmt.Errorf("%s API error: %s", p.name, apiResp.Error.Message)
+	}
+
+	if len(apiResp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from AI")
+	}
+
+	out := &CompletionResponse{
+		Content: apiResp.Choices[0].Message.Content,
+		Model:   apiResp.Model,
+	}
+	if apiResp.Usage != nil {
+		out.Usage = *apiResp.Usage
+	}
+	return out, nil
+}
+
+// Stream requests a server-sent-events completion and forwards each content
+// delta as it arrives. Cancelling ctx closes the upstream connection.
+func (p *OpenAIProvider) Stream(ctx context.Context, req CompletionRequest, onDelta func(delta string) error) (*CompletionResponse, error) {
+	model := req.Model
+	if model == "" {
+		model = p.model
+	}
+
+	jsonData, err := json.Marshal(openAIRequest{
+		Model:         model,
+		Messages:      req.Messages,
+		Stream:        true,
+		StreamOptions: &openAIStreamOptions{IncludeUsage: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	httpReq, err := p.newRequest(ctx, jsonData)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("API request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		var apiResp openAIResponse
+		if json.Unmarshal(body, &apiResp) == nil && apiResp.Error != nil {
+			return nil, fmt.Errorf("%s API error: %s", p.name, apiResp.Error.Message)
+		}
+		return nil, fmt.Errorf("%s API error: status %d", p.name, resp.StatusCode)
+	}
+
+	out := &CompletionResponse{Model: model}
+	var content strings.Builder
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		// Blank lines separate events; lines starting with ':' are keep-alive comments.
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == "[DONE]" {
+			break
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return nil, fmt.Errorf("failed to parse stream chunk: %v", err)
+		}
+		if chunk.Error != nil {
+			return nil, fmt.Errorf("%s API error: %s", p.name, chunk.Error.Message)
+		}
+		if chunk.Model != "" {
+			out.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			out.Usage = *chunk.Usage
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta.Content
+		content.WriteString(delta)
+		if err := onDelta(delta); err != nil {
+			return nil, err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stream: %v", err)
+	}
+
+	out.Content = content.String()
+	return out, nil
+}
+
+func (p *OpenAIProvider) newRequest(ctx context.Context, body []byte) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
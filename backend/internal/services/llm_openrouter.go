@@ -0,0 +1,26 @@
+package services
+
+const (
+	defaultOpenRouterBaseURL = "https://openrouter.ai/api/v1"
+	defaultOpenRouterModel   = "google/gemini-flash-1.5" // Free and available
+)
+
+// NewOpenRouterProvider returns an OpenAI-compatible provider preconfigured
+// for OpenRouter, including the attribution headers OpenRouter asks for.
+func NewOpenRouterProvider(apiKey, model, baseURL, referer string) *OpenAIProvider {
+	if model == "" {
+		model = defaultOpenRouterModel
+	}
+	if baseURL == "" {
+		baseURL = defaultOpenRouterBaseURL
+	}
+	if referer == "" {
+		referer = "http://localhost:5173"
+	}
+
+	p := NewOpenAIProvider(apiKey, model, baseURL)
+	p.name = "openrouter"
+	p.headers["HTTP-Referer"] = referer
+	p.headers["X-Title"] = "Coding For Everyone"
+	return p
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"codefuture-backend/internal/config"
+)
+
+func TestNewLLMProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.Config
+		wantName string
+		wantErr  string
+	}{
+		{"default is openrouter", config.Config{LLMAPIKey: "key"}, "openrouter", ""},
+		{"openrouter", config.Config{LLMProvider: "OpenRouter", LLMAPIKey: "key"}, "openrouter", ""},
+		{"openrouter needs a key", config.Config{LLMProvider: "openrouter"}, "", "LLM_API_KEY"},
+		{"openai", config.Config{LLMProvider: "openai", LLMAPIKey: "key"}, "openai", ""},
+		{"openai needs a key", config.Config{LLMProvider: "openai"}, "", "LLM_API_KEY"},
+		{"ollama", config.Config{LLMProvider: "ollama"}, "ollama", ""},
+		{"fake", config.Config{LLMProvider: "fake"}, "fake", ""},
+		{"missing replay file", config.Config{LLMProvider: "fake", LLMReplayPath: filepath.Join(t.TempDir(), "none.jsonl")}, "", "failed to open LLM recording"},
+		{"unknown", config.Config{LLMProvider: "gpt"}, "", `unknown LLM provider "gpt"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewLLMProvider(&tt.cfg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewLLMProvider: %v", err)
+			}
+			if got := p.Name(); got != tt.wantName {
+				t.Errorf("Name() = %q, want %q", got, tt.wantName)
+			}
+		})
+	}
+}
+
+func TestNewLLMProviderRecording(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "record.jsonl")
+	p, err := NewLLMProvider(&config.Config{LLMProvider: "fake", LLMRecordPath: path})
+	if err != nil {
+		t.Fatalf("NewLLMProvider: %v", err)
+	}
+	if _, ok := p.(*RecordingProvider); !ok {
+		t.Fatalf("provider is %T, want *RecordingProvider", p)
+	}
+	if p.Name() != "fake" {
+		t.Errorf("Name() = %q, want the wrapped provider's name", p.Name())
+	}
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "record.jsonl")
+	inner := NewFakeProvider()
+	inner.Respond = func(req CompletionRequest) (string, error) {
+		return "recorded: " + req.Messages[len(req.Messages)-1].Content, nil
+	}
+	rec := NewRecordingProvider(inner, path)
+
+	ctx := context.Background()
+	first := CompletionRequest{Messages: []Message{{Role: "user", Content: "first"}}}
+	second := CompletionRequest{Messages: []Message{{Role: "system", Content: "tutor"}, {Role: "user", Content: "second"}}}
+	want := map[string]*CompletionResponse{}
+	for _, req := range []CompletionRequest{first, second} {
+		resp, err := rec.Complete(ctx, req)
+		if err != nil {
+			t.Fatalf("Complete: %v", err)
+		}
+		want[req.Messages[len(req.Messages)-1].Content] = resp
+	}
+	var streamed strings.Builder
+	if _, err := rec.Stream(ctx, CompletionRequest{Messages: []Message{{Role: "user", Content: "streamed"}}}, func(d string) error {
+		streamed.WriteString(d)
+		return nil
+	}); err != nil {
+		t.Fatalf("Stream: %v", err)
+	}
+	if streamed.String() != "recorded: streamed" {
+		t.Errorf("streamed %q, want %q", streamed.String(), "recorded: streamed")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read recording: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != 3 {
+		t.Errorf("recording has %d lines, want 3", lines)
+	}
+
+	replay, err := NewFakeProviderFromFile(path)
+	if err != nil {
+		t.Fatalf("NewFakeProviderFromFile: %v", err)
+	}
+	// The model is not part of the key, so a request for another model still matches
+	for _, req := range []CompletionRequest{first, second} {
+		req.Model = "another-model"
+		got, err := replay.Complete(ctx, req)
+		if err != nil {
+			t.Fatalf("replay Complete: %v", err)
+		}
+		w := want[req.Messages[len(req.Messages)-1].Content]
+		if got.Content != w.Content || got.Model != w.Model || got.Usage != w.Usage {
+			t.Errorf("replayed %+v, want %+v", got, w)
+		}
+	}
+
+	// Requests that were not recorded fall back to the canned responses
+	got, err := replay.Complete(ctx, CompletionRequest{Messages: []Message{{Role: "user", Content: "unrecorded"}}})
+	if err != nil {
+		t.Fatalf("replay Complete: %v", err)
+	}
+	if !strings.HasPrefix(got.Content, "This is a fake tutor response") {
+		t.Errorf("unrecorded request got %q, want the canned tutor response", got.Content)
+	}
+}
+
+func TestNewFakeProviderFromFileRejectsBadLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.jsonl")
+	if err := os.WriteFile(path, []byte("{not json}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFakeProviderFromFile(path); err == nil || !strings.Contains(err.Error(), "failed to parse") {
+		t.Errorf("err = %v, want a parse error", err)
+	}
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CreateAccountToken stores the hash of a single-use token mailed to the user.
+func (s *Store) CreateAccountToken(userID int, purpose, tokenHash string, createdAt, expiresAt time.Time) error {
+	if s.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	_, err := s.conn().Exec(`
+		INSERT INTO account_tokens (user_id, purpose, token_hash, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		userID, purpose, tokenHash, createdAt.UTC(), expiresAt.UTC())
+	return err
+}
+
+// ConsumeAccountToken marks the token used and returns its user ID. It
+// returns 0 when the token is unknown, meant for another purpose, expired or
+// already used, so a token can only ever be redeemed once.
+func (s *Store) ConsumeAccountToken(purpose, tokenHash string, now time.Time) (int, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("database not connected")
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	var id, userID int
+	var expiresAt time.Time
+	var usedAt sql.NullTime
+	err = c.QueryRow("SELECT id, user_id, expires_at, used_at FROM account_tokens WHERE token_hash = ? AND purpose = ?", tokenHash, purpose).
+		Scan(&id, &userID, &expiresAt, &usedAt)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if usedAt.Valid || !now.Before(expiresAt) {
+		return 0, nil
+	}
+
+	res, err := c.Exec("UPDATE account_tokens SET used_at = ? WHERE id = ? AND used_at IS NULL", now.UTC(), id)
+	if err != nil {
+		return 0, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		// Redeemed concurrently
+		return 0, err
+	}
+	return userID, tx.Commit()
+}
+
+// InvalidateAccountTokens retires the user's outstanding tokens for purpose,
+// e.g. older reset links once a new one is sent or the password has changed.
+func (s *Store) InvalidateAccountTokens(userID int, purpose string, at time.Time) error {
+	if s.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	_, err := s.conn().Exec("UPDATE account_tokens SET used_at = ? WHERE user_id = ? AND purpose = ? AND used_at IS NULL", at.UTC(), userID, purpose)
+	return err
+}
+
+// LatestAccountTokenAt returns when the user was last sent a token for
+// purpose, or the zero time if they never were.
+func (s *Store) LatestAccountTokenAt(userID int, purpose string) (time.Time, error) {
+	if s.db == nil {
+		return time.Time{}, fmt.Errorf("database not connected")
+	}
+	var createdAt time.Time
+	err := s.conn().QueryRow("SELECT created_at FROM account_tokens WHERE user_id = ? AND purpose = ? ORDER BY created_at DESC LIMIT 1", userID, purpose).
+		Scan(&createdAt)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return createdAt, err
+}
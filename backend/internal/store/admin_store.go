@@ -0,0 +1,261 @@
+package store
+
+import (
+	"codefuture-backend/internal/models"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// likeContains returns a LIKE pattern matching text anywhere, with LIKE's
+// wildcards in text escaped. Use it with ESCAPE '\'.
+func likeContains(text string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return "%" + r.Replace(strings.ToLower(text)) + "%"
+}
+
+// page runs a count over from/where and then the select, so every admin
+// listing reports its total alongside one page of rows.
+func (s *Store) page(columns, from string, where []string, args []interface{}, order string, limit, offset int) (*sql.Rows, int, error) {
+	if len(where) > 0 {
+		from += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	var total int
+	if err := s.conn().QueryRow(`SELECT COUNT(*) FROM `+from, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + columns + ` FROM ` + from + ` ORDER BY ` + order
+	if limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, limit, offset)
+	}
+	rows, err := s.conn().Query(query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return rows, total, nil
+}
+
+// ListUsers returns one page of users matching q and how many match in total.
+func (s *Store) ListUsers(q models.UserQuery) (*models.UserPage, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	var where []string
+	var args []interface{}
+	if q.Text != "" {
+		where = append(where, `(LOWER(name) LIKE ? ESCAPE '\' OR LOWER(email) LIKE ? ESCAPE '\')`)
+		args = append(args, likeContains(q.Text), likeContains(q.Text))
+	}
+	if q.Role != "" {
+		where = append(where, "role = ?")
+		args = append(args, q.Role)
+	}
+
+	rows, total, err := s.page(userColumns, "users", where, args, "id", q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &models.UserPage{Users: []models.User{}, Total: total}
+	for rows.Next() {
+		u, err := s.scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Users = append(page.Users, *u)
+	}
+	return page, rows.Err()
+}
+
+// ChangeUserRole gives the user a new role and records who did it. It
+// reports false if there is no such user.
+func (s *Store) ChangeUserRole(userID, actorID int, role, reason string, at time.Time) (bool, error) {
+	if s.db == nil {
+		return false, fmt.Errorf("database not connected")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	var previous string
+	err = c.QueryRow("SELECT role FROM users WHERE id = ?", userID).Scan(&previous)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if _, err := c.Exec("UPDATE users SET role = ? WHERE id = ?", role, userID); err != nil {
+		return false, err
+	}
+
+	note := previous + " -> " + role
+	if reason != "" {
+		note += ": " + reason
+	}
+	entry := &models.AuditEntry{ActorID: actorID, Action: models.AuditActionChangeRole, TargetType: models.AuditTargetUser,
+		TargetID: userID, Reason: note, CreatedAt: at}
+	if err := writeAudit(c, entry); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// ListLessonPlans returns one page of lesson plan summaries matching q,
+// newest first, and how many match in total.
+func (s *Store) ListLessonPlans(q models.LessonPlanQuery) (*models.LessonPlanPage, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	var where []string
+	var args []interface{}
+	if q.UserID != 0 {
+		where = append(where, "lp.user_id = ?")
+		args = append(args, q.UserID)
+	}
+	if q.Kind != "" {
+		where = append(where, "lp.kind = ?")
+		args = append(args, q.Kind)
+	}
+
+	rows, total, err := s.page(`lp.id, lp.user_id, COALESCE(u.email, ''), lp.kind, lp.persona, lp.goals, lp.created_at`,
+		"lesson_plans lp LEFT JOIN users u ON u.id = lp.user_id", where, args, "lp.id DESC", q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &models.LessonPlanPage{Plans: []models.LessonPlanSummary{}, Total: total}
+	for rows.Next() {
+		var p models.LessonPlanSummary
+		if err := rows.Scan(&p.ID, &p.UserID, &p.OwnerEmail, &p.Kind, &p.Persona, &p.Goals, &p.CreatedAt); err != nil {
+			return nil, err
+		}
+		page.Plans = append(page.Plans, p)
+	}
+	return page, rows.Err()
+}
+
+// DeleteLessonPlan deletes any user's lesson plan, with its lessons and
+// attempts, and records who did it. It reports false if there is no such plan.
+func (s *Store) DeleteLessonPlan(planID, actorID int, reason string, at time.Time) (bool, error) {
+	if s.db == nil {
+		return false, fmt.Errorf("database not connected")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	res, err := c.Exec("DELETE FROM lesson_plans WHERE id = ?", planID)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if err := s.deletePlanContents(c, planID); err != nil {
+		return false, err
+	}
+
+	entry := &models.AuditEntry{ActorID: actorID, Action: models.AuditActionDelete, TargetType: models.AuditTargetLessonPlan,
+		TargetID: planID, Reason: reason, CreatedAt: at}
+	if err := writeAudit(c, entry); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// ListAllPosts returns one page of posts in any status matching q, newest
+// first, and how many match in total.
+func (s *Store) ListAllPosts(q models.AdminPostQuery) (*models.AdminPostPage, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	var where []string
+	var args []interface{}
+	if q.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, q.Status)
+	}
+	if q.AuthorID != 0 {
+		where = append(where, "user_id = ?")
+		args = append(args, q.AuthorID)
+	}
+
+	rows, total, err := s.page(postColumns, "posts", where, args, "id DESC", q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &models.AdminPostPage{Posts: []models.Post{}, Total: total}
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Posts = append(page.Posts, *p)
+	}
+	return page, rows.Err()
+}
+
+// ListContactSubmissions returns one page of contact submissions matching q,
+// newest first, and how many match in total.
+func (s *Store) ListContactSubmissions(q models.ContactQuery) (*models.ContactPage, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	var where []string
+	var args []interface{}
+	if q.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, q.Status)
+	}
+	switch {
+	case q.AssigneeID > 0:
+		where = append(where, "assignee_id = ?")
+		args = append(args, q.AssigneeID)
+	case q.AssigneeID < 0:
+		where = append(where, "assignee_id IS NULL")
+	}
+	if q.Text != "" {
+		where = append(where, `(LOWER(COALESCE(first_name, '') || ' ' || COALESCE(last_name, '')) LIKE ? ESCAPE '\' OR LOWER(email) LIKE ? ESCAPE '\' OR LOWER(message) LIKE ? ESCAPE '\')`)
+		pattern := likeContains(q.Text)
+		args = append(args, pattern, pattern, pattern)
+	}
+
+	rows, total, err := s.page(contactColumns, "contact_submissions", where, args, "id DESC", q.Limit, q.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &models.ContactPage{Submissions: []models.ContactSubmission{}, Total: total}
+	for rows.Next() {
+		sub, err := scanContactSubmission(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Submissions = append(page.Submissions, *sub)
+	}
+	return page, rows.Err()
+}
+
+// RecordAudit appends an entry to the audit log, for admin actions that
+// don't change anything else, like reading personal data.
+func (s *Store) RecordAudit(e *models.AuditEntry) error {
+	if s.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	return writeAudit(s.conn(), e)
+}
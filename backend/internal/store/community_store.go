@@ -2,49 +2,505 @@ package store
 
 import (
 	"codefuture-backend/internal/models"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
 )
 
+// ErrInvalidCursor is returned for a listing cursor that is malformed or was
+// issued for a different sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+const (
+	reactionCountExpr = `(SELECT COUNT(*) FROM post_reactions r WHERE r.post_id = posts.id)`
+	commentCountExpr  = `(SELECT COUNT(*) FROM comments c WHERE c.post_id = posts.id AND c.deleted_at IS NULL AND c.status = 'visible')`
+)
+
+// postColumns selects a post with its total reaction count and live (not
+// deleted or pending) comment count.
+const postColumns = `id, user_id, author_name, title, content, topic, ` + reactionCountExpr + `, created_at, ` + commentCountExpr + `, trending_score, status, locked_at`
+
+// postSortKeys maps each sort order to the expression it ranks by, highest
+// first. Ties are broken by id so every post has a unique position.
+var postSortKeys = map[string]string{
+	models.PostSortNewest:    "created_at",
+	models.PostSortTop:       reactionCountExpr,
+	models.PostSortTrending:  "trending_score",
+	models.PostSortDiscussed: commentCountExpr,
+}
+
+// trendingGravity is how many seconds newer a post must be to outrank one
+// with ten times its reactions: 12.5 hours, as in Reddit's "hot" ranking.
+const trendingGravity = 45000
+
+// trendingScore ranks a post by its reactions decayed by age. It depends on
+// when the post was created rather than on the current time, so a post only
+// moves when its reactions change and cursors stay valid between pages.
+func trendingScore(reactions int, createdAt time.Time) float64 {
+	return math.Log10(math.Max(float64(reactions), 1)) + float64(createdAt.Unix())/trendingGravity
+}
+
+// postCursor marks the last post of a page: its sort value and id.
+type postCursor struct {
+	Sort  string    `json:"s"`
+	Time  time.Time `json:"t,omitzero"`
+	Score float64   `json:"v,omitzero"`
+	ID    int       `json:"id"`
+}
+
+func encodePostCursor(sort string, p *models.Post) string {
+	c := postCursor{Sort: sort, ID: p.ID}
+	switch sort {
+	case models.PostSortNewest:
+		c.Time = p.CreatedAt
+	case models.PostSortTop:
+		c.Score = float64(p.Likes)
+	case models.PostSortTrending:
+		c.Score = p.TrendingScore
+	case models.PostSortDiscussed:
+		c.Score = float64(p.CommentCount)
+	}
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodePostCursor returns the value to resume after and the id to break ties with.
+func decodePostCursor(cursor, sort string) (interface{}, int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	var c postCursor
+	if err := json.Unmarshal(b, &c); err != nil || c.Sort != sort || c.ID <= 0 {
+		return nil, 0, ErrInvalidCursor
+	}
+	switch sort {
+	case models.PostSortNewest:
+		return c.Time.UTC(), c.ID, nil
+	case models.PostSortTrending:
+		return c.Score, c.ID, nil
+	default:
+		return int(c.Score), c.ID, nil
+	}
+}
+
 func (s *Store) CreatePost(post *models.Post) error {
-	res, err := s.db.Exec(`
-		INSERT INTO posts (user_id, author_name, title, content, topic, likes, created_at)
-		VALUES (?, ?, ?, ?, ?, 0, CURRENT_TIMESTAMP)`,
-		post.UserID, post.AuthorName, post.Title, post.Content, post.Topic,
+	if s.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	if post.CreatedAt.IsZero() {
+		post.CreatedAt = time.Now()
+	}
+	post.CreatedAt = post.CreatedAt.UTC()
+	post.TrendingScore = trendingScore(0, post.CreatedAt)
+	if post.Status == "" {
+		post.Status = models.PostStatusVisible
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	id, err := c.insert(`
+		INSERT INTO posts (user_id, author_name, title, content, topic, likes, created_at, trending_score, status)
+		VALUES (?, ?, ?, ?, ?, 0, ?, ?, ?)`,
+		post.UserID, post.AuthorName, post.Title, post.Content, post.Topic, post.CreatedAt, post.TrendingScore, post.Status,
 	)
 	if err != nil {
 		return err
 	}
-	id, err := res.LastInsertId()
+	post.ID = id
+	if post.Status == models.PostStatusVisible {
+		if err := s.indexDocument(c, postDocument(post)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetPosts returns one page of posts matching q in its sort order.
+func (s *Store) GetPosts(q models.PostQuery) (*models.PostPage, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	if q.Sort == "" {
+		q.Sort = models.PostSortNewest
+	}
+	sortKey, ok := postSortKeys[q.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort %q", q.Sort)
+	}
+
+	where := []string{"status = ?"}
+	args := []interface{}{models.PostStatusVisible}
+	if q.Topic != "" {
+		where = append(where, "topic = ?")
+		args = append(args, q.Topic)
+	}
+	if q.AuthorID != 0 {
+		where = append(where, "user_id = ?")
+		args = append(args, q.AuthorID)
+	}
+	if !q.From.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, q.From.UTC())
+	}
+	if !q.To.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, q.To.UTC())
+	}
+	if q.Cursor != "" {
+		after, afterID, err := decodePostCursor(q.Cursor, q.Sort)
+		if err != nil {
+			return nil, err
+		}
+		where = append(where, "("+sortKey+" < ? OR ("+sortKey+" = ? AND id < ?))")
+		args = append(args, after, after, afterID)
+	}
+
+	query := `SELECT ` + postColumns + ` FROM posts WHERE ` + strings.Join(where, " AND ")
+	query += ` ORDER BY ` + sortKey + ` DESC, id DESC`
+	if q.Limit > 0 {
+		// One extra row tells us whether there is another page
+		query += ` LIMIT ?`
+		args = append(args, q.Limit+1)
+	}
+
+	rows, err := s.conn().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	page := &models.PostPage{Posts: []models.Post{}}
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Posts = append(page.Posts, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if q.Limit > 0 && len(page.Posts) > q.Limit {
+		page.Posts = page.Posts[:q.Limit]
+		page.NextCursor = encodePostCursor(q.Sort, &page.Posts[q.Limit-1])
+	}
+	if err := s.attachReactions(page.Posts, q.ViewerID); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+func (s *Store) GetPostByID(id int) (*models.Post, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	p, err := scanPost(s.conn().QueryRow(`SELECT `+postColumns+` FROM posts WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+func scanPost(row rowScanner) (*models.Post, error) {
+	var p models.Post
+	var lockedAt sql.NullTime
+	if err := row.Scan(&p.ID, &p.UserID, &p.AuthorName, &p.Title, &p.Content, &p.Topic, &p.Likes, &p.CreatedAt, &p.CommentCount, &p.TrendingScore, &p.Status, &lockedAt); err != nil {
+		return nil, err
+	}
+	p.Locked = lockedAt.Valid
+	return &p, nil
+}
+
+// AddReaction records the user's reaction. It reports false if it was already there.
+func (s *Store) AddReaction(postID, userID int, reaction string, at time.Time) (bool, error) {
+	if s.db == nil {
+		return false, fmt.Errorf("database not connected")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	_, err = c.Exec("INSERT INTO post_reactions (post_id, user_id, reaction, created_at) VALUES (?, ?, ?, ?)", postID, userID, reaction, at.UTC())
+	if isUniqueViolation(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if err := refreshTrendingScore(c, postID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// RemoveReaction deletes the user's reaction. It reports false if there was none.
+func (s *Store) RemoveReaction(postID, userID int, reaction string) (bool, error) {
+	if s.db == nil {
+		return false, fmt.Errorf("database not connected")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	res, err := c.Exec("DELETE FROM post_reactions WHERE post_id = ? AND user_id = ? AND reaction = ?", postID, userID, reaction)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if err := refreshTrendingScore(c, postID); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// refreshTrendingScore recomputes a post's trending rank after its reactions changed.
+func refreshTrendingScore(c conn, postID int) error {
+	var reactions int
+	var createdAt time.Time
+	err := c.QueryRow(`SELECT `+reactionCountExpr+`, created_at FROM posts WHERE id = ?`, postID).Scan(&reactions, &createdAt)
+	if err != nil {
+		return err
+	}
+	_, err = c.Exec("UPDATE posts SET trending_score = ? WHERE id = ?", trendingScore(reactions, createdAt), postID)
+	return err
+}
+
+// refreshTrendingScores recomputes every post's trending rank. The migration
+// that adds the column runs it, so both backends start from the same scores.
+func refreshTrendingScores(c conn) error {
+	rows, err := c.Query(`SELECT id, ` + reactionCountExpr + `, created_at FROM posts`)
 	if err != nil {
 		return err
 	}
-	post.ID = int(id)
+	scores := map[int]float64{}
+	for rows.Next() {
+		var id, reactions int
+		var createdAt time.Time
+		if err := rows.Scan(&id, &reactions, &createdAt); err != nil {
+			rows.Close()
+			return err
+		}
+		scores[id] = trendingScore(reactions, createdAt)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, score := range scores {
+		if _, err := c.Exec("UPDATE posts SET trending_score = ? WHERE id = ?", score, id); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (s *Store) GetPosts(topic string) ([]models.Post, error) {
-	query := `SELECT id, user_id, author_name, title, content, topic, likes, created_at FROM posts`
-	var args []interface{}
+// GetReactionSummary returns a post's reaction counts and, when viewerID is
+// set, the viewer's own reactions.
+func (s *Store) GetReactionSummary(postID, viewerID int) (*models.ReactionSummary, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	posts := []models.Post{{ID: postID}}
+	if err := s.attachReactions(posts, viewerID); err != nil {
+		return nil, err
+	}
+	summary := &models.ReactionSummary{PostID: postID, Reactions: posts[0].Reactions, ViewerReacted: posts[0].ViewerReacted}
+	for _, n := range summary.Reactions {
+		summary.Likes += n
+	}
+	return summary, nil
+}
+
+// attachReactions fills in per-type reaction counts for posts, and the
+// viewer's reactions when viewerID is set, with one query each.
+func (s *Store) attachReactions(posts []models.Post, viewerID int) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	index := make(map[int]*models.Post, len(posts))
+	placeholders := make([]string, len(posts))
+	args := make([]interface{}, len(posts))
+	for i := range posts {
+		p := &posts[i]
+		p.Reactions = map[string]int{}
+		if viewerID != 0 {
+			p.ViewerReacted = []string{}
+		}
+		index[p.ID] = p
+		placeholders[i] = "?"
+		args[i] = p.ID
+	}
+	in := strings.Join(placeholders, ", ")
+
+	rows, err := s.conn().Query(`
+		SELECT post_id, reaction, COUNT(*) FROM post_reactions
+		WHERE post_id IN (`+in+`)
+		GROUP BY post_id, reaction`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var postID, count int
+		var reaction string
+		if err := rows.Scan(&postID, &reaction, &count); err != nil {
+			return err
+		}
+		index[postID].Reactions[reaction] = count
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if viewerID == 0 {
+		return nil
+	}
+	rows, err = s.conn().Query(`
+		SELECT post_id, reaction FROM post_reactions
+		WHERE user_id = ? AND post_id IN (`+in+`)
+		ORDER BY reaction`, append([]interface{}{viewerID}, args...)...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var postID int
+		var reaction string
+		if err := rows.Scan(&postID, &reaction); err != nil {
+			return err
+		}
+		index[postID].ViewerReacted = append(index[postID].ViewerReacted, reaction)
+	}
+	return rows.Err()
+}
+
+const commentColumns = `id, post_id, parent_id, user_id, author_name, content, created_at, updated_at, deleted_at, status`
 
-	if topic != "" {
-		query += ` WHERE topic = ?`
-		args = append(args, topic)
+// CreateComment stores a comment and fills in its ID and CreatedAt.
+func (s *Store) CreateComment(c *models.Comment) error {
+	if s.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	c.CreatedAt = time.Now()
+	if c.Status == "" {
+		c.Status = models.CommentStatusVisible
 	}
+	id, err := s.conn().insert(`
+		INSERT INTO comments (post_id, parent_id, user_id, author_name, content, created_at, status)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		c.PostID, c.ParentID, c.UserID, c.AuthorName, c.Content, c.CreatedAt.UTC(), c.Status)
+	if err != nil {
+		return err
+	}
+	c.ID = id
+	return nil
+}
 
-	query += ` ORDER BY created_at DESC`
+func (s *Store) GetCommentByID(id int) (*models.Comment, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	c, err := scanComment(s.conn().QueryRow(`SELECT `+commentColumns+` FROM comments WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return c, err
+}
 
-	rows, err := s.db.Query(query, args...)
+// GetCommentsByPostID returns the comments on the post, deleted ones
+// included but pending ones not, oldest first. Callers assemble the thread
+// from ParentID.
+func (s *Store) GetCommentsByPostID(postID int) ([]models.Comment, error) {
+	if s.db == nil {
+		return nil, nil
+	}
+	rows, err := s.conn().Query(`SELECT `+commentColumns+` FROM comments WHERE post_id = ? AND status <> ? ORDER BY created_at, id`, postID, models.CommentStatusPending)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var posts []models.Post
+	var comments []models.Comment
 	for rows.Next() {
-		var p models.Post
-		if err := rows.Scan(&p.ID, &p.UserID, &p.AuthorName, &p.Title, &p.Content, &p.Topic, &p.Likes, &p.CreatedAt); err != nil {
+		c, err := scanComment(rows)
+		if err != nil {
 			return nil, err
 		}
-		posts = append(posts, p)
+		comments = append(comments, *c)
+	}
+	return comments, rows.Err()
+}
+
+// UpdateComment edits the author's own comment. A visible comment takes
+// status, so an edit that screening holds goes back to pending; a comment
+// already held keeps waiting for review. It reports false when the comment
+// doesn't exist, isn't theirs or has been deleted.
+func (s *Store) UpdateComment(id, userID int, content, status string, at time.Time) (bool, error) {
+	if s.db == nil {
+		return false, fmt.Errorf("database not connected")
+	}
+	res, err := s.conn().Exec(`
+		UPDATE comments SET content = ?, updated_at = ?, status = CASE WHEN status = ? THEN ? ELSE status END
+		WHERE id = ? AND user_id = ? AND deleted_at IS NULL`,
+		content, at.UTC(), models.CommentStatusVisible, status, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// DeleteComment soft-deletes the author's own comment, clearing its content
+// but keeping the row so replies stay attached.
+func (s *Store) DeleteComment(id, userID int, at time.Time) (bool, error) {
+	if s.db == nil {
+		return false, fmt.Errorf("database not connected")
+	}
+	res, err := s.conn().Exec("UPDATE comments SET content = '', deleted_at = ? WHERE id = ? AND user_id = ? AND deleted_at IS NULL", at.UTC(), id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func scanComment(row rowScanner) (*models.Comment, error) {
+	var c models.Comment
+	var parentID sql.NullInt64
+	var authorName sql.NullString
+	var updatedAt, deletedAt sql.NullTime
+	if err := row.Scan(&c.ID, &c.PostID, &parentID, &c.UserID, &authorName, &c.Content, &c.CreatedAt, &updatedAt, &deletedAt, &c.Status); err != nil {
+		return nil, err
+	}
+	if parentID.Valid {
+		id := int(parentID.Int64)
+		c.ParentID = &id
+	}
+	c.AuthorName = authorName.String
+	if updatedAt.Valid {
+		c.UpdatedAt = &updatedAt.Time
 	}
-	return posts, nil
+	c.Deleted = deletedAt.Valid
+	return &c, nil
 }
@@ -2,18 +2,38 @@ package store
 
 import (
 	"codefuture-backend/internal/models"
+	"context"
 	"database/sql"
-	"log"
+	"fmt"
+	"log/slog"
 	"os"
+	"strings"
+	"sync/atomic"
 
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type Store struct {
-	db *sql.DB
+	db       *sql.DB
+	dialect  dialect
+	search   string      // search backend, set by Migrate
+	migrated atomic.Bool // Migrate has brought the schema up to date
 }
 
-func NewStore() *Store {
+// NewStore opens the database named by dsn. postgres:// and postgresql://
+// URLs select the Postgres backend; anything else is a SQLite file path, and
+// an empty dsn keeps the default codefuture.db in the backend root.
+func NewStore(dsn string) *Store {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			slog.Warn("failed to connect to database", "err", err)
+			return &Store{db: nil, dialect: dialectPostgres}
+		}
+		return &Store{db: db, dialect: dialectPostgres}
+	}
+
 	// Use SQLite for reliability. Store DB in backend root (up two dirs from cmd/api)
 	dbPath := "../../codefuture.db"
 
@@ -21,100 +41,67 @@ func NewStore() *Store {
 	if _, err := os.Stat("cmd/api/main.go"); err == nil {
 		dbPath = "./codefuture.db"
 	}
+	if dsn != "" {
+		dbPath = strings.TrimPrefix(dsn, "sqlite://")
+	}
 
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
-		log.Printf("Warning: Failed to connect to database: %v", err)
+		slog.Warn("failed to connect to database", "err", err)
 		return &Store{db: nil}
 	}
 
 	return &Store{db: db}
 }
 
-func (s *Store) InitSchema() {
-	if s.db == nil {
-		return
-	}
-
-	query := `
-	CREATE TABLE IF NOT EXISTS users (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		email TEXT UNIQUE,
-		name TEXT,
-		password TEXT,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-	CREATE TABLE IF NOT EXISTS lesson_plans (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER,
-		persona TEXT,
-		goals TEXT,
-		content TEXT,
-		current_lesson_index INTEGER DEFAULT 0,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY(user_id) REFERENCES users(id)
-	);
-	CREATE TABLE IF NOT EXISTS posts (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id INTEGER,
-		author_name TEXT,
-		title TEXT,
-		content TEXT,
-		topic TEXT,
-		likes INTEGER DEFAULT 0,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		FOREIGN KEY(user_id) REFERENCES users(id)
-	);
-	CREATE TABLE IF NOT EXISTS contact_submissions (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		first_name TEXT,
-		last_name TEXT,
-		email TEXT,
-		message TEXT,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-	`
-	_, err := s.db.Exec(query)
-	if err != nil {
-		log.Printf("Error creating schema: %v", err)
-	} else {
-		log.Println("Schema initialized successfully (SQLite)")
-	}
-	// Try to add user_id column if it doesn't exist (primitive migration)
-	_, _ = s.db.Exec("ALTER TABLE contact_submissions ADD COLUMN user_id INTEGER REFERENCES users(id)")
-	// Try to add current_lesson_index column if it doesn't exist (primitive migration for roadmap)
-	_, _ = s.db.Exec("ALTER TABLE lesson_plans ADD COLUMN current_lesson_index INTEGER DEFAULT 0")
+// Dialect names the backend in use ("sqlite" or "postgres").
+func (s *Store) Dialect() string {
+	return s.dialect.String()
 }
 
-func (s *Store) Ping() error {
+func (s *Store) conn() conn {
+	return conn{ex: s.db, d: s.dialect}
+}
+
+func (s *Store) withTx(tx *sql.Tx) conn {
+	return conn{ex: tx, d: s.dialect}
+}
+
+// Ping checks the database answers. A store that failed to open has no
+// database to ping and reports that.
+func (s *Store) Ping(ctx context.Context) error {
 	if s.db == nil {
-		return nil
+		return fmt.Errorf("database not connected")
 	}
-	return s.db.Ping()
+	return s.db.PingContext(ctx)
 }
 
-func (s *Store) SaveLessonPlan(userID *int, persona, goals, content string) (int, error) {
+func (s *Store) SaveLessonPlan(userID *int, kind, persona, goals, content string) (int, error) {
 	if s.db == nil {
 		return 0, nil
 	}
-	// SQLite uses ? for placeholders
-	res, err := s.db.Exec("INSERT INTO lesson_plans (user_id, persona, goals, content) VALUES (?, ?, ?, ?)", userID, persona, goals, content)
+	tx, err := s.db.Begin()
 	if err != nil {
 		return 0, err
 	}
-	id, err := res.LastInsertId()
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	id, err := c.insert("INSERT INTO lesson_plans (user_id, kind, persona, goals, content) VALUES (?, ?, ?, ?, ?)", userID, kind, persona, goals, content)
 	if err != nil {
 		return 0, err
 	}
-	return int(id), nil
+	if err := s.indexPlan(c, id); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
 }
 
 func (s *Store) GetCoursesByUserID(userID int) ([]map[string]interface{}, error) {
 	if s.db == nil {
 		return nil, nil
 	}
-	// SQLite uses ?
-	rows, err := s.db.Query("SELECT id, persona, goals, created_at FROM lesson_plans WHERE user_id = ? ORDER BY created_at DESC", userID)
+	rows, err := s.conn().Query("SELECT id, kind, persona, goals, created_at FROM lesson_plans WHERE user_id = ? ORDER BY created_at DESC, id DESC", userID)
 	if err != nil {
 		return nil, err
 	}
@@ -123,16 +110,17 @@ func (s *Store) GetCoursesByUserID(userID int) ([]map[string]interface{}, error)
 	var courses []map[string]interface{}
 	for rows.Next() {
 		var id int
-		var persona, goals string
+		var kind, persona, goals string
 		var createdAt interface{}
-		if err := rows.Scan(&id, &persona, &goals, &createdAt); err != nil {
+		if err := rows.Scan(&id, &kind, &persona, &goals, &createdAt); err != nil {
 			continue
 		}
 		courses = append(courses, map[string]interface{}{
 			"id":        id,
+			"kind":      kind,
 			"persona":   persona,
 			"goals":     goals,
-			"createdAt": createdAt, // SQLite returns string or time based on driver settings
+			"createdAt": createdAt, // time.Time from both drivers for DATETIME/TIMESTAMPTZ columns
 		})
 	}
 	return courses, nil
@@ -144,12 +132,12 @@ func (s *Store) GetLatestLessonPlan(userID int) (*models.LessonPlan, error) {
 	}
 	var lp models.LessonPlan
 	// Fix: Ensure we scan current_lesson_index. If it's NULL (old schema), SQLite handles DEFAULT 0
-	err := s.db.QueryRow(`
-		SELECT id, persona, goals, content, current_lesson_index, created_at 
+	err := s.conn().QueryRow(`
+		SELECT id, user_id, kind, persona, goals, content, current_lesson_index, created_at 
 		FROM lesson_plans 
 		WHERE user_id = ? 
-		ORDER BY created_at DESC 
-		LIMIT 1`, userID).Scan(&lp.ID, &lp.Persona, &lp.Goals, &lp.Content, &lp.CurrentLessonIndex, &lp.CreatedAt)
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1`, userID).Scan(&lp.ID, &lp.UserID, &lp.Kind, &lp.Persona, &lp.Goals, &lp.Content, &lp.CurrentLessonIndex, &lp.CreatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -164,19 +152,39 @@ func (s *Store) UpdateLessonProgress(planID int, lessonIndex int) error {
 	if s.db == nil {
 		return nil
 	}
-	_, err := s.db.Exec("UPDATE lesson_plans SET current_lesson_index = ? WHERE id = ?", lessonIndex, planID)
+	_, err := s.conn().Exec("UPDATE lesson_plans SET current_lesson_index = ? WHERE id = ?", lessonIndex, planID)
 	return err
 }
 
+func (s *Store) UpdateLessonPlanContent(planID int, content string) error {
+	if s.db == nil {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	if _, err := c.Exec("UPDATE lesson_plans SET content = ? WHERE id = ?", content, planID); err != nil {
+		return err
+	}
+	if err := s.indexPlan(c, planID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
 func (s *Store) GetLessonPlanByID(planID int) (*models.LessonPlan, error) {
 	if s.db == nil {
 		return nil, nil
 	}
 	var lp models.LessonPlan
-	err := s.db.QueryRow(`
-		SELECT id, persona, goals, content, current_lesson_index, created_at 
+	err := s.conn().QueryRow(`
+		SELECT id, user_id, kind, persona, goals, content, current_lesson_index, created_at 
 		FROM lesson_plans 
-		WHERE id = ?`, planID).Scan(&lp.ID, &lp.Persona, &lp.Goals, &lp.Content, &lp.CurrentLessonIndex, &lp.CreatedAt)
+		WHERE id = ?`, planID).Scan(&lp.ID, &lp.UserID, &lp.Kind, &lp.Persona, &lp.Goals, &lp.Content, &lp.CurrentLessonIndex, &lp.CreatedAt)
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -191,21 +199,40 @@ func (s *Store) DeleteCourse(userID int, courseID int) error {
 	if s.db == nil {
 		return nil
 	}
-	_, err := s.db.Exec("DELETE FROM lesson_plans WHERE id = ? AND user_id = ?", courseID, userID)
-	return err
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	res, err := c.Exec("DELETE FROM lesson_plans WHERE id = ? AND user_id = ?", courseID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil
+	}
+	if err := s.deletePlanContents(c, courseID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (s *Store) Close() {
-	if s.db != nil {
-		s.db.Close()
+// deletePlanContents removes what belongs to a deleted plan. Foreign keys
+// are not enforced, so the plan's lessons and attempts go explicitly.
+func (s *Store) deletePlanContents(c conn, planID int) error {
+	if _, err := c.Exec("DELETE FROM lesson_attempts WHERE lesson_id IN (SELECT id FROM lessons WHERE plan_id = ?)", planID); err != nil {
+		return err
 	}
+	if _, err := c.Exec("DELETE FROM lessons WHERE plan_id = ?", planID); err != nil {
+		return err
+	}
+	return s.unindexPlan(c, planID)
 }
 
-func (s *Store) CreateContactSubmission(sub *models.ContactSubmission) error {
-	if s.db == nil {
-		return nil
+func (s *Store) Close() {
+	if s.db != nil {
+		s.db.Close()
 	}
-	_, err := s.db.Exec("INSERT INTO contact_submissions (first_name, last_name, email, message, user_id) VALUES (?, ?, ?, ?, ?)",
-		sub.FirstName, sub.LastName, sub.Email, sub.Message, sub.UserID)
-	return err
 }
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"codefuture-backend/internal/metrics"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// dialect captures the few places where SQLite and Postgres disagree. All
+// queries are written with SQLite-style ? placeholders and rebound on the way out.
+type dialect int
+
+const (
+	dialectSQLite dialect = iota
+	dialectPostgres
+)
+
+func (d dialect) String() string {
+	if d == dialectPostgres {
+		return "postgres"
+	}
+	return "sqlite"
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// rebind rewrites ? placeholders to $1, $2, ... for Postgres. Question marks
+// inside single-quoted string literals are left alone.
+func (d dialect) rebind(query string) string {
+	if d != dialectPostgres || !strings.Contains(query, "?") {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 8)
+	n, inString := 0, false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			inString = !inString
+		case r == '?' && !inString:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// conn wraps an execer so every statement is rebound for the store's dialect.
+type conn struct {
+	ex execer
+	d  dialect
+}
+
+func (c conn) Exec(query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := c.ex.Exec(c.d.rebind(query), args...)
+	metrics.ObserveQuery("exec", time.Since(start), err)
+	return res, err
+}
+
+func (c conn) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := c.ex.Query(c.d.rebind(query), args...)
+	metrics.ObserveQuery("query", time.Since(start), err)
+	return rows, err
+}
+
+// QueryRow runs the query before returning, but its error only surfaces at
+// Scan, so the clock starts here and the query is recorded there.
+func (c conn) QueryRow(query string, args ...interface{}) *row {
+	start := time.Now()
+	return &row{Row: c.ex.QueryRow(c.d.rebind(query), args...), start: start}
+}
+
+// row is a *sql.Row that records the query, timed from QueryRow, when it
+// is scanned.
+type row struct {
+	*sql.Row
+	start time.Time
+}
+
+func (r *row) Scan(dest ...interface{}) error {
+	err := r.Row.Scan(dest...)
+	observed := err
+	if errors.Is(err, sql.ErrNoRows) {
+		observed = nil // an answer, not a failure
+	}
+	metrics.ObserveQuery("query", time.Since(r.start), observed)
+	return err
+}
+
+// insert runs an INSERT and returns the new row's id. Postgres has no
+// LastInsertId, so the id comes back through RETURNING instead.
+func (c conn) insert(query string, args ...interface{}) (int, error) {
+	if c.d == dialectPostgres {
+		var id int
+		err := c.QueryRow(query+" RETURNING id", args...).Scan(&id)
+		return id, err
+	}
+
+	res, err := c.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	return int(id), nil
+}
+
+// isUniqueViolation reports whether err is a UNIQUE constraint failure.
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique ||
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package store
+
+import (
+	"codefuture-backend/internal/models"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const outboxColumns = `id, recipient, template, subject, html_body, text_body, status, attempts, next_attempt_at, last_error, created_at, sent_at`
+
+// EnqueueEmail adds a rendered email to the outbox and fills in its ID.
+func (s *Store) EnqueueEmail(e *models.OutboxEmail) error {
+	if s.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	if e.NextAttemptAt.IsZero() {
+		e.NextAttemptAt = e.CreatedAt
+	}
+	e.Status = models.EmailStatusPending
+	e.CreatedAt = e.CreatedAt.UTC()
+	e.NextAttemptAt = e.NextAttemptAt.UTC()
+	id, err := s.conn().insert(`
+		INSERT INTO email_outbox (recipient, template, subject, html_body, text_body, status, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.To, e.Template, e.Subject, e.HTMLBody, e.TextBody, e.Status, e.NextAttemptAt, e.CreatedAt)
+	if err != nil {
+		return err
+	}
+	e.ID = id
+	return nil
+}
+
+// ClaimDueEmails returns up to limit pending emails that are due at now,
+// oldest first. Each one has its attempt counted and is pushed back to
+// now+lease, so another worker won't pick it up while it is being sent.
+func (s *Store) ClaimDueEmails(now time.Time, lease time.Duration, limit int) ([]models.OutboxEmail, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	now = now.UTC()
+	rows, err := s.conn().Query(`SELECT `+outboxColumns+` FROM email_outbox
+		WHERE status = ? AND next_attempt_at <= ? ORDER BY next_attempt_at, id LIMIT ?`,
+		models.EmailStatusPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	var due []models.OutboxEmail
+	for rows.Next() {
+		e, err := scanOutboxEmail(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		due = append(due, *e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	claimed := []models.OutboxEmail{}
+	for _, e := range due {
+		// Only the worker whose update matches the row still due gets it
+		res, err := s.conn().Exec(`UPDATE email_outbox SET attempts = attempts + 1, next_attempt_at = ?
+			WHERE id = ? AND status = ? AND next_attempt_at <= ?`,
+			now.Add(lease), e.ID, models.EmailStatusPending, now)
+		if err != nil {
+			return nil, err
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			e.Attempts++
+			claimed = append(claimed, e)
+		}
+	}
+	return claimed, nil
+}
+
+func (s *Store) MarkEmailSent(id int, at time.Time) error {
+	if s.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	_, err := s.conn().Exec("UPDATE email_outbox SET status = ?, sent_at = ?, last_error = '' WHERE id = ?",
+		models.EmailStatusSent, at.UTC(), id)
+	return err
+}
+
+// MarkEmailFailed records a failed attempt and schedules the next one at
+// retryAt. A nil retryAt marks the email dead.
+func (s *Store) MarkEmailFailed(id int, lastError string, retryAt *time.Time, at time.Time) error {
+	if s.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	status, next := models.EmailStatusDead, at.UTC()
+	if retryAt != nil {
+		status, next = models.EmailStatusPending, retryAt.UTC()
+	}
+	_, err := s.conn().Exec("UPDATE email_outbox SET status = ?, next_attempt_at = ?, last_error = ? WHERE id = ?",
+		status, next, lastError, id)
+	return err
+}
+
+// CountOutboxEmails returns how many emails the outbox holds in each
+// status, zero included.
+func (s *Store) CountOutboxEmails() (map[string]int, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	rows, err := s.conn().Query(`SELECT status, COUNT(*) FROM email_outbox GROUP BY status`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for _, status := range models.EmailStatuses {
+		counts[status] = 0
+	}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
+
+// ListOutboxEmails returns outbox emails matching q, newest first.
+func (s *Store) ListOutboxEmails(q models.OutboxQuery) ([]models.OutboxEmail, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	query := `SELECT ` + outboxColumns + ` FROM email_outbox`
+	var args []interface{}
+	if q.Status != "" {
+		query += ` WHERE status = ?`
+		args = append(args, q.Status)
+	}
+	query += ` ORDER BY id DESC`
+	if q.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.conn().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	emails := []models.OutboxEmail{}
+	for rows.Next() {
+		e, err := scanOutboxEmail(rows)
+		if err != nil {
+			return nil, err
+		}
+		emails = append(emails, *e)
+	}
+	return emails, rows.Err()
+}
+
+// RetryEmail gives an unsent email a fresh set of attempts starting at at,
+// and records who asked. It reports false if there is no such unsent email.
+func (s *Store) RetryEmail(id, actorID int, at time.Time) (bool, error) {
+	if s.db == nil {
+		return false, fmt.Errorf("database not connected")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	var status string
+	err = c.QueryRow("SELECT status FROM email_outbox WHERE id = ?", id).Scan(&status)
+	if err == sql.ErrNoRows || status == models.EmailStatusSent {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	at = at.UTC()
+	if _, err := c.Exec("UPDATE email_outbox SET status = ?, attempts = 0, next_attempt_at = ? WHERE id = ?",
+		models.EmailStatusPending, at, id); err != nil {
+		return false, err
+	}
+	entry := &models.AuditEntry{ActorID: actorID, Action: models.AuditActionRetry, TargetType: models.AuditTargetEmail,
+		TargetID: id, Reason: "was " + status, CreatedAt: at}
+	if err := writeAudit(c, entry); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func scanOutboxEmail(row rowScanner) (*models.OutboxEmail, error) {
+	var e models.OutboxEmail
+	var sentAt sql.NullTime
+	if err := row.Scan(&e.ID, &e.To, &e.Template, &e.Subject, &e.HTMLBody, &e.TextBody, &e.Status, &e.Attempts,
+		&e.NextAttemptAt, &e.LastError, &e.CreatedAt, &sentAt); err != nil {
+		return nil, err
+	}
+	if sentAt.Valid {
+		e.SentAt = &sentAt.Time
+	}
+	return &e, nil
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"codefuture-backend/internal/models"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// GetUserByIdentity finds the user a provider account is linked to.
+func (s *Store) GetUserByIdentity(provider, providerUserID string) (*models.User, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	return s.scanUser(s.conn().QueryRow(`
+		SELECT u.id, u.name, u.email, u.password, u.created_at, u.email_verified_at, u.role, u.suspended_until, u.suspension_reason
+		FROM user_identities i JOIN users u ON u.id = i.user_id
+		WHERE i.provider = ? AND i.provider_user_id = ?`, provider, providerUserID))
+}
+
+// LinkIdentity links a provider account to identity.UserID and fills in its ID.
+func (s *Store) LinkIdentity(identity *models.UserIdentity) error {
+	if s.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	if identity.CreatedAt.IsZero() {
+		identity.CreatedAt = time.Now()
+	}
+	id, err := s.conn().insert(`
+		INSERT INTO user_identities (user_id, provider, provider_user_id, email, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		identity.UserID, identity.Provider, identity.ProviderUserID, identity.Email, identity.CreatedAt.UTC())
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrIdentityExists
+		}
+		return err
+	}
+	identity.ID = id
+	return nil
+}
+
+func (s *Store) ListIdentities(userID int) ([]models.UserIdentity, error) {
+	if s.db == nil {
+		return nil, nil
+	}
+	rows, err := s.conn().Query(`
+		SELECT id, user_id, provider, provider_user_id, email, created_at
+		FROM user_identities WHERE user_id = ? ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var identities []models.UserIdentity
+	for rows.Next() {
+		var i models.UserIdentity
+		var email sql.NullString
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Provider, &i.ProviderUserID, &email, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		i.Email = email.String
+		identities = append(identities, i)
+	}
+	return identities, rows.Err()
+}
@@ -0,0 +1,224 @@
+package store
+
+import (
+	"codefuture-backend/internal/models"
+	"database/sql"
+	"encoding/json"
+)
+
+// SaveLessons stores the lessons of a plan and fills in their IDs.
+func (s *Store) SaveLessons(lessons []*models.LessonRecord) error {
+	if s.db == nil {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := s.insertLessons(s.withTx(tx), lessons); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SaveCurriculum saves a generated curriculum plan and its lessons in one
+// transaction, so a plan is never left without its lessons. Once the lessons
+// have their PlanID and ID, content builds the plan's content from them.
+func (s *Store) SaveCurriculum(userID *int, persona, goals string, lessons []*models.LessonRecord, content func() (string, error)) (int, error) {
+	if s.db == nil {
+		return 0, nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	id, err := c.insert("INSERT INTO lesson_plans (user_id, kind, persona, goals, content) VALUES (?, ?, ?, ?, ?)", userID, models.PlanKindCurriculum, persona, goals, "{}")
+	if err != nil {
+		return 0, err
+	}
+	for _, l := range lessons {
+		l.PlanID = id
+	}
+	if err := s.insertLessons(c, lessons); err != nil {
+		return 0, err
+	}
+
+	text, err := content()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := c.Exec("UPDATE lesson_plans SET content = ? WHERE id = ?", text, id); err != nil {
+		return 0, err
+	}
+	if err := s.indexPlan(c, id); err != nil {
+		return 0, err
+	}
+	return id, tx.Commit()
+}
+
+// insertLessons inserts lessons, fills in their IDs and indexes them.
+func (s *Store) insertLessons(c conn, lessons []*models.LessonRecord) error {
+	for _, l := range lessons {
+		var prompt, tests interface{}
+		if l.Exercise != nil {
+			testsJSON, err := json.Marshal(l.Exercise.Tests)
+			if err != nil {
+				return err
+			}
+			prompt = l.Exercise.Prompt
+			tests = string(testsJSON)
+		}
+
+		id, err := c.insert(`
+			INSERT INTO lessons (plan_id, lesson_index, title, language, exercise_prompt, exercise_tests)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			l.PlanID, l.LessonIndex, l.Title, l.Language, prompt, tests)
+		if err != nil {
+			return err
+		}
+		l.ID = id
+	}
+	return s.indexLessons(c, lessons)
+}
+
+// indexLessons adds lessons to the search index for their plan's owner.
+func (s *Store) indexLessons(c conn, lessons []*models.LessonRecord) error {
+	owners := map[int]sql.NullInt64{}
+	for _, l := range lessons {
+		owner, seen := owners[l.PlanID]
+		if !seen {
+			err := c.QueryRow("SELECT user_id FROM lesson_plans WHERE id = ?", l.PlanID).Scan(&owner)
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+			owners[l.PlanID] = owner
+		}
+		if !owner.Valid {
+			continue
+		}
+		if err := s.indexDocument(c, lessonDocument(l, int(owner.Int64))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) GetLessonByID(lessonID int) (*models.LessonRecord, error) {
+	if s.db == nil {
+		return nil, nil
+	}
+	row := s.conn().QueryRow(`
+		SELECT id, plan_id, lesson_index, title, language, exercise_prompt, exercise_tests
+		FROM lessons
+		WHERE id = ?`, lessonID)
+
+	l, err := scanLesson(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return l, err
+}
+
+func (s *Store) GetLessonsByPlanID(planID int) ([]models.LessonRecord, error) {
+	if s.db == nil {
+		return nil, nil
+	}
+	rows, err := s.conn().Query(`
+		SELECT id, plan_id, lesson_index, title, language, exercise_prompt, exercise_tests
+		FROM lessons
+		WHERE plan_id = ?
+		ORDER BY lesson_index`, planID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lessons []models.LessonRecord
+	for rows.Next() {
+		l, err := scanLesson(rows)
+		if err != nil {
+			return nil, err
+		}
+		lessons = append(lessons, *l)
+	}
+	return lessons, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanLesson(row rowScanner) (*models.LessonRecord, error) {
+	var l models.LessonRecord
+	var prompt, tests sql.NullString
+	if err := row.Scan(&l.ID, &l.PlanID, &l.LessonIndex, &l.Title, &l.Language, &prompt, &tests); err != nil {
+		return nil, err
+	}
+	if prompt.Valid {
+		l.Exercise = &models.Exercise{Prompt: prompt.String}
+		if tests.Valid && tests.String != "" {
+			if err := json.Unmarshal([]byte(tests.String), &l.Exercise.Tests); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &l, nil
+}
+
+func (s *Store) CreateLessonAttempt(a *models.LessonAttempt) error {
+	if s.db == nil {
+		return nil
+	}
+	id, err := s.conn().insert(`
+		INSERT INTO lesson_attempts (user_id, lesson_id, code, passed, passed_count, total_count)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		a.UserID, a.LessonID, a.Code, a.Passed, a.PassedCount, a.TotalCount)
+	if err != nil {
+		return err
+	}
+	a.ID = id
+	return nil
+}
+
+func (s *Store) GetLessonAttempts(userID, lessonID int) ([]models.LessonAttempt, error) {
+	if s.db == nil {
+		return nil, nil
+	}
+	rows, err := s.conn().Query(`
+		SELECT id, user_id, lesson_id, code, passed, passed_count, total_count, created_at
+		FROM lesson_attempts
+		WHERE user_id = ? AND lesson_id = ?
+		ORDER BY created_at DESC, id DESC`, userID, lessonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var attempts []models.LessonAttempt
+	for rows.Next() {
+		var a models.LessonAttempt
+		if err := rows.Scan(&a.ID, &a.UserID, &a.LessonID, &a.Code, &a.Passed, &a.PassedCount, &a.TotalCount, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+// HasPassedLesson reports whether the user has at least one passing attempt.
+func (s *Store) HasPassedLesson(userID, lessonID int) (bool, error) {
+	if s.db == nil {
+		return false, nil
+	}
+	var exists bool
+	err := s.conn().QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM lesson_attempts WHERE user_id = ? AND lesson_id = ? AND passed = ?)`,
+		userID, lessonID, true).Scan(&exists)
+	return exists, err
+}
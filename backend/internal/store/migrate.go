@@ -0,0 +1,393 @@
+package store
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// Migration is one numbered schema change with its inverse.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus describes a known migration and whether it has been applied.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+// trendingMigration adds posts.trending_score. SQLite cannot compute the
+// score in SQL, so applying it also recomputes the scores in Go.
+const trendingMigration = 11
+
+// loadMigrations reads NNNN_name.up.sql / NNNN_name.down.sql pairs from dir.
+func loadMigrations(dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %v", err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		name := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(name, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(name, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(name, "."+direction+".sql")
+		versionStr, label, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %q", name)
+		}
+		version, err := strconv.Atoi(versionStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %q", name)
+		}
+
+		body, err := fs.ReadFile(migrationFiles, path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		m, exists := byVersion[version]
+		if !exists {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		} else if m.Name != label {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, m.Name, label)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d (%s) has no up script", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// migrations returns the migration set for the store's dialect. Both
+// directories use the same version numbers for the same schema change.
+func (s *Store) migrations() ([]Migration, error) {
+	return loadMigrations("migrations/" + s.dialect.String())
+}
+
+// Migrate applies every pending migration and readies the search index. It
+// is run on startup.
+func (s *Store) Migrate() error {
+	all, err := s.migrations()
+	if err != nil {
+		return err
+	}
+	if len(all) > 0 {
+		if err := s.MigrateTo(all[len(all)-1].Version); err != nil {
+			return err
+		}
+		if err := s.prepareSearch(); err != nil {
+			return err
+		}
+	}
+	s.migrated.Store(true)
+	return nil
+}
+
+// Migrated reports whether Migrate has run successfully, so the schema is
+// the one this build expects.
+func (s *Store) Migrated() bool {
+	return s.migrated.Load()
+}
+
+// MigrateTo moves the schema up or down until target is the newest applied
+// version. Each migration runs in its own transaction together with its
+// schema_migrations bookkeeping, so a failure leaves the schema consistent.
+func (s *Store) MigrateTo(target int) error {
+	if s.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+
+	all, err := s.migrations()
+	if err != nil {
+		return err
+	}
+	if target != 0 && !hasVersion(all, target) {
+		return fmt.Errorf("unknown migration version %d", target)
+	}
+
+	applied, err := s.appliedVersions()
+	if err != nil {
+		return err
+	}
+
+	// Up: oldest first
+	for _, m := range all {
+		if m.Version > target || applied[m.Version] != nil {
+			continue
+		}
+		if err := s.applyMigration(m, true); err != nil {
+			return err
+		}
+	}
+
+	// Down: newest first
+	for i := len(all) - 1; i >= 0; i-- {
+		m := all[i]
+		if m.Version <= target || applied[m.Version] == nil {
+			continue
+		}
+		if err := s.applyMigration(m, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MigrateDown rolls back the newest steps applied migrations.
+func (s *Store) MigrateDown(steps int) error {
+	status, err := s.MigrationStatus()
+	if err != nil {
+		return err
+	}
+
+	var appliedVersions []int
+	for _, st := range status {
+		if st.Applied {
+			appliedVersions = append(appliedVersions, st.Version)
+		}
+	}
+	if steps <= 0 || len(appliedVersions) == 0 {
+		return nil
+	}
+	if steps >= len(appliedVersions) {
+		return s.MigrateTo(0)
+	}
+	return s.MigrateTo(appliedVersions[len(appliedVersions)-1-steps])
+}
+
+// MigrationStatus lists every embedded migration with its applied state.
+func (s *Store) MigrationStatus() ([]MigrationStatus, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+
+	all, err := s.migrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := s.appliedVersions()
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(all))
+	for _, m := range all {
+		status = append(status, MigrationStatus{
+			Version:   m.Version,
+			Name:      m.Name,
+			Applied:   applied[m.Version] != nil,
+			AppliedAt: applied[m.Version],
+		})
+	}
+	return status, nil
+}
+
+func (s *Store) applyMigration(m Migration, up bool) error {
+	script, direction := m.Up, "up"
+	if !up {
+		script, direction = m.Down, "down"
+		if strings.TrimSpace(script) == "" {
+			return fmt.Errorf("migration %04d_%s cannot be reverted (no down script)", m.Version, m.Name)
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	if _, err := c.Exec(script); err != nil {
+		return fmt.Errorf("migration %04d_%s (%s) failed: %v", m.Version, m.Name, direction, err)
+	}
+	if up && m.Version == trendingMigration {
+		if err := refreshTrendingScores(c); err != nil {
+			return fmt.Errorf("migration %04d_%s (%s) failed to score posts: %v", m.Version, m.Name, direction, err)
+		}
+	}
+
+	if up {
+		_, err = c.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.Version, m.Name)
+	} else {
+		_, err = c.Exec("DELETE FROM schema_migrations WHERE version = ?", m.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record migration %04d_%s: %v", m.Version, m.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	slog.Info("migration applied", "version", m.Version, "name", m.Name, "direction", direction)
+	return nil
+}
+
+// appliedVersions returns version -> applied_at for every applied migration,
+// creating the bookkeeping table (and baselining legacy databases) on first use.
+func (s *Store) appliedVersions() (map[int]*time.Time, error) {
+	if err := s.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.conn().Query("SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]*time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = &appliedAt
+	}
+	return applied, rows.Err()
+}
+
+func (s *Store) ensureMigrationsTable() error {
+	exists, err := s.tableExists("schema_migrations")
+	if err != nil || exists {
+		return err
+	}
+
+	baseline, err := s.legacyBaseline()
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	timestampType := "DATETIME"
+	if s.dialect == dialectPostgres {
+		timestampType = "TIMESTAMPTZ"
+	}
+	if _, err := c.Exec(`
+		CREATE TABLE schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at ` + timestampType + ` DEFAULT CURRENT_TIMESTAMP
+		)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %v", err)
+	}
+
+	for _, m := range baseline {
+		if _, err := c.Exec("INSERT INTO schema_migrations (version, name) VALUES (?, ?)", m.Version, m.Name); err != nil {
+			return err
+		}
+		slog.Info("migration marked as applied (existing schema)", "version", m.Version, "name", m.Name)
+	}
+
+	return tx.Commit()
+}
+
+// legacyBaseline detects which migrations a database created by the old
+// InitSchema (CREATE IF NOT EXISTS + best-effort ALTERs) already has, so they
+// are recorded instead of re-run. Postgres databases never used InitSchema.
+func (s *Store) legacyBaseline() ([]Migration, error) {
+	if s.dialect != dialectSQLite {
+		return nil, nil
+	}
+	hasUsers, err := s.tableExists("users")
+	if err != nil || !hasUsers {
+		return nil, err
+	}
+
+	all, err := s.migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	checks := map[int]func() (bool, error){
+		1: func() (bool, error) { return true, nil },
+		2: func() (bool, error) { return s.columnExists("contact_submissions", "user_id") },
+		3: func() (bool, error) { return s.columnExists("lesson_plans", "current_lesson_index") },
+		4: func() (bool, error) { return s.tableExists("lessons") },
+	}
+
+	var baseline []Migration
+	for _, m := range all {
+		check, ok := checks[m.Version]
+		if !ok {
+			break
+		}
+		present, err := check()
+		if err != nil {
+			return nil, err
+		}
+		if present {
+			baseline = append(baseline, m)
+		}
+	}
+	return baseline, nil
+}
+
+func (s *Store) tableExists(name string) (bool, error) {
+	var exists bool
+	if s.dialect == dialectPostgres {
+		err := s.conn().QueryRow("SELECT to_regclass(?) IS NOT NULL", name).Scan(&exists)
+		return exists, err
+	}
+	err := s.conn().QueryRow("SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = ?)", name).Scan(&exists)
+	return exists, err
+}
+
+func (s *Store) columnExists(table, column string) (bool, error) {
+	var exists bool
+	err := s.conn().QueryRow("SELECT EXISTS (SELECT 1 FROM pragma_table_info(?) WHERE name = ?)", table, column).Scan(&exists)
+	return exists, err
+}
+
+func hasVersion(all []Migration, version int) bool {
+	for _, m := range all {
+		if m.Version == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+CREATE TABLE IF NOT EXISTS users (
+	id SERIAL PRIMARY KEY,
+	email TEXT UNIQUE,
+	name TEXT,
+	password TEXT,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS lesson_plans (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER REFERENCES users(id),
+	persona TEXT,
+	goals TEXT,
+	content TEXT,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS posts (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER REFERENCES users(id),
+	author_name TEXT,
+	title TEXT,
+	content TEXT,
+	topic TEXT,
+	likes INTEGER DEFAULT 0,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS contact_submissions (
+	id SERIAL PRIMARY KEY,
+	first_name TEXT,
+	last_name TEXT,
+	email TEXT,
+	message TEXT,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
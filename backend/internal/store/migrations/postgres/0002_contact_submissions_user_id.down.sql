@@ -0,0 +1 @@
+ALTER TABLE contact_submissions DROP COLUMN user_id;
@@ -0,0 +1 @@
+ALTER TABLE lesson_plans ADD COLUMN current_lesson_index INTEGER DEFAULT 0;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS lesson_attempts;
+DROP TABLE IF EXISTS lessons;
@@ -0,0 +1,22 @@
+CREATE TABLE lessons (
+	id SERIAL PRIMARY KEY,
+	plan_id INTEGER NOT NULL REFERENCES lesson_plans(id) ON DELETE CASCADE,
+	lesson_index INTEGER NOT NULL,
+	title TEXT,
+	language TEXT,
+	exercise_prompt TEXT,
+	exercise_tests TEXT
+);
+CREATE INDEX idx_lessons_plan ON lessons(plan_id, lesson_index);
+
+CREATE TABLE lesson_attempts (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	lesson_id INTEGER NOT NULL REFERENCES lessons(id) ON DELETE CASCADE,
+	code TEXT,
+	passed BOOLEAN DEFAULT FALSE,
+	passed_count INTEGER DEFAULT 0,
+	total_count INTEGER DEFAULT 0,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX idx_lesson_attempts_user ON lesson_attempts(user_id, lesson_id);
@@ -0,0 +1 @@
+ALTER TABLE lesson_plans DROP COLUMN kind;
@@ -0,0 +1,4 @@
+ALTER TABLE lesson_plans ADD COLUMN kind TEXT NOT NULL DEFAULT 'curriculum';
+
+-- Plans saved before the column existed: roadmaps are the ones with sections
+UPDATE lesson_plans SET kind = 'roadmap' WHERE content LIKE '%"sections"%';
@@ -0,0 +1,15 @@
+CREATE TABLE sessions (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	refresh_token_hash TEXT NOT NULL UNIQUE,
+	previous_token_hash TEXT,
+	user_agent TEXT,
+	ip TEXT,
+	created_at TIMESTAMPTZ NOT NULL,
+	last_seen_at TIMESTAMPTZ NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	rotated_at TIMESTAMPTZ,
+	revoked_at TIMESTAMPTZ
+);
+CREATE INDEX idx_sessions_user ON sessions(user_id);
+CREATE INDEX idx_sessions_previous_token ON sessions(previous_token_hash);
@@ -0,0 +1,12 @@
+-- External login providers (google, github) linked to a local account.
+-- One user can have several; each provider account belongs to one user.
+CREATE TABLE user_identities (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	provider TEXT NOT NULL,
+	provider_user_id TEXT NOT NULL,
+	email TEXT,
+	created_at TIMESTAMPTZ NOT NULL,
+	UNIQUE(provider, provider_user_id)
+);
+CREATE INDEX idx_user_identities_user ON user_identities(user_id);
@@ -0,0 +1,14 @@
+-- Threaded replies on community posts. Deleting a comment only blanks it
+-- (deleted_at) so replies beneath it keep their place in the thread.
+CREATE TABLE comments (
+	id SERIAL PRIMARY KEY,
+	post_id INTEGER NOT NULL REFERENCES posts(id),
+	parent_id INTEGER REFERENCES comments(id),
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	author_name TEXT,
+	content TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ,
+	deleted_at TIMESTAMPTZ
+);
+CREATE INDEX idx_comments_post ON comments(post_id, created_at);
@@ -0,0 +1,10 @@
+-- One row per user per reaction type on a post. posts.likes is no longer
+-- written; the API derives it from this table.
+CREATE TABLE post_reactions (
+	post_id INTEGER NOT NULL REFERENCES posts(id),
+	user_id INTEGER NOT NULL REFERENCES users(id),
+	reaction TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (post_id, user_id, reaction)
+);
+CREATE INDEX idx_post_reactions_user ON post_reactions(user_id);
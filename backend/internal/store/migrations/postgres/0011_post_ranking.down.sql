@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_posts_user;
+DROP INDEX IF EXISTS idx_posts_trending;
+DROP INDEX IF EXISTS idx_posts_created;
+ALTER TABLE posts DROP COLUMN trending_score;
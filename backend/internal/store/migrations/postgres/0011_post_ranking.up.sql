@@ -0,0 +1,10 @@
+-- Rank for the trending sort, kept up to date by the store: log10 of the
+-- reaction count plus the creation time in units of 12.5 hours.
+ALTER TABLE posts ADD COLUMN trending_score DOUBLE PRECISION NOT NULL DEFAULT 0;
+UPDATE posts SET trending_score =
+	LOG(GREATEST((SELECT COUNT(*) FROM post_reactions r WHERE r.post_id = posts.id), 1))
+	+ EXTRACT(EPOCH FROM created_at) / 45000.0;
+
+CREATE INDEX idx_posts_created ON posts(created_at, id);
+CREATE INDEX idx_posts_trending ON posts(trending_score, id);
+CREATE INDEX idx_posts_user ON posts(user_id);
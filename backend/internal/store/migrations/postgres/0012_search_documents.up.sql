@@ -0,0 +1,19 @@
+-- Everything /api/search can find: one row per post, course, lesson and
+-- roadmap, written by the store together with its source row. visible_to is
+-- the only user who may see a document; NULL means everyone.
+CREATE TABLE search_documents (
+	id SERIAL PRIMARY KEY,
+	kind TEXT NOT NULL,
+	ref_id INTEGER NOT NULL,
+	parent_id INTEGER,
+	visible_to INTEGER REFERENCES users(id),
+	title TEXT NOT NULL,
+	body TEXT NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL,
+	search_vector TSVECTOR GENERATED ALWAYS AS (
+		setweight(to_tsvector('english', title), 'A') || setweight(to_tsvector('english', body), 'B')
+	) STORED,
+	UNIQUE (kind, ref_id)
+);
+CREATE INDEX idx_search_documents_parent ON search_documents(kind, parent_id);
+CREATE INDEX idx_search_documents_vector ON search_documents USING GIN (search_vector);
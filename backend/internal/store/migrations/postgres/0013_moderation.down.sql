@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS audit_log;
+DROP TABLE IF EXISTS post_reports;
+ALTER TABLE posts DROP COLUMN locked_at;
+ALTER TABLE posts DROP COLUMN status;
+ALTER TABLE users DROP COLUMN suspension_reason;
+ALTER TABLE users DROP COLUMN suspended_until;
+ALTER TABLE users DROP COLUMN role;
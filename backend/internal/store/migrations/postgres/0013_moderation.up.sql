@@ -0,0 +1,35 @@
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'learner';
+ALTER TABLE users ADD COLUMN suspended_until TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN suspension_reason TEXT;
+
+-- status is visible, hidden or removed; a locked post takes no new comments
+ALTER TABLE posts ADD COLUMN status TEXT NOT NULL DEFAULT 'visible';
+ALTER TABLE posts ADD COLUMN locked_at TIMESTAMPTZ;
+
+-- One report per user per post; moderators resolve or dismiss them
+CREATE TABLE post_reports (
+	id SERIAL PRIMARY KEY,
+	post_id INTEGER NOT NULL REFERENCES posts(id),
+	reporter_id INTEGER NOT NULL REFERENCES users(id),
+	reason TEXT NOT NULL,
+	details TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'open',
+	created_at TIMESTAMPTZ NOT NULL,
+	resolved_at TIMESTAMPTZ,
+	resolved_by INTEGER REFERENCES users(id),
+	UNIQUE (post_id, reporter_id)
+);
+CREATE INDEX idx_post_reports_status ON post_reports(status, post_id);
+
+-- Every moderation or administrative action, newest last
+CREATE TABLE audit_log (
+	id SERIAL PRIMARY KEY,
+	actor_id INTEGER NOT NULL REFERENCES users(id),
+	action TEXT NOT NULL,
+	target_type TEXT NOT NULL,
+	target_id INTEGER NOT NULL,
+	reason TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_audit_log_target ON audit_log(target_type, target_id);
+CREATE INDEX idx_audit_log_actor ON audit_log(actor_id);
@@ -0,0 +1,24 @@
+-- status is visible, pending (held for review) or removed
+ALTER TABLE comments ADD COLUMN status TEXT NOT NULL DEFAULT 'visible';
+
+-- The automated screening of each new post and comment. Pending decisions
+-- wait for a moderator; content_hash spots the same text posted again.
+CREATE TABLE moderation_decisions (
+	id SERIAL PRIMARY KEY,
+	content_type TEXT NOT NULL,
+	content_id INTEGER NOT NULL,
+	author_id INTEGER NOT NULL REFERENCES users(id),
+	verdict TEXT NOT NULL,
+	score DOUBLE PRECISION NOT NULL DEFAULT 0,
+	labels TEXT NOT NULL DEFAULT '{}',
+	reasons TEXT NOT NULL DEFAULT '[]',
+	classifier TEXT NOT NULL DEFAULT '',
+	content_hash TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	reviewed_at TIMESTAMPTZ,
+	reviewed_by INTEGER REFERENCES users(id),
+	review_action TEXT,
+	UNIQUE (content_type, content_id)
+);
+CREATE INDEX idx_moderation_decisions_pending ON moderation_decisions(verdict, reviewed_at);
+CREATE INDEX idx_moderation_decisions_hash ON moderation_decisions(author_id, content_hash, created_at);
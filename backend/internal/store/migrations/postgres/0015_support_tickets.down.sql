@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS ticket_messages;
+DROP INDEX IF EXISTS idx_contact_submissions_user;
+DROP INDEX IF EXISTS idx_contact_submissions_status;
+ALTER TABLE contact_submissions DROP COLUMN updated_at;
+ALTER TABLE contact_submissions DROP COLUMN suggested_reply;
+ALTER TABLE contact_submissions DROP COLUMN assignee_id;
+ALTER TABLE contact_submissions DROP COLUMN status;
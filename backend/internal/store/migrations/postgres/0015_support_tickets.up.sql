@@ -0,0 +1,22 @@
+-- Each contact submission is a support ticket: open while staff owe a reply,
+-- pending while waiting on the submitter, resolved once done.
+-- suggested_reply holds the editable AI draft until staff send a reply.
+ALTER TABLE contact_submissions ADD COLUMN status TEXT NOT NULL DEFAULT 'open';
+ALTER TABLE contact_submissions ADD COLUMN assignee_id INTEGER REFERENCES users(id);
+ALTER TABLE contact_submissions ADD COLUMN suggested_reply TEXT NOT NULL DEFAULT '';
+ALTER TABLE contact_submissions ADD COLUMN updated_at TIMESTAMPTZ;
+UPDATE contact_submissions SET updated_at = COALESCE(created_at, CURRENT_TIMESTAMP);
+CREATE INDEX idx_contact_submissions_status ON contact_submissions(status, id);
+CREATE INDEX idx_contact_submissions_user ON contact_submissions(user_id);
+
+-- Replies on a ticket after the original message, from staff or the submitter
+CREATE TABLE ticket_messages (
+	id SERIAL PRIMARY KEY,
+	ticket_id INTEGER NOT NULL REFERENCES contact_submissions(id),
+	author_id INTEGER NOT NULL REFERENCES users(id),
+	from_staff BOOLEAN NOT NULL DEFAULT FALSE,
+	body TEXT NOT NULL,
+	emailed_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX idx_ticket_messages_ticket ON ticket_messages(ticket_id, id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS contact_submissions;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS lesson_plans;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,13 @@
+-- SQLite cannot drop a column that takes part in a foreign key, so rebuild the table.
+CREATE TABLE contact_submissions_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	first_name TEXT,
+	last_name TEXT,
+	email TEXT,
+	message TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO contact_submissions_old (id, first_name, last_name, email, message, created_at)
+	SELECT id, first_name, last_name, email, message, created_at FROM contact_submissions;
+DROP TABLE contact_submissions;
+ALTER TABLE contact_submissions_old RENAME TO contact_submissions;
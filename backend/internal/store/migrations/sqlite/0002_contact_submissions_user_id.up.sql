@@ -0,0 +1 @@
+ALTER TABLE contact_submissions ADD COLUMN user_id INTEGER REFERENCES users(id);
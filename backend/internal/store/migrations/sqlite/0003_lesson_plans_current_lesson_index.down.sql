@@ -0,0 +1 @@
+ALTER TABLE lesson_plans DROP COLUMN current_lesson_index;
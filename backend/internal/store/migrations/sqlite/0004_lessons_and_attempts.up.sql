@@ -0,0 +1,25 @@
+CREATE TABLE lessons (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	plan_id INTEGER NOT NULL,
+	lesson_index INTEGER NOT NULL,
+	title TEXT,
+	language TEXT,
+	exercise_prompt TEXT,
+	exercise_tests TEXT,
+	FOREIGN KEY(plan_id) REFERENCES lesson_plans(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_lessons_plan ON lessons(plan_id, lesson_index);
+
+CREATE TABLE lesson_attempts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	lesson_id INTEGER NOT NULL,
+	code TEXT,
+	passed BOOLEAN DEFAULT 0,
+	passed_count INTEGER DEFAULT 0,
+	total_count INTEGER DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY(user_id) REFERENCES users(id),
+	FOREIGN KEY(lesson_id) REFERENCES lessons(id) ON DELETE CASCADE
+);
+CREATE INDEX idx_lesson_attempts_user ON lesson_attempts(user_id, lesson_id);
@@ -0,0 +1,16 @@
+CREATE TABLE sessions (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	refresh_token_hash TEXT NOT NULL UNIQUE,
+	previous_token_hash TEXT,
+	user_agent TEXT,
+	ip TEXT,
+	created_at DATETIME NOT NULL,
+	last_seen_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL,
+	rotated_at DATETIME,
+	revoked_at DATETIME,
+	FOREIGN KEY(user_id) REFERENCES users(id)
+);
+CREATE INDEX idx_sessions_user ON sessions(user_id);
+CREATE INDEX idx_sessions_previous_token ON sessions(previous_token_hash);
@@ -0,0 +1,15 @@
+ALTER TABLE users ADD COLUMN email_verified_at DATETIME;
+
+-- Single-use tokens mailed to the user (password reset, email verification).
+-- Only the SHA-256 hash of the token is stored.
+CREATE TABLE account_tokens (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	purpose TEXT NOT NULL,
+	token_hash TEXT NOT NULL UNIQUE,
+	created_at DATETIME NOT NULL,
+	expires_at DATETIME NOT NULL,
+	used_at DATETIME,
+	FOREIGN KEY(user_id) REFERENCES users(id)
+);
+CREATE INDEX idx_account_tokens_user ON account_tokens(user_id, purpose);
@@ -0,0 +1,13 @@
+-- External login providers (google, github) linked to a local account.
+-- One user can have several; each provider account belongs to one user.
+CREATE TABLE user_identities (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INTEGER NOT NULL,
+	provider TEXT NOT NULL,
+	provider_user_id TEXT NOT NULL,
+	email TEXT,
+	created_at DATETIME NOT NULL,
+	UNIQUE(provider, provider_user_id),
+	FOREIGN KEY(user_id) REFERENCES users(id)
+);
+CREATE INDEX idx_user_identities_user ON user_identities(user_id);
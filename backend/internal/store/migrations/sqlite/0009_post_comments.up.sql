@@ -0,0 +1,17 @@
+-- Threaded replies on community posts. Deleting a comment only blanks it
+-- (deleted_at) so replies beneath it keep their place in the thread.
+CREATE TABLE comments (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	post_id INTEGER NOT NULL,
+	parent_id INTEGER,
+	user_id INTEGER NOT NULL,
+	author_name TEXT,
+	content TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME,
+	deleted_at DATETIME,
+	FOREIGN KEY(post_id) REFERENCES posts(id),
+	FOREIGN KEY(parent_id) REFERENCES comments(id),
+	FOREIGN KEY(user_id) REFERENCES users(id)
+);
+CREATE INDEX idx_comments_post ON comments(post_id, created_at);
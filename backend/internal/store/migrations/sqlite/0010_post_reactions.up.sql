@@ -0,0 +1,12 @@
+-- One row per user per reaction type on a post. posts.likes is no longer
+-- written; the API derives it from this table.
+CREATE TABLE post_reactions (
+	post_id INTEGER NOT NULL,
+	user_id INTEGER NOT NULL,
+	reaction TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY (post_id, user_id, reaction),
+	FOREIGN KEY(post_id) REFERENCES posts(id),
+	FOREIGN KEY(user_id) REFERENCES users(id)
+);
+CREATE INDEX idx_post_reactions_user ON post_reactions(user_id);
@@ -0,0 +1,15 @@
+-- Posts used to take created_at from CURRENT_TIMESTAMP, which has no zone
+-- suffix and so compares unevenly with the values the driver writes. Bring
+-- them to the driver's format so listing cursors compare correctly.
+UPDATE posts SET created_at = created_at || '+00:00' WHERE length(created_at) = 19;
+
+-- Rank for the trending sort, kept up to date by the store: log10 of the
+-- reaction count plus the creation time in units of 12.5 hours. SQLite has no
+-- log10, so the store recomputes existing posts' scores in Go as part of this
+-- migration; the value set here is only a placeholder.
+ALTER TABLE posts ADD COLUMN trending_score REAL NOT NULL DEFAULT 0;
+UPDATE posts SET trending_score = (julianday(created_at) - 2440587.5) * 86400.0 / 45000.0;
+
+CREATE INDEX idx_posts_created ON posts(created_at, id);
+CREATE INDEX idx_posts_trending ON posts(trending_score, id);
+CREATE INDEX idx_posts_user ON posts(user_id);
@@ -0,0 +1,18 @@
+-- Everything /api/search can find: one row per post, course, lesson and
+-- roadmap, written by the store together with its source row. visible_to is
+-- the only user who may see a document; NULL means everyone.
+--
+-- The FTS5 index over this table is built at startup instead of here, as the
+-- driver only has FTS5 when compiled with the sqlite_fts5 build tag.
+CREATE TABLE search_documents (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	kind TEXT NOT NULL,
+	ref_id INTEGER NOT NULL,
+	parent_id INTEGER,
+	visible_to INTEGER,
+	title TEXT NOT NULL,
+	body TEXT NOT NULL,
+	updated_at DATETIME NOT NULL,
+	UNIQUE (kind, ref_id)
+);
+CREATE INDEX idx_search_documents_parent ON search_documents(kind, parent_id);
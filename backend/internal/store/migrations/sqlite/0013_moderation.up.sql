@@ -0,0 +1,38 @@
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'learner';
+ALTER TABLE users ADD COLUMN suspended_until DATETIME;
+ALTER TABLE users ADD COLUMN suspension_reason TEXT;
+
+-- status is visible, hidden or removed; a locked post takes no new comments
+ALTER TABLE posts ADD COLUMN status TEXT NOT NULL DEFAULT 'visible';
+ALTER TABLE posts ADD COLUMN locked_at DATETIME;
+
+-- One report per user per post; moderators resolve or dismiss them
+CREATE TABLE post_reports (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	post_id INTEGER NOT NULL,
+	reporter_id INTEGER NOT NULL,
+	reason TEXT NOT NULL,
+	details TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'open',
+	created_at DATETIME NOT NULL,
+	resolved_at DATETIME,
+	resolved_by INTEGER,
+	UNIQUE (post_id, reporter_id),
+	FOREIGN KEY(post_id) REFERENCES posts(id),
+	FOREIGN KEY(reporter_id) REFERENCES users(id)
+);
+CREATE INDEX idx_post_reports_status ON post_reports(status, post_id);
+
+-- Every moderation or administrative action, newest last
+CREATE TABLE audit_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	actor_id INTEGER NOT NULL,
+	action TEXT NOT NULL,
+	target_type TEXT NOT NULL,
+	target_id INTEGER NOT NULL,
+	reason TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY(actor_id) REFERENCES users(id)
+);
+CREATE INDEX idx_audit_log_target ON audit_log(target_type, target_id);
+CREATE INDEX idx_audit_log_actor ON audit_log(actor_id);
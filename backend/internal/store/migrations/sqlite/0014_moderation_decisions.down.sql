@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS moderation_decisions;
+ALTER TABLE comments DROP COLUMN status;
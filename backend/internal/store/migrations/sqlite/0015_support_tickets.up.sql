@@ -0,0 +1,24 @@
+-- Each contact submission is a support ticket: open while staff owe a reply,
+-- pending while waiting on the submitter, resolved once done.
+-- suggested_reply holds the editable AI draft until staff send a reply.
+ALTER TABLE contact_submissions ADD COLUMN status TEXT NOT NULL DEFAULT 'open';
+ALTER TABLE contact_submissions ADD COLUMN assignee_id INTEGER;
+ALTER TABLE contact_submissions ADD COLUMN suggested_reply TEXT NOT NULL DEFAULT '';
+ALTER TABLE contact_submissions ADD COLUMN updated_at DATETIME;
+UPDATE contact_submissions SET updated_at = COALESCE(created_at, CURRENT_TIMESTAMP);
+CREATE INDEX idx_contact_submissions_status ON contact_submissions(status, id);
+CREATE INDEX idx_contact_submissions_user ON contact_submissions(user_id);
+
+-- Replies on a ticket after the original message, from staff or the submitter
+CREATE TABLE ticket_messages (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	ticket_id INTEGER NOT NULL,
+	author_id INTEGER NOT NULL,
+	from_staff BOOLEAN NOT NULL DEFAULT 0,
+	body TEXT NOT NULL,
+	emailed_at DATETIME,
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY(ticket_id) REFERENCES contact_submissions(id),
+	FOREIGN KEY(author_id) REFERENCES users(id)
+);
+CREATE INDEX idx_ticket_messages_ticket ON ticket_messages(ticket_id, id);
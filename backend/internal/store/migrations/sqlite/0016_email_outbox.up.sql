@@ -0,0 +1,18 @@
+-- Outgoing email, rendered when queued and sent by a background worker.
+-- Failed sends are retried with backoff until they go out or, after too
+-- many attempts, are left as dead letters for an admin to retry.
+CREATE TABLE email_outbox (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	recipient TEXT NOT NULL,
+	template TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	html_body TEXT NOT NULL,
+	text_body TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_attempt_at DATETIME NOT NULL,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	sent_at DATETIME
+);
+CREATE INDEX idx_email_outbox_due ON email_outbox(status, next_attempt_at);
@@ -0,0 +1,9 @@
+-- AI requests per user per UTC day (YYYY-MM-DD), for the daily and monthly
+-- quotas. A month's usage is the sum of its days.
+CREATE TABLE ai_usage (
+	user_id INTEGER NOT NULL,
+	day TEXT NOT NULL,
+	requests INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id, day),
+	FOREIGN KEY(user_id) REFERENCES users(id)
+);
@@ -0,0 +1,577 @@
+package store
+
+import (
+	"codefuture-backend/internal/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ErrAlreadyReported is returned by CreateReport when the user has already
+// reported the post.
+var ErrAlreadyReported = errors.New("post already reported")
+
+// ErrPostRemoved is returned by ApplyPostAction for a removed post, which no
+// action can bring back.
+var ErrPostRemoved = errors.New("post has been removed")
+
+// CreateReport stores a report and fills in its ID, Status and CreatedAt.
+func (s *Store) CreateReport(r *models.Report) error {
+	if s.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	r.Status = models.ReportStatusOpen
+	r.CreatedAt = time.Now().UTC()
+	id, err := s.conn().insert(`
+		INSERT INTO post_reports (post_id, reporter_id, reason, details, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		r.PostID, r.ReporterID, r.Reason, r.Details, r.Status, r.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrAlreadyReported
+	}
+	if err != nil {
+		return err
+	}
+	r.ID = id
+	return nil
+}
+
+// GetModerationQueue returns posts with open reports, most reported first,
+// then those waiting longest.
+func (s *Store) GetModerationQueue(limit int) ([]models.ModerationQueueItem, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	query := `
+		SELECT ` + postColumns + ` FROM posts
+		JOIN (
+			SELECT post_id, COUNT(*) AS open_reports, MIN(created_at) AS first_reported
+			FROM post_reports WHERE status = ? GROUP BY post_id
+		) q ON q.post_id = posts.id
+		ORDER BY q.open_reports DESC, q.first_reported, posts.id`
+	args := []interface{}{models.ReportStatusOpen}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := s.conn().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.ModerationQueueItem{}
+	index := map[int]int{}
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return nil, err
+		}
+		index[p.ID] = len(items)
+		items = append(items, models.ModerationQueueItem{Post: *p, Reports: []models.Report{}})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if len(items) == 0 {
+		return items, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(items)), ", ")
+	args = []interface{}{models.ReportStatusOpen}
+	for _, it := range items {
+		args = append(args, it.Post.ID)
+	}
+	rows, err = s.conn().Query(`SELECT `+reportColumns+` FROM post_reports
+		WHERE status = ? AND post_id IN (`+placeholders+`)
+		ORDER BY created_at, id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r, err := scanReport(rows)
+		if err != nil {
+			return nil, err
+		}
+		it := &items[index[r.PostID]]
+		it.Reports = append(it.Reports, *r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	posts := make([]models.Post, len(items))
+	for i := range items {
+		posts[i] = items[i].Post
+	}
+	if err := s.attachReactions(posts, 0); err != nil {
+		return nil, err
+	}
+	for i := range items {
+		items[i].Post = posts[i]
+	}
+	return items, nil
+}
+
+const reportColumns = `id, post_id, reporter_id, reason, details, status, created_at, resolved_at, resolved_by`
+
+func scanReport(row rowScanner) (*models.Report, error) {
+	var r models.Report
+	var resolvedAt sql.NullTime
+	var resolvedBy sql.NullInt64
+	if err := row.Scan(&r.ID, &r.PostID, &r.ReporterID, &r.Reason, &r.Details, &r.Status, &r.CreatedAt, &resolvedAt, &resolvedBy); err != nil {
+		return nil, err
+	}
+	if resolvedAt.Valid {
+		r.ResolvedAt = &resolvedAt.Time
+	}
+	if resolvedBy.Valid {
+		id := int(resolvedBy.Int64)
+		r.ResolvedBy = &id
+	}
+	return &r, nil
+}
+
+// ApplyPostAction carries out a moderator's action on a post, closes its
+// open reports and records the action in the audit log, all at once. Dismiss
+// closes the reports as dismissed; any other action resolves them, except
+// unhide, approve and unlock, which leave them open. Actions that publish or
+// take down a post held by screening mark its decision reviewed. It returns
+// the post as it now stands, or nil if there is no such post.
+func (s *Store) ApplyPostAction(postID, actorID int, action, reason string, at time.Time) (*models.Post, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	p, err := scanPost(c.QueryRow(`SELECT `+postColumns+` FROM posts WHERE id = ?`, postID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.Status == models.PostStatusRemoved {
+		return nil, ErrPostRemoved
+	}
+	at = at.UTC()
+
+	reportStatus := models.ReportStatusResolved
+	reviewed := false
+	switch action {
+	case models.PostActionHide, models.PostActionRemove:
+		status := models.PostStatusHidden
+		if action == models.PostActionRemove {
+			status = models.PostStatusRemoved
+		}
+		if _, err := c.Exec("UPDATE posts SET status = ? WHERE id = ?", status, postID); err != nil {
+			return nil, err
+		}
+		if err := s.unindexDocuments(c, "kind = ? AND ref_id = ?", models.SearchTypePost, postID); err != nil {
+			return nil, err
+		}
+		reviewed = true
+	case models.PostActionUnhide, models.ContentActionApprove:
+		if _, err := c.Exec("UPDATE posts SET status = ? WHERE id = ?", models.PostStatusVisible, postID); err != nil {
+			return nil, err
+		}
+		if err := s.indexDocument(c, postDocument(p)); err != nil {
+			return nil, err
+		}
+		reportStatus = ""
+		reviewed = true
+	case models.PostActionLock:
+		if _, err := c.Exec("UPDATE posts SET locked_at = ? WHERE id = ? AND locked_at IS NULL", at, postID); err != nil {
+			return nil, err
+		}
+	case models.PostActionUnlock:
+		if _, err := c.Exec("UPDATE posts SET locked_at = NULL WHERE id = ?", postID); err != nil {
+			return nil, err
+		}
+		reportStatus = ""
+	case models.PostActionDismiss:
+		reportStatus = models.ReportStatusDismissed
+	default:
+		return nil, fmt.Errorf("unknown post action %q", action)
+	}
+
+	if reportStatus != "" {
+		_, err := c.Exec("UPDATE post_reports SET status = ?, resolved_at = ?, resolved_by = ? WHERE post_id = ? AND status = ?",
+			reportStatus, at, actorID, postID, models.ReportStatusOpen)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if reviewed {
+		if err := markDecisionReviewed(c, models.ContentTypePost, postID, actorID, action, at); err != nil {
+			return nil, err
+		}
+	}
+	err = writeAudit(c, &models.AuditEntry{ActorID: actorID, Action: action, TargetType: models.AuditTargetPost, TargetID: postID, Reason: reason, CreatedAt: at})
+	if err != nil {
+		return nil, err
+	}
+
+	p, err = scanPost(c.QueryRow(`SELECT `+postColumns+` FROM posts WHERE id = ?`, postID))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	posts := []models.Post{*p}
+	if err := s.attachReactions(posts, 0); err != nil {
+		return nil, err
+	}
+	return &posts[0], nil
+}
+
+// ApplyCommentAction approves a comment held by screening or removes a
+// comment, leaving a deleted placeholder so replies stay attached, and
+// records the action in the audit log. It returns the comment as it now
+// stands, or nil if there is no such comment.
+func (s *Store) ApplyCommentAction(commentID, actorID int, action, reason string, at time.Time) (*models.Comment, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	comment, err := scanComment(c.QueryRow(`SELECT `+commentColumns+` FROM comments WHERE id = ?`, commentID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	at = at.UTC()
+
+	switch action {
+	case models.ContentActionApprove:
+		_, err = c.Exec("UPDATE comments SET status = ? WHERE id = ? AND status = ?", models.CommentStatusVisible, commentID, models.CommentStatusPending)
+	case models.ContentActionRemove:
+		_, err = c.Exec("UPDATE comments SET status = ?, content = '', deleted_at = COALESCE(deleted_at, ?) WHERE id = ?", models.CommentStatusRemoved, at, commentID)
+	default:
+		err = fmt.Errorf("unknown comment action %q", action)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := markDecisionReviewed(c, models.ContentTypeComment, commentID, actorID, action, at); err != nil {
+		return nil, err
+	}
+	err = writeAudit(c, &models.AuditEntry{ActorID: actorID, Action: action, TargetType: models.AuditTargetComment, TargetID: commentID, Reason: reason, CreatedAt: at})
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err = scanComment(c.QueryRow(`SELECT `+commentColumns+` FROM comments WHERE id = ?`, commentID))
+	if err != nil {
+		return nil, err
+	}
+	return comment, tx.Commit()
+}
+
+// SaveModerationDecision stores the screening decision for a post or
+// comment and fills in its ID.
+func (s *Store) SaveModerationDecision(d *models.ModerationDecision) error {
+	if s.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	labels, err := json.Marshal(d.Labels)
+	if err != nil {
+		return err
+	}
+	reasons, err := json.Marshal(d.Reasons)
+	if err != nil {
+		return err
+	}
+	id, err := s.conn().insert(`
+		INSERT INTO moderation_decisions (content_type, content_id, author_id, verdict, score, labels, reasons, classifier, content_hash, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.ContentType, d.ContentID, d.AuthorID, d.Verdict, d.Score, string(labels), string(reasons), d.Classifier, d.ContentHash, d.CreatedAt.UTC())
+	if err != nil {
+		return err
+	}
+	d.ID = id
+	return nil
+}
+
+// CountDuplicateContent counts the author's screened posts and comments
+// since the given time whose text hashed to contentHash.
+func (s *Store) CountDuplicateContent(authorID int, contentHash string, since time.Time) (int, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("database not connected")
+	}
+	var n int
+	err := s.conn().QueryRow(`
+		SELECT COUNT(*) FROM moderation_decisions
+		WHERE author_id = ? AND content_hash = ? AND created_at >= ?`,
+		authorID, contentHash, since.UTC()).Scan(&n)
+	return n, err
+}
+
+// GetPendingContent returns posts and comments held by screening that no
+// moderator has reviewed yet, oldest first.
+func (s *Store) GetPendingContent(limit int) ([]models.PendingItem, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	query := `SELECT ` + decisionColumns + ` FROM moderation_decisions
+		WHERE verdict = ? AND reviewed_at IS NULL
+		ORDER BY created_at, id`
+	args := []interface{}{models.VerdictPending}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := s.conn().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var decisions []models.ModerationDecision
+	for rows.Next() {
+		d, err := scanDecision(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		decisions = append(decisions, *d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	items := []models.PendingItem{}
+	for _, d := range decisions {
+		item := models.PendingItem{Decision: d}
+		switch d.ContentType {
+		case models.ContentTypePost:
+			item.Post, err = s.GetPostByID(d.ContentID)
+			if item.Post != nil {
+				item.Post.Reactions = map[string]int{}
+			}
+		case models.ContentTypeComment:
+			item.Comment, err = s.GetCommentByID(d.ContentID)
+			if item.Comment != nil {
+				item.Comment.Replies = []*models.Comment{}
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		// The content may have been deleted by its author since
+		if item.Post != nil || item.Comment != nil {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// GetModerationDecision returns the screening decision for a post or
+// comment, or nil if it wasn't screened.
+func (s *Store) GetModerationDecision(contentType string, contentID int) (*models.ModerationDecision, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	d, err := scanDecision(s.conn().QueryRow(`SELECT `+decisionColumns+` FROM moderation_decisions WHERE content_type = ? AND content_id = ?`, contentType, contentID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return d, err
+}
+
+const decisionColumns = `id, content_type, content_id, author_id, verdict, score, labels, reasons, classifier, created_at, reviewed_at, reviewed_by, review_action`
+
+func scanDecision(row rowScanner) (*models.ModerationDecision, error) {
+	var d models.ModerationDecision
+	var labels, reasons string
+	var reviewedAt sql.NullTime
+	var reviewedBy sql.NullInt64
+	var reviewAction sql.NullString
+	err := row.Scan(&d.ID, &d.ContentType, &d.ContentID, &d.AuthorID, &d.Verdict, &d.Score, &labels, &reasons, &d.Classifier, &d.CreatedAt, &reviewedAt, &reviewedBy, &reviewAction)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(labels), &d.Labels); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(reasons), &d.Reasons); err != nil {
+		return nil, err
+	}
+	if reviewedAt.Valid {
+		d.ReviewedAt = &reviewedAt.Time
+	}
+	if reviewedBy.Valid {
+		id := int(reviewedBy.Int64)
+		d.ReviewedBy = &id
+	}
+	d.ReviewAction = reviewAction.String
+	return &d, nil
+}
+
+// markDecisionReviewed records the moderator's call on content held by screening.
+func markDecisionReviewed(c conn, contentType string, contentID, actorID int, action string, at time.Time) error {
+	_, err := c.Exec(`
+		UPDATE moderation_decisions SET reviewed_at = ?, reviewed_by = ?, review_action = ?
+		WHERE content_type = ? AND content_id = ? AND verdict = ? AND reviewed_at IS NULL`,
+		at.UTC(), actorID, action, contentType, contentID, models.VerdictPending)
+	return err
+}
+
+// SuspendUser bars the user from signing in until the given time and revokes
+// their sessions. It reports false if there is no such user.
+func (s *Store) SuspendUser(userID, actorID int, until time.Time, reason string, at time.Time) (bool, error) {
+	if s.db == nil {
+		return false, fmt.Errorf("database not connected")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	res, err := c.Exec("UPDATE users SET suspended_until = ?, suspension_reason = ? WHERE id = ?", until.UTC(), reason, userID)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	if _, err := c.Exec("UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL", at.UTC(), userID); err != nil {
+		return false, err
+	}
+	err = writeAudit(c, &models.AuditEntry{ActorID: actorID, Action: models.AuditActionSuspend, TargetType: models.AuditTargetUser, TargetID: userID, Reason: reason, CreatedAt: at})
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// LiftSuspension lets a suspended user sign in again. It reports false if
+// there is no such user or they weren't suspended.
+func (s *Store) LiftSuspension(userID, actorID int, reason string, at time.Time) (bool, error) {
+	if s.db == nil {
+		return false, fmt.Errorf("database not connected")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	res, err := c.Exec("UPDATE users SET suspended_until = NULL, suspension_reason = NULL WHERE id = ? AND suspended_until > ?", userID, at.UTC())
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+	err = writeAudit(c, &models.AuditEntry{ActorID: actorID, Action: models.AuditActionUnsuspend, TargetType: models.AuditTargetUser, TargetID: userID, Reason: reason, CreatedAt: at})
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// SetUserRole changes the user's role. It reports false if there is no such user.
+func (s *Store) SetUserRole(userID int, role string) (bool, error) {
+	if s.db == nil {
+		return false, fmt.Errorf("database not connected")
+	}
+	res, err := s.conn().Exec("UPDATE users SET role = ? WHERE id = ?", role, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// writeAudit appends an entry to the audit log and fills in its ID.
+func writeAudit(c conn, e *models.AuditEntry) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	e.CreatedAt = e.CreatedAt.UTC()
+	id, err := c.insert(`
+		INSERT INTO audit_log (actor_id, action, target_type, target_id, reason, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		e.ActorID, e.Action, e.TargetType, e.TargetID, e.Reason, e.CreatedAt)
+	if err != nil {
+		return err
+	}
+	e.ID = id
+	return nil
+}
+
+// ListAuditLog returns audit entries matching q, newest first.
+func (s *Store) ListAuditLog(q models.AuditQuery) ([]models.AuditEntry, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	var where []string
+	var args []interface{}
+	if q.ActorID != 0 {
+		where = append(where, "a.actor_id = ?")
+		args = append(args, q.ActorID)
+	}
+	if q.TargetType != "" {
+		where = append(where, "a.target_type = ?")
+		args = append(args, q.TargetType)
+	}
+	if q.TargetID != 0 {
+		where = append(where, "a.target_id = ?")
+		args = append(args, q.TargetID)
+	}
+	if q.BeforeID != 0 {
+		where = append(where, "a.id < ?")
+		args = append(args, q.BeforeID)
+	}
+
+	query := `
+		SELECT a.id, a.actor_id, COALESCE(u.name, ''), a.action, a.target_type, a.target_id, a.reason, a.created_at
+		FROM audit_log a LEFT JOIN users u ON u.id = a.actor_id`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY a.id DESC`
+	if q.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.conn().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := rows.Scan(&e.ID, &e.ActorID, &e.ActorName, &e.Action, &e.TargetType, &e.TargetID, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
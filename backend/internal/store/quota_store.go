@@ -0,0 +1,73 @@
+package store
+
+import (
+	"codefuture-backend/internal/models"
+	"fmt"
+	"time"
+)
+
+// GetAIQuota returns the user's AI usage for the UTC day and month containing
+// at, against the given limits.
+func (s *Store) GetAIQuota(userID int, at time.Time, dailyLimit, monthlyLimit int) (*models.AIQuota, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	return aiQuota(s.conn(), userID, at, dailyLimit, monthlyLimit)
+}
+
+// UseAIQuota counts one AI request for the user unless it would go over a
+// limit. It returns the usage including this request, or as it stood if the
+// request was refused, and whether it was allowed.
+func (s *Store) UseAIQuota(userID int, at time.Time, dailyLimit, monthlyLimit int) (*models.AIQuota, bool, error) {
+	if s.db == nil {
+		return nil, false, fmt.Errorf("database not connected")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	// Count first so concurrent requests queue on the row instead of all
+	// passing the check, then take it back if that went over
+	if _, err := c.Exec(`
+		INSERT INTO ai_usage (user_id, day, requests) VALUES (?, ?, 1)
+		ON CONFLICT (user_id, day) DO UPDATE SET requests = ai_usage.requests + 1`,
+		userID, at.UTC().Format(time.DateOnly)); err != nil {
+		return nil, false, err
+	}
+	q, err := aiQuota(c, userID, at, dailyLimit, monthlyLimit)
+	if err != nil {
+		return nil, false, err
+	}
+	if (dailyLimit > 0 && q.DailyUsed > dailyLimit) || (monthlyLimit > 0 && q.MonthlyUsed > monthlyLimit) {
+		q.DailyUsed--
+		q.MonthlyUsed--
+		return q, false, nil
+	}
+	return q, true, tx.Commit()
+}
+
+func aiQuota(c conn, userID int, at time.Time, dailyLimit, monthlyLimit int) (*models.AIQuota, error) {
+	at = at.UTC()
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+	month := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, time.UTC)
+	q := &models.AIQuota{
+		DailyLimit:    dailyLimit,
+		MonthlyLimit:  monthlyLimit,
+		DayResetsAt:   day.AddDate(0, 0, 1),
+		MonthResetsAt: month.AddDate(0, 1, 0),
+	}
+
+	// Days are stored as YYYY-MM-DD, so they compare as strings
+	err := c.QueryRow(`
+		SELECT COALESCE(SUM(CASE WHEN day = ? THEN requests ELSE 0 END), 0), COALESCE(SUM(requests), 0)
+		FROM ai_usage WHERE user_id = ? AND day >= ? AND day < ?`,
+		day.Format(time.DateOnly), userID, month.Format(time.DateOnly), q.MonthResetsAt.Format(time.DateOnly)).
+		Scan(&q.DailyUsed, &q.MonthlyUsed)
+	if err != nil {
+		return nil, err
+	}
+	return q, nil
+}
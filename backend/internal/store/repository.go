@@ -0,0 +1,184 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"codefuture-backend/internal/models"
+)
+
+// ErrEmailExists is returned by CreateUser when the email is already registered.
+var ErrEmailExists = errors.New("email already exists")
+
+// ErrIdentityExists is returned by LinkIdentity when the provider account is
+// already linked to a user.
+var ErrIdentityExists = errors.New("identity already linked")
+
+// UserRepository persists accounts.
+type UserRepository interface {
+	CreateUser(user *models.User) error
+	GetUserByEmail(email string) (*models.User, error)
+	GetUserByID(id int) (*models.User, error)
+	MarkEmailVerified(userID int, at time.Time) error
+	UpdateUserPassword(userID int, passwordHash string) error
+}
+
+// IdentityRepository persists links between users and external login providers.
+type IdentityRepository interface {
+	GetUserByIdentity(provider, providerUserID string) (*models.User, error)
+	LinkIdentity(identity *models.UserIdentity) error
+	ListIdentities(userID int) ([]models.UserIdentity, error)
+}
+
+// AccountTokenRepository persists the single-use tokens behind password
+// reset and email verification links.
+type AccountTokenRepository interface {
+	CreateAccountToken(userID int, purpose, tokenHash string, createdAt, expiresAt time.Time) error
+	ConsumeAccountToken(purpose, tokenHash string, now time.Time) (int, error)
+	InvalidateAccountTokens(userID int, purpose string, at time.Time) error
+	LatestAccountTokenAt(userID int, purpose string) (time.Time, error)
+}
+
+// SessionRepository persists login sessions and their refresh token hashes.
+type SessionRepository interface {
+	CreateSession(sess *models.Session, refreshHash string) error
+	GetSessionByID(id int) (*models.Session, error)
+	GetSessionByRefreshHash(hash string) (*models.Session, error)
+	GetSessionByPreviousHash(hash string) (*models.Session, error)
+	RotateSession(id int, oldHash, newHash string, expiresAt, now time.Time) (bool, error)
+	TouchSession(id int, at time.Time) error
+	ListSessions(userID int) ([]models.Session, error)
+	RevokeSession(userID, id int, at time.Time) (bool, error)
+	RevokeAllSessions(userID int, at time.Time) error
+}
+
+// LessonPlanRepository persists generated curricula/roadmaps and learner progress.
+type LessonPlanRepository interface {
+	SaveLessonPlan(userID *int, kind, persona, goals, content string) (int, error)
+	GetCoursesByUserID(userID int) ([]map[string]interface{}, error)
+	GetLatestLessonPlan(userID int) (*models.LessonPlan, error)
+	GetLessonPlanByID(planID int) (*models.LessonPlan, error)
+	UpdateLessonProgress(planID int, lessonIndex int) error
+	UpdateLessonPlanContent(planID int, content string) error
+	DeleteCourse(userID int, courseID int) error
+}
+
+// LessonRepository persists the lessons of a plan and graded attempts.
+type LessonRepository interface {
+	SaveLessons(lessons []*models.LessonRecord) error
+	SaveCurriculum(userID *int, persona, goals string, lessons []*models.LessonRecord, content func() (string, error)) (int, error)
+	GetLessonByID(lessonID int) (*models.LessonRecord, error)
+	GetLessonsByPlanID(planID int) ([]models.LessonRecord, error)
+	CreateLessonAttempt(a *models.LessonAttempt) error
+	GetLessonAttempts(userID, lessonID int) ([]models.LessonAttempt, error)
+	HasPassedLesson(userID, lessonID int) (bool, error)
+}
+
+// PostRepository persists community posts.
+type PostRepository interface {
+	CreatePost(post *models.Post) error
+	GetPosts(q models.PostQuery) (*models.PostPage, error)
+	GetPostByID(id int) (*models.Post, error)
+}
+
+// ReactionRepository persists per-user reactions on posts.
+type ReactionRepository interface {
+	AddReaction(postID, userID int, reaction string, at time.Time) (bool, error)
+	RemoveReaction(postID, userID int, reaction string) (bool, error)
+	GetReactionSummary(postID, viewerID int) (*models.ReactionSummary, error)
+}
+
+// CommentRepository persists threaded comments on posts.
+type CommentRepository interface {
+	CreateComment(c *models.Comment) error
+	GetCommentByID(id int) (*models.Comment, error)
+	GetCommentsByPostID(postID int) ([]models.Comment, error)
+	UpdateComment(id, userID int, content, status string, at time.Time) (bool, error)
+	DeleteComment(id, userID int, at time.Time) (bool, error)
+}
+
+// ContactRepository persists contact form submissions and the support
+// tickets they open.
+type ContactRepository interface {
+	CreateContactSubmission(sub *models.ContactSubmission) error
+	GetContactSubmission(id int) (*models.ContactSubmission, error)
+	GetTicket(id int) (*models.Ticket, error)
+	ListTicketsByUser(userID int) ([]models.ContactSubmission, error)
+	AddTicketMessage(m *models.TicketMessage, status string) (bool, error)
+	UpdateTicket(ticketID, actorID int, u models.UpdateTicketRequest, at time.Time) (bool, error)
+	SetSuggestedReply(ticketID, actorID int, body string, at time.Time) (bool, error)
+}
+
+// SearchRepository runs full-text search over posts, courses, lessons and roadmaps.
+type SearchRepository interface {
+	Search(q models.SearchQuery) ([]models.SearchResult, error)
+}
+
+// ModerationRepository persists reports, screening decisions, moderator
+// actions, suspensions and the audit log.
+type ModerationRepository interface {
+	CreateReport(r *models.Report) error
+	GetModerationQueue(limit int) ([]models.ModerationQueueItem, error)
+	ApplyPostAction(postID, actorID int, action, reason string, at time.Time) (*models.Post, error)
+	ApplyCommentAction(commentID, actorID int, action, reason string, at time.Time) (*models.Comment, error)
+	SaveModerationDecision(d *models.ModerationDecision) error
+	CountDuplicateContent(authorID int, contentHash string, since time.Time) (int, error)
+	GetPendingContent(limit int) ([]models.PendingItem, error)
+	GetModerationDecision(contentType string, contentID int) (*models.ModerationDecision, error)
+	SuspendUser(userID, actorID int, until time.Time, reason string, at time.Time) (bool, error)
+	LiftSuspension(userID, actorID int, reason string, at time.Time) (bool, error)
+	SetUserRole(userID int, role string) (bool, error)
+	ListAuditLog(q models.AuditQuery) ([]models.AuditEntry, error)
+}
+
+// AdminRepository backs the admin API: listing and searching users, plans,
+// posts and contact submissions, and the changes admins make to them.
+type AdminRepository interface {
+	ListUsers(q models.UserQuery) (*models.UserPage, error)
+	ChangeUserRole(userID, actorID int, role, reason string, at time.Time) (bool, error)
+	ListLessonPlans(q models.LessonPlanQuery) (*models.LessonPlanPage, error)
+	DeleteLessonPlan(planID, actorID int, reason string, at time.Time) (bool, error)
+	ListAllPosts(q models.AdminPostQuery) (*models.AdminPostPage, error)
+	ListContactSubmissions(q models.ContactQuery) (*models.ContactPage, error)
+	RecordAudit(e *models.AuditEntry) error
+}
+
+// EmailRepository is the outgoing email outbox as the admin API sees it;
+// the delivery side is email.Outbox.
+type EmailRepository interface {
+	ListOutboxEmails(q models.OutboxQuery) ([]models.OutboxEmail, error)
+	CountOutboxEmails() (map[string]int, error)
+	RetryEmail(id, actorID int, at time.Time) (bool, error)
+}
+
+// QuotaRepository counts AI requests against each user's daily and monthly
+// quotas.
+type QuotaRepository interface {
+	GetAIQuota(userID int, at time.Time, dailyLimit, monthlyLimit int) (*models.AIQuota, error)
+	UseAIQuota(userID int, at time.Time, dailyLimit, monthlyLimit int) (*models.AIQuota, bool, error)
+}
+
+// Repository is everything the HTTP handlers need from storage. Store
+// implements it for both SQLite and Postgres.
+type Repository interface {
+	Ping(ctx context.Context) error
+	Migrated() bool
+	UserRepository
+	IdentityRepository
+	AccountTokenRepository
+	SessionRepository
+	LessonPlanRepository
+	LessonRepository
+	PostRepository
+	ReactionRepository
+	CommentRepository
+	ContactRepository
+	SearchRepository
+	ModerationRepository
+	AdminRepository
+	EmailRepository
+	QuotaRepository
+}
+
+var _ Repository = (*Store)(nil)
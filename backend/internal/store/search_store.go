@@ -0,0 +1,441 @@
+package store
+
+import (
+	"codefuture-backend/internal/models"
+	"database/sql"
+	"fmt"
+	"html"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Search backends, picked once in prepareSearch.
+const (
+	searchPostgres = "postgres" // tsvector column with a GIN index
+	searchFTS5     = "fts5"     // SQLite FTS5 table mirroring search_documents
+	searchLike     = "like"     // LIKE scan, for SQLite builds without FTS5
+)
+
+const (
+	// maxSearchTerms bounds the work a single query can ask for.
+	maxSearchTerms = 8
+	// snippetRunes is roughly how much body text a result shows.
+	snippetRunes = 160
+)
+
+// Matches come back from the database wrapped in these control characters,
+// which can't be confused with markup. renderMarked turns them into <mark>
+// after escaping everything else.
+const (
+	markStart = "\x02"
+	markEnd   = "\x03"
+)
+
+// searchDoc is one row of search_documents.
+type searchDoc struct {
+	Kind      string
+	RefID     int
+	ParentID  *int
+	VisibleTo *int
+	Title     string
+	Body      string
+}
+
+// SearchBackend names the full-text implementation in use.
+func (s *Store) SearchBackend() string {
+	return s.search
+}
+
+// prepareSearch picks the search backend. On SQLite with FTS5 the index is
+// derived data, rebuilt from search_documents on every start so it can't
+// drift when builds with and without FTS5 share a database. A database that
+// predates search_documents is indexed from scratch.
+func (s *Store) prepareSearch() error {
+	var n int
+	if err := s.conn().QueryRow("SELECT COUNT(*) FROM search_documents").Scan(&n); err != nil {
+		return err
+	}
+
+	switch {
+	case s.dialect == dialectPostgres:
+		s.search = searchPostgres
+	case sqliteHasFTS5(s.db):
+		s.search = searchFTS5
+		if _, err := s.db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS search_fts USING fts5(title, body, tokenize = 'porter unicode61')`); err != nil {
+			return err
+		}
+	default:
+		s.search = searchLike
+		slog.Warn("SQLite was built without FTS5 (-tags sqlite_fts5); search falls back to LIKE")
+	}
+
+	if n == 0 {
+		return s.RebuildSearchIndex()
+	}
+	if s.search == searchFTS5 {
+		return s.rebuildFTS()
+	}
+	return nil
+}
+
+func sqliteHasFTS5(db *sql.DB) bool {
+	var ok bool
+	err := db.QueryRow("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&ok)
+	return err == nil && ok
+}
+
+func (s *Store) rebuildFTS() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM search_fts"); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO search_fts (rowid, title, body) SELECT id, title, body FROM search_documents"); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// RebuildSearchIndex re-creates every search document from the posts,
+// lesson plans and lessons they describe.
+func (s *Store) RebuildSearchIndex() error {
+	if s.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	if s.search == searchFTS5 {
+		if _, err := c.Exec("DELETE FROM search_fts"); err != nil {
+			return err
+		}
+	}
+	if _, err := c.Exec("DELETE FROM search_documents"); err != nil {
+		return err
+	}
+
+	var docs []searchDoc
+	rows, err := c.Query("SELECT id, COALESCE(title, ''), COALESCE(content, '') FROM posts WHERE status = ?", models.PostStatusVisible)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var p models.Post
+		if err := rows.Scan(&p.ID, &p.Title, &p.Content); err != nil {
+			rows.Close()
+			return err
+		}
+		docs = append(docs, postDocument(&p))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Plans made without an account belong to nobody and can't be found
+	rows, err = c.Query("SELECT id, user_id, kind, COALESCE(goals, ''), COALESCE(content, '') FROM lesson_plans WHERE user_id IS NOT NULL")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var lp models.LessonPlan
+		var userID int
+		if err := rows.Scan(&lp.ID, &userID, &lp.Kind, &lp.Goals, &lp.Content); err != nil {
+			rows.Close()
+			return err
+		}
+		lp.UserID = &userID
+		docs = append(docs, planDocument(&lp))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = c.Query(`
+		SELECT l.id, l.plan_id, COALESCE(l.title, ''), p.user_id FROM lessons l
+		JOIN lesson_plans p ON p.id = l.plan_id
+		WHERE p.user_id IS NOT NULL`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var l models.LessonRecord
+		var userID int
+		if err := rows.Scan(&l.ID, &l.PlanID, &l.Title, &userID); err != nil {
+			rows.Close()
+			return err
+		}
+		docs = append(docs, lessonDocument(&l, userID))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, d := range docs {
+		if err := s.indexDocument(c, d); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// indexDocument adds d to the index, replacing any earlier version of it.
+func (s *Store) indexDocument(c conn, d searchDoc) error {
+	if err := s.unindexDocuments(c, "kind = ? AND ref_id = ?", d.Kind, d.RefID); err != nil {
+		return err
+	}
+	id, err := c.insert(`
+		INSERT INTO search_documents (kind, ref_id, parent_id, visible_to, title, body, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		d.Kind, d.RefID, d.ParentID, d.VisibleTo, d.Title, d.Body, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if s.search == searchFTS5 {
+		_, err = c.Exec("INSERT INTO search_fts (rowid, title, body) VALUES (?, ?, ?)", id, d.Title, d.Body)
+	}
+	return err
+}
+
+// unindexDocuments removes the search documents matching where.
+func (s *Store) unindexDocuments(c conn, where string, args ...interface{}) error {
+	if s.search == searchFTS5 {
+		if _, err := c.Exec("DELETE FROM search_fts WHERE rowid IN (SELECT id FROM search_documents WHERE "+where+")", args...); err != nil {
+			return err
+		}
+	}
+	_, err := c.Exec("DELETE FROM search_documents WHERE "+where, args...)
+	return err
+}
+
+// indexPlan re-indexes a lesson plan from its stored row.
+func (s *Store) indexPlan(c conn, planID int) error {
+	var lp models.LessonPlan
+	var userID sql.NullInt64
+	err := c.QueryRow("SELECT id, user_id, kind, COALESCE(goals, ''), COALESCE(content, '') FROM lesson_plans WHERE id = ?", planID).
+		Scan(&lp.ID, &userID, &lp.Kind, &lp.Goals, &lp.Content)
+	if err == sql.ErrNoRows || (err == nil && !userID.Valid) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	owner := int(userID.Int64)
+	lp.UserID = &owner
+	return s.indexDocument(c, planDocument(&lp))
+}
+
+// unindexPlan removes a lesson plan and its lessons from the index.
+func (s *Store) unindexPlan(c conn, planID int) error {
+	return s.unindexDocuments(c, "(kind IN (?, ?) AND ref_id = ?) OR (kind = ? AND parent_id = ?)",
+		models.SearchTypeCourse, models.SearchTypeRoadmap, planID, models.SearchTypeLesson, planID)
+}
+
+func postDocument(p *models.Post) searchDoc {
+	return searchDoc{Kind: models.SearchTypePost, RefID: p.ID, Title: p.Title, Body: p.Content}
+}
+
+// planDocument indexes a course by its title and the goals it was generated
+// for, and a roadmap by its title and the titles of its sections and topics.
+func planDocument(lp *models.LessonPlan) searchDoc {
+	d := searchDoc{Kind: models.SearchTypeCourse, RefID: lp.ID, VisibleTo: lp.UserID, Title: lp.Goals, Body: lp.Goals}
+	content, err := lp.DecodeContent()
+	if err != nil {
+		// Content that doesn't parse is still findable by its goals
+		if lp.Kind == models.PlanKindRoadmap {
+			d.Kind = models.SearchTypeRoadmap
+		}
+		return d
+	}
+
+	switch c := content.(type) {
+	case *models.Curriculum:
+		if c.Title != "" {
+			d.Title = c.Title
+		}
+	case *models.Roadmap:
+		d.Kind = models.SearchTypeRoadmap
+		if c.Title != "" {
+			d.Title = c.Title
+		}
+		var b strings.Builder
+		for _, sec := range c.Sections {
+			topics := make([]string, len(sec.Topics))
+			for i, t := range sec.Topics {
+				topics[i] = t.Title
+			}
+			fmt.Fprintf(&b, "%s: %s\n", sec.Title, strings.Join(topics, ", "))
+		}
+		d.Body = strings.TrimSpace(b.String())
+	}
+	return d
+}
+
+func lessonDocument(l *models.LessonRecord, ownerID int) searchDoc {
+	planID := l.PlanID
+	return searchDoc{Kind: models.SearchTypeLesson, RefID: l.ID, ParentID: &planID, VisibleTo: &ownerID, Title: l.Title, Body: ""}
+}
+
+// Search runs a full-text query and returns the best matches first.
+func (s *Store) Search(q models.SearchQuery) ([]models.SearchResult, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	terms := searchTerms(q.Text)
+	results := []models.SearchResult{}
+	if len(terms) == 0 {
+		return results, nil
+	}
+
+	// Visibility and type filters shared by every backend, on search_documents d
+	filter := "(d.visible_to IS NULL OR d.visible_to = ?)"
+	filterArgs := []interface{}{q.ViewerID}
+	if len(q.Types) > 0 {
+		filter += " AND d.kind IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(q.Types)), ", ") + ")"
+		for _, t := range q.Types {
+			filterArgs = append(filterArgs, t)
+		}
+	}
+
+	var query string
+	var args []interface{}
+	switch s.search {
+	case searchPostgres:
+		tsquery := strings.Join(terms, ":* & ") + ":*"
+		titleOpts := `HighlightAll=true, StartSel="` + markStart + `", StopSel="` + markEnd + `"`
+		bodyOpts := `MaxWords=30, MinWords=10, MaxFragments=2, FragmentDelimiter=" … ", StartSel="` + markStart + `", StopSel="` + markEnd + `"`
+		query = `
+			SELECT d.kind, d.ref_id, d.parent_id,
+				ts_headline('english', d.title, q, ?), ts_headline('english', d.body, q, ?),
+				ts_rank(d.search_vector, q) AS rank
+			FROM search_documents d, to_tsquery('english', ?) q
+			WHERE d.search_vector @@ q AND ` + filter + `
+			ORDER BY rank DESC, d.id DESC LIMIT ? OFFSET ?`
+		args = append([]interface{}{titleOpts, bodyOpts, tsquery}, filterArgs...)
+
+	case searchFTS5:
+		// Every term must match, as a prefix, and is quoted so nothing the
+		// user types is read as FTS5 query syntax. Title hits weigh 10x.
+		match := make([]string, len(terms))
+		for i, t := range terms {
+			match[i] = `"` + t + `"*`
+		}
+		query = `
+			SELECT d.kind, d.ref_id, d.parent_id,
+				highlight(search_fts, 0, char(2), char(3)),
+				snippet(search_fts, 1, char(2), char(3), '…', 24),
+				-bm25(search_fts, 10.0, 1.0) AS rank
+			FROM search_fts JOIN search_documents d ON d.id = search_fts.rowid
+			WHERE search_fts MATCH ? AND ` + filter + `
+			ORDER BY rank DESC, d.id DESC LIMIT ? OFFSET ?`
+		args = append([]interface{}{strings.Join(match, " ")}, filterArgs...)
+
+	default:
+		// Every term must appear in the title or body; title hits weigh 10x
+		var score, where []string
+		var scoreArgs, whereArgs []interface{}
+		for _, t := range terms {
+			like := "%" + t + "%"
+			score = append(score, `(CASE WHEN d.title LIKE ? THEN 10 ELSE 0 END) + (CASE WHEN d.body LIKE ? THEN 1 ELSE 0 END)`)
+			scoreArgs = append(scoreArgs, like, like)
+			where = append(where, `(d.title LIKE ? OR d.body LIKE ?)`)
+			whereArgs = append(whereArgs, like, like)
+		}
+		query = `
+			SELECT d.kind, d.ref_id, d.parent_id, d.title, d.body, ` + strings.Join(score, " + ") + ` AS rank
+			FROM search_documents d
+			WHERE ` + strings.Join(where, " AND ") + ` AND ` + filter + `
+			ORDER BY rank DESC, d.id DESC LIMIT ? OFFSET ?`
+		args = append(append(scoreArgs, whereArgs...), filterArgs...)
+	}
+	args = append(args, q.Limit, q.Offset)
+
+	rows, err := s.conn().Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.SearchResult
+		var parentID sql.NullInt64
+		if err := rows.Scan(&r.Type, &r.ID, &parentID, &r.Title, &r.Snippet, &r.Rank); err != nil {
+			return nil, err
+		}
+		if parentID.Valid {
+			id := int(parentID.Int64)
+			r.ParentID = &id
+		}
+		if s.search == searchLike {
+			r.Title = markTerms(r.Title, terms, 0)
+			r.Snippet = markTerms(r.Snippet, terms, snippetRunes)
+		}
+		r.Title = renderMarked(r.Title)
+		r.Snippet = renderMarked(r.Snippet)
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// searchTerms splits text into lower-case words of letters and digits, the
+// only characters every backend treats the same way.
+func searchTerms(text string) []string {
+	terms := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) > maxSearchTerms {
+		terms = terms[:maxSearchTerms]
+	}
+	return terms
+}
+
+// markTerms wraps case-insensitive matches of terms in the match markers.
+// With a positive limit it first cuts text down to about that many runes
+// around the first match.
+func markTerms(text string, terms []string, limit int) string {
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = regexp.QuoteMeta(t)
+	}
+	re := regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))
+
+	if limit > 0 {
+		runes := []rune(text)
+		if len(runes) > limit {
+			start := 0
+			if loc := re.FindStringIndex(text); loc != nil {
+				start = len([]rune(text[:loc[0]])) - limit/4
+			}
+			start = max(0, min(start, len(runes)-limit))
+			cut := string(runes[start : start+limit])
+			if start > 0 {
+				cut = "…" + cut
+			}
+			if start+limit < len(runes) {
+				cut += "…"
+			}
+			text = cut
+		}
+	}
+	return re.ReplaceAllString(text, markStart+"$0"+markEnd)
+}
+
+// renderMarked HTML-escapes text and turns the match markers into <mark> tags.
+func renderMarked(text string) string {
+	text = html.EscapeString(text)
+	text = strings.ReplaceAll(text, markStart, "<mark>")
+	return strings.ReplaceAll(text, markEnd, "</mark>")
+}
@@ -0,0 +1,163 @@
+package store
+
+import (
+	"codefuture-backend/internal/models"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const sessionColumns = `id, user_id, user_agent, ip, created_at, last_seen_at, expires_at, rotated_at, revoked_at`
+
+// CreateSession stores a new session for the given refresh token hash and fills in its ID.
+func (s *Store) CreateSession(sess *models.Session, refreshHash string) error {
+	if s.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	id, err := s.conn().insert(`
+		INSERT INTO sessions (user_id, refresh_token_hash, user_agent, ip, created_at, last_seen_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		sess.UserID, refreshHash, sess.UserAgent, sess.IP, sess.CreatedAt.UTC(), sess.LastSeenAt.UTC(), sess.ExpiresAt.UTC())
+	if err != nil {
+		return err
+	}
+	sess.ID = id
+	return nil
+}
+
+func (s *Store) GetSessionByID(id int) (*models.Session, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	// The auth middleware calls this on every request, so it also reads the
+	// owner's suspension rather than looking the user up separately
+	var suspendedUntil sql.NullTime
+	sess, err := s.scanSession(s.conn().QueryRow(`
+		SELECT s.id, s.user_id, s.user_agent, s.ip, s.created_at, s.last_seen_at, s.expires_at, s.rotated_at, s.revoked_at, u.suspended_until
+		FROM sessions s
+		LEFT JOIN users u ON u.id = s.user_id
+		WHERE s.id = ?`, id), &suspendedUntil)
+	if sess != nil && suspendedUntil.Valid {
+		sess.UserSuspendedUntil = &suspendedUntil.Time
+	}
+	return sess, err
+}
+
+// GetSessionByRefreshHash finds the session whose current refresh token has this hash.
+func (s *Store) GetSessionByRefreshHash(hash string) (*models.Session, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	return s.scanSession(s.conn().QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE refresh_token_hash = ?`, hash))
+}
+
+// GetSessionByPreviousHash finds the session a refresh token was rotated out
+// of. Seeing such a token again usually means it was copied.
+func (s *Store) GetSessionByPreviousHash(hash string) (*models.Session, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	return s.scanSession(s.conn().QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE previous_token_hash = ?`, hash))
+}
+
+// RotateSession swaps the session's refresh token from oldHash to newHash.
+// It reports false when oldHash is no longer current (a concurrent refresh won).
+func (s *Store) RotateSession(id int, oldHash, newHash string, expiresAt, now time.Time) (bool, error) {
+	if s.db == nil {
+		return false, fmt.Errorf("database not connected")
+	}
+	res, err := s.conn().Exec(`
+		UPDATE sessions
+		SET refresh_token_hash = ?, previous_token_hash = ?, expires_at = ?, last_seen_at = ?, rotated_at = ?
+		WHERE id = ? AND refresh_token_hash = ? AND revoked_at IS NULL`,
+		newHash, oldHash, expiresAt.UTC(), now.UTC(), now.UTC(), id, oldHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// TouchSession records activity on a session.
+func (s *Store) TouchSession(id int, at time.Time) error {
+	if s.db == nil {
+		return nil
+	}
+	_, err := s.conn().Exec("UPDATE sessions SET last_seen_at = ? WHERE id = ?", at.UTC(), id)
+	return err
+}
+
+// ListSessions returns the user's unrevoked, unexpired sessions, most recently used first.
+func (s *Store) ListSessions(userID int) ([]models.Session, error) {
+	if s.db == nil {
+		return nil, nil
+	}
+	rows, err := s.conn().Query(`
+		SELECT `+sessionColumns+`
+		FROM sessions
+		WHERE user_id = ? AND revoked_at IS NULL
+		ORDER BY last_seen_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	now := time.Now()
+	var sessions []models.Session
+	for rows.Next() {
+		sess, err := s.scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		if sess.Active(now) {
+			sessions = append(sessions, *sess)
+		}
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeSession revokes one of the user's sessions. It reports false when no
+// active session with that ID belongs to the user.
+func (s *Store) RevokeSession(userID, id int, at time.Time) (bool, error) {
+	if s.db == nil {
+		return false, nil
+	}
+	res, err := s.conn().Exec("UPDATE sessions SET revoked_at = ? WHERE id = ? AND user_id = ? AND revoked_at IS NULL", at.UTC(), id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// RevokeAllSessions signs the user out everywhere.
+func (s *Store) RevokeAllSessions(userID int, at time.Time) error {
+	if s.db == nil {
+		return nil
+	}
+	_, err := s.conn().Exec("UPDATE sessions SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL", at.UTC(), userID)
+	return err
+}
+
+func (s *Store) scanSession(row rowScanner, extra ...any) (*models.Session, error) {
+	var sess models.Session
+	var userAgent, ip sql.NullString
+	var rotatedAt, revokedAt sql.NullTime
+	dest := []any{&sess.ID, &sess.UserID, &userAgent, &ip, &sess.CreatedAt, &sess.LastSeenAt, &sess.ExpiresAt, &rotatedAt, &revokedAt}
+	err := row.Scan(append(dest, extra...)...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	sess.UserAgent = userAgent.String
+	sess.IP = ip.String
+	if rotatedAt.Valid {
+		sess.RotatedAt = &rotatedAt.Time
+	}
+	if revokedAt.Valid {
+		sess.RevokedAt = &revokedAt.Time
+	}
+	return &sess, nil
+}
@@ -0,0 +1,90 @@
+package store_test
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"codefuture-backend/internal/models"
+	"codefuture-backend/internal/store"
+	"codefuture-backend/internal/store/storetest"
+)
+
+func TestConformanceSQLite(t *testing.T) {
+	runConformance(t, filepath.Join(t.TempDir(), "conformance.db"))
+}
+
+// TestConformancePostgres needs a database to run against. Use an empty one:
+// the suite leaves its rows behind.
+func TestConformancePostgres(t *testing.T) {
+	dsn := os.Getenv("CODEFUTURE_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("CODEFUTURE_TEST_POSTGRES_DSN is not set")
+	}
+	runConformance(t, dsn)
+}
+
+func runConformance(t *testing.T, dsn string) {
+	db := store.NewStore(dsn)
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.Ping(context.Background()); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	for _, c := range storetest.Cases {
+		t.Run(c.Name, func(t *testing.T) {
+			if err := c.Run(db); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// TestTrendingMigrationScoresReactions re-applies the migration that adds
+// trending_score and checks existing posts get the same score the store
+// keeps, reactions included.
+func TestTrendingMigrationScoresReactions(t *testing.T) {
+	db := store.NewStore(filepath.Join(t.TempDir(), "trending.db"))
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	u := &models.User{Name: "Trending", Email: "trending@example.com", Password: "hash"}
+	if err := db.CreateUser(u); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	p := &models.Post{UserID: u.ID, AuthorName: u.Name, Title: "Ranked", Content: "Body", Topic: "general"}
+	if err := db.CreatePost(p); err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	for _, reaction := range models.ReactionTypes {
+		if _, err := db.AddReaction(p.ID, u.ID, reaction, time.Now()); err != nil {
+			t.Fatalf("AddReaction: %v", err)
+		}
+	}
+	before, err := db.GetPostByID(p.ID)
+	if err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+
+	if err := db.MigrateTo(10); err != nil {
+		t.Fatalf("migrate down: %v", err)
+	}
+	if err := db.Migrate(); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	after, err := db.GetPostByID(p.ID)
+	if err != nil {
+		t.Fatalf("GetPostByID: %v", err)
+	}
+	if math.Abs(after.TrendingScore-before.TrendingScore) > 1e-9 {
+		t.Errorf("trending score after migrating = %v, want %v", after.TrendingScore, before.TrendingScore)
+	}
+}
@@ -0,0 +1,1462 @@
+// Package storetest is the conformance suite every store.Repository backend
+// must pass. It only goes through the public interface and creates uniquely
+// named rows, so it can run against a database that already holds data.
+//
+// store_test.go runs it against SQLite, and against Postgres when
+// CODEFUTURE_TEST_POSTGRES_DSN is set: `go test ./internal/store`.
+package storetest
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"codefuture-backend/internal/email"
+	"codefuture-backend/internal/models"
+	"codefuture-backend/internal/store"
+)
+
+// Case is one behaviour shared by all backends.
+type Case struct {
+	Name string
+	Run  func(r store.Repository) error
+}
+
+// Cases is the full suite, in the order it is run.
+var Cases = []Case{
+	{"users", testUsers},
+	{"account tokens", testAccountTokens},
+	{"identities", testIdentities},
+	{"lesson plans", testLessonPlans},
+	{"lessons and attempts", testLessons},
+	{"save curriculum", testSaveCurriculum},
+	{"delete course", testDeleteCourse},
+	{"posts", testPosts},
+	{"post listing", testPostListing},
+	{"reactions", testReactions},
+	{"comments", testComments},
+	{"contact submissions", testContact},
+	{"support tickets", testTickets},
+	{"search", testSearch},
+	{"moderation", testModeration},
+	{"screening", testScreening},
+	{"admin", testAdmin},
+	{"email outbox", testEmailOutbox},
+	{"ai quota", testAIQuota},
+	{"sessions", testSessions},
+}
+
+func unique(prefix string) string {
+	return fmt.Sprintf("%s-%d", prefix, time.Now().UnixNano())
+}
+
+func newUser(r store.Repository) (*models.User, error) {
+	u := &models.User{Name: "Conformance", Email: unique("user") + "@example.com", Password: "hash"}
+	if err := r.CreateUser(u); err != nil {
+		return nil, fmt.Errorf("CreateUser: %v", err)
+	}
+	return u, nil
+}
+
+func testUsers(r store.Repository) error {
+	u, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	if u.ID == 0 {
+		return fmt.Errorf("CreateUser did not set ID")
+	}
+
+	dup := &models.User{Name: "Dup", Email: u.Email, Password: "hash"}
+	if err := r.CreateUser(dup); !errors.Is(err, store.ErrEmailExists) {
+		return fmt.Errorf("duplicate email: got %v, want ErrEmailExists", err)
+	}
+
+	byEmail, err := r.GetUserByEmail(u.Email)
+	if err != nil || byEmail == nil || byEmail.ID != u.ID || byEmail.Name != u.Name {
+		return fmt.Errorf("GetUserByEmail = %+v, %v", byEmail, err)
+	}
+	byID, err := r.GetUserByID(u.ID)
+	if err != nil || byID == nil || byID.Email != u.Email || byID.CreatedAt.IsZero() {
+		return fmt.Errorf("GetUserByID = %+v, %v", byID, err)
+	}
+
+	missing, err := r.GetUserByEmail(unique("missing") + "@example.com")
+	if err != nil || missing != nil {
+		return fmt.Errorf("GetUserByEmail(missing) = %+v, %v; want nil, nil", missing, err)
+	}
+	return nil
+}
+
+func testAccountTokens(r store.Repository) error {
+	u, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	if u2, err := r.GetUserByID(u.ID); err != nil || u2 == nil || u2.EmailVerified {
+		return fmt.Errorf("new user should be unverified: %+v, %v", u2, err)
+	}
+	now := time.Now()
+	purpose := "conformance"
+	if at, err := r.LatestAccountTokenAt(u.ID, purpose); err != nil || !at.IsZero() {
+		return fmt.Errorf("LatestAccountTokenAt(none sent) = %v, %v; want zero", at, err)
+	}
+
+	hash := unique("token")
+	if err := r.CreateAccountToken(u.ID, purpose, hash, now, now.Add(time.Hour)); err != nil {
+		return fmt.Errorf("CreateAccountToken: %v", err)
+	}
+	if id, err := r.ConsumeAccountToken("other", hash, now); err != nil || id != 0 {
+		return fmt.Errorf("ConsumeAccountToken(wrong purpose) = %d, %v; want 0", id, err)
+	}
+	if id, err := r.ConsumeAccountToken(purpose, hash, now); err != nil || id != u.ID {
+		return fmt.Errorf("ConsumeAccountToken = %d, %v; want %d", id, err, u.ID)
+	}
+	if id, err := r.ConsumeAccountToken(purpose, hash, now); err != nil || id != 0 {
+		return fmt.Errorf("ConsumeAccountToken(reused) = %d, %v; want 0", id, err)
+	}
+
+	expired := unique("token")
+	if err := r.CreateAccountToken(u.ID, purpose, expired, now.Add(-2*time.Hour), now.Add(-time.Hour)); err != nil {
+		return fmt.Errorf("CreateAccountToken: %v", err)
+	}
+	if id, err := r.ConsumeAccountToken(purpose, expired, now); err != nil || id != 0 {
+		return fmt.Errorf("ConsumeAccountToken(expired) = %d, %v; want 0", id, err)
+	}
+	if at, err := r.LatestAccountTokenAt(u.ID, purpose); err != nil || at.Sub(now).Abs() > time.Second {
+		return fmt.Errorf("LatestAccountTokenAt = %v, %v; want %v", at, err, now)
+	}
+
+	stale := unique("token")
+	if err := r.CreateAccountToken(u.ID, purpose, stale, now, now.Add(time.Hour)); err != nil {
+		return fmt.Errorf("CreateAccountToken: %v", err)
+	}
+	if err := r.InvalidateAccountTokens(u.ID, purpose, now); err != nil {
+		return fmt.Errorf("InvalidateAccountTokens: %v", err)
+	}
+	if id, err := r.ConsumeAccountToken(purpose, stale, now); err != nil || id != 0 {
+		return fmt.Errorf("ConsumeAccountToken(invalidated) = %d, %v; want 0", id, err)
+	}
+
+	if err := r.MarkEmailVerified(u.ID, now); err != nil {
+		return fmt.Errorf("MarkEmailVerified: %v", err)
+	}
+	if err := r.UpdateUserPassword(u.ID, "new-hash"); err != nil {
+		return fmt.Errorf("UpdateUserPassword: %v", err)
+	}
+	u2, err := r.GetUserByEmail(u.Email)
+	if err != nil || u2 == nil || !u2.EmailVerified || u2.Password != "new-hash" {
+		return fmt.Errorf("user after verify/reset = %+v, %v", u2, err)
+	}
+	return nil
+}
+
+func testIdentities(r store.Repository) error {
+	u, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	subject := unique("subject")
+	if got, err := r.GetUserByIdentity("conformance", subject); err != nil || got != nil {
+		return fmt.Errorf("GetUserByIdentity(unlinked) = %+v, %v; want nil", got, err)
+	}
+
+	identity := &models.UserIdentity{UserID: u.ID, Provider: "conformance", ProviderUserID: subject, Email: u.Email}
+	if err := r.LinkIdentity(identity); err != nil || identity.ID == 0 {
+		return fmt.Errorf("LinkIdentity: %v (id %d)", err, identity.ID)
+	}
+	other, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	dup := &models.UserIdentity{UserID: other.ID, Provider: "conformance", ProviderUserID: subject}
+	if err := r.LinkIdentity(dup); !errors.Is(err, store.ErrIdentityExists) {
+		return fmt.Errorf("duplicate identity: got %v, want ErrIdentityExists", err)
+	}
+	if err := r.LinkIdentity(&models.UserIdentity{UserID: u.ID, Provider: "conformance-2", ProviderUserID: subject}); err != nil {
+		return fmt.Errorf("LinkIdentity(second provider): %v", err)
+	}
+
+	got, err := r.GetUserByIdentity("conformance", subject)
+	if err != nil || got == nil || got.ID != u.ID || got.Email != u.Email {
+		return fmt.Errorf("GetUserByIdentity = %+v, %v; want user %d", got, err, u.ID)
+	}
+	if list, err := r.ListIdentities(u.ID); err != nil || len(list) != 2 || list[0].Provider != "conformance" {
+		return fmt.Errorf("ListIdentities = %+v, %v; want 2 oldest first", list, err)
+	}
+	return nil
+}
+
+func testLessonPlans(r store.Repository) error {
+	u, err := newUser(r)
+	if err != nil {
+		return err
+	}
+
+	first, err := r.SaveLessonPlan(&u.ID, models.PlanKindCurriculum, "student", "learn go", `{"v":1}`)
+	if err != nil {
+		return fmt.Errorf("SaveLessonPlan: %v", err)
+	}
+	second, err := r.SaveLessonPlan(&u.ID, models.PlanKindRoadmap, "student", "learn sql", `{"v":2}`)
+	if err != nil {
+		return fmt.Errorf("SaveLessonPlan: %v", err)
+	}
+	if first == 0 || second == 0 || first == second {
+		return fmt.Errorf("SaveLessonPlan ids = %d, %d", first, second)
+	}
+	if _, err := r.SaveLessonPlan(nil, models.PlanKindCurriculum, "guest", "anonymous", "{}"); err != nil {
+		return fmt.Errorf("SaveLessonPlan(nil user): %v", err)
+	}
+
+	latest, err := r.GetLatestLessonPlan(u.ID)
+	if err != nil || latest == nil || latest.ID != second {
+		return fmt.Errorf("GetLatestLessonPlan = %+v, %v; want plan %d", latest, err, second)
+	}
+	if latest.UserID == nil || *latest.UserID != u.ID || latest.Kind != models.PlanKindRoadmap || latest.CurrentLessonIndex != 0 {
+		return fmt.Errorf("GetLatestLessonPlan fields = %+v", latest)
+	}
+
+	if err := r.UpdateLessonProgress(first, 3); err != nil {
+		return fmt.Errorf("UpdateLessonProgress: %v", err)
+	}
+	if err := r.UpdateLessonPlanContent(first, `{"v":"edited"}`); err != nil {
+		return fmt.Errorf("UpdateLessonPlanContent: %v", err)
+	}
+	plan, err := r.GetLessonPlanByID(first)
+	if err != nil || plan == nil || plan.CurrentLessonIndex != 3 || plan.Content != `{"v":"edited"}` || plan.Goals != "learn go" || plan.Kind != models.PlanKindCurriculum {
+		return fmt.Errorf("GetLessonPlanByID = %+v, %v", plan, err)
+	}
+
+	missing, err := r.GetLessonPlanByID(-1)
+	if err != nil || missing != nil {
+		return fmt.Errorf("GetLessonPlanByID(missing) = %+v, %v; want nil, nil", missing, err)
+	}
+
+	courses, err := r.GetCoursesByUserID(u.ID)
+	if err != nil || len(courses) != 2 {
+		return fmt.Errorf("GetCoursesByUserID = %d courses, %v; want 2", len(courses), err)
+	}
+	if courses[0]["id"] != second {
+		return fmt.Errorf("GetCoursesByUserID not newest first: %v", courses[0]["id"])
+	}
+	return nil
+}
+
+func testLessons(r store.Repository) error {
+	u, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	planID, err := r.SaveLessonPlan(&u.ID, models.PlanKindCurriculum, "student", "exercises", "{}")
+	if err != nil {
+		return fmt.Errorf("SaveLessonPlan: %v", err)
+	}
+
+	exercise := &models.Exercise{
+		Prompt: "Echo the input",
+		Tests: []models.TestCase{
+			{Name: "visible", Stdin: "a", ExpectedOutput: "a"},
+			{Name: "hidden", Stdin: "b", ExpectedOutput: "b", Hidden: true},
+		},
+	}
+	lessons := []*models.LessonRecord{
+		{PlanID: planID, LessonIndex: 0, Title: "Intro", Language: "python"},
+		{PlanID: planID, LessonIndex: 1, Title: "Echo", Language: "python", Exercise: exercise},
+	}
+	if err := r.SaveLessons(lessons); err != nil {
+		return fmt.Errorf("SaveLessons: %v", err)
+	}
+	if lessons[0].ID == 0 || lessons[1].ID == 0 {
+		return fmt.Errorf("SaveLessons did not set IDs")
+	}
+
+	stored, err := r.GetLessonsByPlanID(planID)
+	if err != nil || len(stored) != 2 || stored[0].LessonIndex != 0 || stored[1].LessonIndex != 1 {
+		return fmt.Errorf("GetLessonsByPlanID = %+v, %v", stored, err)
+	}
+	if stored[0].Exercise != nil {
+		return fmt.Errorf("lesson without exercise came back with one")
+	}
+
+	echo, err := r.GetLessonByID(lessons[1].ID)
+	if err != nil || echo == nil || echo.Exercise == nil {
+		return fmt.Errorf("GetLessonByID = %+v, %v", echo, err)
+	}
+	if echo.Exercise.Prompt != exercise.Prompt || len(echo.Exercise.Tests) != 2 || !echo.Exercise.Tests[1].Hidden {
+		return fmt.Errorf("exercise did not round-trip: %+v", echo.Exercise)
+	}
+	if missing, err := r.GetLessonByID(-1); err != nil || missing != nil {
+		return fmt.Errorf("GetLessonByID(missing) = %+v, %v; want nil, nil", missing, err)
+	}
+
+	if passed, err := r.HasPassedLesson(u.ID, echo.ID); err != nil || passed {
+		return fmt.Errorf("HasPassedLesson before attempts = %v, %v", passed, err)
+	}
+
+	failed := &models.LessonAttempt{UserID: u.ID, LessonID: echo.ID, Code: "print()", PassedCount: 1, TotalCount: 2}
+	if err := r.CreateLessonAttempt(failed); err != nil || failed.ID == 0 {
+		return fmt.Errorf("CreateLessonAttempt: %v (id %d)", err, failed.ID)
+	}
+	if passed, err := r.HasPassedLesson(u.ID, echo.ID); err != nil || passed {
+		return fmt.Errorf("HasPassedLesson after failed attempt = %v, %v", passed, err)
+	}
+
+	ok := &models.LessonAttempt{UserID: u.ID, LessonID: echo.ID, Code: "print(input())", Passed: true, PassedCount: 2, TotalCount: 2}
+	if err := r.CreateLessonAttempt(ok); err != nil {
+		return fmt.Errorf("CreateLessonAttempt: %v", err)
+	}
+	if passed, err := r.HasPassedLesson(u.ID, echo.ID); err != nil || !passed {
+		return fmt.Errorf("HasPassedLesson after passing attempt = %v, %v", passed, err)
+	}
+
+	attempts, err := r.GetLessonAttempts(u.ID, echo.ID)
+	if err != nil || len(attempts) != 2 {
+		return fmt.Errorf("GetLessonAttempts = %d attempts, %v; want 2", len(attempts), err)
+	}
+	if attempts[0].ID != ok.ID || !attempts[0].Passed || attempts[1].Passed {
+		return fmt.Errorf("GetLessonAttempts not newest first: %+v", attempts)
+	}
+	return nil
+}
+
+func testSaveCurriculum(r store.Repository) error {
+	u, err := newUser(r)
+	if err != nil {
+		return err
+	}
+
+	failing := []*models.LessonRecord{{LessonIndex: 0, Title: "Lost", Language: "go"}}
+	if _, err := r.SaveCurriculum(&u.ID, "student", "rolled back", failing, func() (string, error) {
+		return "", fmt.Errorf("encode failed")
+	}); err == nil {
+		return fmt.Errorf("SaveCurriculum with failing content succeeded")
+	}
+	if courses, err := r.GetCoursesByUserID(u.ID); err != nil || len(courses) != 0 {
+		return fmt.Errorf("failed SaveCurriculum left %d plans, %v; want none", len(courses), err)
+	}
+
+	lessons := []*models.LessonRecord{
+		{LessonIndex: 0, Title: "Intro", Language: "go"},
+		{LessonIndex: 1, Title: "Loops", Language: "go", Exercise: &models.Exercise{Prompt: "Count"}},
+	}
+	planID, err := r.SaveCurriculum(&u.ID, "student", "learn go", lessons, func() (string, error) {
+		return fmt.Sprintf(`{"lessons":[%d,%d]}`, lessons[0].ID, lessons[1].ID), nil
+	})
+	if err != nil {
+		return fmt.Errorf("SaveCurriculum: %v", err)
+	}
+	if lessons[0].ID == 0 || lessons[1].PlanID != planID {
+		return fmt.Errorf("SaveCurriculum did not fill in lessons: %+v", lessons)
+	}
+
+	plan, err := r.GetLessonPlanByID(planID)
+	want := fmt.Sprintf(`{"lessons":[%d,%d]}`, lessons[0].ID, lessons[1].ID)
+	if err != nil || plan == nil || plan.Content != want || plan.Kind != models.PlanKindCurriculum {
+		return fmt.Errorf("GetLessonPlanByID = %+v, %v; want content %s", plan, err, want)
+	}
+	stored, err := r.GetLessonsByPlanID(planID)
+	if err != nil || len(stored) != 2 || stored[1].Exercise == nil {
+		return fmt.Errorf("GetLessonsByPlanID = %+v, %v", stored, err)
+	}
+	return nil
+}
+
+func testDeleteCourse(r store.Repository) error {
+	owner, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	other, err := newUser(r)
+	if err != nil {
+		return err
+	}
+
+	planID, err := r.SaveLessonPlan(&owner.ID, models.PlanKindCurriculum, "student", "to delete", "{}")
+	if err != nil {
+		return fmt.Errorf("SaveLessonPlan: %v", err)
+	}
+	lesson := &models.LessonRecord{PlanID: planID, Title: "Only", Language: "go", Exercise: &models.Exercise{Prompt: "p"}}
+	if err := r.SaveLessons([]*models.LessonRecord{lesson}); err != nil {
+		return fmt.Errorf("SaveLessons: %v", err)
+	}
+	attempt := &models.LessonAttempt{UserID: owner.ID, LessonID: lesson.ID, Passed: true}
+	if err := r.CreateLessonAttempt(attempt); err != nil {
+		return fmt.Errorf("CreateLessonAttempt: %v", err)
+	}
+
+	if err := r.DeleteCourse(other.ID, planID); err != nil {
+		return fmt.Errorf("DeleteCourse(other user): %v", err)
+	}
+	if plan, _ := r.GetLessonPlanByID(planID); plan == nil {
+		return fmt.Errorf("DeleteCourse removed another user's plan")
+	}
+
+	if err := r.DeleteCourse(owner.ID, planID); err != nil {
+		return fmt.Errorf("DeleteCourse: %v", err)
+	}
+	if plan, err := r.GetLessonPlanByID(planID); err != nil || plan != nil {
+		return fmt.Errorf("plan still present after DeleteCourse: %+v, %v", plan, err)
+	}
+	if lessons, err := r.GetLessonsByPlanID(planID); err != nil || len(lessons) != 0 {
+		return fmt.Errorf("lessons still present after DeleteCourse: %d, %v", len(lessons), err)
+	}
+	if passed, err := r.HasPassedLesson(owner.ID, lesson.ID); err != nil || passed {
+		return fmt.Errorf("attempts still present after DeleteCourse: %v, %v", passed, err)
+	}
+	return nil
+}
+
+func testPosts(r store.Repository) error {
+	u, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	topic := unique("topic")
+
+	older := &models.Post{UserID: u.ID, AuthorName: u.Name, Title: "First", Content: "hello", Topic: topic}
+	if err := r.CreatePost(older); err != nil || older.ID == 0 {
+		return fmt.Errorf("CreatePost: %v (id %d)", err, older.ID)
+	}
+	newer := &models.Post{UserID: u.ID, AuthorName: u.Name, Title: "Second", Content: "again", Topic: topic}
+	if err := r.CreatePost(newer); err != nil {
+		return fmt.Errorf("CreatePost: %v", err)
+	}
+	if err := r.CreatePost(&models.Post{UserID: u.ID, AuthorName: u.Name, Title: "Elsewhere", Topic: unique("other")}); err != nil {
+		return fmt.Errorf("CreatePost: %v", err)
+	}
+
+	page, err := r.GetPosts(models.PostQuery{Topic: topic})
+	if err != nil || len(page.Posts) != 2 || page.NextCursor != "" {
+		return fmt.Errorf("GetPosts(topic) = %+v, %v; want 2 posts on one page", page, err)
+	}
+	if p := page.Posts[0]; p.ID != newer.ID || p.Likes != 0 || p.AuthorName != u.Name {
+		return fmt.Errorf("GetPosts not newest first or fields wrong: %+v", p)
+	}
+
+	all, err := r.GetPosts(models.PostQuery{})
+	if err != nil || len(all.Posts) < 3 {
+		return fmt.Errorf("GetPosts(all) = %+v, %v; want at least 3", all, err)
+	}
+	return nil
+}
+
+func testPostListing(r store.Repository) error {
+	author, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	other, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	topic := unique("topic")
+
+	// Five posts a day apart, oldest first
+	base := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	var ids []int
+	for i := 0; i < 5; i++ {
+		u := author
+		if i == 2 {
+			u = other
+		}
+		p := &models.Post{UserID: u.ID, AuthorName: u.Name, Title: fmt.Sprintf("P%d", i), Topic: topic, CreatedAt: base.AddDate(0, 0, i)}
+		if err := r.CreatePost(p); err != nil {
+			return fmt.Errorf("CreatePost: %v", err)
+		}
+		ids = append(ids, p.ID)
+	}
+
+	// P1 gets three reactions, P3 one; P0 gets two comments, P4 one
+	for _, u := range []*models.User{author, other} {
+		if _, err := r.AddReaction(ids[1], u.ID, models.ReactionLike, base); err != nil {
+			return fmt.Errorf("AddReaction: %v", err)
+		}
+	}
+	for _, react := range []struct {
+		post int
+		kind string
+	}{{ids[1], models.ReactionLove}, {ids[3], models.ReactionLike}} {
+		if _, err := r.AddReaction(react.post, author.ID, react.kind, base); err != nil {
+			return fmt.Errorf("AddReaction: %v", err)
+		}
+	}
+	for _, postID := range []int{ids[0], ids[0], ids[4]} {
+		if err := r.CreateComment(&models.Comment{PostID: postID, UserID: other.ID, AuthorName: other.Name, Content: "hi"}); err != nil {
+			return fmt.Errorf("CreateComment: %v", err)
+		}
+	}
+
+	// Walks every page two posts at a time
+	collect := func(q models.PostQuery) ([]int, error) {
+		q.Topic, q.Limit = topic, 2
+		var got []int
+		for pages := 0; pages < 10; pages++ {
+			page, err := r.GetPosts(q)
+			if err != nil {
+				return nil, err
+			}
+			for _, p := range page.Posts {
+				got = append(got, p.ID)
+			}
+			if page.NextCursor == "" {
+				return got, nil
+			}
+			q.Cursor = page.NextCursor
+		}
+		return nil, fmt.Errorf("cursor never ran out")
+	}
+
+	cases := []struct {
+		q    models.PostQuery
+		want []int
+	}{
+		{models.PostQuery{}, []int{ids[4], ids[3], ids[2], ids[1], ids[0]}},
+		{models.PostQuery{Sort: models.PostSortTop}, []int{ids[1], ids[3], ids[4], ids[2], ids[0]}},
+		{models.PostQuery{Sort: models.PostSortDiscussed}, []int{ids[0], ids[4], ids[3], ids[2], ids[1]}},
+		// Three reactions are worth less than four days' head start
+		{models.PostQuery{Sort: models.PostSortTrending}, []int{ids[4], ids[3], ids[2], ids[1], ids[0]}},
+		{models.PostQuery{AuthorID: other.ID}, []int{ids[2]}},
+		{models.PostQuery{From: base.AddDate(0, 0, 1), To: base.AddDate(0, 0, 3)}, []int{ids[2], ids[1]}},
+	}
+	for _, c := range cases {
+		got, err := collect(c.q)
+		if err != nil {
+			return fmt.Errorf("GetPosts(%+v): %v", c.q, err)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(c.want) {
+			return fmt.Errorf("GetPosts(%+v) = %v; want %v", c.q, got, c.want)
+		}
+	}
+
+	page, err := r.GetPosts(models.PostQuery{Topic: topic, Limit: 2})
+	if err != nil {
+		return fmt.Errorf("GetPosts: %v", err)
+	}
+	if _, err := r.GetPosts(models.PostQuery{Topic: topic, Sort: models.PostSortTop, Cursor: page.NextCursor}); !errors.Is(err, store.ErrInvalidCursor) {
+		return fmt.Errorf("GetPosts with another sort's cursor = %v; want ErrInvalidCursor", err)
+	}
+	if _, err := r.GetPosts(models.PostQuery{Topic: topic, Cursor: "garbage"}); !errors.Is(err, store.ErrInvalidCursor) {
+		return fmt.Errorf("GetPosts with garbage cursor = %v; want ErrInvalidCursor", err)
+	}
+	return nil
+}
+
+func testReactions(r store.Repository) error {
+	author, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	fan, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	topic := unique("topic")
+	post := &models.Post{UserID: author.ID, AuthorName: author.Name, Title: "Liked", Topic: topic}
+	if err := r.CreatePost(post); err != nil {
+		return fmt.Errorf("CreatePost: %v", err)
+	}
+
+	now := time.Now()
+	if ok, err := r.AddReaction(post.ID, fan.ID, models.ReactionLike, now); err != nil || !ok {
+		return fmt.Errorf("AddReaction = %v, %v; want true", ok, err)
+	}
+	if ok, err := r.AddReaction(post.ID, fan.ID, models.ReactionLike, now); err != nil || ok {
+		return fmt.Errorf("AddReaction(duplicate) = %v, %v; want false", ok, err)
+	}
+	if _, err := r.AddReaction(post.ID, fan.ID, models.ReactionLove, now); err != nil {
+		return fmt.Errorf("AddReaction(love): %v", err)
+	}
+	if _, err := r.AddReaction(post.ID, author.ID, models.ReactionLike, now); err != nil {
+		return fmt.Errorf("AddReaction(author): %v", err)
+	}
+
+	page, err := r.GetPosts(models.PostQuery{Topic: topic, ViewerID: fan.ID})
+	if err != nil || len(page.Posts) != 1 {
+		return fmt.Errorf("GetPosts = %+v, %v; want 1 post", page, err)
+	}
+	p := page.Posts[0]
+	if p.Likes != 3 || p.Reactions[models.ReactionLike] != 2 || p.Reactions[models.ReactionLove] != 1 {
+		return fmt.Errorf("reaction counts = %d %v; want 3 like:2 love:1", p.Likes, p.Reactions)
+	}
+	if len(p.ViewerReacted) != 2 || p.ViewerReacted[0] != models.ReactionLike || p.ViewerReacted[1] != models.ReactionLove {
+		return fmt.Errorf("ViewerReacted = %v; want [like love]", p.ViewerReacted)
+	}
+	if anon, err := r.GetPosts(models.PostQuery{Topic: topic}); err != nil || anon.Posts[0].ViewerReacted != nil {
+		return fmt.Errorf("anonymous GetPosts = %+v, %v; want no ViewerReacted", anon, err)
+	}
+
+	if ok, err := r.RemoveReaction(post.ID, fan.ID, models.ReactionLike); err != nil || !ok {
+		return fmt.Errorf("RemoveReaction = %v, %v; want true", ok, err)
+	}
+	if ok, err := r.RemoveReaction(post.ID, fan.ID, models.ReactionLike); err != nil || ok {
+		return fmt.Errorf("RemoveReaction(again) = %v, %v; want false", ok, err)
+	}
+	sum, err := r.GetReactionSummary(post.ID, fan.ID)
+	if err != nil || sum.Likes != 2 || sum.Reactions[models.ReactionLike] != 1 || len(sum.ViewerReacted) != 1 {
+		return fmt.Errorf("GetReactionSummary = %+v, %v; want 2 total, like:1, viewer [love]", sum, err)
+	}
+	return nil
+}
+
+func testComments(r store.Repository) error {
+	u, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	topic := unique("topic")
+	post := &models.Post{UserID: u.ID, AuthorName: u.Name, Title: "Thread", Content: "hello", Topic: topic}
+	if err := r.CreatePost(post); err != nil {
+		return fmt.Errorf("CreatePost: %v", err)
+	}
+
+	root := &models.Comment{PostID: post.ID, UserID: u.ID, AuthorName: u.Name, Content: "first"}
+	if err := r.CreateComment(root); err != nil || root.ID == 0 {
+		return fmt.Errorf("CreateComment: %v (id %d)", err, root.ID)
+	}
+	reply := &models.Comment{PostID: post.ID, ParentID: &root.ID, UserID: u.ID, AuthorName: u.Name, Content: "reply"}
+	if err := r.CreateComment(reply); err != nil {
+		return fmt.Errorf("CreateComment(reply): %v", err)
+	}
+
+	if ok, err := r.UpdateComment(root.ID, u.ID+1, "hijack", models.CommentStatusVisible, time.Now()); err != nil || ok {
+		return fmt.Errorf("UpdateComment by another user = %v, %v; want false", ok, err)
+	}
+	if ok, err := r.UpdateComment(root.ID, u.ID, "edited", models.CommentStatusVisible, time.Now()); err != nil || !ok {
+		return fmt.Errorf("UpdateComment = %v, %v; want true", ok, err)
+	}
+	if ok, err := r.DeleteComment(root.ID, u.ID, time.Now()); err != nil || !ok {
+		return fmt.Errorf("DeleteComment = %v, %v; want true", ok, err)
+	}
+	if ok, err := r.UpdateComment(root.ID, u.ID, "again", models.CommentStatusVisible, time.Now()); err != nil || ok {
+		return fmt.Errorf("UpdateComment after delete = %v, %v; want false", ok, err)
+	}
+
+	comments, err := r.GetCommentsByPostID(post.ID)
+	if err != nil || len(comments) != 2 {
+		return fmt.Errorf("GetCommentsByPostID = %d comments, %v; want 2", len(comments), err)
+	}
+	if c := comments[0]; c.ID != root.ID || !c.Deleted || c.Content != "" || c.UpdatedAt == nil {
+		return fmt.Errorf("deleted comment = %+v", c)
+	}
+	if c := comments[1]; c.ParentID == nil || *c.ParentID != root.ID || c.Content != "reply" {
+		return fmt.Errorf("reply = %+v", c)
+	}
+
+	got, err := r.GetPostByID(post.ID)
+	if err != nil || got == nil || got.CommentCount != 1 {
+		return fmt.Errorf("GetPostByID comment count = %+v, %v; want 1", got, err)
+	}
+	page, err := r.GetPosts(models.PostQuery{Topic: topic})
+	if err != nil || len(page.Posts) != 1 || page.Posts[0].CommentCount != 1 {
+		return fmt.Errorf("GetPosts comment count = %+v, %v; want 1", page, err)
+	}
+
+	// An edit screening holds takes the comment off the thread until reviewed
+	held := &models.Comment{PostID: post.ID, UserID: u.ID, AuthorName: u.Name, Content: "fine"}
+	if err := r.CreateComment(held); err != nil {
+		return fmt.Errorf("CreateComment: %v", err)
+	}
+	if ok, err := r.UpdateComment(held.ID, u.ID, "held", models.CommentStatusPending, time.Now()); err != nil || !ok {
+		return fmt.Errorf("UpdateComment(pending) = %v, %v; want true", ok, err)
+	}
+	if ok, err := r.UpdateComment(held.ID, u.ID, "fine again", models.CommentStatusVisible, time.Now()); err != nil || !ok {
+		return fmt.Errorf("UpdateComment of held comment = %v, %v; want true", ok, err)
+	}
+	if c, err := r.GetCommentByID(held.ID); err != nil || c == nil || c.Status != models.CommentStatusPending || c.Content != "fine again" {
+		return fmt.Errorf("edited held comment = %+v, %v; want still pending", c, err)
+	}
+	if comments, err := r.GetCommentsByPostID(post.ID); err != nil || len(comments) != 2 {
+		return fmt.Errorf("GetCommentsByPostID with held edit = %d comments, %v; want 2", len(comments), err)
+	}
+	return nil
+}
+
+func testSearch(r store.Repository) error {
+	owner, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	stranger, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	word := fmt.Sprintf("zebra%d", time.Now().UnixNano())
+
+	inBody := &models.Post{UserID: owner.ID, AuthorName: owner.Name, Title: "Stripes", Content: "Look: <b>" + word + "</b>", Topic: unique("topic")}
+	if err := r.CreatePost(inBody); err != nil {
+		return fmt.Errorf("CreatePost: %v", err)
+	}
+	inTitle := &models.Post{UserID: owner.ID, AuthorName: owner.Name, Title: "All about " + word, Content: "hooves", Topic: unique("topic")}
+	if err := r.CreatePost(inTitle); err != nil {
+		return fmt.Errorf("CreatePost: %v", err)
+	}
+
+	roadmap := `{"title":"Savanna","sections":[{"title":"Grazers","topics":[{"title":"` + word + ` herds"}]}]}`
+	roadmapID, err := r.SaveLessonPlan(&owner.ID, models.PlanKindRoadmap, "guide", "learn animals", roadmap)
+	if err != nil {
+		return fmt.Errorf("SaveLessonPlan(roadmap): %v", err)
+	}
+	courseID, err := r.SaveLessonPlan(&owner.ID, models.PlanKindCurriculum, "student", "tame a "+word, "{}")
+	if err != nil {
+		return fmt.Errorf("SaveLessonPlan(course): %v", err)
+	}
+	lesson := &models.LessonRecord{PlanID: courseID, Title: "Feeding the " + word, Language: "go"}
+	if err := r.SaveLessons([]*models.LessonRecord{lesson}); err != nil {
+		return fmt.Errorf("SaveLessons: %v", err)
+	}
+
+	kinds := func(results []models.SearchResult) string {
+		var got []string
+		for _, res := range results {
+			got = append(got, fmt.Sprintf("%s:%d", res.Type, res.ID))
+		}
+		return strings.Join(got, " ")
+	}
+
+	// Only posts are public, and a title match outranks a body match
+	results, err := r.Search(models.SearchQuery{Text: word, ViewerID: stranger.ID, Limit: 10})
+	if want := fmt.Sprintf("post:%d post:%d", inTitle.ID, inBody.ID); err != nil || kinds(results) != want {
+		return fmt.Errorf("Search as stranger = %q, %v; want %q", kinds(results), err, want)
+	}
+	if snip := results[1].Snippet; !strings.Contains(snip, "&lt;b&gt;<mark>") || !strings.Contains(snip, "</mark>&lt;/b&gt;") {
+		return fmt.Errorf("snippet %q is not escaped and highlighted", snip)
+	}
+	if !strings.Contains(results[0].Title, "<mark>") {
+		return fmt.Errorf("title %q is not highlighted", results[0].Title)
+	}
+
+	results, err = r.Search(models.SearchQuery{Text: word, ViewerID: owner.ID, Types: []string{models.SearchTypeRoadmap, models.SearchTypeCourse, models.SearchTypeLesson}, Limit: 10})
+	if err != nil || len(results) != 3 {
+		return fmt.Errorf("Search as owner = %q, %v; want roadmap, course and lesson", kinds(results), err)
+	}
+	for _, res := range results {
+		if res.Type == models.SearchTypeLesson && (res.ID != lesson.ID || res.ParentID == nil || *res.ParentID != courseID) {
+			return fmt.Errorf("lesson result = %+v", res)
+		}
+		if res.Type == models.SearchTypeRoadmap && res.ID != roadmapID {
+			return fmt.Errorf("roadmap result = %+v", res)
+		}
+	}
+
+	// A prefix matches, and every word has to
+	if results, err := r.Search(models.SearchQuery{Text: word[:len(word)-3] + " stripes", ViewerID: owner.ID, Limit: 10}); err != nil || kinds(results) != fmt.Sprintf("post:%d", inBody.ID) {
+		return fmt.Errorf("Search(prefix and word) = %q, %v; want the body match only", kinds(results), err)
+	}
+
+	if err := r.DeleteCourse(owner.ID, courseID); err != nil {
+		return fmt.Errorf("DeleteCourse: %v", err)
+	}
+	results, err = r.Search(models.SearchQuery{Text: word, ViewerID: owner.ID, Types: []string{models.SearchTypeCourse, models.SearchTypeLesson}, Limit: 10})
+	if err != nil || len(results) != 0 {
+		return fmt.Errorf("Search after DeleteCourse = %q, %v; want nothing", kinds(results), err)
+	}
+	return nil
+}
+
+func testModeration(r store.Repository) error {
+	author, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	moderator, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	if author.Role != models.RoleLearner {
+		return fmt.Errorf("new user role = %q, want %q", author.Role, models.RoleLearner)
+	}
+	if ok, err := r.SetUserRole(moderator.ID, models.RoleModerator); err != nil || !ok {
+		return fmt.Errorf("SetUserRole = %v, %v; want true", ok, err)
+	}
+	if got, err := r.GetUserByID(moderator.ID); err != nil || got == nil || !got.IsModerator() {
+		return fmt.Errorf("GetUserByID after SetUserRole = %+v, %v", got, err)
+	}
+
+	word := fmt.Sprintf("okapi%d", time.Now().UnixNano())
+	topic := unique("topic")
+	post := &models.Post{UserID: author.ID, AuthorName: author.Name, Title: "Flagged " + word, Topic: topic}
+	if err := r.CreatePost(post); err != nil {
+		return fmt.Errorf("CreatePost: %v", err)
+	}
+	if post.Status != models.PostStatusVisible {
+		return fmt.Errorf("new post status = %q", post.Status)
+	}
+
+	report := &models.Report{PostID: post.ID, ReporterID: moderator.ID, Reason: models.ReportReasonSpam}
+	if err := r.CreateReport(report); err != nil || report.ID == 0 || report.Status != models.ReportStatusOpen {
+		return fmt.Errorf("CreateReport = %+v, %v", report, err)
+	}
+	if err := r.CreateReport(&models.Report{PostID: post.ID, ReporterID: moderator.ID, Reason: models.ReportReasonOther}); !errors.Is(err, store.ErrAlreadyReported) {
+		return fmt.Errorf("second CreateReport = %v, want ErrAlreadyReported", err)
+	}
+	inQueue := func() (*models.ModerationQueueItem, error) {
+		items, err := r.GetModerationQueue(0)
+		if err != nil {
+			return nil, fmt.Errorf("GetModerationQueue: %v", err)
+		}
+		for i := range items {
+			if items[i].Post.ID == post.ID {
+				return &items[i], nil
+			}
+		}
+		return nil, nil
+	}
+	item, err := inQueue()
+	if err != nil {
+		return err
+	}
+	if item == nil || len(item.Reports) != 1 || item.Reports[0].ID != report.ID {
+		return fmt.Errorf("queue item = %+v; want the post with its report", item)
+	}
+
+	now := time.Now()
+	hidden, err := r.ApplyPostAction(post.ID, moderator.ID, models.PostActionHide, "spam", now)
+	if err != nil || hidden == nil || hidden.Status != models.PostStatusHidden {
+		return fmt.Errorf("ApplyPostAction(hide) = %+v, %v", hidden, err)
+	}
+	if item, err := inQueue(); err != nil || item != nil {
+		return fmt.Errorf("queue after hide = %+v, %v; want the post gone", item, err)
+	}
+	page, err := r.GetPosts(models.PostQuery{Topic: topic})
+	if err != nil || len(page.Posts) != 0 {
+		return fmt.Errorf("GetPosts after hide = %+v, %v; want nothing", page, err)
+	}
+	if results, err := r.Search(models.SearchQuery{Text: word, Limit: 10}); err != nil || len(results) != 0 {
+		return fmt.Errorf("Search after hide = %+v, %v; want nothing", results, err)
+	}
+
+	shown, err := r.ApplyPostAction(post.ID, moderator.ID, models.PostActionUnhide, "", now)
+	if err != nil || shown == nil || shown.Status != models.PostStatusVisible {
+		return fmt.Errorf("ApplyPostAction(unhide) = %+v, %v", shown, err)
+	}
+	if results, err := r.Search(models.SearchQuery{Text: word, Limit: 10}); err != nil || len(results) != 1 {
+		return fmt.Errorf("Search after unhide = %+v, %v; want the post", results, err)
+	}
+	locked, err := r.ApplyPostAction(post.ID, moderator.ID, models.PostActionLock, "", now)
+	if err != nil || locked == nil || !locked.Locked {
+		return fmt.Errorf("ApplyPostAction(lock) = %+v, %v", locked, err)
+	}
+	if _, err := r.ApplyPostAction(post.ID, moderator.ID, models.PostActionRemove, "", now); err != nil {
+		return fmt.Errorf("ApplyPostAction(remove): %v", err)
+	}
+	if _, err := r.ApplyPostAction(post.ID, moderator.ID, models.PostActionUnhide, "", now); !errors.Is(err, store.ErrPostRemoved) {
+		return fmt.Errorf("ApplyPostAction after remove = %v, want ErrPostRemoved", err)
+	}
+	if p, err := r.ApplyPostAction(-1, moderator.ID, models.PostActionHide, "", now); err != nil || p != nil {
+		return fmt.Errorf("ApplyPostAction(missing) = %+v, %v; want nil", p, err)
+	}
+
+	until := now.Add(time.Hour)
+	if ok, err := r.SuspendUser(author.ID, moderator.ID, until, "rude", now); err != nil || !ok {
+		return fmt.Errorf("SuspendUser = %v, %v; want true", ok, err)
+	}
+	got, err := r.GetUserByID(author.ID)
+	if err != nil || got == nil || !got.Suspended(now) || got.Suspended(until.Add(time.Second)) || got.SuspensionReason != "rude" {
+		return fmt.Errorf("suspended user = %+v, %v", got, err)
+	}
+	if ok, err := r.LiftSuspension(author.ID, moderator.ID, "", now); err != nil || !ok {
+		return fmt.Errorf("LiftSuspension = %v, %v; want true", ok, err)
+	}
+	if ok, err := r.LiftSuspension(author.ID, moderator.ID, "", now); err != nil || ok {
+		return fmt.Errorf("second LiftSuspension = %v, %v; want false", ok, err)
+	}
+	if got, err := r.GetUserByID(author.ID); err != nil || got == nil || got.Suspended(now) {
+		return fmt.Errorf("user after LiftSuspension = %+v, %v", got, err)
+	}
+
+	entries, err := r.ListAuditLog(models.AuditQuery{ActorID: moderator.ID})
+	if err != nil {
+		return fmt.Errorf("ListAuditLog: %v", err)
+	}
+	var actions []string
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+	}
+	want := "unsuspend suspend remove lock unhide hide"
+	if strings.Join(actions, " ") != want {
+		return fmt.Errorf("audit actions = %q, want %q", strings.Join(actions, " "), want)
+	}
+	if entries[0].ActorName != moderator.Name || entries[5].TargetType != models.AuditTargetPost || entries[5].Reason != "spam" {
+		return fmt.Errorf("oldest audit entry = %+v", entries[5])
+	}
+	older, err := r.ListAuditLog(models.AuditQuery{ActorID: moderator.ID, BeforeID: entries[1].ID, Limit: 2})
+	if err != nil || len(older) != 2 || older[0].ID != entries[2].ID {
+		return fmt.Errorf("ListAuditLog(before) = %+v, %v", older, err)
+	}
+	return nil
+}
+
+func testScreening(r store.Repository) error {
+	author, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	moderator, err := newUser(r)
+	if err != nil {
+		return err
+	}
+
+	word := fmt.Sprintf("quokka%d", time.Now().UnixNano())
+	topic := unique("topic")
+	post := &models.Post{UserID: author.ID, AuthorName: author.Name, Title: "Held " + word, Topic: topic, Status: models.PostStatusPending}
+	if err := r.CreatePost(post); err != nil {
+		return fmt.Errorf("CreatePost(pending): %v", err)
+	}
+	hash := unique("hash")
+	decision := &models.ModerationDecision{
+		ContentType: models.ContentTypePost, ContentID: post.ID, AuthorID: author.ID,
+		Verdict: models.VerdictPending, Score: 0.9,
+		Labels:  map[string]float64{models.LabelSpam: 0.9, models.LabelToxicity: 0},
+		Reasons: []string{"Contains 5 links (limit 3)"}, ContentHash: hash, CreatedAt: time.Now(),
+	}
+	if err := r.SaveModerationDecision(decision); err != nil || decision.ID == 0 {
+		return fmt.Errorf("SaveModerationDecision = %v, id %d", err, decision.ID)
+	}
+	if n, err := r.CountDuplicateContent(author.ID, hash, time.Now().Add(-time.Hour)); err != nil || n != 1 {
+		return fmt.Errorf("CountDuplicateContent = %d, %v; want 1", n, err)
+	}
+	if n, err := r.CountDuplicateContent(moderator.ID, hash, time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		return fmt.Errorf("CountDuplicateContent(other author) = %d, %v; want 0", n, err)
+	}
+
+	page, err := r.GetPosts(models.PostQuery{Topic: topic})
+	if err != nil || len(page.Posts) != 0 {
+		return fmt.Errorf("GetPosts with a pending post = %+v, %v; want nothing", page, err)
+	}
+	if results, err := r.Search(models.SearchQuery{Text: word, Limit: 10}); err != nil || len(results) != 0 {
+		return fmt.Errorf("Search for a pending post = %+v, %v; want nothing", results, err)
+	}
+	pendingPost := func() (*models.PendingItem, error) {
+		items, err := r.GetPendingContent(0)
+		if err != nil {
+			return nil, fmt.Errorf("GetPendingContent: %v", err)
+		}
+		for i := range items {
+			if items[i].Post != nil && items[i].Post.ID == post.ID {
+				return &items[i], nil
+			}
+		}
+		return nil, nil
+	}
+	item, err := pendingPost()
+	if err != nil {
+		return err
+	}
+	if item == nil || item.Decision.Labels[models.LabelSpam] != 0.9 || len(item.Decision.Reasons) != 1 {
+		return fmt.Errorf("pending item = %+v; want the post with its decision", item)
+	}
+
+	approved, err := r.ApplyPostAction(post.ID, moderator.ID, models.ContentActionApprove, "", time.Now())
+	if err != nil || approved == nil || approved.Status != models.PostStatusVisible {
+		return fmt.Errorf("ApplyPostAction(approve) = %+v, %v", approved, err)
+	}
+	if item, err := pendingPost(); err != nil || item != nil {
+		return fmt.Errorf("pending item after approve = %+v, %v; want none", item, err)
+	}
+	got, err := r.GetModerationDecision(models.ContentTypePost, post.ID)
+	if err != nil || got == nil || got.ReviewedBy == nil || *got.ReviewedBy != moderator.ID || got.ReviewAction != models.ContentActionApprove {
+		return fmt.Errorf("decision after approve = %+v, %v", got, err)
+	}
+	if results, err := r.Search(models.SearchQuery{Text: word, Limit: 10}); err != nil || len(results) != 1 {
+		return fmt.Errorf("Search after approve = %+v, %v; want the post", results, err)
+	}
+
+	held := &models.Comment{PostID: post.ID, UserID: author.ID, AuthorName: author.Name, Content: "held", Status: models.CommentStatusPending}
+	if err := r.CreateComment(held); err != nil {
+		return fmt.Errorf("CreateComment(pending): %v", err)
+	}
+	if comments, err := r.GetCommentsByPostID(post.ID); err != nil || len(comments) != 0 {
+		return fmt.Errorf("GetCommentsByPostID with a pending comment = %+v, %v; want nothing", comments, err)
+	}
+	if p, err := r.GetPostByID(post.ID); err != nil || p.CommentCount != 0 {
+		return fmt.Errorf("comment count with a pending comment = %+v, %v; want 0", p, err)
+	}
+	c, err := r.ApplyCommentAction(held.ID, moderator.ID, models.ContentActionApprove, "", time.Now())
+	if err != nil || c == nil || c.Status != models.CommentStatusVisible {
+		return fmt.Errorf("ApplyCommentAction(approve) = %+v, %v", c, err)
+	}
+	if comments, err := r.GetCommentsByPostID(post.ID); err != nil || len(comments) != 1 {
+		return fmt.Errorf("GetCommentsByPostID after approve = %+v, %v; want the comment", comments, err)
+	}
+	c, err = r.ApplyCommentAction(held.ID, moderator.ID, models.ContentActionRemove, "spam", time.Now())
+	if err != nil || c == nil || c.Status != models.CommentStatusRemoved || !c.Deleted || c.Content != "" {
+		return fmt.Errorf("ApplyCommentAction(remove) = %+v, %v", c, err)
+	}
+	if c, err := r.ApplyCommentAction(-1, moderator.ID, models.ContentActionRemove, "", time.Now()); err != nil || c != nil {
+		return fmt.Errorf("ApplyCommentAction(missing) = %+v, %v; want nil", c, err)
+	}
+	return nil
+}
+
+func testContact(r store.Repository) error {
+	u, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	anonymous := &models.ContactSubmission{FirstName: "Ada", LastName: "L", Email: "ada@example.com", Message: "hi"}
+	if err := r.CreateContactSubmission(anonymous); err != nil {
+		return fmt.Errorf("CreateContactSubmission(anonymous): %v", err)
+	}
+	signedIn := &models.ContactSubmission{FirstName: "Grace", Email: u.Email, Message: "hello", UserID: &u.ID}
+	if err := r.CreateContactSubmission(signedIn); err != nil {
+		return fmt.Errorf("CreateContactSubmission(user): %v", err)
+	}
+	if signedIn.ID == 0 || signedIn.ID == anonymous.ID {
+		return fmt.Errorf("CreateContactSubmission IDs = %d, %d", anonymous.ID, signedIn.ID)
+	}
+	got, err := r.GetContactSubmission(signedIn.ID)
+	if err != nil || got == nil || got.Email != u.Email || got.UserID == nil || *got.UserID != u.ID || got.LastName != "" {
+		return fmt.Errorf("GetContactSubmission = %+v, %v", got, err)
+	}
+	if got, err := r.GetContactSubmission(-1); err != nil || got != nil {
+		return fmt.Errorf("GetContactSubmission(missing) = %+v, %v; want nil, nil", got, err)
+	}
+	return nil
+}
+
+func testTickets(r store.Repository) error {
+	submitter, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	staff, err := newUser(r)
+	if err != nil {
+		return err
+	}
+
+	sub := &models.ContactSubmission{FirstName: "Ada", Email: submitter.Email, Message: "My course won't load", UserID: &submitter.ID,
+		Status: models.TicketStatusResolved, SuggestedReply: "forged"}
+	if err := r.CreateContactSubmission(sub); err != nil {
+		return fmt.Errorf("CreateContactSubmission: %v", err)
+	}
+	if sub.Status != models.TicketStatusOpen || sub.SuggestedReply != "" {
+		return fmt.Errorf("new ticket = %+v; want open with no suggested reply", sub)
+	}
+	if ok, err := r.SetSuggestedReply(sub.ID, 0, "Try reloading", time.Now()); err != nil || !ok {
+		return fmt.Errorf("SetSuggestedReply(draft) = %v, %v; want true", ok, err)
+	}
+	if ok, err := r.SetSuggestedReply(-1, 0, "x", time.Now()); err != nil || ok {
+		return fmt.Errorf("SetSuggestedReply(missing) = %v, %v; want false", ok, err)
+	}
+
+	reply := &models.TicketMessage{TicketID: sub.ID, AuthorID: staff.ID, FromStaff: true, Body: "Looking into it"}
+	if ok, err := r.AddTicketMessage(reply, models.TicketStatusPending); err != nil || !ok || reply.ID == 0 {
+		return fmt.Errorf("AddTicketMessage(staff) = %v, %v (id %d)", ok, err, reply.ID)
+	}
+	ticket, err := r.GetTicket(sub.ID)
+	if err != nil || ticket == nil || ticket.Status != models.TicketStatusPending || ticket.SuggestedReply != "Try reloading" {
+		return fmt.Errorf("GetTicket after staff message = %+v, %v; want pending, draft kept", ticket, err)
+	}
+
+	emailedAt := time.Now()
+	emailed := &models.TicketMessage{TicketID: sub.ID, AuthorID: staff.ID, FromStaff: true, Body: "Try reloading", EmailedAt: &emailedAt}
+	if ok, err := r.AddTicketMessage(emailed, models.TicketStatusPending); err != nil || !ok {
+		return fmt.Errorf("AddTicketMessage(emailed) = %v, %v", ok, err)
+	}
+	answer := &models.TicketMessage{TicketID: sub.ID, AuthorID: submitter.ID, Body: "Still broken"}
+	if ok, err := r.AddTicketMessage(answer, models.TicketStatusOpen); err != nil || !ok || answer.AuthorName != submitter.Name {
+		return fmt.Errorf("AddTicketMessage(submitter) = %v, %v (author %q)", ok, err, answer.AuthorName)
+	}
+	if ok, err := r.AddTicketMessage(&models.TicketMessage{TicketID: -1, AuthorID: submitter.ID, Body: "x"}, models.TicketStatusOpen); err != nil || ok {
+		return fmt.Errorf("AddTicketMessage(missing) = %v, %v; want false", ok, err)
+	}
+
+	ticket, err = r.GetTicket(sub.ID)
+	if err != nil || ticket == nil {
+		return fmt.Errorf("GetTicket = %+v, %v", ticket, err)
+	}
+	if ticket.Status != models.TicketStatusOpen || ticket.SuggestedReply != "" || len(ticket.Messages) != 3 {
+		return fmt.Errorf("ticket = %+v; want reopened, draft used, three messages", ticket)
+	}
+	if m := ticket.Messages[1]; m.ID != emailed.ID || !m.FromStaff || m.EmailedAt == nil || m.AuthorName != staff.Name {
+		return fmt.Errorf("emailed message = %+v", m)
+	}
+	if m := ticket.Messages[2]; m.FromStaff || m.EmailedAt != nil || m.Body != "Still broken" {
+		return fmt.Errorf("submitter message = %+v", m)
+	}
+
+	resolved := models.TicketStatusResolved
+	if ok, err := r.UpdateTicket(sub.ID, staff.ID, models.UpdateTicketRequest{Status: &resolved, AssigneeID: &staff.ID}, time.Now()); err != nil || !ok {
+		return fmt.Errorf("UpdateTicket = %v, %v; want true", ok, err)
+	}
+	unassign := 0
+	if ok, err := r.UpdateTicket(sub.ID, staff.ID, models.UpdateTicketRequest{AssigneeID: &unassign}, time.Now()); err != nil || !ok {
+		return fmt.Errorf("UpdateTicket(unassign) = %v, %v; want true", ok, err)
+	}
+	if ok, err := r.UpdateTicket(-1, staff.ID, models.UpdateTicketRequest{Status: &resolved}, time.Now()); err != nil || ok {
+		return fmt.Errorf("UpdateTicket(missing) = %v, %v; want false", ok, err)
+	}
+	got, err := r.GetContactSubmission(sub.ID)
+	if err != nil || got == nil || got.Status != models.TicketStatusResolved || got.AssigneeID != nil {
+		return fmt.Errorf("GetContactSubmission after UpdateTicket = %+v, %v", got, err)
+	}
+
+	entries, err := r.ListAuditLog(models.AuditQuery{TargetType: models.AuditTargetContact, TargetID: sub.ID})
+	if err != nil {
+		return fmt.Errorf("ListAuditLog: %v", err)
+	}
+	var actions []string
+	for _, e := range entries {
+		actions = append(actions, e.Action)
+	}
+	want := []string{models.AuditActionUpdate, models.AuditActionUpdate, models.AuditActionEmailReply, models.AuditActionReply}
+	if !slices.Equal(actions, want) || entries[1].Reason != "status open -> resolved; assigned to "+strconv.Itoa(staff.ID) {
+		return fmt.Errorf("audit actions = %v (%+v); want %v", actions, entries, want)
+	}
+
+	mine, err := r.ListTicketsByUser(submitter.ID)
+	if err != nil || len(mine) != 1 || mine[0].ID != sub.ID {
+		return fmt.Errorf("ListTicketsByUser = %+v, %v", mine, err)
+	}
+	page, err := r.ListContactSubmissions(models.ContactQuery{Status: models.TicketStatusResolved, AssigneeID: -1, Text: submitter.Email, Limit: 10})
+	if err != nil || page.Total != 1 {
+		return fmt.Errorf("ListContactSubmissions(resolved, unassigned) = %+v, %v", page, err)
+	}
+	return nil
+}
+
+func testAdmin(r store.Repository) error {
+	admin, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	word := fmt.Sprintf("zebu%d", time.Now().UnixNano())
+	member := &models.User{Name: "Zed " + strings.ToUpper(word), Email: unique("admin") + "@example.com", Password: "hash"}
+	if err := r.CreateUser(member); err != nil {
+		return fmt.Errorf("CreateUser: %v", err)
+	}
+
+	// Search ignores case, and LIKE wildcards in it are matched literally
+	page, err := r.ListUsers(models.UserQuery{Text: word, Limit: 10})
+	if err != nil || page.Total != 1 || len(page.Users) != 1 || page.Users[0].ID != member.ID {
+		return fmt.Errorf("ListUsers(%q) = %+v, %v; want only the member", word, page, err)
+	}
+	if page, err := r.ListUsers(models.UserQuery{Text: word[:4] + "%", Limit: 10}); err != nil || page.Total != 0 {
+		return fmt.Errorf("ListUsers(wildcard) = %+v, %v; want nothing", page, err)
+	}
+
+	if ok, err := r.ChangeUserRole(member.ID, admin.ID, models.RoleModerator, "helps out", time.Now()); err != nil || !ok {
+		return fmt.Errorf("ChangeUserRole = %v, %v; want true", ok, err)
+	}
+	if ok, err := r.ChangeUserRole(-1, admin.ID, models.RoleModerator, "", time.Now()); err != nil || ok {
+		return fmt.Errorf("ChangeUserRole(missing) = %v, %v; want false", ok, err)
+	}
+	page, err = r.ListUsers(models.UserQuery{Text: word, Role: models.RoleModerator, Limit: 10})
+	if err != nil || page.Total != 1 || page.Users[0].Role != models.RoleModerator {
+		return fmt.Errorf("ListUsers(role) after ChangeUserRole = %+v, %v", page, err)
+	}
+	entries, err := r.ListAuditLog(models.AuditQuery{TargetType: models.AuditTargetUser, TargetID: member.ID})
+	if err != nil || len(entries) != 1 || entries[0].Action != models.AuditActionChangeRole || entries[0].ActorID != admin.ID ||
+		entries[0].Reason != "learner -> moderator: helps out" {
+		return fmt.Errorf("audit after ChangeUserRole = %+v, %v", entries, err)
+	}
+
+	first, err := r.SaveLessonPlan(&member.ID, models.PlanKindCurriculum, "student", "learn go", `{"v":1}`)
+	if err != nil {
+		return fmt.Errorf("SaveLessonPlan: %v", err)
+	}
+	second, err := r.SaveLessonPlan(&member.ID, models.PlanKindRoadmap, "student", "learn sql", `{"v":2}`)
+	if err != nil {
+		return fmt.Errorf("SaveLessonPlan: %v", err)
+	}
+	plans, err := r.ListLessonPlans(models.LessonPlanQuery{UserID: member.ID, Limit: 10})
+	if err != nil || plans.Total != 2 || len(plans.Plans) != 2 || plans.Plans[0].ID != second || plans.Plans[0].OwnerEmail != member.Email {
+		return fmt.Errorf("ListLessonPlans = %+v, %v; want both plans, newest first", plans, err)
+	}
+	plans, err = r.ListLessonPlans(models.LessonPlanQuery{UserID: member.ID, Kind: models.PlanKindCurriculum, Limit: 10})
+	if err != nil || plans.Total != 1 || plans.Plans[0].ID != first {
+		return fmt.Errorf("ListLessonPlans(kind) = %+v, %v", plans, err)
+	}
+	if ok, err := r.DeleteLessonPlan(first, admin.ID, "cleanup", time.Now()); err != nil || !ok {
+		return fmt.Errorf("DeleteLessonPlan = %v, %v; want true", ok, err)
+	}
+	if ok, err := r.DeleteLessonPlan(first, admin.ID, "", time.Now()); err != nil || ok {
+		return fmt.Errorf("second DeleteLessonPlan = %v, %v; want false", ok, err)
+	}
+	if plan, err := r.GetLessonPlanByID(first); err != nil || plan != nil {
+		return fmt.Errorf("GetLessonPlanByID after DeleteLessonPlan = %+v, %v", plan, err)
+	}
+	entries, err = r.ListAuditLog(models.AuditQuery{TargetType: models.AuditTargetLessonPlan, TargetID: first})
+	if err != nil || len(entries) != 1 || entries[0].Action != models.AuditActionDelete || entries[0].Reason != "cleanup" {
+		return fmt.Errorf("audit after DeleteLessonPlan = %+v, %v", entries, err)
+	}
+
+	shown := &models.Post{UserID: member.ID, AuthorName: member.Name, Title: "Shown", Topic: unique("topic")}
+	held := &models.Post{UserID: member.ID, AuthorName: member.Name, Title: "Held", Topic: unique("topic"), Status: models.PostStatusPending}
+	for _, p := range []*models.Post{shown, held} {
+		if err := r.CreatePost(p); err != nil {
+			return fmt.Errorf("CreatePost: %v", err)
+		}
+	}
+	posts, err := r.ListAllPosts(models.AdminPostQuery{AuthorID: member.ID, Limit: 10})
+	if err != nil || posts.Total != 2 || posts.Posts[0].ID != held.ID {
+		return fmt.Errorf("ListAllPosts = %+v, %v; want both posts, newest first", posts, err)
+	}
+	posts, err = r.ListAllPosts(models.AdminPostQuery{AuthorID: member.ID, Status: models.PostStatusPending, Limit: 10})
+	if err != nil || posts.Total != 1 || posts.Posts[0].ID != held.ID {
+		return fmt.Errorf("ListAllPosts(pending) = %+v, %v", posts, err)
+	}
+
+	sub := &models.ContactSubmission{FirstName: "Ada", LastName: "Okapi", Email: unique("contact") + "@example.com", Message: "About " + word}
+	if err := r.CreateContactSubmission(sub); err != nil {
+		return fmt.Errorf("CreateContactSubmission: %v", err)
+	}
+	contact, err := r.ListContactSubmissions(models.ContactQuery{Text: strings.ToUpper(word), Limit: 10})
+	if err != nil || contact.Total != 1 || contact.Submissions[0].ID != sub.ID || contact.Submissions[0].LastName != "Okapi" {
+		return fmt.Errorf("ListContactSubmissions = %+v, %v", contact, err)
+	}
+	if contact, err := r.ListContactSubmissions(models.ContactQuery{Text: "ada okapi", Limit: 1}); err != nil || contact.Total < 1 || len(contact.Submissions) != 1 {
+		return fmt.Errorf("ListContactSubmissions(full name, limit 1) = %+v, %v", contact, err)
+	}
+
+	view := &models.AuditEntry{ActorID: admin.ID, Action: models.AuditActionView, TargetType: models.AuditTargetContact, TargetID: sub.ID}
+	if err := r.RecordAudit(view); err != nil || view.ID == 0 {
+		return fmt.Errorf("RecordAudit = %v (id %d)", err, view.ID)
+	}
+	entries, err = r.ListAuditLog(models.AuditQuery{ActorID: admin.ID, Limit: 1})
+	if err != nil || len(entries) != 1 || entries[0].ID != view.ID {
+		return fmt.Errorf("ListAuditLog(actor) = %+v, %v; want the view first", entries, err)
+	}
+	return nil
+}
+
+func testEmailOutbox(r store.Repository) error {
+	outbox, ok := r.(email.Outbox)
+	if !ok {
+		return errors.New("repository does not implement email.Outbox")
+	}
+	admin, err := newUser(r)
+	if err != nil {
+		return err
+	}
+
+	// Far enough in the past that a running worker's own claims can't
+	// interfere, and claims here only see email queued at this time.
+	base := time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC)
+	contains := func(emails []models.OutboxEmail, id int) *models.OutboxEmail {
+		for i := range emails {
+			if emails[i].ID == id {
+				return &emails[i]
+			}
+		}
+		return nil
+	}
+	claim := func() ([]models.OutboxEmail, error) {
+		return outbox.ClaimDueEmails(base, time.Minute, 100)
+	}
+
+	due := &models.OutboxEmail{To: unique("due") + "@example.com", Template: "test", Subject: "Hi", HTMLBody: "<p>Hi</p>",
+		TextBody: "Hi", NextAttemptAt: base, CreatedAt: base}
+	later := &models.OutboxEmail{To: unique("later") + "@example.com", Template: "test", Subject: "Later", HTMLBody: "<p>Later</p>",
+		TextBody: "Later", NextAttemptAt: base.Add(time.Hour), CreatedAt: base}
+	for _, e := range []*models.OutboxEmail{due, later} {
+		if err := outbox.EnqueueEmail(e); err != nil || e.ID == 0 {
+			return fmt.Errorf("EnqueueEmail = %v (id %d)", err, e.ID)
+		}
+	}
+
+	claimed, err := claim()
+	if err != nil {
+		return fmt.Errorf("ClaimDueEmails: %v", err)
+	}
+	if e := contains(claimed, due.ID); e == nil || e.Attempts != 1 || e.HTMLBody != "<p>Hi</p>" || e.To != due.To {
+		return fmt.Errorf("ClaimDueEmails returned %+v for the due email; want it with 1 attempt", e)
+	}
+	if contains(claimed, later.ID) != nil {
+		return errors.New("ClaimDueEmails returned an email that isn't due")
+	}
+	if claimed, err = claim(); err != nil || contains(claimed, due.ID) != nil {
+		return fmt.Errorf("ClaimDueEmails during lease = %v; want the email hidden", err)
+	}
+
+	if err := outbox.MarkEmailFailed(due.ID, "connection refused", &base, base); err != nil {
+		return fmt.Errorf("MarkEmailFailed(retry): %v", err)
+	}
+	if claimed, err = claim(); err != nil || contains(claimed, due.ID) == nil || contains(claimed, due.ID).Attempts != 2 {
+		return fmt.Errorf("ClaimDueEmails after failure = %v; want the email again with 2 attempts", err)
+	}
+	if err := outbox.MarkEmailFailed(due.ID, "mailbox full", nil, base); err != nil {
+		return fmt.Errorf("MarkEmailFailed(dead): %v", err)
+	}
+	if claimed, err = claim(); err != nil || contains(claimed, due.ID) != nil {
+		return fmt.Errorf("ClaimDueEmails after dead-lettering = %v; want nothing", err)
+	}
+	dead, err := r.ListOutboxEmails(models.OutboxQuery{Status: models.EmailStatusDead, Limit: 50})
+	if err != nil {
+		return fmt.Errorf("ListOutboxEmails: %v", err)
+	}
+	if e := contains(dead, due.ID); e == nil || e.LastError != "mailbox full" || e.Attempts != 2 {
+		return fmt.Errorf("dead email = %+v; want it with its last error", e)
+	}
+
+	if ok, err := r.RetryEmail(due.ID, admin.ID, base); err != nil || !ok {
+		return fmt.Errorf("RetryEmail = %v, %v; want true", ok, err)
+	}
+	claimed, err = claim()
+	if err != nil || contains(claimed, due.ID) == nil || contains(claimed, due.ID).Attempts != 1 {
+		return fmt.Errorf("ClaimDueEmails after retry = %v; want the email with a fresh attempt count", err)
+	}
+	sentAt := time.Now()
+	if err := outbox.MarkEmailSent(due.ID, sentAt); err != nil {
+		return fmt.Errorf("MarkEmailSent: %v", err)
+	}
+	sent, err := r.ListOutboxEmails(models.OutboxQuery{Status: models.EmailStatusSent, Limit: 50})
+	if err != nil {
+		return fmt.Errorf("ListOutboxEmails(sent): %v", err)
+	}
+	if e := contains(sent, due.ID); e == nil || e.SentAt == nil || e.LastError != "" {
+		return fmt.Errorf("sent email = %+v; want sent_at set and the error cleared", e)
+	}
+	if ok, err := r.RetryEmail(due.ID, admin.ID, base); err != nil || ok {
+		return fmt.Errorf("RetryEmail(sent) = %v, %v; want false", ok, err)
+	}
+	if ok, err := r.RetryEmail(-1, admin.ID, base); err != nil || ok {
+		return fmt.Errorf("RetryEmail(missing) = %v, %v; want false", ok, err)
+	}
+
+	entries, err := r.ListAuditLog(models.AuditQuery{TargetType: models.AuditTargetEmail, TargetID: due.ID})
+	if err != nil || len(entries) != 1 || entries[0].Action != models.AuditActionRetry || entries[0].ActorID != admin.ID {
+		return fmt.Errorf("audit for retried email = %+v, %v; want one retry by the admin", entries, err)
+	}
+
+	// Don't leave anything for a real worker to send
+	return outbox.MarkEmailSent(later.ID, time.Now())
+}
+
+func testAIQuota(r store.Repository) error {
+	u, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	day := time.Date(2030, 3, 31, 23, 0, 0, 0, time.UTC)
+	use := func(at time.Time) (*models.AIQuota, bool, error) {
+		return r.UseAIQuota(u.ID, at, 2, 3)
+	}
+
+	for i := 1; i <= 2; i++ {
+		q, ok, err := use(day)
+		if err != nil || !ok || q.DailyUsed != i || q.MonthlyUsed != i {
+			return fmt.Errorf("UseAIQuota #%d = %+v, %v, %v; want allowed", i, q, ok, err)
+		}
+	}
+	q, ok, err := use(day)
+	if err != nil || ok || q.DailyUsed != 2 || q.DailyRemaining() != 0 {
+		return fmt.Errorf("UseAIQuota over the daily limit = %+v, %v, %v; want refused", q, ok, err)
+	}
+	if resets, exhausted := q.Exhausted(); !exhausted || !resets.Equal(time.Date(2030, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		return fmt.Errorf("Exhausted = %v, %v; want reset at midnight", resets, exhausted)
+	}
+
+	// Earlier the same month, so only the monthly limit is left
+	earlier := day.AddDate(0, 0, -1)
+	if q, ok, err = use(earlier); err != nil || !ok || q.DailyUsed != 1 || q.MonthlyUsed != 3 {
+		return fmt.Errorf("UseAIQuota on another day = %+v, %v, %v; want allowed", q, ok, err)
+	}
+	if q, ok, err = use(earlier); err != nil || ok || q.MonthlyRemaining() != 0 {
+		return fmt.Errorf("UseAIQuota over the monthly limit = %+v, %v, %v; want refused", q, ok, err)
+	}
+	if resets, _ := q.Exhausted(); !resets.Equal(time.Date(2030, 4, 1, 0, 0, 0, 0, time.UTC)) {
+		return fmt.Errorf("monthly quota resets at %v; want the first of the month", resets)
+	}
+
+	next := day.Add(2 * time.Hour)
+	if q, ok, err = use(next); err != nil || !ok || q.DailyUsed != 1 || q.MonthlyUsed != 1 {
+		return fmt.Errorf("UseAIQuota in a new month = %+v, %v, %v; want a fresh quota", q, ok, err)
+	}
+	if q, ok, err = r.UseAIQuota(u.ID, day, 0, 0); err != nil || !ok {
+		return fmt.Errorf("UseAIQuota unlimited = %+v, %v, %v; want allowed", q, ok, err)
+	}
+	q, err = r.GetAIQuota(u.ID, day, 2, 3)
+	if err != nil || q.DailyUsed != 3 || q.MonthlyUsed != 4 || q.DailyLimit != 2 {
+		return fmt.Errorf("GetAIQuota = %+v, %v; want 3 today and 4 this month", q, err)
+	}
+	return nil
+}
+
+func testSessions(r store.Repository) error {
+	u, err := newUser(r)
+	if err != nil {
+		return err
+	}
+	now := time.Now().Truncate(time.Second)
+	newSession := func(hash string) (*models.Session, error) {
+		sess := &models.Session{UserID: u.ID, UserAgent: "conformance", IP: "127.0.0.1", CreatedAt: now, LastSeenAt: now, ExpiresAt: now.Add(time.Hour)}
+		if err := r.CreateSession(sess, hash); err != nil || sess.ID == 0 {
+			return nil, fmt.Errorf("CreateSession: %v (id %d)", err, sess.ID)
+		}
+		return sess, nil
+	}
+
+	first, second := unique("hash-a"), unique("hash-b")
+	sess, err := newSession(first)
+	if err != nil {
+		return err
+	}
+	got, err := r.GetSessionByRefreshHash(first)
+	if err != nil || got == nil || got.ID != sess.ID || !got.ExpiresAt.Equal(sess.ExpiresAt) {
+		return fmt.Errorf("GetSessionByRefreshHash = %+v, %v", got, err)
+	}
+
+	if ok, err := r.RotateSession(sess.ID, first, second, now.Add(2*time.Hour), now); err != nil || !ok {
+		return fmt.Errorf("RotateSession = %v, %v; want true", ok, err)
+	}
+	if ok, err := r.RotateSession(sess.ID, first, unique("hash-c"), now.Add(2*time.Hour), now); err != nil || ok {
+		return fmt.Errorf("RotateSession with stale hash = %v, %v; want false", ok, err)
+	}
+	if got, err := r.GetSessionByRefreshHash(first); err != nil || got != nil {
+		return fmt.Errorf("old hash still current: %+v, %v", got, err)
+	}
+	prev, err := r.GetSessionByPreviousHash(first)
+	if err != nil || prev == nil || prev.ID != sess.ID || prev.RotatedAt == nil {
+		return fmt.Errorf("GetSessionByPreviousHash = %+v, %v", prev, err)
+	}
+
+	other, err := newSession(unique("hash-d"))
+	if err != nil {
+		return err
+	}
+	if list, err := r.ListSessions(u.ID); err != nil || len(list) != 2 {
+		return fmt.Errorf("ListSessions = %d sessions, %v; want 2", len(list), err)
+	}
+
+	if ok, err := r.RevokeSession(u.ID+1, sess.ID, now); err != nil || ok {
+		return fmt.Errorf("RevokeSession by another user = %v, %v; want false", ok, err)
+	}
+	if ok, err := r.RevokeSession(u.ID, sess.ID, now); err != nil || !ok {
+		return fmt.Errorf("RevokeSession = %v, %v; want true", ok, err)
+	}
+	if got, err := r.GetSessionByID(sess.ID); err != nil || got == nil || got.Active(now) {
+		return fmt.Errorf("revoked session still active: %+v, %v", got, err)
+	}
+	if ok, err := r.RotateSession(sess.ID, second, unique("hash-e"), now.Add(time.Hour), now); err != nil || ok {
+		return fmt.Errorf("RotateSession on revoked session = %v, %v; want false", ok, err)
+	}
+
+	if err := r.RevokeAllSessions(u.ID, now); err != nil {
+		return fmt.Errorf("RevokeAllSessions: %v", err)
+	}
+	if got, err := r.GetSessionByID(other.ID); err != nil || got == nil || got.RevokedAt == nil {
+		return fmt.Errorf("session survived RevokeAllSessions: %+v, %v", got, err)
+	}
+	if list, err := r.ListSessions(u.ID); err != nil || len(list) != 0 {
+		return fmt.Errorf("ListSessions after revoke all = %d, %v; want 0", len(list), err)
+	}
+
+	// The auth middleware reads the user's suspension off the session, revoked or not
+	if ok, err := r.SuspendUser(u.ID, u.ID, now.Add(time.Hour), "conformance", now); err != nil || !ok {
+		return fmt.Errorf("SuspendUser = %v, %v; want true", ok, err)
+	}
+	if got, err := r.GetSessionByID(other.ID); err != nil || got == nil || !got.UserSuspended(now) {
+		return fmt.Errorf("session of suspended user = %+v, %v; want UserSuspended", got, err)
+	}
+	if ok, err := r.LiftSuspension(u.ID, u.ID, "", now); err != nil || !ok {
+		return fmt.Errorf("LiftSuspension = %v, %v; want true", ok, err)
+	}
+	if got, err := r.GetSessionByID(other.ID); err != nil || got == nil || got.UserSuspended(now) {
+		return fmt.Errorf("session after LiftSuspension = %+v, %v; want not UserSuspended", got, err)
+	}
+	return nil
+}
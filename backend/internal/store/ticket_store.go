@@ -0,0 +1,257 @@
+package store
+
+import (
+	"codefuture-backend/internal/models"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const contactColumns = `id, user_id, COALESCE(first_name, ''), COALESCE(last_name, ''), COALESCE(email, ''), COALESCE(message, ''),
+	status, assignee_id, suggested_reply, created_at, updated_at`
+
+// CreateContactSubmission stores a new submission as an open ticket and fills
+// in its ID, Status and timestamps.
+func (s *Store) CreateContactSubmission(sub *models.ContactSubmission) error {
+	if s.db == nil {
+		return nil
+	}
+	sub.Status = models.TicketStatusOpen
+	sub.AssigneeID = nil
+	sub.SuggestedReply = ""
+	sub.CreatedAt = time.Now().UTC()
+	sub.UpdatedAt = sub.CreatedAt
+	id, err := s.conn().insert(`
+		INSERT INTO contact_submissions (first_name, last_name, email, message, user_id, status, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		sub.FirstName, sub.LastName, sub.Email, sub.Message, sub.UserID, sub.Status, sub.CreatedAt, sub.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	sub.ID = id
+	return nil
+}
+
+func (s *Store) GetContactSubmission(id int) (*models.ContactSubmission, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	sub, err := scanContactSubmission(s.conn().QueryRow(`SELECT `+contactColumns+` FROM contact_submissions WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return sub, err
+}
+
+func scanContactSubmission(row rowScanner) (*models.ContactSubmission, error) {
+	var sub models.ContactSubmission
+	if err := row.Scan(&sub.ID, &sub.UserID, &sub.FirstName, &sub.LastName, &sub.Email, &sub.Message,
+		&sub.Status, &sub.AssigneeID, &sub.SuggestedReply, &sub.CreatedAt, &sub.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// GetTicket returns a contact submission with its replies, or nil if there
+// is no such ticket.
+func (s *Store) GetTicket(id int) (*models.Ticket, error) {
+	sub, err := s.GetContactSubmission(id)
+	if err != nil || sub == nil {
+		return nil, err
+	}
+
+	rows, err := s.conn().Query(`
+		SELECT m.id, m.ticket_id, m.author_id, COALESCE(u.name, ''), m.from_staff, m.body, m.emailed_at, m.created_at
+		FROM ticket_messages m LEFT JOIN users u ON u.id = m.author_id
+		WHERE m.ticket_id = ? ORDER BY m.id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	t := &models.Ticket{ContactSubmission: *sub, Messages: []models.TicketMessage{}}
+	for rows.Next() {
+		var m models.TicketMessage
+		var emailedAt sql.NullTime
+		if err := rows.Scan(&m.ID, &m.TicketID, &m.AuthorID, &m.AuthorName, &m.FromStaff, &m.Body, &emailedAt, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		if emailedAt.Valid {
+			m.EmailedAt = &emailedAt.Time
+		}
+		t.Messages = append(t.Messages, m)
+	}
+	return t, rows.Err()
+}
+
+// ListTicketsByUser returns the tickets a user submitted while signed in,
+// newest first.
+func (s *Store) ListTicketsByUser(userID int) ([]models.ContactSubmission, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database not connected")
+	}
+	rows, err := s.conn().Query(`SELECT `+contactColumns+` FROM contact_submissions WHERE user_id = ? ORDER BY id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tickets := []models.ContactSubmission{}
+	for rows.Next() {
+		sub, err := scanContactSubmission(rows)
+		if err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, *sub)
+	}
+	return tickets, rows.Err()
+}
+
+// AddTicketMessage appends m to its ticket, moves the ticket to status and
+// fills in m's ID and AuthorName. Staff messages are audited, and one that was emailed
+// clears the suggested reply it replaces. It reports false if there is no
+// such ticket.
+func (s *Store) AddTicketMessage(m *models.TicketMessage, status string) (bool, error) {
+	if s.db == nil {
+		return false, fmt.Errorf("database not connected")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+	m.CreatedAt = m.CreatedAt.UTC()
+
+	update := `UPDATE contact_submissions SET status = ?, updated_at = ?`
+	if m.EmailedAt != nil {
+		update += `, suggested_reply = ''`
+	}
+	res, err := c.Exec(update+` WHERE id = ?`, status, m.CreatedAt, m.TicketID)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+
+	var emailedAt interface{}
+	if m.EmailedAt != nil {
+		emailedAt = m.EmailedAt.UTC()
+	}
+	id, err := c.insert(`
+		INSERT INTO ticket_messages (ticket_id, author_id, from_staff, body, emailed_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		m.TicketID, m.AuthorID, m.FromStaff, m.Body, emailedAt, m.CreatedAt)
+	if err != nil {
+		return false, err
+	}
+	m.ID = id
+	if err := c.QueryRow("SELECT name FROM users WHERE id = ?", m.AuthorID).Scan(&m.AuthorName); err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+
+	if m.FromStaff {
+		action := models.AuditActionReply
+		if m.EmailedAt != nil {
+			action = models.AuditActionEmailReply
+		}
+		entry := &models.AuditEntry{ActorID: m.AuthorID, Action: action, TargetType: models.AuditTargetContact,
+			TargetID: m.TicketID, Reason: "status " + status, CreatedAt: m.CreatedAt}
+		if err := writeAudit(c, entry); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// UpdateTicket changes a ticket's status or assignee and records who did it.
+// It reports false if there is no such ticket.
+func (s *Store) UpdateTicket(ticketID, actorID int, u models.UpdateTicketRequest, at time.Time) (bool, error) {
+	if s.db == nil {
+		return false, fmt.Errorf("database not connected")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	var status string
+	var assigneeID sql.NullInt64
+	err = c.QueryRow("SELECT status, assignee_id FROM contact_submissions WHERE id = ?", ticketID).Scan(&status, &assigneeID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	var changes []string
+	if u.Status != nil && *u.Status != status {
+		changes = append(changes, "status "+status+" -> "+*u.Status)
+		status = *u.Status
+	}
+	if u.AssigneeID != nil {
+		if *u.AssigneeID == 0 {
+			assigneeID = sql.NullInt64{}
+			changes = append(changes, "unassigned")
+		} else {
+			assigneeID = sql.NullInt64{Int64: int64(*u.AssigneeID), Valid: true}
+			changes = append(changes, "assigned to "+strconv.Itoa(*u.AssigneeID))
+		}
+	}
+	if len(changes) == 0 {
+		return true, nil
+	}
+
+	at = at.UTC()
+	if _, err := c.Exec("UPDATE contact_submissions SET status = ?, assignee_id = ?, updated_at = ? WHERE id = ?",
+		status, assigneeID, at, ticketID); err != nil {
+		return false, err
+	}
+	entry := &models.AuditEntry{ActorID: actorID, Action: models.AuditActionUpdate, TargetType: models.AuditTargetContact,
+		TargetID: ticketID, Reason: strings.Join(changes, "; "), CreatedAt: at}
+	if err := writeAudit(c, entry); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// SetSuggestedReply replaces a ticket's suggested reply. An actorID of 0
+// stores the AI draft made when the ticket arrives, which isn't audited;
+// edits by staff are. It reports false if there is no such ticket.
+func (s *Store) SetSuggestedReply(ticketID, actorID int, body string, at time.Time) (bool, error) {
+	if s.db == nil {
+		return false, fmt.Errorf("database not connected")
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	c := s.withTx(tx)
+
+	res, err := c.Exec("UPDATE contact_submissions SET suggested_reply = ? WHERE id = ?", body, ticketID)
+	if err != nil {
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
+	}
+	if actorID != 0 {
+		entry := &models.AuditEntry{ActorID: actorID, Action: models.AuditActionEditDraft, TargetType: models.AuditTargetContact,
+			TargetID: ticketID, CreatedAt: at}
+		if err := writeAudit(c, entry); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
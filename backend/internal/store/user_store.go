@@ -4,30 +4,27 @@ import (
 	"codefuture-backend/internal/models"
 	"database/sql"
 	"fmt"
-	"strings"
 	"time"
 )
 
+const userColumns = `id, name, email, password, created_at, email_verified_at, role, suspended_until, suspension_reason`
+
 func (s *Store) CreateUser(user *models.User) error {
 	if s.db == nil {
 		return fmt.Errorf("database not connected")
 	}
 
 	query := `INSERT INTO users (name, email, password) VALUES (?, ?, ?)`
-	result, err := s.db.Exec(query, user.Name, user.Email, user.Password)
+	id, err := s.conn().insert(query, user.Name, user.Email, user.Password)
 	if err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") { // SQLite specific error
-			return fmt.Errorf("email already exists")
+		if isUniqueViolation(err) {
+			return ErrEmailExists
 		}
 		return fmt.Errorf("error creating user: %v", err)
 	}
 
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("error getting last insert id: %v", err)
-	}
-
-	user.ID = int(id)
+	user.ID = id
+	user.Role = models.RoleLearner
 	user.CreatedAt = time.Now()
 	return nil
 }
@@ -37,16 +34,7 @@ func (s *Store) GetUserByEmail(email string) (*models.User, error) {
 		return nil, fmt.Errorf("database not connected")
 	}
 
-	user := &models.User{}
-	query := `SELECT id, name, email, password, created_at FROM users WHERE email = ?`
-	err := s.db.QueryRow(query, email).Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.CreatedAt)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // Not found
-		}
-		return nil, fmt.Errorf("error finding user: %v", err)
-	}
-	return user, nil
+	return s.scanUser(s.conn().QueryRow(`SELECT `+userColumns+` FROM users WHERE email = ?`, email))
 }
 
 func (s *Store) GetUserByID(id int) (*models.User, error) {
@@ -54,14 +42,42 @@ func (s *Store) GetUserByID(id int) (*models.User, error) {
 		return nil, fmt.Errorf("database not connected")
 	}
 
+	return s.scanUser(s.conn().QueryRow(`SELECT `+userColumns+` FROM users WHERE id = ?`, id))
+}
+
+// MarkEmailVerified records that the user proved they own their email address.
+func (s *Store) MarkEmailVerified(userID int, at time.Time) error {
+	if s.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	_, err := s.conn().Exec("UPDATE users SET email_verified_at = ? WHERE id = ? AND email_verified_at IS NULL", at.UTC(), userID)
+	return err
+}
+
+func (s *Store) UpdateUserPassword(userID int, passwordHash string) error {
+	if s.db == nil {
+		return fmt.Errorf("database not connected")
+	}
+	_, err := s.conn().Exec("UPDATE users SET password = ? WHERE id = ?", passwordHash, userID)
+	return err
+}
+
+func (s *Store) scanUser(row rowScanner) (*models.User, error) {
 	user := &models.User{}
-	query := `SELECT id, name, email, password, created_at FROM users WHERE id = ?`
-	err := s.db.QueryRow(query, id).Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.CreatedAt)
+	var verifiedAt, suspendedUntil sql.NullTime
+	var suspensionReason sql.NullString
+	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.CreatedAt, &verifiedAt,
+		&user.Role, &suspendedUntil, &suspensionReason)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil // Not found
 		}
 		return nil, fmt.Errorf("error finding user: %v", err)
 	}
+	user.EmailVerified = verifiedAt.Valid
+	if suspendedUntil.Valid {
+		user.SuspendedUntil = &suspendedUntil.Time
+		user.SuspensionReason = suspensionReason.String
+	}
 	return user, nil
 }
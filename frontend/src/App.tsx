@@ -6,6 +6,7 @@ import { SidebarLayout } from './layout/SidebarLayout';
 
 // Pages
 import { LandingPage } from './components/common/LandingPage';
+import { ForgotPassword, ResetPassword, VerifyEmail } from './features/auth/AccountRecovery';
 import { Login } from './features/auth/Login';
 import { Signup } from './features/auth/Signup';
 import { Community } from './features/community/Community';
@@ -42,6 +43,9 @@ export default function App() {
         {/* Auth Routes (No Navbar/Footer) */}
         <Route path="/login" element={<GuestRoute><Login /></GuestRoute>} />
         <Route path="/signup" element={<GuestRoute><Signup /></GuestRoute>} />
+        <Route path="/forgot-password" element={<GuestRoute><ForgotPassword /></GuestRoute>} />
+        <Route path="/reset-password" element={<ResetPassword />} />
+        <Route path="/verify-email" element={<VerifyEmail />} />
 
         {/* Main Layout Routes */}
         <Route 
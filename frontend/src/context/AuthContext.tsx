@@ -1,16 +1,25 @@
-import { createContext, ReactNode, useContext, useEffect, useState } from 'react';
+import { createContext, ReactNode, useCallback, useContext, useEffect, useState } from 'react';
 
 interface User {
   id: number;
   email: string;
   name: string;
+  email_verified?: boolean;
+  role?: 'learner' | 'moderator' | 'admin';
+}
+
+interface Session {
+  token: string;
+  refresh_token?: string;
+  expires_at?: string;
+  user: User;
 }
 
 interface AuthContextType {
   user: User | null;
   token: string | null;
-  login: (token: string, user: User) => void;
-  logout: () => void;
+  login: (token: string, user: User, refreshToken?: string, expiresAt?: string) => void;
+  logout: () => Promise<void>;
   isLoading: boolean;
   isAuthenticated: boolean;
   loginWithGoogle: () => void;
@@ -20,56 +29,128 @@ interface AuthContextType {
   error: string | null;
 }
 
+const API_URL = 'http://localhost:8081/api';
+
+// Refresh this long before the access token expires
+const REFRESH_MARGIN_MS = 60 * 1000;
+
 const AuthContext = createContext<AuthContextType | undefined>(undefined);
 
 export const AuthProvider = ({ children }: { children: ReactNode }) => {
   const [user, setUser] = useState<User | null>(null);
   const [token, setToken] = useState<string | null>(null);
   const [isLoading, setIsLoading] = useState(true);
+  const [expiresAt, setExpiresAt] = useState<string | null>(null);
   const [error, setError] = useState<string | null>(null);
 
+  const clearSession = useCallback(() => {
+    setToken(null);
+    setUser(null);
+    setExpiresAt(null);
+    localStorage.removeItem('token');
+    localStorage.removeItem('refresh_token');
+    localStorage.removeItem('expires_at');
+    localStorage.removeItem('user');
+  }, []);
+
+  const login = useCallback((newToken: string, newUser: User, refreshToken?: string, newExpiresAt?: string) => {
+    setToken(newToken);
+    setUser(newUser);
+    setExpiresAt(newExpiresAt || null);
+    setError(null);
+    localStorage.setItem('token', newToken);
+    localStorage.setItem('user', JSON.stringify(newUser));
+    if (refreshToken) localStorage.setItem('refresh_token', refreshToken);
+    if (newExpiresAt) localStorage.setItem('expires_at', newExpiresAt);
+  }, []);
+
+  const startSession = useCallback((data: Session) => {
+    login(data.token, data.user, data.refresh_token, data.expires_at);
+  }, [login]);
+
+  // Exchanges the stored refresh token for a new token pair. Reads from
+  // localStorage so a refresh done in another tab is picked up.
+  const refreshSession = useCallback(async () => {
+    const refreshToken = localStorage.getItem('refresh_token');
+    if (!refreshToken) {
+      clearSession();
+      return;
+    }
+    try {
+      const response = await fetch(`${API_URL}/auth/refresh`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ refresh_token: refreshToken }),
+      });
+      if (response.status === 401) {
+        // Another tab may have rotated the token already
+        if (localStorage.getItem('refresh_token') === refreshToken) {
+          clearSession();
+        } else {
+          const storedToken = localStorage.getItem('token');
+          if (storedToken) setToken(storedToken);
+          setExpiresAt(localStorage.getItem('expires_at'));
+        }
+        return;
+      }
+      if (!response.ok) return;
+      startSession(await response.json());
+    } catch (err) {
+      console.error('Failed to refresh session', err);
+    }
+  }, [clearSession, startSession]);
+
   useEffect(() => {
     // Check local storage on mount
     const storedToken = localStorage.getItem('token');
     const storedUser = localStorage.getItem('user');
+    const storedExpiry = localStorage.getItem('expires_at');
 
     if (storedToken && storedUser) {
       setToken(storedToken);
       setUser(JSON.parse(storedUser));
+      setExpiresAt(storedExpiry);
     }
     setIsLoading(false);
   }, []);
 
-  const login = (newToken: string, newUser: User) => {
-    setToken(newToken);
-    setUser(newUser);
-    setError(null);
-    localStorage.setItem('token', newToken);
-    localStorage.setItem('user', JSON.stringify(newUser));
-  };
-
-  const logout = () => {
-    setToken(null);
-    setUser(null);
-    localStorage.removeItem('token');
-    localStorage.removeItem('user');
+  useEffect(() => {
+    if (!token || !expiresAt) return;
+    const delay = new Date(expiresAt).getTime() - Date.now() - REFRESH_MARGIN_MS;
+    const timer = setTimeout(refreshSession, Math.max(delay, 0));
+    return () => clearTimeout(timer);
+  }, [token, expiresAt, refreshSession]);
+
+  const logout = async () => {
+    const currentToken = localStorage.getItem('token');
+    clearSession();
+    if (!currentToken) return;
+    try {
+      // Revoke the session server-side so the refresh token stops working
+      await fetch(`${API_URL}/auth/logout`, {
+        method: 'POST',
+        headers: { Authorization: `Bearer ${currentToken}` },
+      });
+    } catch (err) {
+      console.error('Failed to revoke session', err);
+    }
   };
 
   const loginWithGoogle = () => {
       // Redirect to backend OAuth
-      window.location.href = 'http://localhost:8081/api/auth/google/login';
+      window.location.href = `${API_URL}/auth/google/login`;
   };
 
   const loginWithGithub = () => {
       // Redirect to backend OAuth
-      window.location.href = 'http://localhost:8081/api/auth/github/login';
+      window.location.href = `${API_URL}/auth/github/login`;
   };
 
   const loginWithEmail = async (email: string, password: string) => {
     setIsLoading(true);
     setError(null);
     try {
-      const response = await fetch('http://localhost:8081/api/login', {
+      const response = await fetch(`${API_URL}/login`, {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({ email, password }),
@@ -81,7 +162,7 @@ export const AuthProvider = ({ children }: { children: ReactNode }) => {
         throw new Error(data.error || 'Login failed');
       }
 
-      login(data.token, data.user);
+      startSession(data);
     } catch (err: any) {
       setError(err.message);
       throw err;
@@ -94,7 +175,7 @@ export const AuthProvider = ({ children }: { children: ReactNode }) => {
     setIsLoading(true);
     setError(null);
     try {
-      const response = await fetch('http://localhost:8081/api/signup', {
+      const response = await fetch(`${API_URL}/signup`, {
         method: 'POST',
         headers: { 'Content-Type': 'application/json' },
         body: JSON.stringify({ name, email, password }),
@@ -106,7 +187,7 @@ export const AuthProvider = ({ children }: { children: ReactNode }) => {
         throw new Error(data.error || 'Signup failed');
       }
 
-      login(data.token, data.user);
+      startSession(data);
     } catch (err: any) {
       setError(err.message);
       throw err;
@@ -0,0 +1,181 @@
+import { Code2 } from 'lucide-react';
+import { ReactNode, useEffect, useRef, useState } from 'react';
+import { useNavigate, useSearchParams } from 'react-router-dom';
+import { authService } from '../../services/auth.service';
+
+const inputClass = "w-full bg-slate-900/50 border border-slate-800 rounded-xl py-3.5 px-4 text-white placeholder:text-slate-600 focus:ring-2 focus:ring-brand-500/20 focus:border-brand-500 outline-none transition-all font-sans";
+const buttonClass = "w-full py-3.5 rounded-xl bg-gradient-to-r from-brand-600 to-purple-600 text-white font-semibold hover:opacity-90 disabled:opacity-50 transition-opacity";
+
+const AuthCard = ({ title, subtitle, children }: { title: string; subtitle: string; children: ReactNode }) => {
+    const navigate = useNavigate();
+    return (
+        <div className="min-h-screen w-full flex items-center justify-center bg-slate-950 text-white font-sans px-6">
+            <div className="max-w-md w-full">
+                <div onClick={() => navigate('/')} className="flex items-center gap-3 cursor-pointer mb-10">
+                    <div className="w-10 h-10 bg-gradient-to-br from-brand-600 to-purple-600 rounded-xl flex items-center justify-center shadow-lg shadow-brand-500/20">
+                        <Code2 className="w-6 h-6 text-white" />
+                    </div>
+                    <span className="font-bold text-xl tracking-tight">Code Anyone</span>
+                </div>
+                <h1 className="text-3xl font-bold tracking-tight mb-3">{title}</h1>
+                <p className="text-slate-400 mb-8">{subtitle}</p>
+                {children}
+                <button onClick={() => navigate('/login')} className="mt-8 text-sm text-brand-400 hover:text-brand-300 font-medium">
+                    Back to sign in
+                </button>
+            </div>
+        </div>
+    );
+};
+
+const Notice = ({ kind, children }: { kind: 'error' | 'success'; children: ReactNode }) => (
+    <div className={`mb-6 p-4 rounded-xl border text-sm ${kind === 'error'
+        ? 'bg-red-500/10 border-red-500/20 text-red-400'
+        : 'bg-green-500/10 border-green-500/20 text-green-400'}`}>
+        {children}
+    </div>
+);
+
+export const ForgotPassword = () => {
+    const [email, setEmail] = useState('');
+    const [message, setMessage] = useState<string | null>(null);
+    const [error, setError] = useState<string | null>(null);
+    const [isLoading, setIsLoading] = useState(false);
+
+    const handleSubmit = async (e: React.FormEvent) => {
+        e.preventDefault();
+        setIsLoading(true);
+        setError(null);
+        try {
+            const data = await authService.forgotPassword(email);
+            setMessage(data.message);
+        } catch (err: any) {
+            setError(err.message);
+        } finally {
+            setIsLoading(false);
+        }
+    };
+
+    return (
+        <AuthCard title="Forgot your password?" subtitle="Enter your email and we'll send you a link to reset it.">
+            {error && <Notice kind="error">{error}</Notice>}
+            {message ? (
+                <Notice kind="success">{message}</Notice>
+            ) : (
+                <form className="space-y-5" onSubmit={handleSubmit}>
+                    <input type="email" className={inputClass} placeholder="name@company.com"
+                        value={email} onChange={(e) => setEmail(e.target.value)} required />
+                    <button type="submit" className={buttonClass} disabled={isLoading}>
+                        {isLoading ? 'Sending...' : 'Send reset link'}
+                    </button>
+                </form>
+            )}
+        </AuthCard>
+    );
+};
+
+export const ResetPassword = () => {
+    const [searchParams] = useSearchParams();
+    const navigate = useNavigate();
+    const token = searchParams.get('token') || '';
+    const [password, setPassword] = useState('');
+    const [confirm, setConfirm] = useState('');
+    const [error, setError] = useState<string | null>(null);
+    const [done, setDone] = useState(false);
+    const [isLoading, setIsLoading] = useState(false);
+
+    const handleSubmit = async (e: React.FormEvent) => {
+        e.preventDefault();
+        if (password !== confirm) {
+            setError('Passwords do not match');
+            return;
+        }
+        setIsLoading(true);
+        setError(null);
+        try {
+            await authService.resetPassword(token, password);
+            setDone(true);
+        } catch (err: any) {
+            setError(err.message);
+        } finally {
+            setIsLoading(false);
+        }
+    };
+
+    if (!token) {
+        return (
+            <AuthCard title="Reset password" subtitle="This reset link is incomplete.">
+                <Notice kind="error">Please use the link from your email, or request a new one.</Notice>
+                <button onClick={() => navigate('/forgot-password')} className={buttonClass}>Request a new link</button>
+            </AuthCard>
+        );
+    }
+
+    return (
+        <AuthCard title="Choose a new password" subtitle="You'll be signed out of all your devices.">
+            {error && <Notice kind="error">{error}</Notice>}
+            {done ? (
+                <>
+                    <Notice kind="success">Your password has been reset.</Notice>
+                    <button onClick={() => navigate('/login')} className={buttonClass}>Sign in</button>
+                </>
+            ) : (
+                <form className="space-y-5" onSubmit={handleSubmit}>
+                    <input type="password" className={inputClass} placeholder="New password"
+                        value={password} onChange={(e) => setPassword(e.target.value)} required />
+                    <input type="password" className={inputClass} placeholder="Confirm new password"
+                        value={confirm} onChange={(e) => setConfirm(e.target.value)} required />
+                    <button type="submit" className={buttonClass} disabled={isLoading}>
+                        {isLoading ? 'Saving...' : 'Reset password'}
+                    </button>
+                </form>
+            )}
+        </AuthCard>
+    );
+};
+
+export const VerifyEmail = () => {
+    const [searchParams] = useSearchParams();
+    const navigate = useNavigate();
+    const [status, setStatus] = useState<'pending' | 'success' | 'error'>('pending');
+    const [error, setError] = useState<string | null>(null);
+    const hasVerified = useRef(false);
+
+    useEffect(() => {
+        // Tokens are single-use, so make sure StrictMode's double effect doesn't burn it
+        if (hasVerified.current) return;
+        hasVerified.current = true;
+
+        const token = searchParams.get('token');
+        if (!token) {
+            setStatus('error');
+            setError('This verification link is incomplete.');
+            return;
+        }
+        authService.verifyEmail(token)
+            .then(() => {
+                setStatus('success');
+                const storedUser = localStorage.getItem('user');
+                if (storedUser) {
+                    localStorage.setItem('user', JSON.stringify({ ...JSON.parse(storedUser), email_verified: true }));
+                }
+            })
+            .catch((err) => {
+                setStatus('error');
+                setError(err.message);
+            });
+    }, [searchParams]);
+
+    return (
+        <AuthCard title="Verify your email" subtitle="Confirming your email address keeps your account secure.">
+            {status === 'pending' && <p className="text-slate-400">Verifying...</p>}
+            {status === 'error' && <Notice kind="error">{error}</Notice>}
+            {status === 'success' && (
+                <>
+                    <Notice kind="success">Your email address has been verified.</Notice>
+                    <button onClick={() => navigate('/dashboard')} className={buttonClass}>Continue</button>
+                </>
+            )}
+        </AuthCard>
+    );
+};
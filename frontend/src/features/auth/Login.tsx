@@ -2,6 +2,7 @@ import { Code2 } from 'lucide-react';
 import { useEffect, useRef, useState } from 'react';
 import { useNavigate, useSearchParams } from 'react-router-dom';
 import { useAuth } from '../../context/AuthContext';
+import { authService } from '../../services/auth.service';
 
 export const Login = () => {
     const { loginWithGoogle, loginWithGithub, loginWithEmail, login, error, isLoading } = useAuth();
@@ -9,24 +10,25 @@ export const Login = () => {
     const [searchParams] = useSearchParams();
     const [email, setEmail] = useState('');
     const [password, setPassword] = useState('');
+    const [callbackError, setCallbackError] = useState<string | null>(null);
     const hasProcessedCallback = useRef(false);
 
     useEffect(() => {
-        const token = searchParams.get('token');
-        const userId = searchParams.get('user_id');
-        const name = searchParams.get('name');
-        const emailParam = searchParams.get('email');
-        
-        if (token && !hasProcessedCallback.current) {
+        // OAuth callback: the backend hands us a one-time code, never the tokens
+        const code = searchParams.get('code');
+        const oauthError = searchParams.get('error');
+
+        if (oauthError) {
+            setCallbackError(oauthError);
+        }
+        if (code && !hasProcessedCallback.current) {
             hasProcessedCallback.current = true;
-            const user = {
-                id: Number(userId) || 0,
-                name: name || 'User',
-                email: emailParam || '',
-            };
-            
-            login(token, user);
-            navigate('/dashboard');
+            authService.exchangeOAuthCode(code)
+                .then((data) => {
+                    login(data.token, data.user, data.refresh_token, data.expires_at);
+                    navigate('/dashboard');
+                })
+                .catch((err) => setCallbackError(err.message));
         }
     }, [searchParams]);
 
@@ -148,12 +150,12 @@ export const Login = () => {
                     </div>
 
                     {/* Error Message */}
-                    {error && (
+                    {(error || callbackError) && (
                         <div className="mb-8 p-4 bg-red-500/10 border border-red-500/20 rounded-xl flex items-start gap-3 text-red-400 text-sm animate-in slide-in-from-top-2">
                             <svg className="w-5 h-5 flex-shrink-0 mt-0.5" fill="none" viewBox="0 0 24 24" stroke="currentColor">
                                 <path strokeLinecap="round" strokeLinejoin="round" strokeWidth={2} d="M12 8v4m0 4h.01M21 12a9 9 0 11-18 0 9 9 0 0118 0z" />
                             </svg>
-                            {error}
+                            {error || callbackError}
                         </div>
                     )}
 
@@ -181,7 +183,7 @@ export const Login = () => {
                          <div className="space-y-1.5">
                             <div className="flex justify-between items-center ml-1">
                                 <label className="block text-sm font-medium text-slate-300">Password</label>
-                                <button type="button" className="text-sm text-brand-400 hover:text-brand-300 font-medium transition-colors">Forgot password?</button>
+                                <button type="button" onClick={() => navigate('/forgot-password')} className="text-sm text-brand-400 hover:text-brand-300 font-medium transition-colors">Forgot password?</button>
                             </div>
                             <div className="relative group">
                                 <div className="absolute inset-y-0 left-0 pl-4 flex items-center pointer-events-none">
@@ -0,0 +1,170 @@
+import { useEffect, useState } from 'react';
+import { useNavigate } from 'react-router-dom';
+import { useAuth } from '../../context/AuthContext';
+import { Comment, communityService } from '../../services/community.service';
+
+interface CommentThreadProps {
+  postId: number;
+  onCountChange?: (delta: number) => void;
+}
+
+const CommentForm = ({ initial = '', submitLabel, onSubmit, onCancel }: {
+  initial?: string;
+  submitLabel: string;
+  onSubmit: (content: string) => Promise<void>;
+  onCancel?: () => void;
+}) => {
+  const [content, setContent] = useState(initial);
+  const [isSaving, setIsSaving] = useState(false);
+
+  const handleSubmit = async (e: React.FormEvent) => {
+    e.preventDefault();
+    if (!content.trim()) return;
+    setIsSaving(true);
+    try {
+      await onSubmit(content);
+      setContent('');
+    } catch {
+      // The thread shows the error; keep the draft so it can be retried
+    } finally {
+      setIsSaving(false);
+    }
+  };
+
+  return (
+    <form onSubmit={handleSubmit} className="space-y-2">
+      <textarea
+        value={content}
+        onChange={(e) => setContent(e.target.value)}
+        rows={2}
+        placeholder="Add to the discussion"
+        className="w-full bg-slate-950 border border-slate-800 rounded-lg p-3 text-sm text-white placeholder:text-slate-600 focus:border-brand-500 outline-none resize-y"
+      />
+      <div className="flex gap-2">
+        <button type="submit" disabled={isSaving || !content.trim()} className="px-3 py-1.5 rounded-lg bg-brand-600 text-white text-xs font-bold hover:bg-brand-500 disabled:opacity-50">
+          {submitLabel}
+        </button>
+        {onCancel && (
+          <button type="button" onClick={onCancel} className="px-3 py-1.5 rounded-lg text-slate-400 text-xs font-bold hover:text-white">
+            Cancel
+          </button>
+        )}
+      </div>
+    </form>
+  );
+};
+
+export const CommentThread = ({ postId, onCountChange }: CommentThreadProps) => {
+  const { token, user } = useAuth();
+  const navigate = useNavigate();
+  const [comments, setComments] = useState<Comment[]>([]);
+  const [isLoading, setIsLoading] = useState(true);
+  const [replyTo, setReplyTo] = useState<number | null>(null);
+  const [editing, setEditing] = useState<number | null>(null);
+  const [error, setError] = useState<string | null>(null);
+  const [notice, setNotice] = useState<string | null>(null);
+
+  const load = async () => {
+    try {
+      setComments(await communityService.getComments(postId));
+    } catch (err: any) {
+      setError(err.message);
+    } finally {
+      setIsLoading(false);
+    }
+  };
+
+  useEffect(() => {
+    load();
+  }, [postId]);
+
+  const run = async (action: () => Promise<unknown>) => {
+    if (!token) {
+      navigate('/login');
+      return;
+    }
+    setError(null);
+    setNotice(null);
+    try {
+      await action();
+      await load();
+    } catch (err: any) {
+      setError(err.message);
+      throw err;
+    }
+  };
+
+  const create = (content: string, parentId?: number) => run(async () => {
+    const comment = await communityService.createComment(token!, postId, content, parentId);
+    setReplyTo(null);
+    if (comment.status === 'pending') {
+      setNotice('Your comment will appear once a moderator has reviewed it.');
+    } else {
+      onCountChange?.(1);
+    }
+  });
+
+  const update = (id: number, content: string) => run(async () => {
+    await communityService.updateComment(token!, id, content);
+    setEditing(null);
+  });
+
+  const remove = (id: number) => {
+    if (!window.confirm('Delete this comment?')) return;
+    run(async () => {
+      await communityService.deleteComment(token!, id);
+      onCountChange?.(-1);
+    }).catch(() => {});
+  };
+
+  const renderComment = (comment: Comment, depth: number) => (
+    <div key={comment.id} className={depth > 0 ? 'pl-4 border-l border-slate-800' : ''}>
+      <div className="py-3">
+        {comment.deleted ? (
+          <div className="text-sm italic text-slate-600">[deleted]</div>
+        ) : editing === comment.id ? (
+          <CommentForm initial={comment.content} submitLabel="Save" onSubmit={(c) => update(comment.id, c)} onCancel={() => setEditing(null)} />
+        ) : (
+          <>
+            <div className="flex items-center gap-2 text-xs text-slate-500 mb-1">
+              <span className="font-bold text-slate-300">{comment.author_name || 'Anonymous'}</span>
+              <span>{new Date(comment.created_at).toLocaleString()}</span>
+              {comment.updated_at && <span>(edited)</span>}
+            </div>
+            <div className="text-sm text-slate-200 whitespace-pre-wrap">{comment.content}</div>
+            <div className="flex gap-3 mt-1 text-xs text-slate-500">
+              <button onClick={() => setReplyTo(replyTo === comment.id ? null : comment.id)} className="hover:text-white">Reply</button>
+              {user?.id === comment.user_id && (
+                <>
+                  <button onClick={() => setEditing(comment.id)} className="hover:text-white">Edit</button>
+                  <button onClick={() => remove(comment.id)} className="hover:text-red-400">Delete</button>
+                </>
+              )}
+            </div>
+          </>
+        )}
+        {replyTo === comment.id && (
+          <div className="mt-2">
+            <CommentForm submitLabel="Reply" onSubmit={(c) => create(c, comment.id)} onCancel={() => setReplyTo(null)} />
+          </div>
+        )}
+      </div>
+      {comment.replies.map((reply) => renderComment(reply, depth + 1))}
+    </div>
+  );
+
+  return (
+    <div className="mt-4 pt-4 border-t border-slate-800">
+      {error && <div className="mb-3 text-sm text-red-400">{error}</div>}
+      {notice && <div className="mb-3 text-sm text-amber-300">{notice}</div>}
+      <CommentForm submitLabel="Comment" onSubmit={(c) => create(c)} />
+      {isLoading ? (
+        <div className="py-4 text-sm text-slate-500">Loading comments...</div>
+      ) : comments.length === 0 ? (
+        <div className="py-4 text-sm text-slate-500">No comments yet. Start the conversation!</div>
+      ) : (
+        <div className="mt-2">{comments.map((c) => renderComment(c, 0))}</div>
+      )}
+    </div>
+  );
+};
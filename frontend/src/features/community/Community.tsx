@@ -1,10 +1,10 @@
 
 import {
     Bold as BoldIcon, BookOpen, Code as CodeIcon,
-    Hash, Heart,
+    EyeOff, Flag, Hash, Heart,
     Image as ImageIcon, Italic as ItalicIcon, Layout, Link as LinkIcon,
-    List as ListIcon, MessageCircle, MessageSquare,
-    PenTool, Search, Share2,
+    List as ListIcon, Lock, MessageCircle, MessageSquare,
+    PenTool, Share2,
     Trophy,
     X
 } from 'lucide-react';
@@ -12,7 +12,17 @@ import { useEffect, useState } from 'react';
 import ReactMarkdown from 'react-markdown';
 import { useNavigate } from 'react-router-dom';
 import { useAuth } from '../../context/AuthContext';
-import { communityService, Post } from '../../services/community.service';
+import { authService } from '../../services/auth.service';
+import { communityService, Post, PostAction, PostSort, REACTION_TYPES, ReactionType, REPORT_REASONS, ReportReason } from '../../services/community.service';
+import { CommentThread } from './CommentThread';
+import { SearchBox } from './SearchBox';
+
+const REACTION_EMOJI: Record<ReactionType, string> = {
+  like: '👍',
+  love: '❤️',
+  celebrate: '🎉',
+  insightful: '💡',
+};
 
 export const Community = () => {
   const { token, user } = useAuth();
@@ -28,16 +38,73 @@ export const Community = () => {
   const [isPreview, setIsPreview] = useState(false);
 
   const [filter, setFilter] = useState('');
+  const [sort, setSort] = useState<PostSort>('newest');
+  const [author, setAuthor] = useState<{ id: number; name: string } | null>(null);
+  const [nextCursor, setNextCursor] = useState('');
+  const [isLoadingMore, setIsLoadingMore] = useState(false);
+  const [openThread, setOpenThread] = useState<number | null>(null);
+  const [reporting, setReporting] = useState<number | null>(null);
+  const [reported, setReported] = useState<number[]>([]);
+  const isModerator = user?.role === 'moderator' || user?.role === 'admin';
+
+  const adjustCommentCount = (postId: number, delta: number) => {
+    setPosts((prev) => prev.map((p) => p.id === postId ? { ...p, comment_count: p.comment_count + delta } : p));
+  };
+
+  const showPost = (postId: number) => {
+    setOpenThread(postId);
+    document.getElementById(`post-${postId}`)?.scrollIntoView({ behavior: 'smooth', block: 'start' });
+  };
+
+  const handleReact = async (postId: number, reaction: ReactionType) => {
+    if (!token) return navigate('/login');
+    try {
+      const summary = await communityService.toggleReaction(token, postId, reaction);
+      setPosts((prev) => prev.map((p) => p.id === postId
+        ? { ...p, likes: summary.likes, reactions: summary.reactions, viewer_reacted: summary.viewer_reacted }
+        : p));
+    } catch (error) {
+      console.error('Failed to react:', error);
+    }
+  };
+
+  const handleReport = async (postId: number, reason: ReportReason) => {
+    if (!token) return navigate('/login');
+    setReporting(null);
+    try {
+      await communityService.reportPost(token, postId, reason);
+      setReported((prev) => [...prev, postId]);
+    } catch (error: any) {
+      // A repeat report still means the post is flagged by this user
+      if (error.status === 409) setReported((prev) => [...prev, postId]);
+      else console.error('Failed to report post:', error);
+    }
+  };
+
+  const handleModerate = async (postId: number, action: PostAction) => {
+    if (!token) return;
+    try {
+      const updated = await communityService.moderatePost(token, postId, action);
+      setPosts((prev) => updated.status === 'visible'
+        ? prev.map((p) => p.id === postId ? { ...p, locked: updated.locked } : p)
+        : prev.filter((p) => p.id !== postId));
+    } catch (error) {
+      console.error('Failed to moderate post:', error);
+    }
+  };
 
   useEffect(() => {
     loadPosts();
-  }, [token, filter]);
+  }, [token, filter, sort, author]);
+
+  const listOptions = () => ({ topic: filter, sort, author: author?.id });
 
   const loadPosts = async () => {
     setIsLoading(true);
     try {
-      const data = await communityService.getPosts(token || undefined, filter);
-      setPosts(data);
+      const page = await communityService.getPosts(token || undefined, listOptions());
+      setPosts(page.posts);
+      setNextCursor(page.next_cursor);
     } catch (error) {
       console.error('Failed to load posts:', error);
     } finally {
@@ -45,6 +112,20 @@ export const Community = () => {
     }
   };
 
+  const loadMore = async () => {
+    if (!nextCursor) return;
+    setIsLoadingMore(true);
+    try {
+      const page = await communityService.getPosts(token || undefined, { ...listOptions(), cursor: nextCursor });
+      setPosts((prev) => [...prev, ...page.posts]);
+      setNextCursor(page.next_cursor);
+    } catch (error) {
+      console.error('Failed to load more posts:', error);
+    } finally {
+      setIsLoadingMore(false);
+    }
+  };
+
   const handleSubmit = async (e: React.FormEvent) => {
     e.preventDefault();
     if (!token) return navigate('/login');
@@ -52,13 +133,23 @@ export const Community = () => {
 
     try {
         const newPost = await communityService.createPost(token, title, content, topic);
-        setPosts([newPost, ...posts]);
+        if (newPost.status === 'pending') {
+            alert('Thanks! Your post will appear once a moderator has reviewed it.');
+        } else {
+            setPosts([newPost, ...posts]);
+        }
         setIsCreating(false);
         setTitle('');
         setContent('');
         setTopic('General');
-    } catch (error) {
+    } catch (error: any) {
        console.error('Failed to create post:', error);
+       // 403: the server requires a verified email before posting
+       if (error.status === 403 && window.confirm(`${error.message}. Send the verification email again?`)) {
+           authService.resendVerification(token)
+               .then(() => alert('Verification email sent. Please check your inbox.'))
+               .catch((err) => alert(err.message));
+       }
     }
   };
 
@@ -78,14 +169,7 @@ export const Community = () => {
            {/* ... Header Content ... */}
            <div className="max-w-[1400px] mx-auto px-4 h-16 flex items-center justify-between gap-4">
                <div className="flex items-center gap-4 flex-1 max-w-xl">
-                   <div className="relative w-full group">
-                       <input 
-                           type="text" 
-                           placeholder="Search..." 
-                           className="w-full bg-slate-950 border border-slate-700/50 rounded-lg px-4 py-2 pl-10 text-sm focus:border-brand-500 outline-none transition-all placeholder:text-slate-600 group-hover:bg-slate-950/80"
-                       />
-                       <Search className="absolute left-3 top-2.5 w-4 h-4 text-slate-500 group-focus-within:text-brand-400 transition-colors" />
-                   </div>
+                   <SearchBox onSelectPost={showPost} />
                </div>
                
                <div className="flex items-center gap-3">
@@ -269,6 +353,29 @@ export const Community = () => {
                    </div>
                )}
 
+               {/* Sort & Filters */}
+               <div className="flex flex-wrap items-center gap-2">
+                   {([
+                       { value: 'newest', label: 'Latest' },
+                       { value: 'trending', label: 'Trending' },
+                       { value: 'top', label: 'Top' },
+                       { value: 'discussed', label: 'Most Discussed' },
+                   ] as { value: PostSort; label: string }[]).map((item) => (
+                       <button
+                           key={item.value}
+                           onClick={() => setSort(item.value)}
+                           className={`px-3 py-1.5 rounded-lg text-sm font-bold transition-colors ${sort === item.value ? 'bg-slate-800 text-white' : 'text-slate-400 hover:text-white hover:bg-slate-800/50'}`}
+                       >
+                           {item.label}
+                       </button>
+                   ))}
+                   {author && (
+                       <button onClick={() => setAuthor(null)} className="ml-auto flex items-center gap-1 px-3 py-1.5 rounded-full text-xs font-bold bg-brand-500/20 text-brand-300 hover:bg-brand-500/30">
+                           By {author.name} <X className="w-3 h-3" />
+                       </button>
+                   )}
+               </div>
+
                {/* Posts Feed */}
                <div className="space-y-4">
                    {isLoading ? (
@@ -283,7 +390,7 @@ export const Community = () => {
                        </div>
                    ) : (
                        posts.map(post => (
-                           <div key={post.id} className="group bg-slate-900 border border-slate-800 hover:border-slate-700 rounded-xl overflow-hidden transition-all duration-300 hover:shadow-2xl hover:shadow-black/50">
+                           <div key={post.id} id={`post-${post.id}`} className="group bg-slate-900 border border-slate-800 hover:border-slate-700 rounded-xl overflow-hidden transition-all duration-300 hover:shadow-2xl hover:shadow-black/50">
                                {/* Random Cover Gradient based on ID */}
                                {post.id % 3 === 0 && (
                                    <div className="h-32 w-full bg-gradient-to-r from-brand-600 via-purple-600 to-indigo-600 opacity-80 group-hover:opacity-100 transition-opacity"></div>
@@ -295,7 +402,7 @@ export const Community = () => {
                                            {post.author_name?.[0]?.toUpperCase() || 'U'}
                                        </div>
                                        <div>
-                                           <div className="font-bold text-white text-sm hover:text-brand-400 cursor-pointer">{post.author_name || 'Anonymous'}</div>
+                                           <div onClick={() => setAuthor({ id: post.user_id, name: post.author_name || 'Anonymous' })} className="font-bold text-white text-sm hover:text-brand-400 cursor-pointer">{post.author_name || 'Anonymous'}</div>
                                            <div className="text-xs text-slate-500">{new Date(post.created_at).toLocaleDateString()}</div>
                                        </div>
                                    </div>
@@ -303,6 +410,7 @@ export const Community = () => {
                                    <div className="pl-0 sm:pl-12">
                                        <h2 className="text-2xl font-black text-white mb-3 hover:text-brand-400 cursor-pointer leading-tight">
                                            {post.title}
+                                           {post.locked && <Lock className="inline w-4 h-4 ml-2 text-slate-500" aria-label="Locked" />}
                                        </h2>
                                        
                                        <div className="flex flex-wrap gap-2 mb-4">
@@ -313,28 +421,84 @@ export const Community = () => {
 
                                        <div className="flex items-center justify-between pt-2">
                                            <div className="flex items-center gap-6">
-                                               <button className="flex items-center gap-2 text-slate-400 hover:text-white hover:bg-slate-800 px-2 py-1 rounded-lg transition-colors">
-                                                   <Heart className="w-5 h-5" />
-                                                   <span className="text-sm">{post.likes} <span className="hidden sm:inline">Reactions</span></span>
-                                               </button>
-                                               <button className="flex items-center gap-2 text-slate-400 hover:text-white hover:bg-slate-800 px-2 py-1 rounded-lg transition-colors">
+                                               <div className="flex items-center gap-1">
+                                                   <Heart className="w-5 h-5 text-slate-400" />
+                                                   {REACTION_TYPES.map((type) => {
+                                                       const reacted = post.viewer_reacted?.includes(type);
+                                                       return (
+                                                           <button
+                                                               key={type}
+                                                               onClick={() => handleReact(post.id, type)}
+                                                               title={type}
+                                                               className={`flex items-center gap-1 px-2 py-1 rounded-lg text-sm transition-colors ${reacted ? 'bg-brand-500/20 text-brand-300' : 'text-slate-400 hover:text-white hover:bg-slate-800'}`}
+                                                           >
+                                                               <span>{REACTION_EMOJI[type]}</span>
+                                                               {(post.reactions?.[type] ?? 0) > 0 && <span>{post.reactions[type]}</span>}
+                                                           </button>
+                                                       );
+                                                   })}
+                                                   <span className="text-sm text-slate-400 ml-1">{post.likes} <span className="hidden sm:inline">Reactions</span></span>
+                                               </div>
+                                               <button onClick={() => setOpenThread(openThread === post.id ? null : post.id)} className="flex items-center gap-2 text-slate-400 hover:text-white hover:bg-slate-800 px-2 py-1 rounded-lg transition-colors">
                                                    <MessageCircle className="w-5 h-5" />
-                                                   <span className="text-sm">Comments</span>
+                                                   <span className="text-sm">{post.comment_count} <span className="hidden sm:inline">Comments</span></span>
                                                </button>
                                            </div>
                                            
-                                           <div className="flex items-center gap-2 text-xs text-slate-500">
+                                           <div className="relative flex items-center gap-2 text-xs text-slate-500">
                                                <span>3 min read</span>
+                                               {isModerator && (
+                                                   <>
+                                                       <button onClick={() => handleModerate(post.id, post.locked ? 'unlock' : 'lock')} title={post.locked ? 'Unlock comments' : 'Lock comments'} className={`p-2 hover:bg-slate-800 rounded-lg transition-colors ${post.locked ? 'text-amber-400' : 'text-slate-400 hover:text-white'}`}>
+                                                           <Lock className="w-4 h-4" />
+                                                       </button>
+                                                       <button onClick={() => handleModerate(post.id, 'hide')} title="Hide post" className="p-2 hover:bg-slate-800 rounded-lg text-slate-400 hover:text-white transition-colors">
+                                                           <EyeOff className="w-4 h-4" />
+                                                       </button>
+                                                   </>
+                                               )}
+                                               {user && user.id !== post.user_id && (
+                                                   <button
+                                                       onClick={() => setReporting(reporting === post.id ? null : post.id)}
+                                                       disabled={reported.includes(post.id)}
+                                                       title={reported.includes(post.id) ? 'Reported' : 'Report post'}
+                                                       className={`p-2 rounded-lg transition-colors ${reported.includes(post.id) ? 'text-red-400' : 'text-slate-400 hover:text-white hover:bg-slate-800'}`}
+                                                   >
+                                                       <Flag className="w-4 h-4" />
+                                                   </button>
+                                               )}
+                                               {reporting === post.id && (
+                                                   <div className="absolute right-0 bottom-full mb-2 z-10 w-44 rounded-lg border border-slate-700 bg-slate-900 shadow-xl py-1">
+                                                       {REPORT_REASONS.map((reason) => (
+                                                           <button key={reason} onClick={() => handleReport(post.id, reason)} className="block w-full text-left px-3 py-1.5 text-sm text-slate-300 hover:bg-slate-800 hover:text-white capitalize">
+                                                               {reason.replace('_', ' ')}
+                                                           </button>
+                                                       ))}
+                                                   </div>
+                                               )}
                                                <button className="p-2 hover:bg-slate-800 rounded-lg text-slate-400 hover:text-white transition-colors">
                                                    <Share2 className="w-4 h-4" />
                                                </button>
                                            </div>
                                        </div>
+
+                                       {openThread === post.id && (
+                                           <CommentThread postId={post.id} onCountChange={(delta) => adjustCommentCount(post.id, delta)} />
+                                       )}
                                    </div>
                                </div>
                            </div>
                        ))
                    )}
+                   {!isLoading && nextCursor && (
+                       <button
+                           onClick={loadMore}
+                           disabled={isLoadingMore}
+                           className="w-full py-3 rounded-xl border border-slate-800 text-sm font-bold text-slate-300 hover:text-white hover:bg-slate-900 transition-colors disabled:opacity-50"
+                       >
+                           {isLoadingMore ? 'Loading...' : 'Load more'}
+                       </button>
+                   )}
                </div>
            </div>
 
@@ -372,14 +536,15 @@ export const Community = () => {
                        <h3 className="font-bold text-white">#discuss</h3>
                    </div>
                    <div className="divide-y divide-slate-800">
-                       <div className="p-4 hover:bg-slate-800/50 transition-colors cursor-pointer">
-                           <div className="text-sm font-medium text-slate-200 hover:text-brand-300">What was your big "Aben" moment in coding?</div>
-                           <div className="mt-1 text-xs text-slate-500">45 comments</div>
-                       </div>
-                       <div className="p-4 hover:bg-slate-800/50 transition-colors cursor-pointer">
-                           <div className="text-sm font-medium text-slate-200 hover:text-brand-300">Rust vs Go in 2025: Thoughts?</div>
-                           <div className="mt-1 text-xs text-slate-500">12 comments</div>
-                       </div>
+                       {[...posts].filter((p) => p.comment_count > 0).sort((a, b) => b.comment_count - a.comment_count).slice(0, 3).map((p) => (
+                           <div key={p.id} onClick={() => setOpenThread(p.id)} className="p-4 hover:bg-slate-800/50 transition-colors cursor-pointer">
+                               <div className="text-sm font-medium text-slate-200 hover:text-brand-300">{p.title}</div>
+                               <div className="mt-1 text-xs text-slate-500">{p.comment_count} {p.comment_count === 1 ? 'comment' : 'comments'}</div>
+                           </div>
+                       ))}
+                       {!posts.some((p) => p.comment_count > 0) && (
+                           <div className="p-4 text-xs text-slate-500">No discussions yet.</div>
+                       )}
                    </div>
                </div>
 
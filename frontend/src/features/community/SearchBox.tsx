@@ -0,0 +1,106 @@
+import { BookOpen, FileText, Map as MapIcon, MessageSquare, Search } from 'lucide-react';
+import { useEffect, useRef, useState } from 'react';
+import { useNavigate } from 'react-router-dom';
+import { useAuth } from '../../context/AuthContext';
+import { SearchResult, searchService } from '../../services/search.service';
+
+const TYPE_ICONS = {
+  post: MessageSquare,
+  course: BookOpen,
+  lesson: FileText,
+  roadmap: MapIcon,
+};
+
+interface SearchBoxProps {
+  onSelectPost: (postId: number) => void;
+}
+
+export const SearchBox = ({ onSelectPost }: SearchBoxProps) => {
+  const { token } = useAuth();
+  const navigate = useNavigate();
+  const [query, setQuery] = useState('');
+  const [results, setResults] = useState<SearchResult[]>([]);
+  const [isOpen, setIsOpen] = useState(false);
+  const latest = useRef('');
+
+  // Debounced so typing doesn't send a request per keystroke
+  useEffect(() => {
+    const q = query.trim();
+    latest.current = q;
+    if (!q) {
+      setResults([]);
+      return;
+    }
+    const timer = setTimeout(async () => {
+      try {
+        const found = await searchService.search(q, token || undefined);
+        if (latest.current === q) setResults(found);
+      } catch (error) {
+        console.error('Search failed:', error);
+      }
+    }, 250);
+    return () => clearTimeout(timer);
+  }, [query, token]);
+
+  const open = (result: SearchResult) => {
+    setIsOpen(false);
+    switch (result.type) {
+      case 'post':
+        onSelectPost(result.id);
+        break;
+      case 'course':
+        navigate(`/course/${result.id}`);
+        break;
+      case 'lesson':
+        navigate(`/course/${result.parent_id}`);
+        break;
+      case 'roadmap':
+        navigate(`/roadmap/view/${result.id}`);
+        break;
+    }
+  };
+
+  return (
+    <div className="relative w-full group">
+      <input
+        type="text"
+        value={query}
+        onChange={(e) => { setQuery(e.target.value); setIsOpen(true); }}
+        onFocus={() => setIsOpen(true)}
+        onBlur={() => setTimeout(() => setIsOpen(false), 150)}
+        placeholder="Search posts, courses and roadmaps..."
+        className="w-full bg-slate-950 border border-slate-700/50 rounded-lg px-4 py-2 pl-10 text-sm focus:border-brand-500 outline-none transition-all placeholder:text-slate-600 group-hover:bg-slate-950/80"
+      />
+      <Search className="absolute left-3 top-2.5 w-4 h-4 text-slate-500 group-focus-within:text-brand-400 transition-colors" />
+
+      {isOpen && query.trim() && (
+        <div className="absolute left-0 right-0 mt-2 max-h-96 overflow-y-auto bg-slate-900 border border-slate-700 rounded-lg shadow-2xl z-40">
+          {results.length === 0 ? (
+            <div className="p-4 text-sm text-slate-500">No results</div>
+          ) : (
+            results.map((result) => {
+              const Icon = TYPE_ICONS[result.type];
+              return (
+                <button
+                  key={`${result.type}-${result.id}`}
+                  onMouseDown={(e) => e.preventDefault()}
+                  onClick={() => open(result)}
+                  className="w-full text-left flex gap-3 p-3 hover:bg-slate-800 transition-colors border-b border-slate-800 last:border-0 [&_mark]:bg-brand-500/30 [&_mark]:text-white"
+                >
+                  <Icon className="w-4 h-4 mt-0.5 text-slate-500 shrink-0" />
+                  <div className="min-w-0">
+                    <div className="text-sm font-medium text-slate-200 truncate" dangerouslySetInnerHTML={{ __html: result.title }} />
+                    {result.snippet && (
+                      <div className="text-xs text-slate-500 line-clamp-2" dangerouslySetInnerHTML={{ __html: result.snippet }} />
+                    )}
+                  </div>
+                  <span className="ml-auto text-[10px] uppercase tracking-wide text-slate-600">{result.type}</span>
+                </button>
+              );
+            })
+          )}
+        </div>
+      )}
+    </div>
+  );
+};
@@ -4,7 +4,7 @@ import React, { useEffect, useState } from 'react';
 import ReactMarkdown from 'react-markdown';
 import { useNavigate, useParams } from 'react-router-dom';
 import { useAuth } from '../../context/AuthContext';
-import { chatWithTutor, simulateCodeExecution } from '../../services/gemini';
+import { chatWithTutorStream, simulateCodeExecution } from '../../services/gemini';
 import { Lesson, UserPersona } from '../../types';
 
 interface LearningStudioProps {
@@ -93,14 +93,23 @@ export const LearningStudio: React.FC<LearningStudioProps> = ({ user }) => {
         text: msg.content
       }));
       
-      const response = await chatWithTutor(
+      // Add an empty assistant message and grow it as deltas arrive
+      setChatMessages(prev => [...prev, { role: 'assistant', content: '' }]);
+
+      await chatWithTutorStream(
         chatInput,
         history,
         user?.persona || UserPersona.PROFESSIONAL,
-        code
+        code,
+        (delta) => {
+          setChatMessages(prev => {
+            const next = [...prev];
+            const last = next[next.length - 1];
+            next[next.length - 1] = { ...last, content: last.content + delta };
+            return next;
+          });
+        }
       );
-      
-      setChatMessages(prev => [...prev, { role: 'assistant', content: response }]);
     } catch (error) {
       setChatMessages(prev => [...prev, { 
         role: 'assistant', 
@@ -4,6 +4,8 @@ const API_URL = 'http://localhost:8081/api';
 
 interface AuthResponse {
   token: string;
+  refresh_token: string;
+  expires_at: string;
   user: User;
 }
 
@@ -36,11 +38,11 @@ export const authService = {
     return data;
   },
 
-  async socialLoginDemo(provider: string): Promise<AuthResponse> {
-    const response = await fetch(`${API_URL}/auth/social-demo`, {
+  async exchangeOAuthCode(code: string): Promise<AuthResponse> {
+    const response = await fetch(`${API_URL}/auth/exchange`, {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
-      body: JSON.stringify({ provider }),
+      body: JSON.stringify({ code }),
     });
 
     const data = await response.json();
@@ -48,5 +50,57 @@ export const authService = {
       throw new Error(data.error || 'Social login failed');
     }
     return data;
+  },
+
+  async forgotPassword(email: string): Promise<{ message: string }> {
+    const response = await fetch(`${API_URL}/auth/forgot-password`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ email }),
+    });
+
+    const data = await response.json();
+    if (!response.ok) {
+      throw new Error(data.error || 'Request failed');
+    }
+    return data;
+  },
+
+  async resetPassword(token: string, password: string): Promise<void> {
+    const response = await fetch(`${API_URL}/auth/reset-password`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ token, password }),
+    });
+
+    const data = await response.json();
+    if (!response.ok) {
+      throw new Error(data.error || 'Password reset failed');
+    }
+  },
+
+  async verifyEmail(token: string): Promise<void> {
+    const response = await fetch(`${API_URL}/auth/verify-email`, {
+      method: 'POST',
+      headers: { 'Content-Type': 'application/json' },
+      body: JSON.stringify({ token }),
+    });
+
+    const data = await response.json();
+    if (!response.ok) {
+      throw new Error(data.error || 'Email verification failed');
+    }
+  },
+
+  async resendVerification(token: string): Promise<void> {
+    const response = await fetch(`${API_URL}/auth/resend-verification`, {
+      method: 'POST',
+      headers: { Authorization: `Bearer ${token}` },
+    });
+
+    const data = await response.json();
+    if (!response.ok) {
+      throw new Error(data.error || 'Could not send verification email');
+    }
   }
 };
@@ -9,25 +9,93 @@ export interface Post {
   content: string;
   topic: string;
   likes: number;
+  reactions: Record<string, number>;
+  viewer_reacted: string[] | null;
+  comment_count: number;
+  status: 'visible' | 'pending' | 'hidden' | 'removed';
+  locked: boolean;
   created_at: string;
 }
 
+export type PostSort = 'newest' | 'top' | 'trending' | 'discussed';
+
+export interface PostListOptions {
+  topic?: string;
+  sort?: PostSort;
+  author?: number;
+  from?: string; // YYYY-MM-DD or RFC 3339
+  to?: string;
+  limit?: number;
+  cursor?: string;
+}
+
+export interface PostPage {
+  posts: Post[];
+  next_cursor: string;
+}
+
+export const REACTION_TYPES = ['like', 'love', 'celebrate', 'insightful'] as const;
+export type ReactionType = typeof REACTION_TYPES[number];
+
+export interface ReactionSummary {
+  post_id: number;
+  likes: number;
+  reactions: Record<string, number>;
+  viewer_reacted: string[];
+}
+
+export const REPORT_REASONS = ['spam', 'harassment', 'inappropriate', 'off_topic', 'other'] as const;
+export type ReportReason = typeof REPORT_REASONS[number];
+
+export type PostAction = 'hide' | 'unhide' | 'lock' | 'unlock' | 'remove' | 'dismiss' | 'approve';
+
+export interface Comment {
+  id: number;
+  post_id: number;
+  parent_id: number | null;
+  user_id: number;
+  author_name: string;
+  content: string;
+  created_at: string;
+  updated_at?: string;
+  deleted: boolean;
+  status: 'visible' | 'pending' | 'removed';
+  replies: Comment[];
+}
+
+const authHeaders = (token: string) => ({
+  'Content-Type': 'application/json',
+  'Authorization': `Bearer ${token}`
+});
+
+const failIfNotOk = async (response: Response, fallback: string) => {
+  if (!response.ok) {
+    const data = await response.json().catch(() => ({}));
+    throw Object.assign(new Error(data.error || fallback), { status: response.status });
+  }
+};
+
 export const communityService = {
-  getPosts: async (token?: string, topic?: string): Promise<Post[]> => {
-    let url = `${API_URL}/community/posts`;
-    if (topic && topic !== 'All') {
-        url += `?topic=${encodeURIComponent(topic)}`;
-    }
+  getPosts: async (token?: string, options: PostListOptions = {}): Promise<PostPage> => {
+    const params = new URLSearchParams();
+    if (options.topic && options.topic !== 'All') params.set('topic', options.topic);
+    if (options.sort) params.set('sort', options.sort);
+    if (options.author) params.set('author', String(options.author));
+    if (options.from) params.set('from', options.from);
+    if (options.to) params.set('to', options.to);
+    if (options.limit) params.set('limit', String(options.limit));
+    if (options.cursor) params.set('cursor', options.cursor);
 
     const headers: any = { 'Content-Type': 'application/json' };
     if (token) headers['Authorization'] = `Bearer ${token}`;
 
-    const response = await fetch(url, {
+    const query = params.toString();
+    const response = await fetch(`${API_URL}/community/posts${query ? `?${query}` : ''}`, {
         method: 'GET',
         headers
     });
     
-    if (!response.ok) throw new Error('Failed to fetch posts');
+    await failIfNotOk(response, 'Failed to fetch posts');
     return response.json();
   },
 
@@ -41,7 +109,73 @@ export const communityService = {
       body: JSON.stringify({ title, content, topic })
     });
 
-    if (!response.ok) throw new Error('Failed to create post');
+    if (!response.ok) {
+      const data = await response.json().catch(() => ({}));
+      throw Object.assign(new Error(data.error || 'Failed to create post'), { status: response.status });
+    }
     return response.json();
+  },
+
+  toggleReaction: async (token: string, postId: number, reaction: ReactionType): Promise<ReactionSummary> => {
+    const response = await fetch(`${API_URL}/community/posts/${postId}/reactions/${reaction}`, {
+      method: 'POST',
+      headers: authHeaders(token)
+    });
+    await failIfNotOk(response, 'Failed to react');
+    return response.json();
+  },
+
+  reportPost: async (token: string, postId: number, reason: ReportReason, details = ''): Promise<void> => {
+    const response = await fetch(`${API_URL}/community/posts/${postId}/report`, {
+      method: 'POST',
+      headers: authHeaders(token),
+      body: JSON.stringify({ reason, details })
+    });
+    await failIfNotOk(response, 'Failed to report post');
+  },
+
+  // Moderators and admins only
+  moderatePost: async (token: string, postId: number, action: PostAction, reason = ''): Promise<Post> => {
+    const response = await fetch(`${API_URL}/moderation/posts/${postId}`, {
+      method: 'POST',
+      headers: authHeaders(token),
+      body: JSON.stringify({ action, reason })
+    });
+    await failIfNotOk(response, 'Failed to moderate post');
+    return response.json();
+  },
+
+  getComments: async (postId: number): Promise<Comment[]> => {
+    const response = await fetch(`${API_URL}/community/posts/${postId}/comments`);
+    await failIfNotOk(response, 'Failed to fetch comments');
+    return response.json();
+  },
+
+  createComment: async (token: string, postId: number, content: string, parentId?: number): Promise<Comment> => {
+    const response = await fetch(`${API_URL}/community/posts/${postId}/comments`, {
+      method: 'POST',
+      headers: authHeaders(token),
+      body: JSON.stringify({ content, parent_id: parentId ?? null })
+    });
+    await failIfNotOk(response, 'Failed to post comment');
+    return response.json();
+  },
+
+  updateComment: async (token: string, commentId: number, content: string): Promise<Comment> => {
+    const response = await fetch(`${API_URL}/community/comments/${commentId}`, {
+      method: 'PUT',
+      headers: authHeaders(token),
+      body: JSON.stringify({ content })
+    });
+    await failIfNotOk(response, 'Failed to update comment');
+    return response.json();
+  },
+
+  deleteComment: async (token: string, commentId: number): Promise<void> => {
+    const response = await fetch(`${API_URL}/community/comments/${commentId}`, {
+      method: 'DELETE',
+      headers: authHeaders(token)
+    });
+    await failIfNotOk(response, 'Failed to delete comment');
   }
 };
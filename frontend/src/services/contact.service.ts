@@ -1,5 +1,35 @@
 const API_URL = 'http://localhost:8081/api';
 
+export type TicketStatus = 'open' | 'pending' | 'resolved';
+
+export interface TicketMessage {
+  id: number;
+  ticket_id: number;
+  author_id: number;
+  author_name: string;
+  from_staff: boolean;
+  body: string;
+  emailed_at?: string;
+  created_at: string;
+}
+
+export interface Ticket {
+  id: number;
+  first_name: string;
+  last_name: string;
+  email: string;
+  message: string;
+  status: TicketStatus;
+  created_at: string;
+  updated_at: string;
+  messages?: TicketMessage[]; // only on a single ticket
+}
+
+const authHeaders = (token: string) => ({
+  'Content-Type': 'application/json',
+  'Authorization': `Bearer ${token}`
+});
+
 export const contactService = {
   submit: async (data: { firstName: string; lastName: string; email: string; message: string }) => {
     const token = localStorage.getItem('token');
@@ -21,5 +51,28 @@ export const contactService = {
     
     if (!response.ok) throw new Error('Failed to submit contact form');
     return response.json();
+  },
+
+  // Tickets the signed-in user opened through the contact form, newest first
+  getMyTickets: async (token: string): Promise<Ticket[]> => {
+    const response = await fetch(`${API_URL}/me/tickets`, { headers: authHeaders(token) });
+    if (!response.ok) throw new Error('Failed to fetch tickets');
+    return response.json();
+  },
+
+  getMyTicket: async (token: string, id: number): Promise<Ticket> => {
+    const response = await fetch(`${API_URL}/me/tickets/${id}`, { headers: authHeaders(token) });
+    if (!response.ok) throw new Error('Failed to fetch ticket');
+    return response.json();
+  },
+
+  replyToTicket: async (token: string, id: number, body: string): Promise<TicketMessage> => {
+    const response = await fetch(`${API_URL}/me/tickets/${id}/messages`, {
+      method: 'POST',
+      headers: authHeaders(token),
+      body: JSON.stringify({ body })
+    });
+    if (!response.ok) throw new Error('Failed to send reply');
+    return response.json();
   }
 };
@@ -43,7 +43,7 @@ export const courseService = {
   },
 
   async deleteCourse(id: number, token: string): Promise<void> {
-    const response = await fetch(`${API_URL}/courses?id=${id}`, {
+    const response = await fetch(`${API_URL}/courses/${id}`, {
       method: 'DELETE',
       headers: { 
         'Authorization': `Bearer ${token}` 
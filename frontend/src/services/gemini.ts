@@ -49,6 +49,70 @@ export const chatWithTutor = async (
   }
 };
 
+// Streams the tutor reply over Server-Sent Events, calling onDelta for each
+// text fragment. Resolves with the full reply once the "done" event arrives.
+export const chatWithTutorStream = async (
+  message: string,
+  history: { role: 'user' | 'model', text: string }[],
+  persona: UserPersona,
+  currentCode: string,
+  onDelta: (text: string) => void,
+  signal?: AbortSignal
+): Promise<string> => {
+  const response = await fetch(`${BACKEND_URL}/chat/stream`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json',
+      'Accept': 'text/event-stream',
+    },
+    body: JSON.stringify({ message, history, persona, currentCode }),
+    signal,
+  });
+
+  if (!response.ok || !response.body) {
+    throw new Error('Network response was not ok');
+  }
+
+  const reader = response.body.getReader();
+  const decoder = new TextDecoder();
+  let buffer = '';
+  let fullText = '';
+
+  while (true) {
+    const { value, done } = await reader.read();
+    if (done) break;
+    buffer += decoder.decode(value, { stream: true });
+
+    // Events are separated by a blank line
+    let boundary = buffer.indexOf('\n\n');
+    while (boundary !== -1) {
+      const rawEvent = buffer.slice(0, boundary);
+      buffer = buffer.slice(boundary + 2);
+      boundary = buffer.indexOf('\n\n');
+
+      let event = 'message';
+      let data = '';
+      for (const line of rawEvent.split('\n')) {
+        if (line.startsWith('event:')) event = line.slice(6).trim();
+        else if (line.startsWith('data:')) data += line.slice(5).trim();
+      }
+      if (!data) continue;
+
+      const payload = JSON.parse(data);
+      if (event === 'delta') {
+        fullText += payload.text;
+        onDelta(payload.text);
+      } else if (event === 'done') {
+        return payload.text ?? fullText;
+      } else if (event === 'error') {
+        throw new Error(payload.error || 'Chat stream failed');
+      }
+    }
+  }
+
+  return fullText;
+};
+
 export const simulateCodeExecution = async (code: string, language: string): Promise<string> => {
   try {
     const response = await fetch(`${BACKEND_URL}/execute`, {
@@ -0,0 +1,39 @@
+const API_URL = 'http://localhost:8081/api';
+
+export interface TestResult {
+  name: string;
+  passed: boolean;
+  hidden: boolean;
+  stdin?: string;
+  expected?: string;
+  actual?: string;
+  stderr?: string;
+  timed_out?: boolean;
+}
+
+export interface SubmitLessonResponse {
+  attempt_id: number;
+  passed: boolean;
+  passed_count: number;
+  total_count: number;
+  results: TestResult[];
+  advanced: boolean;
+  current_index: number;
+}
+
+export const lessonService = {
+  submit: async (token: string, lessonId: number, code: string): Promise<SubmitLessonResponse> => {
+    const response = await fetch(`${API_URL}/lessons/${lessonId}/submit`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+        'Authorization': `Bearer ${token}`
+      },
+      body: JSON.stringify({ code })
+    });
+
+    const data = await response.json();
+    if (!response.ok) throw new Error(data.error || 'Failed to submit solution');
+    return data;
+  }
+};
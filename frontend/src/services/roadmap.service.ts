@@ -4,6 +4,7 @@ export interface RoadmapData {
     found: boolean;
     data?: {
         plan_id: number;
+        kind: 'curriculum' | 'roadmap';
         content: {
             title: string;
             description: string;
@@ -54,7 +55,7 @@ export const roadmapService = {
 
   getRoadmapById: async (id: string) => {
       // Public access
-      const response = await fetch(`${API_URL}/roadmap/view?id=${id}`);
+      const response = await fetch(`${API_URL}/roadmaps/${id}`);
       if (!response.ok) throw new Error('Failed to fetch roadmap');
       return response.json();
   }
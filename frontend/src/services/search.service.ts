@@ -0,0 +1,31 @@
+const API_URL = 'http://localhost:8081/api';
+
+export type SearchType = 'post' | 'course' | 'lesson' | 'roadmap';
+
+export interface SearchResult {
+  type: SearchType;
+  id: number;
+  parent_id?: number;
+  // HTML-escaped by the server, with matches wrapped in <mark>
+  title: string;
+  snippet: string;
+  rank: number;
+}
+
+export const searchService = {
+  search: async (query: string, token?: string, types?: SearchType[]): Promise<SearchResult[]> => {
+    const params = new URLSearchParams({ q: query });
+    if (types && types.length > 0) params.set('type', types.join(','));
+
+    const headers: any = {};
+    if (token) headers['Authorization'] = `Bearer ${token}`;
+
+    const response = await fetch(`${API_URL}/search?${params}`, { headers });
+    if (!response.ok) {
+      const data = await response.json().catch(() => ({}));
+      throw new Error(data.error || 'Search failed');
+    }
+    const data = await response.json();
+    return data.results;
+  }
+};
@@ -51,5 +51,7 @@ export interface User {
   id: number;
   name: string;
   email: string;
+  email_verified?: boolean;
+  role?: 'learner' | 'moderator' | 'admin';
   created_at?: string;
 }
\ No newline at end of file